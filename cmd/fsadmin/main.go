@@ -0,0 +1,334 @@
+// Command fsadmin là CLI quản trị chạy trực tiếp trên database và storage của server, đọc cùng config
+// với cmd/server (.env, DATABASE_URL...). Chạy từ cùng thư mục với server để thấy đúng thư mục uploads.
+//
+//	fsadmin bootstrap -username U -email E          tạo admin đầu tiên (password: ADMIN_PASSWORD hoặc stdin)
+//	fsadmin users list [-role R] [-suspended]       liệt kê user
+//	fsadmin users suspend|unsuspend <id|email>      khóa/mở khóa tài khoản
+//	fsadmin users reset-2fa [-passkeys] <id|email>  tắt TOTP (và xóa passkey) khi user mất authenticator
+//	fsadmin cleanup [-dry-run]                      xóa file hết hạn, -dry-run chỉ liệt kê
+//	fsadmin storage check                           đối chiếu metadata với file trong storage
+//	fsadmin blacklist purge                         dọn blacklist JWT đã hết hạn
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/app"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/signer"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: fsadmin <command> [flags] [args]
+
+commands:
+  bootstrap -username U -email E          create the first admin (password from ADMIN_PASSWORD or stdin)
+  users list [-role R] [-suspended]       list users
+  users suspend <id|email>                suspend an account and revoke its sessions
+  users unsuspend <id|email>              reactivate a suspended account
+  users reset-2fa [-passkeys] <id|email>  disable TOTP, -passkeys also removes passkeys
+  cleanup [-dry-run]                      delete expired files, -dry-run only lists them
+  storage check                           compare file metadata with stored files
+  blacklist purge                         remove expired JWT blacklist entries`
+
+// errUsage: sai cú pháp lệnh, in usage và thoát với mã 2.
+var errUsage = errors.New("invalid usage")
+
+// errProblems: lệnh chạy xong nhưng phát hiện vấn đề (vd. storage không khớp), thoát với mã 1.
+var errProblems = errors.New("problems found")
+
+type services struct {
+	admin service.AdminService
+	users service.UserAdminService
+}
+
+// openServices dựng service giống cmd/server: cùng config, cùng backend database và storage.
+func openServices() *services {
+	cfg := config.NewConfig()
+	repos := app.OpenRepositories(cfg)
+	store := storage.NewLocalStorage(app.UploadDir)
+
+	guard := service.NewBruteForceGuard(repos.Attempt)
+	exports := service.NewExportService(cfg, repos.Export, repos.User, repos.File, repos.Shared, repos.LoginHistory, store, signer.NewHMACSigner())
+
+	return &services{
+		admin: service.NewAdminService(cfg, repos.File, store, guard, exports),
+		users: service.NewUserAdminService(cfg.Policy, repos.User, repos.Auth, repos.WebAuthn),
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+
+	// load .env only in local
+	if os.Getenv("RAILWAY_ENVIRONMENT") == "" {
+		_ = godotenv.Load()
+	}
+
+	err := run(os.Args[1:])
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	case errors.Is(err, errProblems):
+		os.Exit(1)
+	default:
+		log.Fatal(err)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "bootstrap":
+		return bootstrap(args)
+	case "users":
+		if len(args) == 0 {
+			return errUsage
+		}
+		switch args[0] {
+		case "list":
+			return listUsers(args[1:])
+		case "suspend":
+			return suspendUser(args[1:], true)
+		case "unsuspend":
+			return suspendUser(args[1:], false)
+		case "reset-2fa":
+			return resetTwoFactor(args[1:])
+		}
+	case "cleanup":
+		return cleanup(args)
+	case "storage":
+		if len(args) == 1 && args[0] == "check" {
+			return checkStorage()
+		}
+	case "blacklist":
+		if len(args) == 1 && args[0] == "purge" {
+			return purgeBlacklist()
+		}
+	}
+	return errUsage
+}
+
+// parseFlags parse flag của một lệnh con và kiểm tra đúng số đối số còn lại.
+func parseFlags(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return nil, errUsage
+	}
+	if fs.NArg() != positional {
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
+
+// statusErr đổi *utils.ReturnStatus thành error để in ra khi thoát.
+func statusErr(status *utils.ReturnStatus) error {
+	if status == nil {
+		return nil
+	}
+	return errors.New(status.String())
+}
+
+func bootstrap(args []string) error {
+	fs := flag.NewFlagSet("bootstrap", flag.ContinueOnError)
+	username := fs.String("username", "", "username of the admin")
+	email := fs.String("email", "", "email of the admin")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if *username == "" || *email == "" {
+		return errUsage
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	user, status := openServices().users.BootstrapAdmin(context.Background(), *username, *email, password)
+	if status != nil {
+		return statusErr(status)
+	}
+
+	fmt.Printf("created admin %s <%s> (id %s)\n", user.Username, user.Email, user.Id)
+	return nil
+}
+
+// readPassword lấy password từ ADMIN_PASSWORD, nếu không có thì đọc một dòng từ stdin để không
+// lộ password trong lịch sử shell hay danh sách process.
+func readPassword() (string, error) {
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func listUsers(args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	role := fs.String("role", "", "only users with this role")
+	suspended := fs.Bool("suspended", false, "only suspended users")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	users, status := openServices().users.ListUsers(context.Background())
+	if status != nil {
+		return statusErr(status)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tUSERNAME\tROLE\tVERIFIED\tTOTP\tSTATUS")
+	for _, u := range users {
+		if (*role != "" && u.Role != *role) || (*suspended && !u.Suspended) {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", u.Id, u.Email, u.Username, u.Role, yesNo(u.EmailVerified), yesNo(u.EnableTOTP), userStatus(u))
+	}
+	return w.Flush()
+}
+
+func suspendUser(args []string, suspended bool) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	user, status := openServices().users.SetSuspended(context.Background(), args[0], suspended)
+	if status != nil {
+		return statusErr(status)
+	}
+
+	fmt.Printf("%s is now %s\n", user.Email, userStatus(*user))
+	return nil
+}
+
+func resetTwoFactor(args []string) error {
+	fs := flag.NewFlagSet("users reset-2fa", flag.ContinueOnError)
+	passkeys := fs.Bool("passkeys", false, "also remove all passkeys of the user")
+	rest, err := parseFlags(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	user, removed, status := openServices().users.ResetTwoFactor(context.Background(), rest[0], *passkeys)
+	if status != nil {
+		return statusErr(status)
+	}
+
+	fmt.Printf("TOTP disabled for %s", user.Email)
+	if *passkeys {
+		fmt.Printf(", %d passkey(s) removed", removed)
+	}
+	fmt.Println()
+	return nil
+}
+
+func cleanup(args []string) error {
+	fs := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the files that would be deleted")
+	if _, err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	svc := openServices()
+	if !*dryRun {
+		deleted, status := svc.admin.CleanupExpiredFiles(context.Background())
+		if status != nil {
+			return statusErr(status)
+		}
+		fmt.Printf("deleted %d expired file(s)\n", deleted)
+		return nil
+	}
+
+	files, status := svc.admin.PreviewCleanup(context.Background())
+	if status != nil {
+		return statusErr(status)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSIZE\tEXPIRED")
+	for _, f := range files {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", f.Id, f.FileName, f.FileSize, f.AvailableTo.Format(time.RFC3339))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d file(s) would be deleted\n", len(files))
+	return nil
+}
+
+func checkStorage() error {
+	report, status := openServices().admin.CheckStorage(context.Background())
+	if status != nil {
+		return statusErr(status)
+	}
+
+	fmt.Printf("checked %d file(s)\n", report.Checked)
+	for _, f := range report.Missing {
+		fmt.Printf("missing   %s  %s\n", f.Id, f.FileName)
+	}
+	for _, f := range report.SizeMismatch {
+		fmt.Printf("size      %s  %s (expected %d bytes)\n", f.Id, f.FileName, f.FileSize)
+	}
+	for _, name := range report.Orphans {
+		fmt.Printf("orphan    %s\n", name)
+	}
+
+	problems := len(report.Missing) + len(report.SizeMismatch) + len(report.Orphans)
+	if problems > 0 {
+		fmt.Printf("%d problem(s): %d missing, %d size mismatch, %d orphan\n", problems, len(report.Missing), len(report.SizeMismatch), len(report.Orphans))
+		return errProblems
+	}
+	fmt.Println("storage is consistent")
+	return nil
+}
+
+func purgeBlacklist() error {
+	purged, status := openServices().users.PurgeTokenBlacklist(context.Background())
+	if status != nil {
+		return statusErr(status)
+	}
+
+	fmt.Printf("purged %d expired entries\n", purged)
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func userStatus(u domain.User) string {
+	if u.Suspended {
+		return "suspended"
+	}
+	return "active"
+}
@@ -0,0 +1,154 @@
+// Command migrate chạy migration nhúng trong binary trên DATABASE_URL, đọc cùng config với cmd/server.
+//
+//	migrate up [N]      áp dụng N migration tiếp theo, bỏ trống = tất cả
+//	migrate down [N]    hoàn tác N migration gần nhất (mặc định 1), "down all" hoàn tác toàn bộ
+//	migrate status      version hiện tại và các migration chưa chạy
+//	migrate force V     đặt version V và xóa cờ dirty sau khi đã sửa tay một migration lỗi
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate up [N] | down [N|all] | status | force V")
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 || len(args) > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// load .env only in local
+	if os.Getenv("RAILWAY_ENVIRONMENT") == "" {
+		_ = godotenv.Load()
+	}
+
+	m, err := database.NewMigrator(config.NewConfig().DSN())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer m.Close()
+
+	if err := run(m, args[0], args[1:]); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			log.Println("no change")
+			return
+		}
+		m.Close()
+		log.Fatal(err)
+	}
+}
+
+func run(m *database.Migrator, command string, args []string) error {
+	switch command {
+	case "up":
+		if len(args) == 0 {
+			return report(m, m.Up())
+		}
+		n, err := steps(args[0])
+		if err != nil {
+			return err
+		}
+		return report(m, m.Steps(n))
+
+	case "down":
+		if len(args) == 1 && args[0] == "all" {
+			return report(m, m.Down())
+		}
+		n := 1
+		if len(args) == 1 {
+			var err error
+			if n, err = steps(args[0]); err != nil {
+				return err
+			}
+		}
+		return report(m, m.Steps(-n))
+
+	case "status":
+		if len(args) != 0 {
+			return errors.New("status takes no arguments")
+		}
+		return status(m)
+
+	case "force":
+		if len(args) != 1 {
+			return errors.New("force requires a version")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		return report(m, m.Force(version))
+	}
+
+	return fmt.Errorf("unknown command %q", command)
+}
+
+func steps(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", arg)
+	}
+	return n, nil
+}
+
+// report in version sau khi lệnh chạy xong, kể cả khi lệnh lỗi giữa chừng để biết đã dừng ở đâu.
+func report(m *database.Migrator, err error) error {
+	version, dirty, verr := m.Version()
+	switch {
+	case verr == nil:
+		log.Printf("database at version %d (dirty: %t)", version, dirty)
+	case errors.Is(verr, migrate.ErrNilVersion):
+		log.Println("database has no migrations applied")
+	}
+	return err
+}
+
+func status(m *database.Migrator) error {
+	current, dirty, err := m.Version()
+	applied := true
+	if errors.Is(err, migrate.ErrNilVersion) {
+		applied = false
+	} else if err != nil {
+		return err
+	}
+
+	versions, err := m.Versions()
+	if err != nil {
+		return err
+	}
+
+	if applied {
+		fmt.Printf("version: %d\n", current)
+	} else {
+		fmt.Println("version: none")
+	}
+	fmt.Printf("dirty:   %t\n", dirty)
+
+	pending := 0
+	for _, v := range versions {
+		state := "applied"
+		if !applied || v > current {
+			state = "pending"
+			pending++
+		} else if dirty && v == current {
+			state = "dirty"
+		}
+		fmt.Printf("%6d  %s\n", v, state)
+	}
+	fmt.Printf("%d pending\n", pending)
+	return nil
+}
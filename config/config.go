@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
 )
@@ -13,17 +15,111 @@ type SystemPolicy struct {
 	MaxValidityDays          int
 	DefaultValidityDays      int
 	RequirePasswordMinLength int
+	PasswordRequiredClasses  []string // lower | upper | digit | special
+	PasswordCheckBreached    bool     // chặn password nằm trong danh sách bị lộ
+	ShareTokenLength         int
+	ShareTokenAlphabet       string
+	SignedURLMaxTTLMinutes   int
+	RateLimits               map[string]RateLimitRule
+	RegistrationMode         string // open | invite | domain
+	RequireEmailVerification bool
+	UserDirectoryMode        string // off | exact | domain | all: phạm vi của GET /users/search
+	UserSearchMaxResults     int
+}
+
+// policyMu bảo vệ SystemPolicy dùng chung: PATCH /admin/policy ghi đè policy trong khi
+// middleware rate limit đọc nó ở mọi request.
+var policyMu sync.RWMutex
+
+// SnapshotPolicy trả về bản sao của policy, đọc dưới read lock. Map/slice trong bản sao dùng chung
+// với policy gốc nên người cập nhật phải thay map/slice mới thay vì sửa tại chỗ.
+func SnapshotPolicy(policy *SystemPolicy) SystemPolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return *policy
+}
+
+// ReplacePolicy ghi đè policy bằng updated dưới write lock.
+func ReplacePolicy(policy *SystemPolicy, updated SystemPolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	*policy = updated
+}
+
+// RateLimitRule: mỗi caller được tối đa Limit request trong WindowSeconds giây
+// (token bucket dung lượng Limit, nạp lại đều trong cả window).
+type RateLimitRule struct {
+	Limit         int `json:"limit"`
+	WindowSeconds int `json:"windowSeconds"`
+}
+
+// Tên các nhóm route có giới hạn riêng. Policy "<tên>_anonymous" (nếu có) áp dụng
+// cho caller chưa đăng nhập.
+const (
+	RateLimitAPI             = "api"
+	RateLimitAuth            = "auth"
+	RateLimitFiles           = "files"
+	RateLimitUpload          = "upload"
+	RateLimitUploadAnonymous = "upload_anonymous"
+	RateLimitUserSearch      = "user_search"
+)
+
+func DefaultRateLimits() map[string]RateLimitRule {
+	return map[string]RateLimitRule{
+		RateLimitAPI:             {Limit: 300, WindowSeconds: 60},
+		RateLimitAuth:            {Limit: 30, WindowSeconds: 60},
+		RateLimitFiles:           {Limit: 120, WindowSeconds: 60},
+		RateLimitUpload:          {Limit: 60, WindowSeconds: 3600},
+		RateLimitUploadAnonymous: {Limit: 10, WindowSeconds: 3600},
+		RateLimitUserSearch:      {Limit: 30, WindowSeconds: 60},
+	}
 }
 
 type CORSConfig struct {
 	AllowedOrigins []string
 }
 
+// OIDCProviderConfig: một identity provider cho đăng nhập SSO (authorization code + PKCE).
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	RoleClaim    string   // claim chứa role/group, rỗng = không đồng bộ role từ IdP
+	AdminValues  []string // giá trị của RoleClaim được map thành role admin
+	AllowSignup  bool     // tự tạo tài khoản cho user lần đầu đăng nhập
+}
+
+// WebAuthnConfig: relying party cho passkey. RPID là domain của frontend (không có scheme/port),
+// RPOrigins là các origin trình duyệt được phép gửi ceremony.
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+}
+
+// MailConfig: SMTP gửi email xác minh và lời mời. SMTPHost rỗng -> email chỉ được ghi ra log.
+type MailConfig struct {
+	From           string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	VerifyEmailURL string // link trong email xác minh, token được nối vào ?token=
+}
+
 type Config struct {
 	ServerAddress string
 	DatabaseURL   string
+	AutoMigrate   bool   // chạy migration Postgres nhúng trong binary lúc khởi động (AUTO_MIGRATE=true)
+	PublicBaseURL string // URL công khai của API, dùng để dựng mọi link trả về cho client
 	Policy        *SystemPolicy
 	CORS          CORSConfig
+	OIDC          []OIDCProviderConfig
+	WebAuthn      WebAuthnConfig
+	Mail          MailConfig
 }
 
 func NewConfig() *Config {
@@ -32,16 +128,34 @@ func NewConfig() *Config {
 		panic("DATABASE_URL is required")
 	}
 
+	port := utils.GetEnv("SERVER_PORT", "8080")
+	publicBaseURL := strings.TrimRight(utils.GetEnv("PUBLIC_BASE_URL", fmt.Sprintf("http://localhost:%s", port)), "/")
+
 	return &Config{
-		ServerAddress: fmt.Sprintf(":%s", utils.GetEnv("SERVER_PORT", "8080")),
+		ServerAddress: fmt.Sprintf(":%s", port),
 		DatabaseURL:   dbURL,
+		AutoMigrate:   utils.GetEnv("AUTO_MIGRATE", "false") == "true",
+		PublicBaseURL: publicBaseURL,
 		CORS:          loadCORSConfig(),
+		OIDC:          loadOIDCProviders(publicBaseURL),
+		WebAuthn:      loadWebAuthnConfig(publicBaseURL),
+		Mail:          loadMailConfig(publicBaseURL),
 		Policy: &SystemPolicy{
 			MaxFileSizeMB:            50,
 			MinValidityHours:         1,
 			MaxValidityDays:          30,
 			DefaultValidityDays:      7,
-			RequirePasswordMinLength: 6,
+			RequirePasswordMinLength: 8,
+			PasswordRequiredClasses:  []string{},
+			PasswordCheckBreached:    true,
+			ShareTokenLength:         16,
+			ShareTokenAlphabet:       utils.DefaultTokenAlphabet,
+			SignedURLMaxTTLMinutes:   60,
+			RateLimits:               DefaultRateLimits(),
+			RegistrationMode:         utils.GetEnv("REGISTRATION_MODE", "open"),
+			RequireEmailVerification: utils.GetEnv("REQUIRE_EMAIL_VERIFICATION", "true") == "true",
+			UserDirectoryMode:        utils.GetEnv("USER_DIRECTORY_MODE", "domain"),
+			UserSearchMaxResults:     10,
 		},
 	}
 }
@@ -50,6 +164,11 @@ func (c *Config) DSN() string {
 	return c.DatabaseURL
 }
 
+// PublicURL nối path vào PublicBaseURL, ví dụ PublicURL("files/abc").
+func (c *Config) PublicURL(path string) string {
+	return c.PublicBaseURL + "/" + strings.TrimLeft(path, "/")
+}
+
 func loadCORSConfig() CORSConfig {
 	return CORSConfig{
 		AllowedOrigins: splitAndTrim(
@@ -58,6 +177,64 @@ func loadCORSConfig() CORSConfig {
 	}
 }
 
+// loadOIDCProviders đọc OIDC_PROVIDERS=corp,google rồi OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, ...
+// cho từng provider. Provider thiếu issuer hoặc client ID bị bỏ qua.
+func loadOIDCProviders(publicBaseURL string) []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range splitAndTrim(utils.GetEnv("OIDC_PROVIDERS", "")) {
+		if name == "" {
+			continue
+		}
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name)) + "_"
+
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimRight(utils.GetEnv(prefix+"ISSUER", ""), "/"),
+			ClientID:     utils.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: utils.GetEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  utils.GetEnv(prefix+"REDIRECT_URL", publicBaseURL+"/auth/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(utils.GetEnv(prefix+"SCOPES", "openid email profile")),
+			RoleClaim:    utils.GetEnv(prefix+"ROLE_CLAIM", ""),
+			AdminValues:  splitAndTrim(utils.GetEnv(prefix+"ADMIN_VALUES", "")),
+			AllowSignup:  utils.GetEnv(prefix+"ALLOW_SIGNUP", "true") == "true",
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
+
+// loadWebAuthnConfig mặc định lấy domain/origin từ PUBLIC_BASE_URL, đổi qua WEBAUTHN_RP_ID
+// và WEBAUTHN_RP_ORIGINS khi frontend chạy ở domain khác API.
+func loadWebAuthnConfig(publicBaseURL string) WebAuthnConfig {
+	rpID, origin := "localhost", "http://localhost"
+	if parsed, err := url.Parse(publicBaseURL); err == nil && parsed.Host != "" {
+		rpID = parsed.Hostname()
+		origin = parsed.Scheme + "://" + parsed.Host
+	}
+
+	return WebAuthnConfig{
+		RPID:          utils.GetEnv("WEBAUTHN_RP_ID", rpID),
+		RPDisplayName: utils.GetEnv("WEBAUTHN_RP_NAME", "File Sharing"),
+		RPOrigins:     splitAndTrim(utils.GetEnv("WEBAUTHN_RP_ORIGINS", origin)),
+	}
+}
+
+func loadMailConfig(publicBaseURL string) MailConfig {
+	return MailConfig{
+		From:           utils.GetEnv("MAIL_FROM", "File Sharing <no-reply@localhost>"),
+		SMTPHost:       utils.GetEnv("SMTP_HOST", ""),
+		SMTPPort:       utils.GetEnv("SMTP_PORT", "587"),
+		SMTPUsername:   utils.GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword:   utils.GetEnv("SMTP_PASSWORD", ""),
+		VerifyEmailURL: utils.GetEnv("EMAIL_VERIFY_URL", publicBaseURL+"/auth/verify-email"),
+	}
+}
+
 func splitAndTrim(s string) []string {
 	if s == "" {
 		return nil
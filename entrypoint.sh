@@ -7,7 +7,7 @@ until pg_isready -d "$DATABASE_URL"; do
 done
 
 echo "Running migrations..."
-migrate -path /app/migrations -database "$DATABASE_URL" up
+./migrate up
 
 echo "Starting app..."
 exec ./main
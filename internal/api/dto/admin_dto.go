@@ -3,11 +3,30 @@ package dto
 import "github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
 
 type UpdatePolicyRequest struct {
-	MaxFileSizeMB            *int `json:"maxFileSizeMB" validate:"omitempty,min_int=1,max_int=500"` // Ví dụ: max 500MB
-	MinValidityHours         *int `json:"minValidityHours" validate:"omitempty,min_int=1,max_int=24"`
-	MaxValidityDays          *int `json:"maxValidityDays" validate:"omitempty,min_int=1,max_int=365"`
-	DefaultValidityDays      *int `json:"defaultValidityDays" validate:"omitempty,min_int=1,max_int=365"`
-	RequirePasswordMinLength *int `json:"requirePasswordMinLength" validate:"omitempty,min_int=6,max_int=32"`
+	MaxFileSizeMB            *int    `json:"maxFileSizeMB" validate:"omitempty,min_int=1,max_int=500"` // Ví dụ: max 500MB
+	MinValidityHours         *int    `json:"minValidityHours" validate:"omitempty,min_int=1,max_int=24"`
+	MaxValidityDays          *int    `json:"maxValidityDays" validate:"omitempty,min_int=1,max_int=365"`
+	DefaultValidityDays      *int    `json:"defaultValidityDays" validate:"omitempty,min_int=1,max_int=365"`
+	RequirePasswordMinLength *int    `json:"requirePasswordMinLength" validate:"omitempty,min_int=6,max_int=32"`
+	ShareTokenLength         *int    `json:"shareTokenLength" binding:"omitempty,min=8,max=64"`
+	ShareTokenAlphabet       *string `json:"shareTokenAlphabet" binding:"omitempty,oneof=alphanumeric lowercase base58"`
+	SignedURLMaxTTLMinutes   *int    `json:"signedUrlMaxTtlMinutes" binding:"omitempty,min=1,max=1440"`
+	RegistrationMode         *string `json:"registrationMode" binding:"omitempty,oneof=open invite domain"`
+	RequireEmailVerification *bool   `json:"requireEmailVerification"`
+	UserDirectoryMode        *string `json:"userDirectoryMode" binding:"omitempty,oneof=off exact domain all"`
+	UserSearchMaxResults     *int    `json:"userSearchMaxResults" binding:"omitempty,min=1,max=50"`
+
+	// Gửi [] để bỏ yêu cầu nhóm ký tự
+	PasswordRequiredClasses []string `json:"passwordRequiredClasses" binding:"omitempty,dive,oneof=lower upper digit special"`
+	PasswordCheckBreached   *bool    `json:"passwordCheckBreached"`
+
+	// Chỉ cần gửi các policy muốn đổi, ví dụ {"upload_anonymous": {"limit": 5, "windowSeconds": 3600}}
+	RateLimits map[string]RateLimitRule `json:"rateLimits" binding:"omitempty,dive"`
+}
+
+type RateLimitRule struct {
+	Limit         int `json:"limit" binding:"min=1,max=100000"`
+	WindowSeconds int `json:"windowSeconds" binding:"min=1,max=86400"`
 }
 
 func (r *UpdatePolicyRequest) ToMap() map[string]interface{} {
@@ -29,5 +48,45 @@ func (r *UpdatePolicyRequest) ToMap() map[string]interface{} {
 		updates[utils.CamelToSnake("RequirePasswordMinLength")] = *r.RequirePasswordMinLength
 	}
 
+	if r.ShareTokenLength != nil {
+		updates[utils.CamelToSnake("ShareTokenLength")] = *r.ShareTokenLength
+	}
+	if r.ShareTokenAlphabet != nil {
+		updates[utils.CamelToSnake("ShareTokenAlphabet")] = *r.ShareTokenAlphabet
+	}
+	if r.SignedURLMaxTTLMinutes != nil {
+		updates[utils.CamelToSnake("SignedURLMaxTTLMinutes")] = *r.SignedURLMaxTTLMinutes
+	}
+	if r.RegistrationMode != nil {
+		updates[utils.CamelToSnake("RegistrationMode")] = *r.RegistrationMode
+	}
+	if r.RequireEmailVerification != nil {
+		updates[utils.CamelToSnake("RequireEmailVerification")] = *r.RequireEmailVerification
+	}
+	if r.PasswordRequiredClasses != nil {
+		updates[utils.CamelToSnake("PasswordRequiredClasses")] = r.PasswordRequiredClasses
+	}
+	if r.PasswordCheckBreached != nil {
+		updates[utils.CamelToSnake("PasswordCheckBreached")] = *r.PasswordCheckBreached
+	}
+	if r.UserDirectoryMode != nil {
+		updates[utils.CamelToSnake("UserDirectoryMode")] = *r.UserDirectoryMode
+	}
+	if r.UserSearchMaxResults != nil {
+		updates[utils.CamelToSnake("UserSearchMaxResults")] = *r.UserSearchMaxResults
+	}
+	if r.RateLimits != nil {
+		updates[utils.CamelToSnake("RateLimits")] = r.RateLimits
+	}
+
 	return updates
 }
+
+type SetEmailDomainRuleRequest struct {
+	Rule string `json:"rule" binding:"required,oneof=allow deny"`
+}
+
+type CreateInviteRequest struct {
+	Email         string `json:"email" binding:"omitempty,email"`
+	ExpiresInDays *int   `json:"expiresInDays" binding:"omitempty,min=1,max=90"`
+}
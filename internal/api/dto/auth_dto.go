@@ -1 +1,31 @@
 package dto
+
+import "encoding/json"
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type WebAuthnRegisterBeginRequest struct {
+	Name string `json:"name" binding:"omitempty,max=100"`
+}
+
+// WebAuthnFinishRequest: Credential là nguyên PublicKeyCredential trình duyệt trả về
+// (đã encode base64url như navigator.credentials.*().toJSON()).
+type WebAuthnFinishRequest struct {
+	SessionID  string          `json:"sessionId" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+type WebAuthnLoginBeginRequest struct {
+	CID string `json:"cid" binding:"required"`
+}
+
+type WebAuthnLoginFinishRequest struct {
+	CID string `json:"cid" binding:"required"`
+	WebAuthnFinishRequest
+}
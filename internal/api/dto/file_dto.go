@@ -12,7 +12,8 @@ type UploadRequest struct {
 	// Sử dụng string để validate file extension
 	FileNameForValidation string `form:"file_validation_placeholder" validate:"file_ext=pdf jpg png txt"`
 
-	Password *string `form:"password" validate:"omitempty,min=6"`
+	// Độ dài, nhóm ký tự... do password policy trong SystemPolicy kiểm tra
+	Password *string `form:"password"`
 
 	// ISO Date: YYYY-MM-DDTHH:MM:SSZ
 	AvailableFrom *time.Time `form:"availableFrom" time_format:"2006-01-02T15:04:05Z"`
@@ -22,6 +23,120 @@ type UploadRequest struct {
 	SharedWith []string `form:"sharedWith"`
 
 	EnableTOTP bool `form:"enableTOTP"`
+
+	// Giới hạn số lượt tải; bỏ trống hoặc 0 = không giới hạn
+	MaxDownloads  *int `form:"maxDownloads" binding:"omitempty,min=1"`
+	DeleteOnLimit bool `form:"deleteOnLimit"` // Xóa file khi dùng hết lượt tải
+
+	// Link tùy chỉnh (vanity slug), chỉ dành cho user đã đăng nhập
+	Slug *string `form:"slug" binding:"omitempty,min=3,max=64,slug"`
+
+	// Thư mục chứa file (của chính người upload), bỏ trống = thư mục gốc
+	FolderId *string `form:"folderId" binding:"omitempty,uuid"`
+
+	// Mô tả và tag do user đặt, dùng cho tìm kiếm
+	Description *string  `form:"description" binding:"omitempty,max=1000"`
+	Tags        []string `form:"tags" binding:"max=20,dive,max=50"`
+}
+
+// UpdateShareLinkRequest là DTO cho PATCH /files/info/:id/link
+type UpdateShareLinkRequest struct {
+	// 0 = bỏ giới hạn
+	MaxDownloads  *int  `json:"maxDownloads" binding:"omitempty,min=0"`
+	DeleteOnLimit *bool `json:"deleteOnLimit"`
+
+	Slug            *string `json:"slug" binding:"omitempty,min=3,max=64,slug"`
+	RegenerateToken bool    `json:"regenerateToken"` // Thu hồi link cũ, sinh token ngẫu nhiên mới
+}
+
+// UpdateFileMetadataRequest là DTO cho PATCH /files/info/:id. Field bỏ trống = giữ nguyên,
+// description rỗng = xóa mô tả, tags rỗng = xóa mọi tag.
+type UpdateFileMetadataRequest struct {
+	Description *string   `json:"description" binding:"omitempty,max=1000"`
+	Tags        *[]string `json:"tags" binding:"omitempty,max=20,dive,max=50"`
+}
+
+// PageQuery là query string phân trang chung của các endpoint danh sách.
+// sortBy hợp lệ tùy endpoint, cursor lấy từ pagination.nextCursor của trang trước.
+type PageQuery struct {
+	Cursor string `form:"cursor" binding:"max=1024"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=100"`
+	SortBy string `form:"sortBy"`
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// ListMyFilesQuery là query string của GET /files/my
+type ListMyFilesQuery struct {
+	PageQuery
+	Status   string `form:"status,default=all" binding:"oneof=all active pending expired"`
+	FolderId string `form:"folderId"` // "root" = chỉ file ở thư mục gốc
+}
+
+// FileSearchQuery là query string của GET /files/search
+type FileSearchQuery struct {
+	PageQuery
+	Q        string     `form:"q" binding:"max=200"`
+	Scope    string     `form:"scope" binding:"omitempty,oneof=all mine shared"`
+	Tags     string     `form:"tags" binding:"max=1000"` // phân tách bằng dấu phẩy, file phải có đủ các tag
+	MimeType string     `form:"mimeType" binding:"max=100"`
+	MinSize  *int64     `form:"minSize" binding:"omitempty,min=0"`
+	MaxSize  *int64     `form:"maxSize" binding:"omitempty,min=0"`
+	From     *time.Time `form:"from"` // RFC 3339
+	To       *time.Time `form:"to"`
+	Owner    string     `form:"owner" binding:"omitempty,email"`
+}
+
+// FileSearchResult là một phần tử trong kết quả GET /files/search
+type FileSearchResult struct {
+	Id          string    `json:"id"`
+	FileName    string    `json:"fileName"`
+	Description *string   `json:"description"`
+	Tags        []string  `json:"tags"`
+	MimeType    string    `json:"mimeType"`
+	FileSize    int64     `json:"fileSize"`
+	Owner       *string   `json:"owner"` // email, nil = upload ẩn danh
+	IsOwner     bool      `json:"isOwner"`
+	HasPassword bool      `json:"hasPassword"`
+	ShareToken  string    `json:"shareToken"`
+	ShareLink   string    `json:"shareLink"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Rank        float64   `json:"rank"`
+}
+
+// MoveFileRequest là DTO cho PATCH /files/info/:id/folder, folderId null = thư mục gốc
+type MoveFileRequest struct {
+	FolderId *string `json:"folderId" binding:"omitempty,uuid"`
+}
+
+// ArchiveRequest là DTO cho POST /files/archive. Có thể kết hợp nhiều nguồn, file trùng chỉ lấy một lần.
+type ArchiveRequest struct {
+	FileIds     []string          `json:"fileIds" binding:"omitempty,max=500,dive,uuid"`
+	ShareTokens []string          `json:"shareTokens" binding:"omitempty,max=500,dive,required"`
+	FolderId    *string           `json:"folderId" binding:"omitempty,uuid"` // cả cây thư mục
+	Filter      *ArchiveFilter    `json:"filter"`                            // bộ lọc của GET /files/my
+	Passwords   map[string]string `json:"passwords"`                         // key: id file hoặc share token
+}
+
+// ArchiveFilter là bộ lọc của GET /files/my dùng cho POST /files/archive
+type ArchiveFilter struct {
+	Status   string `json:"status" binding:"omitempty,oneof=all active pending expired"`
+	FolderId string `json:"folderId"` // rỗng = mọi thư mục, "root" = chỉ file ở thư mục gốc
+}
+
+// SignedURLRequest là DTO cho POST /files/:shareToken/signed-url
+type SignedURLRequest struct {
+	ExpiresIn *int `json:"expiresIn" binding:"omitempty,min=30"` // Số giây, mặc định 300
+	BindIP    bool `json:"bindIp"`                               // Chỉ IP đã xin URL mới tải được
+}
+
+// SignedDownloadQuery là query string của GET /files/signed/:id
+type SignedDownloadQuery struct {
+	Version   int    `form:"v" binding:"required"`
+	ExpiresAt int64  `form:"exp" binding:"required"`
+	UserID    string `form:"u"`
+	BindIP    bool   `form:"ip"`
+	Signature string `form:"sig" binding:"required"`
 }
 
 type AccessibleFile struct {
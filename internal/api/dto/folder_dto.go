@@ -0,0 +1,23 @@
+package dto
+
+// CreateFolderRequest là DTO cho POST /folders, parentId bỏ trống = thư mục gốc
+type CreateFolderRequest struct {
+	Name     string  `json:"name" binding:"required,max=255"`
+	ParentId *string `json:"parentId" binding:"omitempty,uuid"`
+}
+
+// RenameFolderRequest là DTO cho PATCH /folders/:id
+type RenameFolderRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// MoveFolderRequest là DTO cho POST /folders/:id/move, parentId null = thư mục gốc
+type MoveFolderRequest struct {
+	ParentId *string `json:"parentId" binding:"omitempty,uuid"`
+}
+
+// ShareFolderRequest là DTO cho PUT /folders/:id/share: thay toàn bộ danh sách người nhận.
+// Phần tử là email hoặc id nhóm, danh sách rỗng = ngừng chia sẻ.
+type ShareFolderRequest struct {
+	SharedWith []string `json:"sharedWith" binding:"max=100"`
+}
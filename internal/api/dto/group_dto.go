@@ -0,0 +1,24 @@
+package dto
+
+// CreateGroupRequest là DTO cho POST /groups
+type CreateGroupRequest struct {
+	Name        string  `json:"name" binding:"required,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+}
+
+// UpdateGroupRequest là DTO cho PATCH /groups/:id, chỉ gửi các trường muốn đổi.
+type UpdateGroupRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+}
+
+// AddGroupMemberRequest là DTO cho POST /groups/:id/members
+type AddGroupMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=owner member"` // mặc định member
+}
+
+// UpdateGroupMemberRequest là DTO cho PATCH /groups/:id/members/:userId
+type UpdateGroupMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner member"`
+}
@@ -1 +1,42 @@
 package dto
+
+import "encoding/json"
+
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=files:read files:write admin"`
+	ExpiresInDays *int     `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+// UpdateProfileRequest là DTO cho PATCH /user, chỉ gửi các trường muốn đổi.
+type UpdateProfileRequest struct {
+	Username *string `json:"username" binding:"omitempty,min=1,max=100"`
+	Email    *string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+// Cách xử lý file của tài khoản bị xóa
+const (
+	AccountFilesDelete   = "delete"
+	AccountFilesTransfer = "transfer"
+)
+
+// DeleteAccountRequest là DTO cho DELETE /user
+type DeleteAccountRequest struct {
+	// Bắt buộc nếu tài khoản có password; tài khoản tạo qua SSO thì đăng nhập lại qua SSO hoặc dùng passkey
+	Password string `json:"password"`
+	TOTPCode string `json:"code"` // bắt buộc nếu tài khoản đã bật TOTP
+	Files    string `json:"files" binding:"required,oneof=delete transfer"`
+	// Email admin nhận file khi files=transfer; bỏ trống = admin đầu tiên theo username
+	TransferTo string `json:"transferTo" binding:"omitempty,email"`
+
+	// Thay cho code với tài khoản có passkey: sessionId của POST /auth/webauthn/reauth/begin và
+	// PublicKeyCredential trình duyệt trả về
+	WebAuthnSessionID  string          `json:"webAuthnSessionId"`
+	WebAuthnCredential json.RawMessage `json:"webAuthnCredential" binding:"required_with=WebAuthnSessionID"`
+}
+
+// ExportDownloadQuery là query string của GET /user/export/:id/download
+type ExportDownloadQuery struct {
+	ExpiresAt int64  `form:"exp" binding:"required"`
+	Signature string `form:"sig" binding:"required"`
+}
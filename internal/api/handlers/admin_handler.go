@@ -9,15 +9,18 @@ import (
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type AdminHandler struct {
-	admin_service service.AdminService
+	admin_service        service.AdminService
+	registration_service service.RegistrationService
 }
 
-func NewAdminHandler(admin_service service.AdminService) *AdminHandler {
+func NewAdminHandler(admin_service service.AdminService, registration_service service.RegistrationService) *AdminHandler {
 	return &AdminHandler{
-		admin_service: admin_service,
+		admin_service:        admin_service,
+		registration_service: registration_service,
 	}
 }
 
@@ -65,3 +68,88 @@ func (ah *AdminHandler) CleanupExpiredFiles(ctx *gin.Context) {
 		"timestamp":    time.Now().UTC().Format(time.RFC3339),
 	})
 }
+
+func (ah *AdminHandler) ListEmailDomainRules(ctx *gin.Context) {
+	rules, err := ah.registration_service.ListDomainRules(ctx)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (ah *AdminHandler) SetEmailDomainRule(ctx *gin.Context) {
+	var req dto.SetEmailDomainRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	rule, err := ah.registration_service.SetDomainRule(ctx, ctx.Param("domain"), req.Rule)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Email domain rule saved",
+		"rule":    rule,
+	})
+}
+
+func (ah *AdminHandler) DeleteEmailDomainRule(ctx *gin.Context) {
+	if err := ah.registration_service.DeleteDomainRule(ctx, ctx.Param("domain")); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Email domain rule removed", nil)
+}
+
+func (ah *AdminHandler) ListInvites(ctx *gin.Context) {
+	invites, err := ah.registration_service.ListInvites(ctx)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"invites": invites})
+}
+
+func (ah *AdminHandler) CreateInvite(ctx *gin.Context) {
+	var req dto.CreateInviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	adminID, _ := getUserIDFromContext(ctx)
+	invite, token, err := ah.registration_service.CreateInvite(ctx, adminID, req.Email, req.ExpiresInDays)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	// Mã mời chỉ trả về một lần, DB chỉ lưu hash
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Invitation created",
+		"invite":  invite,
+		"token":   token,
+	})
+}
+
+func (ah *AdminHandler) DeleteInvite(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if uuid.Validate(id) != nil {
+		utils.Response(utils.ErrCodeInviteNotFound).Export(ctx)
+		return
+	}
+
+	if err := ah.registration_service.DeleteInvite(ctx, id); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Invitation revoked", nil)
+}
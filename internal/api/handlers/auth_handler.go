@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
@@ -12,12 +13,18 @@ import (
 )
 
 type AuthHandler struct {
-	auth_service service.AuthService
+	auth_service         service.AuthService
+	oidc_service         service.OIDCService
+	webauthn_service     service.WebAuthnService
+	registration_service service.RegistrationService
 }
 
-func NewAuthHandler(auth_service service.AuthService) *AuthHandler {
+func NewAuthHandler(auth_service service.AuthService, oidc_service service.OIDCService, webauthn_service service.WebAuthnService, registration_service service.RegistrationService) *AuthHandler {
 	return &AuthHandler{
-		auth_service: auth_service,
+		auth_service:         auth_service,
+		oidc_service:         oidc_service,
+		webauthn_service:     webauthn_service,
+		registration_service: registration_service,
 	}
 }
 
@@ -31,18 +38,52 @@ func (uh *AuthHandler) CreateUser(ctx *gin.Context) {
 		return
 	}
 
-	createdUser, err := uh.auth_service.CreateUser(user.Username, user.Password, user.Email)
+	createdUser, err := uh.auth_service.CreateUser(ctx, user.Username, user.Password, user.Email, user.InviteToken)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		err.Export(ctx)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "User registered successfully",
-		"userId":  createdUser.Id,
+		"message":                   "User registered successfully",
+		"userId":                    createdUser.Id,
+		"emailVerificationRequired": !createdUser.EmailVerified,
 	})
 }
 
+// VerifyEmail nhận token từ query (link trong email) hoặc body {token}.
+func (uh *AuthHandler) VerifyEmail(ctx *gin.Context) {
+	req := dto.VerifyEmailRequest{Token: ctx.Query("token")}
+	if req.Token == "" {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+			return
+		}
+	}
+
+	if err := uh.registration_service.VerifyEmail(ctx, req.Token); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Email verified successfully", nil)
+}
+
+func (uh *AuthHandler) ResendVerification(ctx *gin.Context) {
+	var req dto.ResendVerificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	if err := uh.registration_service.ResendVerification(ctx, req.Email); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "If the account exists and is not verified yet, a new verification email has been sent", nil)
+}
+
 func (ah *AuthHandler) Login(ctx *gin.Context) {
 	var input domain.LoginInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -50,21 +91,29 @@ func (ah *AuthHandler) Login(ctx *gin.Context) {
 		return
 	}
 
-	user, token, err := ah.auth_service.Login(input.Email, input.Password)
+	user, token, err := ah.auth_service.Login(ctx, input.Email, input.Password, ctx.ClientIP())
 	if err != nil {
 		err.Export(ctx)
 		return
 	}
 
-	if user.EnableTOTP {
+	if user.EnableTOTP || user.EnableWebAuthn {
+		message := "TOTP verification required"
+		if !user.EnableTOTP {
+			message = "WebAuthn verification required"
+		}
+		// Client chọn một trong các phương thức được bật: /auth/login/totp hoặc /auth/login/webauthn
 		ctx.JSON(http.StatusOK, gin.H{
-			"requireTOTP": user.EnableTOTP,
-			"cid":         token,
-			"message":     "TOTP verification required",
+			"requireTOTP":     user.EnableTOTP,
+			"requireWebAuthn": user.EnableWebAuthn,
+			"cid":             token,
+			"message":         message,
 		})
 		return
 	}
 
+	ah.auth_service.RecordLogin(ctx, user.Id, domain.LOGIN_PASSWORD, ctx.ClientIP(), ctx.Request.UserAgent())
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"accessToken": token,
 		"user": gin.H{
@@ -206,12 +255,14 @@ func (ah *AuthHandler) LoginTOTP(ctx *gin.Context) {
 		return
 	}
 
-	user, accessToken, err := ah.auth_service.LoginTOTP(input.CID, input.TOTPCode)
+	user, accessToken, err := ah.auth_service.LoginTOTP(ctx, input.CID, input.TOTPCode, ctx.ClientIP())
 	if err != nil {
 		err.Export(ctx)
 		return
 	}
 
+	ah.auth_service.RecordLogin(ctx, user.Id, domain.LOGIN_TOTP, ctx.ClientIP(), ctx.Request.UserAgent())
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"accessToken": accessToken,
 		"user": gin.H{
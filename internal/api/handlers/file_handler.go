@@ -1,9 +1,11 @@
 package handlers
 
 import (
-	"fmt"
 	"io"
+	"log"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
@@ -41,13 +43,6 @@ func (fh *FileHandler) UploadFile(ctx *gin.Context) {
 		return
 	}
 
-	if req.Password != nil {
-		if len(*req.Password) < 8 {
-			utils.ResponseMsg(utils.ErrCodeBadRequest, "Password must be at least 8 characters long").Export(ctx)
-			return
-		}
-	}
-
 	var userID *string
 	if val, exists := ctx.Get("userID"); exists && val != "" {
 		strVal := val.(string)
@@ -66,6 +61,11 @@ func (fh *FileHandler) UploadFile(ctx *gin.Context) {
 		return
 	}
 
+	if userID == nil && req.Slug != nil {
+		utils.Response(utils.ErrCodeUploadBearerRequired).Export(ctx)
+		return
+	}
+
 	uploadedFile, berr := fh.file_service.UploadFile(ctx, fileHeader, &req, userID)
 	if berr != nil {
 		berr.Export(ctx)
@@ -76,9 +76,36 @@ func (fh *FileHandler) UploadFile(ctx *gin.Context) {
 		"id":         uploadedFile.Id,
 		"fileName":   uploadedFile.FileName,
 		"shareToken": uploadedFile.ShareToken,
+		"shareLink":  uploadedFile.ShareLink,
 		"isPublic":   uploadedFile.IsPublic,
 	}
 
+	if uploadedFile.FolderId != nil {
+		response["folderId"] = *uploadedFile.FolderId
+	}
+
+	if uploadedFile.Description != nil {
+		response["description"] = *uploadedFile.Description
+	}
+
+	if len(uploadedFile.Tags) > 0 {
+		response["tags"] = uploadedFile.Tags
+	}
+
+	if len(uploadedFile.SharedGroups) > 0 {
+		response["sharedWithGroups"] = uploadedFile.SharedGroups
+	}
+
+	if uploadedFile.Shares != nil {
+		response["sharedWith"] = uploadedFile.Shares.Resolved
+		response["unresolvedRecipients"] = uploadedFile.Shares.Unresolved
+	}
+
+	if uploadedFile.MaxDownloads != nil {
+		response["maxDownloads"] = *uploadedFile.MaxDownloads
+		response["deleteOnLimit"] = uploadedFile.DeleteOnLimit
+	}
+
 	//utils.ResponseSuccess(ctx, http.StatusCreated, "File uploaded successfully", gin.H{"file": response})
 	ctx.JSON(http.StatusCreated, gin.H{
 		"success": true,
@@ -121,21 +148,19 @@ func (fh *FileHandler) GetMyFiles(ctx *gin.Context) {
 		return
 	}
 
-	status := ctx.DefaultQuery("status", "all")
-	page := utils.GetIntQuery(ctx, "page", 1)
-	limit := utils.GetIntQuery(ctx, "limit", 20)
-	sortBy := ctx.DefaultQuery("sortBy", "createdAt")
-	order := ctx.DefaultQuery("order", "desc")
+	var query dto.ListMyFilesQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
 
-	params := domain.ListFileParams{
-		Status: strings.ToLower(status),
-		Page:   page,
-		Limit:  limit,
-		SortBy: sortBy,
-		Order:  strings.ToLower(order),
+	// folderId=root: chỉ file ở thư mục gốc
+	if query.FolderId != "" && query.FolderId != "root" && uuid.Validate(query.FolderId) != nil {
+		utils.ResponseMsg(utils.ErrCodeBadRequest, "Invalid folderId").Export(ctx)
+		return
 	}
 
-	result, err := fh.file_service.GetMyFiles(ctx, userID.(string), params)
+	result, err := fh.file_service.GetMyFiles(ctx, userID.(string), &query)
 
 	if err != nil {
 		err.Export(ctx)
@@ -176,6 +201,8 @@ func (fh *FileHandler) GetFileInfo(ctx *gin.Context) {
 		"hasPassword": file.HasPassword,
 		"fileSize":    file.FileSize,
 		"mimeType":    file.MimeType,
+		"description": file.Description,
+		"tags":        file.Tags,
 	}
 
 	//utils.ResponseSuccess(ctx, http.StatusOK, "File retrieved successfully", gin.H{"file": result})
@@ -214,7 +241,7 @@ func (fh *FileHandler) GetFileInfoVerbose(ctx *gin.Context) {
 		"fileSize":    file.FileSize,
 		"mimeType":    file.MimeType,
 		"shareToken":  file.ShareToken,
-		"shareLink":   fmt.Sprintf("http://localhost:8080/api/files/%s", file.ShareToken),
+		"shareLink":   file.ShareLink,
 		"isPublic":    file.IsPublic,
 		"hasPassword": file.HasPassword,
 
@@ -224,7 +251,14 @@ func (fh *FileHandler) GetFileInfoVerbose(ctx *gin.Context) {
 
 		"hoursRemaining": file.AvailableTo.Sub(file.AvailableFrom).Hours(),
 
-		"createdAt": file.CreatedAt,
+		"maxDownloads":  file.MaxDownloads,
+		"deleteOnLimit": file.DeleteOnLimit,
+		"downloadCount": file.DownloadCount,
+
+		"createdAt":   file.CreatedAt,
+		"folderId":    file.FolderId,
+		"description": file.Description,
+		"tags":        file.Tags,
 	}
 
 	out["owner"] = gin.H{
@@ -236,6 +270,7 @@ func (fh *FileHandler) GetFileInfoVerbose(ctx *gin.Context) {
 
 	if shared != nil {
 		out["sharedWith"] = shared
+		out["sharedWithGroups"] = file.SharedGroups
 	}
 
 	//utils.ResponseSuccess(ctx, http.StatusOK, "File retrieved successfully", gin.H{"file": result})
@@ -244,26 +279,190 @@ func (fh *FileHandler) GetFileInfoVerbose(ctx *gin.Context) {
 	})
 }
 
-func (fh *FileHandler) getFileData(ctx *gin.Context) (*domain.File, []byte, *utils.ReturnStatus) {
+func (fh *FileHandler) UpdateShareLink(ctx *gin.Context) {
+	fileID := ctx.Param("id")
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	if uuid.Validate(fileID) != nil {
+		utils.Response(utils.ErrCodeFileNotFound).Export(ctx)
+		return
+	}
+
+	var req dto.UpdateShareLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	file, err := fh.file_service.UpdateShareLink(ctx, fileID, userID.(string), &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Share link updated successfully",
+		"link": gin.H{
+			"shareToken":    file.ShareToken,
+			"shareLink":     file.ShareLink,
+			"maxDownloads":  file.MaxDownloads,
+			"deleteOnLimit": file.DeleteOnLimit,
+			"downloadCount": file.DownloadCount,
+		},
+	})
+}
+
+func (fh *FileHandler) MoveFile(ctx *gin.Context) {
+	fileID := ctx.Param("id")
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	if uuid.Validate(fileID) != nil {
+		utils.Response(utils.ErrCodeFileNotFound).Export(ctx)
+		return
+	}
+
+	var req dto.MoveFileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	file, err := fh.file_service.MoveFile(ctx, fileID, userID.(string), req.FolderId)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "File moved",
+		"file": gin.H{
+			"id":       file.Id,
+			"fileName": file.FileName,
+			"folderId": file.FolderId,
+		},
+	})
+}
+
+func (fh *FileHandler) UpdateFileMetadata(ctx *gin.Context) {
+	fileID := ctx.Param("id")
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	if uuid.Validate(fileID) != nil {
+		utils.Response(utils.ErrCodeFileNotFound).Export(ctx)
+		return
+	}
+
+	var req dto.UpdateFileMetadataRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	file, err := fh.file_service.UpdateFileMetadata(ctx, fileID, userID.(string), &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "File updated",
+		"file": gin.H{
+			"id":          file.Id,
+			"fileName":    file.FileName,
+			"description": file.Description,
+			"tags":        file.Tags,
+		},
+	})
+}
+
+func (fh *FileHandler) SearchFiles(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var query dto.FileSearchQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	files, pagination, err := fh.file_service.SearchFiles(ctx, userID.(string), &query)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"files":      files,
+		"pagination": pagination,
+	})
+}
+
+func (fh *FileHandler) GetShareQRCode(ctx *gin.Context) {
+	ident := ctx.Param("id")
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	format := strings.ToLower(ctx.DefaultQuery("format", "png"))
+	size := utils.GetIntQuery(ctx, "size", 256)
+	if size < 64 || size > 1024 {
+		utils.ResponseMsg(utils.ErrCodeBadRequest, "size must be between 64 and 1024").Export(ctx)
+		return
+	}
+
+	image, contentType, err := fh.file_service.GetShareQRCode(ctx, ident, userID.(string), format, size)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.Data(http.StatusOK, contentType, image)
+}
+
+// filePassword đọc password từ header X-File-Password; query ?password= chỉ còn để tương thích ngược.
+func filePassword(ctx *gin.Context) string {
+	if password := ctx.GetHeader("X-File-Password"); password != "" {
+		return password
+	}
+	return ctx.Query("password")
+}
+
+func (fh *FileHandler) getFileData(ctx *gin.Context) (*domain.File, io.Reader, *utils.ReturnStatus) {
 	fileToken := ctx.Param("shareToken")
-	password := ctx.Query("password")
+	password := filePassword(ctx)
 	userIDptr, exists := ctx.Get("userID")
 	var userID string = ""
 	if exists {
 		userID = userIDptr.(string)
 	}
 
-	info, file, download_err := fh.file_service.DownloadFile(ctx, fileToken, userID, password)
-	if download_err != nil {
-		return nil, nil, download_err
-	}
+	return fh.file_service.DownloadFile(ctx, fileToken, userID, password, ctx.ClientIP())
+}
 
-	fileBytes, readerr := io.ReadAll(file)
-	if readerr != nil {
-		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, readerr.Error())
+// streamFile stream nội dung file ra response rồi đóng reader (file burn-after-reading bị xóa lúc này).
+func streamFile(ctx *gin.Context, info *domain.File, file io.Reader, headers map[string]string) {
+	if closer, ok := file.(io.Closer); ok {
+		defer closer.Close()
 	}
 
-	return info, fileBytes, nil
+	ctx.DataFromReader(http.StatusOK, info.FileSize, info.MimeType, file, headers)
 }
 
 func (fh *FileHandler) DownloadFile(ctx *gin.Context) {
@@ -273,7 +472,7 @@ func (fh *FileHandler) DownloadFile(ctx *gin.Context) {
 		return
 	}
 
-	ctx.Data(http.StatusOK, info.MimeType, file)
+	streamFile(ctx, info, file, nil)
 }
 
 func (fh *FileHandler) PreviewFile(ctx *gin.Context) {
@@ -283,8 +482,88 @@ func (fh *FileHandler) PreviewFile(ctx *gin.Context) {
 		return
 	}
 
-	ctx.Header("Content-Disposition", "inline; filename=\""+info.FileName+"\"")
-	ctx.Data(http.StatusOK, info.MimeType, file)
+	streamFile(ctx, info, file, map[string]string{"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": info.FileName})})
+}
+
+func (fh *FileHandler) CreateSignedURL(ctx *gin.Context) {
+	fileToken := ctx.Param("shareToken")
+	userID := ""
+	if val, exists := ctx.Get("userID"); exists {
+		userID = val.(string)
+	}
+
+	var req dto.SignedURLRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+			return
+		}
+	}
+
+	signedURL, expiresAt, err := fh.file_service.CreateSignedURL(ctx, fileToken, userID, filePassword(ctx), ctx.ClientIP(), &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"url":       signedURL,
+		"expiresAt": expiresAt,
+		"ipBound":   req.BindIP,
+	})
+}
+
+func (fh *FileHandler) DownloadSigned(ctx *gin.Context) {
+	fileID := ctx.Param("id")
+	if uuid.Validate(fileID) != nil {
+		utils.Response(utils.ErrCodeFileNotFound).Export(ctx)
+		return
+	}
+
+	var query dto.SignedDownloadQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.Response(utils.ErrCodeSignedURLInvalid).Export(ctx)
+		return
+	}
+
+	info, file, err := fh.file_service.DownloadSigned(ctx, fileID, &query, ctx.ClientIP())
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	streamFile(ctx, info, file, map[string]string{"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": info.FileName})})
+}
+
+func (fh *FileHandler) DownloadArchive(ctx *gin.Context) {
+	userID := ""
+	if val, exists := ctx.Get("userID"); exists {
+		userID = val.(string)
+	}
+
+	var req dto.ArchiveRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	// Mọi kiểm tra quyền xong trước khi gửi header, sau đó không còn trả lỗi JSON được nữa.
+	archive, err := fh.file_service.PrepareArchive(ctx, &req, userID, ctx.ClientIP())
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.Header("Content-Type", "application/zip")
+	// Tên archive lấy từ tên thư mục do user đặt nên phải escape (dấu nháy, ký tự Unicode).
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archive.Name}))
+	ctx.Header("X-Archive-Files", strconv.Itoa(len(archive.Entries)))
+	ctx.Header("X-Archive-Skipped", strconv.Itoa(archive.Skipped))
+	ctx.Status(http.StatusOK)
+
+	if err := fh.file_service.WriteArchive(ctx, archive, userID, ctx.Writer); err != nil {
+		log.Printf("Archive: streaming aborted: %v", err)
+	}
 }
 
 func (fh *FileHandler) GetFileDownloadHistory(ctx *gin.Context) {
@@ -295,14 +574,13 @@ func (fh *FileHandler) GetFileDownloadHistory(ctx *gin.Context) {
 		return
 	}
 
-	page := utils.GetIntQuery(ctx, "page", 1)
-	limit := utils.GetIntQuery(ctx, "limit", 20)
-	if limit == 0 {
-		utils.ResponseError(ctx, utils.NewError("Limit must not be 0", utils.ErrCodeBadRequest))
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
 		return
 	}
 
-	history, download_err := fh.file_service.GetFileDownloadHistory(ctx, fileID, userID.(string), page, limit)
+	history, download_err := fh.file_service.GetFileDownloadHistory(ctx, fileID, userID.(string), &query)
 	if download_err != nil {
 		download_err.Export(ctx)
 		return
@@ -353,34 +631,20 @@ func (fh *FileHandler) GetAccessibleFiles(ctx *gin.Context) {
 		return
 	}
 
-	files, err := fh.file_service.GetAccessibleFiles(ctx, userID.(string))
-	if err != nil {
-		err.Export(ctx)
+	var query dto.PageQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
 		return
 	}
 
-	page := utils.GetIntQuery(ctx, "page", 1)
-	limit := utils.GetIntQuery(ctx, "limit", 20)
-	totalPages := 1
-
-	if len(files) != 0 {
-		totalPages = (len(files) + limit) / limit
-	}
-
-	if page > totalPages {
-		page = totalPages
+	files, pagination, err := fh.file_service.GetAccessibleFiles(ctx, userID.(string), &query)
+	if err != nil {
+		err.Export(ctx)
+		return
 	}
 
-	start := limit * (page - 1)
-	end := min(start+limit, len(files))
-
 	ctx.JSON(http.StatusOK, gin.H{
-		"files": files[start:end],
-		"pagination": gin.H{
-			"currentPage": page,
-			"totalPages":  totalPages,
-			"totalFiles":  len(files),
-			"limit":       limit,
-		},
+		"files":      files,
+		"pagination": pagination,
 	})
 }
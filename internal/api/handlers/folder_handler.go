@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FolderHandler struct {
+	folder_service service.FolderService
+}
+
+func NewFolderHandler(folder_service service.FolderService) *FolderHandler {
+	return &FolderHandler{folder_service: folder_service}
+}
+
+// folderParams đọc userID từ context và id thư mục từ URL.
+func folderParams(ctx *gin.Context) (string, string, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return "", "", false
+	}
+
+	folderID := ctx.Param("id")
+	if uuid.Validate(folderID) != nil {
+		utils.Response(utils.ErrCodeFolderNotFound).Export(ctx)
+		return "", "", false
+	}
+
+	return userID.(string), folderID, true
+}
+
+func (fh *FolderHandler) CreateFolder(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var req dto.CreateFolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	folder, err := fh.folder_service.CreateFolder(ctx, userID.(string), &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Folder created",
+		"folder":  folder,
+	})
+}
+
+func (fh *FolderHandler) ListFolders(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	folders, err := fh.folder_service.ListFolders(ctx, userID.(string))
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+func (fh *FolderHandler) ListSharedFolders(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	folders, err := fh.folder_service.ListSharedFolders(ctx, userID.(string))
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+func (fh *FolderHandler) GetFolder(ctx *gin.Context) {
+	userID, folderID, ok := folderParams(ctx)
+	if !ok {
+		return
+	}
+
+	contents, err := fh.folder_service.GetFolder(ctx, userID, folderID)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, contents)
+}
+
+func (fh *FolderHandler) RenameFolder(ctx *gin.Context) {
+	userID, folderID, ok := folderParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.RenameFolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	folder, err := fh.folder_service.RenameFolder(ctx, userID, folderID, req.Name)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Folder renamed",
+		"folder":  folder,
+	})
+}
+
+func (fh *FolderHandler) MoveFolder(ctx *gin.Context) {
+	userID, folderID, ok := folderParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.MoveFolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	folder, err := fh.folder_service.MoveFolder(ctx, userID, folderID, req.ParentId)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Folder moved",
+		"folder":  folder,
+	})
+}
+
+func (fh *FolderHandler) DeleteFolder(ctx *gin.Context) {
+	userID, folderID, ok := folderParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := fh.folder_service.DeleteFolder(ctx, userID, folderID); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Folder deleted, files inside were moved to the root folder", nil)
+}
+
+func (fh *FolderHandler) ShareFolder(ctx *gin.Context) {
+	userID, folderID, ok := folderParams(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.ShareFolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	shares, err := fh.folder_service.ShareFolder(ctx, userID, folderID, req.SharedWith)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Folder sharing updated",
+		"shares":  shares,
+	})
+}
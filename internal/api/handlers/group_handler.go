@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type GroupHandler struct {
+	group_service service.GroupService
+}
+
+func NewGroupHandler(group_service service.GroupService) *GroupHandler {
+	return &GroupHandler{group_service: group_service}
+}
+
+// groupParams đọc userID từ context và id nhóm (và id thành viên nếu có) từ URL.
+func groupParams(ctx *gin.Context, withMember bool) (string, string, string, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return "", "", "", false
+	}
+
+	groupID := ctx.Param("id")
+	if uuid.Validate(groupID) != nil {
+		utils.Response(utils.ErrCodeGroupNotFound).Export(ctx)
+		return "", "", "", false
+	}
+
+	memberID := ""
+	if withMember {
+		memberID = ctx.Param("userId")
+		if uuid.Validate(memberID) != nil {
+			utils.Response(utils.ErrCodeGroupMemberNotFound).Export(ctx)
+			return "", "", "", false
+		}
+	}
+
+	return userID.(string), groupID, memberID, true
+}
+
+func (gh *GroupHandler) CreateGroup(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var req dto.CreateGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	group, err := gh.group_service.CreateGroup(ctx, userID.(string), &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Group created",
+		"group":   group,
+	})
+}
+
+func (gh *GroupHandler) ListGroups(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	groups, err := gh.group_service.ListGroups(ctx, userID.(string))
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+func (gh *GroupHandler) GetGroup(ctx *gin.Context) {
+	userID, groupID, _, ok := groupParams(ctx, false)
+	if !ok {
+		return
+	}
+
+	group, members, err := gh.group_service.GetGroup(ctx, userID, groupID)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"group":   group,
+		"members": members,
+	})
+}
+
+func (gh *GroupHandler) UpdateGroup(ctx *gin.Context) {
+	userID, groupID, _, ok := groupParams(ctx, false)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	group, err := gh.group_service.UpdateGroup(ctx, userID, groupID, &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Group updated",
+		"group":   group,
+	})
+}
+
+func (gh *GroupHandler) DeleteGroup(ctx *gin.Context) {
+	userID, groupID, _, ok := groupParams(ctx, false)
+	if !ok {
+		return
+	}
+
+	if err := gh.group_service.DeleteGroup(ctx, userID, groupID); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Group deleted", nil)
+}
+
+func (gh *GroupHandler) AddMember(ctx *gin.Context) {
+	userID, groupID, _, ok := groupParams(ctx, false)
+	if !ok {
+		return
+	}
+
+	var req dto.AddGroupMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	members, err := gh.group_service.AddMember(ctx, userID, groupID, &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Member added",
+		"members": members,
+	})
+}
+
+func (gh *GroupHandler) UpdateMember(ctx *gin.Context) {
+	userID, groupID, memberID, ok := groupParams(ctx, true)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateGroupMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	members, err := gh.group_service.UpdateMember(ctx, userID, groupID, memberID, req.Role)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Member updated",
+		"members": members,
+	})
+}
+
+func (gh *GroupHandler) RemoveMember(ctx *gin.Context) {
+	userID, groupID, memberID, ok := groupParams(ctx, true)
+	if !ok {
+		return
+	}
+
+	if err := gh.group_service.RemoveMember(ctx, userID, groupID, memberID); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Member removed", nil)
+}
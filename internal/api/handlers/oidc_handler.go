@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (ah *AuthHandler) OIDCProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"providers": ah.oidc_service.Providers()})
+}
+
+// OIDCLogin chuyển hướng trình duyệt sang IdP. SPA có thể gọi với ?mode=json
+// để nhận authorizationUrl rồi tự điều hướng.
+func (ah *AuthHandler) OIDCLogin(ctx *gin.Context) {
+	authURL, err := ah.oidc_service.BeginLogin(ctx, ctx.Param("provider"))
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	if ctx.Query("mode") == "json" {
+		ctx.JSON(http.StatusOK, gin.H{"authorizationUrl": authURL})
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+func (ah *AuthHandler) OIDCCallback(ctx *gin.Context) {
+	if idpErr := ctx.Query("error"); idpErr != "" {
+		message := ctx.Query("error_description")
+		if message == "" {
+			message = idpErr
+		}
+		utils.ResponseMsg(utils.ErrCodeOIDCFailed, message).Export(ctx)
+		return
+	}
+
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		utils.Response(utils.ErrCodeOIDCStateInvalid).Export(ctx)
+		return
+	}
+
+	user, accessToken, err := ah.oidc_service.CompleteLogin(ctx, ctx.Param("provider"), code, state)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ah.auth_service.RecordLogin(ctx, user.Id, domain.LOGIN_OIDC, ctx.ClientIP(), ctx.Request.UserAgent())
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"accessToken": accessToken,
+		"user": gin.H{
+			"id":       user.Id,
+			"username": user.Username,
+			"email":    user.Email,
+			"role":     user.Role,
+		},
+	})
+}
@@ -1,20 +1,31 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
+	"time"
 
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	user_service service.UserService
+	user_service      service.UserService
+	api_token_service service.APITokenService
+	export_service    service.ExportService
 }
 
-func NewUserHandler(user_service service.UserService) *UserHandler {
+func NewUserHandler(user_service service.UserService, api_token_service service.APITokenService, export_service service.ExportService) *UserHandler {
 	return &UserHandler{
-		user_service: user_service,
+		user_service:      user_service,
+		api_token_service: api_token_service,
+		export_service:    export_service,
 	}
 }
 
@@ -25,8 +36,14 @@ func (uh *UserHandler) GetUserById(ctx *gin.Context) {
 		return
 	}
 
+	targetID := ctx.Param("id")
+	if targetID != "" && uuid.Validate(targetID) != nil {
+		utils.Response(utils.ErrCodeUserNotFound).Export(ctx)
+		return
+	}
+
 	var user domain.UserResponse
-	createdUser, err := uh.user_service.GetUserById(userID.(string))
+	createdUser, err := uh.user_service.GetProfile(ctx, userID.(string), targetID)
 	if err != nil {
 		err.Export(ctx)
 		return
@@ -37,6 +54,23 @@ func (uh *UserHandler) GetUserById(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+// SearchUsers: type-ahead chọn người nhận khi chia sẻ file, chỉ trả về username và email.
+func (uh *UserHandler) SearchUsers(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	users, err := uh.user_service.SearchUsers(ctx, userID.(string), ctx.Query("q"))
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"users": users})
+}
+
 func (uh *UserHandler) GetUserByEmail(ctx *gin.Context) {
 	email := ctx.Query("email")
 	if email == "" {
@@ -53,3 +87,204 @@ func (uh *UserHandler) GetUserByEmail(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, gin.H{"user": user})
 }
+
+func (uh *UserHandler) ListAPITokens(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	tokens, err := uh.api_token_service.ListTokens(ctx, userID.(string))
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (uh *UserHandler) CreateAPIToken(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var req dto.CreateAPITokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	raw, token, err := uh.api_token_service.CreateToken(ctx, userID.(string), &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	// Plaintext chỉ trả về đúng một lần ở đây.
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message":  "API token created. Copy it now, it will not be shown again.",
+		"token":    raw,
+		"apiToken": token,
+	})
+}
+
+func (uh *UserHandler) RevokeAPIToken(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	tokenID := ctx.Param("id")
+	if uuid.Validate(tokenID) != nil {
+		utils.Response(utils.ErrCodeAPITokenNotFound).Export(ctx)
+		return
+	}
+
+	if err := uh.api_token_service.RevokeToken(ctx, userID.(string), tokenID); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "API token revoked", nil)
+}
+
+func (uh *UserHandler) UpdateProfile(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	user, emailPending, err := uh.user_service.UpdateProfile(ctx, userID.(string), &req)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	message := "Profile updated"
+	if emailPending {
+		message = "Profile updated. Open the link sent to your new email address to complete the change."
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":                   message,
+		"user":                      user,
+		"emailVerificationRequired": emailPending,
+	})
+}
+
+func (uh *UserHandler) DeleteAccount(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var req dto.DeleteAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	// Thời điểm cấp phiên hiện tại, dùng làm bằng chứng đăng nhập lại với tài khoản SSO không có password
+	var authenticatedAt time.Time
+	claimsValue, _ := ctx.Get("user")
+	if claims, ok := claimsValue.(*jwt.Claims); ok && claims.IssuedAt != nil {
+		authenticatedAt = claims.IssuedAt.Time
+	}
+
+	if err := uh.user_service.DeleteAccount(ctx, userID.(string), authenticatedAt, &req, ctx.ClientIP()); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Account deleted", nil)
+}
+
+func (uh *UserHandler) StartExport(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	job, err := uh.export_service.StartExport(ctx, userID.(string))
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "Data export started",
+		"export":  job,
+	})
+}
+
+func (uh *UserHandler) GetExport(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	jobID := ctx.Param("id")
+	if uuid.Validate(jobID) != nil {
+		utils.Response(utils.ErrCodeExportNotFound).Export(ctx)
+		return
+	}
+
+	job, downloadURL, err := uh.export_service.GetExport(ctx, userID.(string), jobID)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	resp := gin.H{"export": job}
+	if downloadURL != "" {
+		resp["downloadUrl"] = downloadURL
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DownloadExport tải archive qua signed link, không cần Bearer token.
+func (uh *UserHandler) DownloadExport(ctx *gin.Context) {
+	jobID := ctx.Param("id")
+	if uuid.Validate(jobID) != nil {
+		utils.Response(utils.ErrCodeExportNotFound).Export(ctx)
+		return
+	}
+
+	var query dto.ExportDownloadQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.Response(utils.ErrCodeSignedURLInvalid).Export(ctx)
+		return
+	}
+
+	job, reader, err := uh.export_service.DownloadExport(ctx, jobID, &query)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	var size int64 = -1
+	if job.Size != nil {
+		size = *job.Size
+	}
+
+	// Stream thẳng từ storage, archive có thể rất lớn
+	ctx.DataFromReader(http.StatusOK, size, "application/zip", reader, map[string]string{
+		"Content-Disposition": "attachment; filename=\"export-" + job.CreatedAt.UTC().Format("20060102") + ".zip\"",
+	})
+}
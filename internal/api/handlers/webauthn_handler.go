@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (ah *AuthHandler) BeginWebAuthnRegistration(ctx *gin.Context) {
+	userID, ok := getUserIDFromContext(ctx)
+	if !ok {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var req dto.WebAuthnRegisterBeginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	sessionID, options, err := ah.webauthn_service.BeginRegistration(ctx, userID, req.Name)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	// options.publicKey truyền thẳng vào navigator.credentials.create()
+	ctx.JSON(http.StatusOK, gin.H{
+		"sessionId": sessionID,
+		"options":   options,
+	})
+}
+
+func (ah *AuthHandler) FinishWebAuthnRegistration(ctx *gin.Context) {
+	userID, ok := getUserIDFromContext(ctx)
+	if !ok {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	var req dto.WebAuthnFinishRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	credential, err := ah.webauthn_service.FinishRegistration(ctx, userID, req.SessionID, req.Credential)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message":    "Authenticator registered successfully",
+		"credential": credential,
+	})
+}
+
+func (ah *AuthHandler) ListWebAuthnCredentials(ctx *gin.Context) {
+	userID, ok := getUserIDFromContext(ctx)
+	if !ok {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	credentials, err := ah.webauthn_service.ListCredentials(ctx, userID)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"credentials": credentials})
+}
+
+func (ah *AuthHandler) DeleteWebAuthnCredential(ctx *gin.Context) {
+	userID, ok := getUserIDFromContext(ctx)
+	if !ok {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	credentialID := ctx.Param("id")
+	if uuid.Validate(credentialID) != nil {
+		utils.Response(utils.ErrCodeWebAuthnCredentialNotFound).Export(ctx)
+		return
+	}
+
+	if err := ah.webauthn_service.DeleteCredential(ctx, userID, credentialID); err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	utils.ResponseSuccess(ctx, http.StatusOK, "Authenticator removed", nil)
+}
+
+// BeginWebAuthnReauth: assertion dùng cho thao tác cần xác nhận lại, ví dụ DELETE /user.
+func (ah *AuthHandler) BeginWebAuthnReauth(ctx *gin.Context) {
+	userID, ok := getUserIDFromContext(ctx)
+	if !ok {
+		utils.Response(utils.ErrCodeBearerInvalid).Export(ctx)
+		return
+	}
+
+	sessionID, options, err := ah.webauthn_service.BeginReauthentication(ctx, userID)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"sessionId": sessionID,
+		"options":   options,
+	})
+}
+
+func (ah *AuthHandler) BeginWebAuthnLogin(ctx *gin.Context) {
+	var req dto.WebAuthnLoginBeginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	sessionID, options, err := ah.webauthn_service.BeginSecondFactor(ctx, req.CID)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"sessionId": sessionID,
+		"options":   options,
+	})
+}
+
+func (ah *AuthHandler) LoginWebAuthn(ctx *gin.Context) {
+	var req dto.WebAuthnLoginFinishRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	user, accessToken, err := ah.webauthn_service.FinishSecondFactor(ctx, req.CID, req.SessionID, req.Credential, ctx.ClientIP())
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ah.respondLoggedIn(ctx, user, accessToken, domain.LOGIN_WEBAUTHN)
+}
+
+func (ah *AuthHandler) BeginPasskeyLogin(ctx *gin.Context) {
+	sessionID, options, err := ah.webauthn_service.BeginPasskeyLogin(ctx)
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"sessionId": sessionID,
+		"options":   options,
+	})
+}
+
+func (ah *AuthHandler) LoginPasskey(ctx *gin.Context) {
+	var req dto.WebAuthnFinishRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ResponseValidator(ctx, validation.HandleValidationErrors(err))
+		return
+	}
+
+	user, accessToken, err := ah.webauthn_service.FinishPasskeyLogin(ctx, req.SessionID, req.Credential, ctx.ClientIP())
+	if err != nil {
+		err.Export(ctx)
+		return
+	}
+
+	ah.respondLoggedIn(ctx, user, accessToken, domain.LOGIN_PASSKEY)
+}
+
+func (ah *AuthHandler) respondLoggedIn(ctx *gin.Context, user *domain.User, accessToken string, method string) {
+	ah.auth_service.RecordLogin(ctx, user.Id, method, ctx.ClientIP(), ctx.Request.UserAgent())
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"accessToken": accessToken,
+		"user": gin.H{
+			"id":       user.Id,
+			"username": user.Username,
+			"email":    user.Email,
+		},
+	})
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -21,11 +22,22 @@ func (ar *AdminRoutes) Register(r *gin.RouterGroup) {
 	{
 		admin.Use(middleware.AuthMiddleware())
 		admin.Use(middleware.AdminAuthMiddleware())
+		admin.Use(middleware.RequireScope(domain.SCOPE_ADMIN))
 		// Cần có middleware kiểm tra quyền Admin tại đây
 		admin.GET("/policy", ar.handler.GetSystemPolicy)      // Lấy cấu hình hệ thống
 		admin.PATCH("/policy", ar.handler.UpdateSystemPolicy) // Cập nhật cấu hình hệ thống
 
 		// Cleanup có thể là protected route cho admin/cron job
 		admin.POST("/cleanup", ar.handler.CleanupExpiredFiles) // Xóa file hết hạn
+
+		// Allow/deny list domain email khi đăng ký
+		admin.GET("/email-domains", ar.handler.ListEmailDomainRules)
+		admin.PUT("/email-domains/:domain", ar.handler.SetEmailDomainRule)
+		admin.DELETE("/email-domains/:domain", ar.handler.DeleteEmailDomainRule)
+
+		// Mã mời cho chế độ đăng ký invite-only
+		admin.GET("/invites", ar.handler.ListInvites)
+		admin.POST("/invites", ar.handler.CreateInvite)
+		admin.DELETE("/invites/:id", ar.handler.DeleteInvite)
 	}
 }
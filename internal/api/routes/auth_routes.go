@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/middleware"
 	"github.com/gin-gonic/gin"
@@ -18,17 +19,38 @@ func NewAuthRoutes(handler *handlers.AuthHandler) *AuthRoutes {
 
 func (ur *AuthRoutes) Register(r *gin.RouterGroup) {
 	auth := r.Group("/auth")
+	auth.Use(middleware.RateLimit(config.RateLimitAuth))
 	{
 		auth.POST("/register", ur.handler.CreateUser)
+		auth.GET("/verify-email", ur.handler.VerifyEmail) // link trong email xác minh
+		auth.POST("/verify-email", ur.handler.VerifyEmail)
+		auth.POST("/verify-email/resend", ur.handler.ResendVerification)
 		auth.POST("/login", ur.handler.Login)
 		auth.POST("/login/totp", ur.handler.LoginTOTP)
+		auth.POST("/login/webauthn/begin", ur.handler.BeginWebAuthnLogin)
+		auth.POST("/login/webauthn", ur.handler.LoginWebAuthn)
+
+		// Passkey thay cho email + password
+		auth.POST("/passkey/begin", ur.handler.BeginPasskeyLogin)
+		auth.POST("/passkey/login", ur.handler.LoginPasskey)
+
+		// SSO qua OpenID Connect, đăng nhập bằng password vẫn dùng song song.
+		auth.GET("/oidc/providers", ur.handler.OIDCProviders)
+		auth.GET("/oidc/:provider/login", ur.handler.OIDCLogin)
+		auth.GET("/oidc/:provider/callback", ur.handler.OIDCCallback)
 	}
 	protected := auth.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(), middleware.SessionOnly())
 	{
 		// protected.POST("/password/change", ur.handler.ChangePassword)
 		protected.POST("/totp/setup", ur.handler.SetupTOTP)
 		protected.POST("/totp/verify", ur.handler.VerifyTOTP)
+
+		protected.POST("/webauthn/register/begin", ur.handler.BeginWebAuthnRegistration)
+		protected.POST("/webauthn/register/finish", ur.handler.FinishWebAuthnRegistration)
+		protected.GET("/webauthn/credentials", ur.handler.ListWebAuthnCredentials)
+		protected.DELETE("/webauthn/credentials/:id", ur.handler.DeleteWebAuthnCredential)
+		protected.POST("/webauthn/reauth/begin", ur.handler.BeginWebAuthnReauth)
 		// protected.POST("/totp/disable", ur.handler.DisableTOTP)
 		protected.POST("/logout", ur.handler.Logout)
 	}
@@ -1,7 +1,9 @@
 package routes
 
 import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -18,27 +20,49 @@ func NewFileRoutes(handler *handlers.FileHandler) *FileRoutes {
 
 func (fr *FileRoutes) Register(r *gin.RouterGroup) {
 	files := r.Group("/files")
+
+	// URL đã ký tự mang quyền truy cập, không cần Bearer hay password.
+	files.GET("/signed/:id", middleware.RateLimit(config.RateLimitFiles), fr.handler.DownloadSigned)
+
 	optional := files.Group("/")
 	optional.Use(middleware.AuthMiddlewareUpload())
 	{
-		optional.POST("/upload", fr.handler.UploadFile)
+		// Upload có bucket riêng (chặt hơn cho anonymous), không tính vào "files".
+		optional.POST("/upload", middleware.RequireScope(domain.SCOPE_FILES_WRITE), middleware.RateLimit(config.RateLimitUpload), fr.handler.UploadFile)
+	}
+	shared := optional.Group("")
+	shared.Use(middleware.RequireScope(domain.SCOPE_FILES_READ), middleware.RateLimit(config.RateLimitFiles))
+	{
+		shared.GET("/:shareToken", fr.handler.GetFileInfo)
 
-		optional.GET("/:shareToken", fr.handler.GetFileInfo)
+		shared.GET("/:shareToken/preview", fr.handler.PreviewFile)
+		shared.GET("/:shareToken/download", fr.handler.DownloadFile)
+		shared.POST("/:shareToken/signed-url", fr.handler.CreateSignedURL)
 
-		optional.GET("/:shareToken/preview", fr.handler.PreviewFile)
-		optional.GET("/:shareToken/download", fr.handler.DownloadFile)
+		// ZIP nhiều file (id, share token, thư mục hoặc bộ lọc của /files/my), stream trực tiếp.
+		shared.POST("/archive", fr.handler.DownloadArchive)
 	}
 	protected := files.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(), middleware.RateLimit(config.RateLimitAPI))
 	{
-		protected.GET("/available", fr.handler.GetAccessibleFiles)
+		read := middleware.RequireScope(domain.SCOPE_FILES_READ)
+		write := middleware.RequireScope(domain.SCOPE_FILES_WRITE)
+
+		protected.GET("/available", read, fr.handler.GetAccessibleFiles)
+
+		protected.GET("/my", read, fr.handler.GetMyFiles)
 
-		protected.GET("/my", fr.handler.GetMyFiles)
+		// Tìm kiếm full-text trên tên, mô tả, tag; xếp theo độ liên quan.
+		protected.GET("/search", read, fr.handler.SearchFiles)
 
 		// Sử dụng ID.
-		protected.DELETE("/info/:id", fr.handler.DeleteFile)
-		protected.GET("/info/:id", fr.handler.GetFileInfoVerbose)
-		protected.GET("/stats/:id", fr.handler.GetFileStats)
-		protected.GET("/download-history/:id", fr.handler.GetFileDownloadHistory)
+		protected.DELETE("/info/:id", write, fr.handler.DeleteFile)
+		protected.GET("/info/:id", read, fr.handler.GetFileInfoVerbose)
+		protected.PATCH("/info/:id", write, fr.handler.UpdateFileMetadata)
+		protected.PATCH("/info/:id/link", write, fr.handler.UpdateShareLink)
+		protected.PATCH("/info/:id/folder", write, fr.handler.MoveFile)
+		protected.GET("/info/:id/qr", read, fr.handler.GetShareQRCode)
+		protected.GET("/stats/:id", read, fr.handler.GetFileStats)
+		protected.GET("/download-history/:id", read, fr.handler.GetFileDownloadHistory)
 	}
 }
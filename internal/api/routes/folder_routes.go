@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type FolderRoutes struct {
+	handler *handlers.FolderHandler
+}
+
+func NewFolderRoutes(handler *handlers.FolderHandler) *FolderRoutes {
+	return &FolderRoutes{
+		handler: handler,
+	}
+}
+
+func (fr *FolderRoutes) Register(r *gin.RouterGroup) {
+	folders := r.Group("/folders")
+	{
+		read := middleware.RequireScope(domain.SCOPE_FILES_READ)
+		write := middleware.RequireScope(domain.SCOPE_FILES_WRITE)
+
+		folders.GET("", read, fr.handler.ListFolders)
+		folders.GET("/shared", read, fr.handler.ListSharedFolders)
+		folders.POST("", write, fr.handler.CreateFolder)
+		folders.GET("/:id", read, fr.handler.GetFolder)
+		folders.PATCH("/:id", write, fr.handler.RenameFolder)
+		folders.POST("/:id/move", write, fr.handler.MoveFolder)
+		folders.DELETE("/:id", write, fr.handler.DeleteFolder)
+
+		// Chia sẻ cả thư mục, áp dụng cho mọi file trong cây con kể cả file thêm vào sau.
+		folders.PUT("/:id/share", write, fr.handler.ShareFolder)
+	}
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type GroupRoutes struct {
+	handler *handlers.GroupHandler
+}
+
+func NewGroupRoutes(handler *handlers.GroupHandler) *GroupRoutes {
+	return &GroupRoutes{
+		handler: handler,
+	}
+}
+
+func (gr *GroupRoutes) Register(r *gin.RouterGroup) {
+	groups := r.Group("/groups")
+	{
+		read := middleware.RequireScope(domain.SCOPE_FILES_READ)
+		write := middleware.RequireScope(domain.SCOPE_FILES_WRITE)
+
+		groups.GET("", read, gr.handler.ListGroups)
+		groups.POST("", write, gr.handler.CreateGroup)
+		groups.GET("/:id", read, gr.handler.GetGroup)
+		groups.PATCH("/:id", write, gr.handler.UpdateGroup)
+		groups.DELETE("/:id", write, gr.handler.DeleteGroup)
+
+		// Thêm/xóa thành viên có hiệu lực ngay với mọi file đã chia sẻ cho nhóm.
+		groups.POST("/:id/members", write, gr.handler.AddMember)
+		groups.PATCH("/:id/members/:userId", write, gr.handler.UpdateMember)
+		groups.DELETE("/:id/members/:userId", write, gr.handler.RemoveMember)
+	}
+}
@@ -1,9 +1,11 @@
 package routes
 
 import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/middleware"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,16 +13,18 @@ type Route interface {
 	Register(r *gin.RouterGroup)
 }
 
-func RegisterRoutes(r *gin.Engine, authService jwt.TokenService, authRepo repository.AuthRepository, routes ...Route) {
+func RegisterRoutes(r *gin.Engine, authService jwt.TokenService, authRepo repository.AuthRepository, apiTokenService service.APITokenService, policy *config.SystemPolicy, routes ...Route) {
 
 	api := r.Group("/")
 
-	middleware.InitAuthMiddleware(authService, authRepo)
+	middleware.InitAuthMiddleware(authService, authRepo, apiTokenService)
+	middleware.InitRateLimitMiddleware(policy)
 
 	protected := api.Group("")
 
 	protected.Use(
 		middleware.AuthMiddleware(),
+		middleware.RateLimit(config.RateLimitAPI),
 	)
 
 	for _, route := range routes {
@@ -29,6 +33,9 @@ func RegisterRoutes(r *gin.Engine, authService jwt.TokenService, authRepo reposi
 			route.Register(api)
 		case *FileRoutes:
 			route.Register(api)
+		case *UserRoutes:
+			// Tự gắn AuthMiddleware: /user/export/:id/download là link ký sẵn, không cần token
+			route.Register(api)
 		default:
 			route.Register(protected)
 		}
@@ -1,7 +1,10 @@
 package routes
 
 import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,9 +19,48 @@ func NewUserRoutes(handler *handlers.UserHandler) *UserRoutes {
 }
 
 func (ur *UserRoutes) Register(r *gin.RouterGroup) {
+	// Link tải archive export đã được ký, không cần Bearer token
+	r.GET("/user/export/:id/download", middleware.RateLimit(config.RateLimitFiles), ur.handler.DownloadExport)
+
+	// Tìm người nhận khi chia sẻ, giới hạn riêng để không dò được cả danh bạ
+	directory := r.Group("/users")
+	directory.Use(
+		middleware.AuthMiddleware(),
+		middleware.RequireScope(domain.SCOPE_FILES_WRITE),
+		middleware.RateLimit(config.RateLimitUserSearch),
+	)
+	{
+		directory.GET("/search", ur.handler.SearchUsers)
+	}
+
 	users := r.Group("/user")
+	users.Use(
+		middleware.AuthMiddleware(),
+		middleware.RateLimit(config.RateLimitAPI),
+	)
 	{
 		users.GET("/:id", ur.handler.GetUserById)
 		users.GET("", ur.handler.GetUserById)
 	}
+
+	// Đổi thông tin/xóa tài khoản chỉ qua phiên đăng nhập, không qua API token.
+	account := users.Group("")
+	account.Use(middleware.SessionOnly())
+	{
+		account.PATCH("", ur.handler.UpdateProfile)
+		account.DELETE("", ur.handler.DeleteAccount)
+
+		// Archive chứa toàn bộ dữ liệu tài khoản nên chỉ phiên đăng nhập mới được yêu cầu.
+		account.POST("/export", ur.handler.StartExport)
+		account.GET("/export/:id", ur.handler.GetExport)
+	}
+
+	// Quản lý API token bắt buộc phiên đăng nhập, một key bị lộ không tự tạo thêm key được.
+	tokens := users.Group("/tokens")
+	tokens.Use(middleware.SessionOnly())
+	{
+		tokens.GET("", ur.handler.ListAPITokens)
+		tokens.POST("", ur.handler.CreateAPIToken)
+		tokens.DELETE("/:id", ur.handler.RevokeAPIToken)
+	}
 }
@@ -19,11 +19,14 @@ func NewAdminModule(
 	cfg *config.Config,
 	fileRepo repository.FileRepository, // <-- THÊM
 	storageService storage.Storage, // <-- THÊM
+	guard service.BruteForceGuard, // Cleanup dọn luôn các bộ đếm đoán sai đã cũ
+	registrationService service.RegistrationService, // allow/deny list domain và mã mời
+	exportService service.ExportService, // Cleanup xóa luôn archive export đã hết hạn
 ) Module {
 
 	// Policy tĩnh: không cần Repository
-	adminService := service.NewAdminService(cfg, fileRepo, storageService) // <-- CẬP NHẬT
-	adminHandler := handlers.NewAdminHandler(adminService)
+	adminService := service.NewAdminService(cfg, fileRepo, storageService, guard, exportService) // <-- CẬP NHẬT
+	adminHandler := handlers.NewAdminHandler(adminService, registrationService)
 	adminRoutes := routes.NewAdminRoutes(adminHandler)
 
 	return &adminModule{
@@ -9,8 +9,13 @@ import (
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/routes"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/mail"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/signer"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository/sqlite"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -27,7 +32,8 @@ type Application struct {
 }
 
 type ModuleContext struct {
-	DB *sql.DB
+	DB    *sql.DB
+	Repos *repository.Repositories
 }
 
 func (a *Application) Router() *gin.Engine {
@@ -44,8 +50,8 @@ func NewApplication(cfg *config.Config) *Application {
 
 	corsConfig := cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-File-Password"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -58,38 +64,72 @@ func NewApplication(cfg *config.Config) *Application {
 
 	r.Use(cors.New(corsConfig))
 
-	if err := database.InitDB(); err != nil {
-		log.Fatalf("unable to connnect to db: %v", err)
+	if err := validation.InitValidator(); err != nil {
+		log.Fatalf("unable to register custom validators: %v", err)
 	}
 
+	repos := OpenRepositories(cfg)
+
 	ctx := &ModuleContext{
-		DB: database.DB,
+		DB:    database.DB,
+		Repos: repos,
 	}
 
 	tokenService := jwt.NewJWTService()
-	authRepo := repository.NewAuthRepository(database.DB)
+	authRepo := repos.Auth
 
 	// Khởi tạo Repositories cần thiết
-	fileRepo := repository.NewFileRepository(database.DB)
-	sharedRepo := repository.NewSharedRepository(database.DB)
+	fileRepo := repos.File
+	sharedRepo := repos.Shared
+
+	userRepo := repos.User
+
+	// Nhóm người dùng: file module cần để kiểm tra quyền truy cập qua nhóm
+	groupRepo := repos.Group
+
+	// Thư mục: file thừa hưởng quyền chia sẻ từ thư mục chứa nó
+	folderRepo := repos.Folder
 
-	userRepo := repository.NewSQLUserRepository(database.DB)
+	// Unit of work: các repository trên tham gia chung transaction qua context
+	txManager := repos.Tx
 
 	// Khởi tạo Storage Service
 	// Cần đảm bảo đường dẫn này đúng với CWD: "cmd/server/uploads"
-	storageService := storage.NewLocalStorage("uploads")
+	storageService := storage.NewLocalStorage(UploadDir)
+
+	// Ký các direct download URL ngắn hạn
+	urlSigner := signer.NewHMACSigner()
+
+	// Chống dò password/TOTP, bộ đếm lưu trong database để dùng chung giữa các replica
+	guard := service.NewBruteForceGuard(repos.Attempt)
+
+	// Personal access token cho script/CI, dùng chung giữa middleware và /user/tokens
+	apiTokenService := service.NewAPITokenService(repos.APIToken, userRepo)
+
+	// Chế độ đăng ký, allow/deny list domain, mã mời và email xác minh; dùng chung giữa auth và admin
+	registrationService := service.NewRegistrationService(cfg, repos.Registration, userRepo, mail.NewMailer(cfg.Mail))
+
+	// Export dữ liệu cá nhân; admin cleanup dọn các archive hết hạn
+	exportService := service.NewExportService(cfg, repos.Export, userRepo, fileRepo, sharedRepo, repos.LoginHistory, storageService, urlSigner)
+
+	// WebAuthn: đăng nhập ở auth module, xác nhận lại trước khi xóa tài khoản ở user module
+	webAuthnService := service.NewWebAuthnService(cfg.WebAuthn, repos.WebAuthn, userRepo, tokenService, guard)
 
 	modules := []Module{
-		NewUserModule(ctx),
-		NewAuthModule(ctx, tokenService),
+		NewUserModule(cfg, ctx, fileRepo, storageService, apiTokenService, registrationService, guard, exportService, webAuthnService),
+		NewAuthModule(cfg, ctx, tokenService, guard, registrationService, webAuthnService),
 
 		// CẬP NHẬT: Thêm fileRepo và storageService cho Admin Module
-		NewAdminModule(cfg, fileRepo, storageService),
+		NewAdminModule(cfg, fileRepo, storageService, guard, registrationService, exportService),
 
-		NewFileModule(cfg, fileRepo, sharedRepo, userRepo, storageService),
+		NewFileModule(cfg, fileRepo, sharedRepo, userRepo, groupRepo, folderRepo, txManager, storageService, urlSigner, guard),
+
+		NewGroupModule(groupRepo, userRepo),
+
+		NewFolderModule(cfg, folderRepo, fileRepo, groupRepo, userRepo),
 	}
 
-	routes.RegisterRoutes(r, tokenService, authRepo, getModuleRoutes(modules)...)
+	routes.RegisterRoutes(r, tokenService, authRepo, apiTokenService, cfg.Policy, getModuleRoutes(modules)...)
 
 	return &Application{
 		config:  cfg,
@@ -99,6 +139,30 @@ func NewApplication(cfg *config.Config) *Application {
 	}
 }
 
+// UploadDir là thư mục lưu file upload, tính từ thư mục làm việc của server (và cmd/fsadmin).
+const UploadDir = "uploads"
+
+// OpenRepositories kết nối database theo DATABASE_URL, chạy migration nếu bật AUTO_MIGRATE và trả
+// về repository của backend tương ứng. Dùng chung cho server và cmd/fsadmin.
+func OpenRepositories(cfg *config.Config) *repository.Repositories {
+	if err := database.InitDB(); err != nil {
+		log.Fatalf("unable to connnect to db: %v", err)
+	}
+
+	// SQLite luôn tự migrate khi mở file; Postgres chỉ migrate khi bật AUTO_MIGRATE
+	if cfg.AutoMigrate && database.Driver == database.DriverPostgres {
+		if err := database.RunMigrations(database.DB); err != nil {
+			log.Fatalf("unable to migrate db: %v", err)
+		}
+	}
+
+	// Backend lưu trữ theo DATABASE_URL: Postgres hoặc sqlite://path cho cài đặt một node
+	if database.Driver == database.DriverSQLite {
+		return sqlite.NewRepositories(database.DB)
+	}
+	return repository.NewPostgresRepositories(database.DB)
+}
+
 func (a *Application) Run() error {
 	if a.config.ServerAddress == "" {
 		a.config.ServerAddress = ":8080"
@@ -1,10 +1,10 @@
 package app
 
 import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/routes"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
-	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
 )
 
@@ -12,11 +12,14 @@ type AuthModule struct {
 	routes routes.Route
 }
 
-func NewAuthModule(ctx *ModuleContext, tokenService jwt.TokenService) *AuthModule {
-	userRepository := repository.NewSQLUserRepository(ctx.DB)
-	authRepository := repository.NewAuthRepository(ctx.DB)
-	authService := service.NewAuthService(userRepository, authRepository, tokenService)
-	authHandler := handlers.NewAuthHandler(authService)
+func NewAuthModule(cfg *config.Config, ctx *ModuleContext, tokenService jwt.TokenService, guard service.BruteForceGuard, registrationService service.RegistrationService, webAuthnService service.WebAuthnService) *AuthModule {
+	userRepository := ctx.Repos.User
+	authRepository := ctx.Repos.Auth
+	oidcRepository := ctx.Repos.OIDC
+	webAuthnRepository := ctx.Repos.WebAuthn
+	authService := service.NewAuthService(userRepository, authRepository, webAuthnRepository, registrationService, tokenService, guard, cfg.Policy, ctx.Repos.LoginHistory)
+	oidcService := service.NewOIDCService(cfg.OIDC, userRepository, authRepository, oidcRepository, registrationService, tokenService)
+	authHandler := handlers.NewAuthHandler(authService, oidcService, webAuthnService, registrationService)
 	authRoutes := routes.NewAuthRoutes(authHandler)
 	return &AuthModule{routes: authRoutes}
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/routes"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/signer"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
@@ -18,9 +19,14 @@ func NewFileModule(
 	fileRepo repository.FileRepository,
 	sharedRepo repository.SharedRepository,
 	userRepo repository.UserRepository,
+	groupRepo repository.GroupRepository,
+	folderRepo repository.FolderRepository,
+	txManager repository.TxManager,
 	storageService storage.Storage,
+	urlSigner signer.URLSigner,
+	guard service.BruteForceGuard,
 ) Module {
-	fileService := service.NewFileService(cfg, fileRepo, sharedRepo, userRepo, storageService)
+	fileService := service.NewFileService(cfg, fileRepo, sharedRepo, userRepo, groupRepo, folderRepo, txManager, storageService, urlSigner, guard)
 	fileHandler := handlers.NewFileHandler(fileService)
 	fileRoutes := routes.NewFileRoutes(fileHandler)
 
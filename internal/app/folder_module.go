@@ -0,0 +1,33 @@
+package app
+
+import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/routes"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+)
+
+type folderModule struct {
+	routes routes.Route
+}
+
+func NewFolderModule(
+	cfg *config.Config,
+	folderRepo repository.FolderRepository,
+	fileRepo repository.FileRepository,
+	groupRepo repository.GroupRepository,
+	userRepo repository.UserRepository,
+) Module {
+	folderService := service.NewFolderService(cfg, folderRepo, fileRepo, groupRepo, userRepo)
+	folderHandler := handlers.NewFolderHandler(folderService)
+	folderRoutes := routes.NewFolderRoutes(folderHandler)
+
+	return &folderModule{
+		routes: folderRoutes,
+	}
+}
+
+func (m *folderModule) Routes() routes.Route {
+	return m.routes
+}
@@ -0,0 +1,26 @@
+package app
+
+import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/routes"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+)
+
+type groupModule struct {
+	routes routes.Route
+}
+
+func NewGroupModule(groupRepo repository.GroupRepository, userRepo repository.UserRepository) Module {
+	groupService := service.NewGroupService(groupRepo, userRepo)
+	groupHandler := handlers.NewGroupHandler(groupService)
+	groupRoutes := routes.NewGroupRoutes(groupHandler)
+
+	return &groupModule{
+		routes: groupRoutes,
+	}
+}
+
+func (m *groupModule) Routes() routes.Route {
+	return m.routes
+}
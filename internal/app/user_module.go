@@ -1,8 +1,10 @@
 package app
 
 import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/handlers"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/routes"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
 )
@@ -11,10 +13,11 @@ type UserModule struct {
 	routes routes.Route
 }
 
-func NewUserModule(ctx *ModuleContext) *UserModule {
-	userRepository := repository.NewSQLUserRepository(ctx.DB)
-	userService := service.NewUserService(userRepository)
-	userHandler := handlers.NewUserHandler(userService)
+func NewUserModule(cfg *config.Config, ctx *ModuleContext, fileRepo repository.FileRepository, storageService storage.Storage, apiTokenService service.APITokenService, registrationService service.RegistrationService, guard service.BruteForceGuard, exportService service.ExportService, webAuthnService service.WebAuthnService) *UserModule {
+	userRepository := ctx.Repos.User
+	authRepository := ctx.Repos.Auth
+	userService := service.NewUserService(cfg, userRepository, authRepository, fileRepo, storageService, registrationService, guard, exportService, webAuthnService, ctx.Repos.Tx)
+	userHandler := handlers.NewUserHandler(userService, apiTokenService, exportService)
 	userRoutes := routes.NewUserRoutes(userHandler)
 	return &UserModule{routes: userRoutes}
 }
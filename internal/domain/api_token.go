@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+const APITokenPrefix = "pat_"
+
+const (
+	SCOPE_FILES_READ  = "files:read"
+	SCOPE_FILES_WRITE = "files:write"
+	SCOPE_ADMIN       = "admin"
+)
+
+var APITokenScopes = []string{SCOPE_FILES_READ, SCOPE_FILES_WRITE, SCOPE_ADMIN}
+
+type APIToken struct {
+	Id         string     `json:"id"`
+	UserId     string     `json:"-"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+func (t *APIToken) Expired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
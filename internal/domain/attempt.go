@@ -0,0 +1,27 @@
+package domain
+
+type AttemptScope string
+
+const (
+	ATTEMPT_IP          AttemptScope = "ip"
+	ATTEMPT_ACCOUNT     AttemptScope = "account"
+	ATTEMPT_SHARE_TOKEN AttemptScope = "share_token"
+)
+
+// AttemptKey xác định một bộ đếm đoán sai, ví dụ {ip, 1.2.3.4} hoặc {account, a@b.com}.
+type AttemptKey struct {
+	Scope AttemptScope
+	Key   string
+}
+
+func IPAttempt(ip string) AttemptKey {
+	return AttemptKey{Scope: ATTEMPT_IP, Key: ip}
+}
+
+func AccountAttempt(email string) AttemptKey {
+	return AttemptKey{Scope: ATTEMPT_ACCOUNT, Key: email}
+}
+
+func ShareTokenAttempt(token string) AttemptKey {
+	return AttemptKey{Scope: ATTEMPT_SHARE_TOKEN, Key: token}
+}
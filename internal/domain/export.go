@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+type ExportStatus string
+
+const (
+	EXPORT_PENDING ExportStatus = "pending"
+	EXPORT_RUNNING ExportStatus = "running"
+	EXPORT_READY   ExportStatus = "ready"
+	EXPORT_FAILED  ExportStatus = "failed"
+)
+
+// ExportJob: một lần export dữ liệu cá nhân, archive ZIP được build nền rồi tải qua signed link.
+type ExportJob struct {
+	Id          string       `json:"id"`
+	UserId      string       `json:"-"`
+	Status      ExportStatus `json:"status"`
+	Error       *string      `json:"error,omitempty"`
+	Size        *int64       `json:"size,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	CompletedAt *time.Time   `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time   `json:"expiresAt,omitempty"`
+}
+
+// Done báo job đã kết thúc (thành công hoặc lỗi).
+func (j *ExportJob) Done() bool {
+	return j.Status == EXPORT_READY || j.Status == EXPORT_FAILED
+}
+
+const (
+	LOGIN_PASSWORD = "password"
+	LOGIN_TOTP     = "totp"
+	LOGIN_WEBAUTHN = "webauthn"
+	LOGIN_PASSKEY  = "passkey"
+	LOGIN_OIDC     = "oidc"
+)
+
+type LoginRecord struct {
+	Id        string    `json:"id"`
+	UserId    string    `json:"-"`
+	Method    string    `json:"method"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UserDownload: một lượt tải do chính user thực hiện (file có thể của người khác).
+type UserDownload struct {
+	FileId       string    `json:"fileId"`
+	FileName     string    `json:"fileName"`
+	DownloadedAt time.Time `json:"downloadedAt"`
+}
+
+// SharedFile: file người khác chia sẻ riêng cho user.
+type SharedFile struct {
+	FileId     string    `json:"fileId"`
+	FileName   string    `json:"fileName"`
+	OwnerEmail *string   `json:"ownerEmail"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
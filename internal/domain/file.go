@@ -13,11 +13,15 @@ const (
 type File struct {
 	Id            string     `json:"id" db:"id"`
 	OwnerId       *string    `json:"ownerId" db:"user_id"`
+	FolderId      *string    `json:"folderId" db:"folder_id"` // nil = thư mục gốc
 	FileName      string     `json:"fileName" db:"name"`
+	Description   *string    `json:"description" db:"description"`
+	Tags          []string   `json:"tags" db:"tags"` // chữ thường, không trùng
 	StorageName   string     `json:"-" db:"storage_name"`
 	FileSize      int64      `json:"fileSize" db:"size"`
 	MimeType      string     `json:"mimeType" db:"type"`
 	ShareToken    string     `json:"shareToken" db:"share_token"`
+	ShareLink     string     `json:"shareLink" db:"-"`
 	IsPublic      bool       `json:"isPublic" db:"is_public"`
 	HasPassword   bool       `json:"hasPassword" db:"has_password"`
 	PasswordHash  *string    `json:"-" db:"password"`
@@ -25,16 +29,61 @@ type File struct {
 	AvailableFrom time.Time  `json:"availableFrom" db:"available_from"`
 	AvailableTo   time.Time  `json:"availableTo" db:"available_to"`
 	ValidityDays  int        `json:"-" db:"validity_days"`
+	MaxDownloads  *int       `json:"maxDownloads" db:"max_downloads"` // nil = không giới hạn
+	DeleteOnLimit bool       `json:"deleteOnLimit" db:"delete_on_limit"`
+	DownloadCount int64      `json:"downloadCount" db:"download_count"`
+	Version       int        `json:"-" db:"version"` // tăng mỗi lần đổi share link
 	Status        FileStatus `json:"status"`
 	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt     *time.Time `json:"-" db:"updated_at"`
+	SharedGroups  []string   `json:"sharedWithGroups,omitempty" db:"-"` // id các nhóm được chia sẻ
+	Owner         *FileOwner `json:"-" db:"-"`                          // JOIN từ users, nil = upload ẩn danh
+
+	// Kết quả chia sẻ theo email lúc upload, nil khi không chia sẻ cho email nào
+	Shares *ShareResult `json:"-" db:"-"`
+}
+
+// StorageReport là kết quả đối chiếu metadata file trong database với file trong storage.
+type StorageReport struct {
+	Checked      int      // số file có metadata đã kiểm tra
+	Missing      []File   // có metadata nhưng không có file trong storage
+	SizeMismatch []File   // kích thước trong storage khác FileSize
+	Orphans      []string // file trong storage không thuộc metadata nào (có thể là upload đang chạy dở)
+}
+
+// FileOwner là thông tin chủ file được đọc cùng file trong một truy vấn.
+type FileOwner struct {
+	Id       string
+	Username string
+	Email    string
+	Role     string
+}
+
+// StatusAt tính trạng thái hiệu lực của file tại thời điểm now.
+func (f *File) StatusAt(now time.Time) FileStatus {
+	if now.Before(f.AvailableFrom) {
+		return FILE_PENDING
+	} else if now.After(f.AvailableTo) {
+		return FILE_EXPIRED
+	}
+	return FILE_ACTIVE
 }
 
-type Pagination struct {
-	CurrentPage  int `json:"currentPage"`
-	TotalPages   int `json:"totalPages"`
-	TotalRecords int `json:"totalRecords"`
-	Limit        int `json:"limit"`
+// DownloadLimitReached báo link đã dùng hết số lượt tải cho phép.
+func (f *File) DownloadLimitReached() bool {
+	return f.MaxDownloads != nil && f.DownloadCount >= int64(*f.MaxDownloads)
+}
+
+// Archive là tập file đã qua kiểm tra quyền của POST /files/archive, ghi ra ZIP khi stream.
+type Archive struct {
+	Name    string
+	Entries []ArchiveEntry
+	Skipped int // file trong thư mục/bộ lọc bị bỏ qua vì không tải được (hết hạn, cần password...)
+}
+
+type ArchiveEntry struct {
+	Path string // đường dẫn trong ZIP
+	File *File
 }
 
 type Downloader struct {
@@ -58,11 +107,29 @@ type FileDownloadHistory struct {
 }
 
 type ListFileParams struct {
-	Status string
-	Page   int
-	Limit  int
-	SortBy string
-	Order  string
+	Status   string
+	FolderId string // rỗng = mọi thư mục, "root" = chỉ file ở thư mục gốc
+	Page     PageRequest
+}
+
+// FileSearchParams là bộ lọc của GET /files/search, giá trị zero = không lọc.
+type FileSearchParams struct {
+	Query    string // tsquery đã chuẩn hóa (khớp tiền tố từng từ), rỗng = không tìm theo từ khóa
+	Scope    string // "all" | "mine" | "shared"
+	Tags     []string
+	MimeType string // "image/png" hoặc "image/*"
+	MinSize  *int64
+	MaxSize  *int64
+	From     *time.Time // created_at >= From
+	To       *time.Time // created_at < To
+	Owner    string     // email chủ file
+	Page     PageRequest
+}
+
+// FileSearchHit là một kết quả tìm kiếm, Rank = 0 khi không có từ khóa.
+type FileSearchHit struct {
+	File File
+	Rank float64
 }
 
 type FileSummary struct {
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Folder: thư mục của user. Path là materialized path "/<id gốc>/.../<id>/".
+type Folder struct {
+	Id        string    `json:"id"`
+	OwnerId   string    `json:"ownerId"`
+	ParentId  *string   `json:"parentId"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Contains báo other nằm trong cây con của f (kể cả chính f).
+func (f *Folder) Contains(other *Folder) bool {
+	return len(other.Path) >= len(f.Path) && other.Path[:len(f.Path)] == f.Path
+}
+
+// FolderShares: danh sách người nhận của một thư mục, áp dụng cho mọi file trong cây con.
+type FolderShares struct {
+	SharedWith       []string `json:"sharedWith"`       // email
+	SharedWithGroups []string `json:"sharedWithGroups"` // id nhóm
+}
+
+// FolderContents là nội dung GET /folders/:id. Shares chỉ có khi người xem là owner/admin.
+type FolderContents struct {
+	Folder  *Folder       `json:"folder"`
+	Folders []Folder      `json:"folders"`
+	Files   []File        `json:"files"`
+	Shares  *FolderShares `json:"shares,omitempty"`
+}
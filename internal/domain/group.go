@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// Vai trò trong nhóm. Owner quản lý tên nhóm và thành viên, member chỉ nhận file được chia sẻ.
+const (
+	GROUP_OWNER  = "owner"
+	GROUP_MEMBER = "member"
+)
+
+// Group: nhóm người dùng, sharedWith của file có thể trỏ tới id nhóm thay vì từng email.
+type Group struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	MemberCount int       `json:"memberCount"`
+	// Vai trò của người đang xem; rỗng khi admin xem nhóm mình không tham gia.
+	MyRole string `json:"myRole,omitempty"`
+}
+
+type GroupMember struct {
+	UserId   string    `json:"userId"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	AddedAt  time.Time `json:"addedAt"`
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Khóa sắp xếp của các endpoint danh sách.
+const (
+	SORT_CREATED_AT    = "createdAt"
+	SORT_NAME          = "name"
+	SORT_SIZE          = "size"
+	SORT_EXPIRY        = "expiry"       // availableTo
+	SORT_RELEVANCE     = "relevance"    // chỉ GET /files/search
+	SORT_DOWNLOADED_AT = "downloadedAt" // lịch sử tải
+)
+
+const (
+	ORDER_ASC  = "asc"
+	ORDER_DESC = "desc"
+)
+
+// FileSorts là các khóa sắp xếp hợp lệ của danh sách file, SearchSorts của GET /files/search.
+var (
+	FileSorts   = []string{SORT_CREATED_AT, SORT_NAME, SORT_SIZE, SORT_EXPIRY}
+	SearchSorts = []string{SORT_RELEVANCE, SORT_CREATED_AT, SORT_NAME, SORT_SIZE, SORT_EXPIRY}
+)
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// PageRequest là một trang của phân trang keyset, After = nil là trang đầu.
+type PageRequest struct {
+	SortBy string
+	Order  string
+	Limit  int // <= 0 = không giới hạn
+	After  *Cursor
+}
+
+// Cursor là vị trí phần tử cuối của trang trước: giá trị các khóa sắp xếp
+// (dạng text của Postgres) và id để phân định các phần tử trùng khóa.
+type Cursor struct {
+	SortBy string   `json:"s"`
+	Order  string   `json:"o"`
+	Keys   []string `json:"k"`
+	Id     string   `json:"i"`
+}
+
+// Encode trả về token opaque gửi cho client trong pagination.nextCursor.
+func (c *Cursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeCursor đọc token do Encode sinh ra.
+func DecodeCursor(token string) (*Cursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil || c.Id == "" || len(c.Keys) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Pagination là envelope phân trang chung của các endpoint danh sách.
+type Pagination struct {
+	Limit      int     `json:"limit"`
+	SortBy     string  `json:"sortBy"`
+	Order      string  `json:"order"`
+	HasMore    bool    `json:"hasMore"`
+	NextCursor *string `json:"nextCursor"` // nil = trang cuối
+}
+
+// NewPagination tạo envelope cho trang page, next = nil khi không còn dữ liệu.
+func NewPagination(page PageRequest, next *Cursor) Pagination {
+	out := Pagination{
+		Limit:   page.Limit,
+		SortBy:  page.SortBy,
+		Order:   page.Order,
+		HasMore: next != nil,
+	}
+	if next != nil {
+		token := next.Encode()
+		out.NextCursor = &token
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// Chế độ đăng ký tài khoản (SystemPolicy.RegistrationMode)
+const (
+	REGISTRATION_OPEN   = "open"   // ai cũng đăng ký được, trừ domain bị chặn
+	REGISTRATION_INVITE = "invite" // cần mã mời do admin tạo
+	REGISTRATION_DOMAIN = "domain" // chỉ email thuộc domain được cho phép
+)
+
+var RegistrationModes = []string{REGISTRATION_OPEN, REGISTRATION_INVITE, REGISTRATION_DOMAIN}
+
+const (
+	DOMAIN_RULE_ALLOW = "allow"
+	DOMAIN_RULE_DENY  = "deny"
+)
+
+type EmailDomainRule struct {
+	Domain    string    `json:"domain"`
+	Rule      string    `json:"rule"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Invite struct {
+	Id        string     `json:"id"`
+	TokenHash string     `json:"-"`
+	Email     string     `json:"email,omitempty"` // rỗng = không giới hạn email
+	CreatedBy string     `json:"createdBy,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	UsedBy    string     `json:"usedBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// SignupTicket: kết quả kiểm tra một lượt đăng ký trước khi tạo user.
+type SignupTicket struct {
+	Invite        *Invite // invite đã được giữ chỗ, nil nếu đăng ký không cần mã mời
+	EmailVerified bool    // true khi không cần gửi email xác minh
+}
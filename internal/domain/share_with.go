@@ -13,4 +13,11 @@ type SharedWith struct {
 type Shared struct {
 	FileId  string   `json:"fileId"`
 	UserIds []string `json:"userIds"`
+	Emails  []string `json:"emails"` // cùng thứ tự với UserIds
+}
+
+// ShareResult là kết quả chia sẻ theo email: Resolved khớp tài khoản, Unresolved không tìm thấy.
+type ShareResult struct {
+	Resolved   []string `json:"resolved"`
+	Unresolved []string `json:"unresolved"`
 }
@@ -8,12 +8,17 @@ type User struct {
 	Role       string `json:"role"`
 	EnableTOTP bool   `json:"enableTOTP"`
 	SecretTOTP string `json:"secretTOTP"`
+	EmailVerified bool `json:"emailVerified"`
+	Suspended     bool `json:"suspended"` // admin tạm khóa tài khoản (users.suspended_at khác NULL)
+
+	EnableWebAuthn bool `json:"enableWebAuthn"` // không lưu trong bảng users, tính từ webauthn_credentials
 }
 
 type UserCreate struct {
 	Username string `json:"username" binding:"required"`
-	Email    string `json:"email" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	InviteToken string `json:"inviteToken"`
 }
 
 type UserResponse struct {
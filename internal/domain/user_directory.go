@@ -0,0 +1,25 @@
+package domain
+
+// Phạm vi tìm user khi chia sẻ file (SystemPolicy.UserDirectoryMode). Admin luôn tìm được mọi user.
+const (
+	USER_DIRECTORY_OFF    = "off"    // tắt tìm kiếm
+	USER_DIRECTORY_EXACT  = "exact"  // chỉ khớp đúng email, không gợi ý theo tiền tố
+	USER_DIRECTORY_DOMAIN = "domain" // chỉ user cùng domain email với người tìm
+	USER_DIRECTORY_ALL    = "all"    // mọi user
+)
+
+var UserDirectoryModes = []string{USER_DIRECTORY_OFF, USER_DIRECTORY_EXACT, USER_DIRECTORY_DOMAIN, USER_DIRECTORY_ALL}
+
+// PublicProfile: thông tin tối thiểu của user khác, dùng cho type-ahead khi chia sẻ.
+type PublicProfile struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type UserSearchParams struct {
+	Query      string // đã chuẩn hóa chữ thường
+	ExactEmail bool
+	Domain     string // rỗng = mọi domain
+	ExcludeId  string
+	Limit      int
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+type WebAuthnPurpose string
+
+const (
+	WEBAUTHN_REGISTER WebAuthnPurpose = "register"
+	WEBAUTHN_MFA      WebAuthnPurpose = "mfa"
+	WEBAUTHN_PASSKEY  WebAuthnPurpose = "passkey"
+	WEBAUTHN_REAUTH   WebAuthnPurpose = "reauth"
+)
+
+type WebAuthnCredential struct {
+	Id           string     `json:"id"`
+	UserId       string     `json:"-"`
+	CredentialID []byte     `json:"-"`
+	Name         string     `json:"name"`
+	Data         []byte     `json:"-"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastUsedAt   *time.Time `json:"lastUsedAt"`
+}
+
+type WebAuthnSession struct {
+	Id        string
+	Purpose   WebAuthnPurpose
+	UserId    string // rỗng với passkey login (chưa biết user)
+	Cid       string // CID của bước Login khi dùng làm second factor
+	Name      string // tên authenticator khi đăng ký
+	Data      []byte
+	ExpiresAt time.Time
+}
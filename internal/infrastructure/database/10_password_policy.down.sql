@@ -0,0 +1 @@
+ALTER TABLE files ADD CONSTRAINT files_password_check CHECK (length(password) >= 6);
@@ -0,0 +1,3 @@
+-- Cột password lưu bcrypt hash (luôn 60 ký tự) nên CHECK này không kiểm tra được gì.
+-- Độ dài/độ mạnh của password do password policy trong SystemPolicy kiểm tra trước khi hash.
+ALTER TABLE files DROP CONSTRAINT IF EXISTS files_password_check;
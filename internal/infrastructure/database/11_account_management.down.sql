@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_token_revocations;
+ALTER TABLE email_verifications DROP COLUMN IF EXISTS email;
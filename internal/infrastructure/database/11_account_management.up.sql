@@ -0,0 +1,10 @@
+-- Link xác minh cho email mới khi user đổi email: email chỉ được thay sau khi mở link.
+-- NULL = xác minh email hiện tại (khi đăng ký).
+ALTER TABLE email_verifications ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+
+-- JWT của user cấp trước revoked_at bị từ chối. Không có FK vì vẫn cần sau khi user bị xóa;
+-- có thể dọn các dòng cũ hơn thời hạn access token.
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    user_id UUID PRIMARY KEY,
+    revoked_at TIMESTAMPTZ NOT NULL
+);
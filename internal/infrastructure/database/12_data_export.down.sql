@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS export_jobs;
+DROP TABLE IF EXISTS login_history;
@@ -0,0 +1,27 @@
+-- Lịch sử đăng nhập, dùng cho export dữ liệu cá nhân.
+CREATE TABLE IF NOT EXISTS login_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    method VARCHAR(20) NOT NULL, -- password | totp | webauthn | passkey | oidc
+    ip VARCHAR(64),
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT login_history_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS login_history_user_id_idx ON login_history (user_id, created_at DESC);
+
+-- Job export dữ liệu cá nhân (GDPR). Archive ZIP nằm trong storage tại exports/<id>.zip.
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending | running | ready | failed
+    error TEXT,
+    size BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    CONSTRAINT export_jobs_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS export_jobs_user_id_idx ON export_jobs (user_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS shared_groups;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
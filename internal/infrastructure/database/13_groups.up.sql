@@ -0,0 +1,31 @@
+-- Nhóm người dùng (team) để chia sẻ file một lần cho nhiều người.
+CREATE TABLE IF NOT EXISTS groups (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    role VARCHAR(10) NOT NULL DEFAULT 'member', -- owner | member
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, user_id),
+    CONSTRAINT group_members_group_id_fkey FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT group_members_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS group_members_user_id_idx ON group_members (user_id);
+
+-- File chia sẻ cho cả nhóm. Quyền truy cập được tính theo group_members tại thời điểm kiểm tra,
+-- nên thêm/xóa thành viên có hiệu lực ngay.
+CREATE TABLE IF NOT EXISTS shared_groups (
+    group_id UUID NOT NULL,
+    file_id UUID NOT NULL,
+    PRIMARY KEY (group_id, file_id),
+    CONSTRAINT shared_groups_group_id_fkey FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT shared_groups_file_id_fkey FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS shared_groups_file_id_idx ON shared_groups (file_id);
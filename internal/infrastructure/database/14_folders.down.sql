@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS shared_folder_groups;
+DROP TABLE IF EXISTS shared_folders;
+ALTER TABLE files DROP COLUMN IF EXISTS folder_id;
+DROP TABLE IF EXISTS folders;
@@ -0,0 +1,42 @@
+-- Thư mục của user. path là materialized path '/<id gốc>/.../<id>/' để lấy cả cây con bằng
+-- LIKE 'path%' và lấy tổ tiên bằng cách tách path, không cần truy vấn đệ quy.
+CREATE TABLE IF NOT EXISTS folders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    parent_id UUID,
+    name VARCHAR(255) NOT NULL,
+    path TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT folders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT folders_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES folders(id) ON DELETE CASCADE
+);
+
+-- Không trùng tên (không phân biệt hoa thường) trong cùng một thư mục cha.
+CREATE UNIQUE INDEX IF NOT EXISTS folders_sibling_name_key
+    ON folders (user_id, COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), lower(name));
+CREATE INDEX IF NOT EXISTS folders_path_idx ON folders (path text_pattern_ops);
+
+-- Xóa thư mục không xóa file, file bên trong trở về thư mục gốc.
+ALTER TABLE files ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS files_folder_id_idx ON files (folder_id);
+
+-- Chia sẻ cả thư mục: áp dụng cho mọi file trong cây con, kể cả file thêm vào sau.
+CREATE TABLE IF NOT EXISTS shared_folders (
+    folder_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (folder_id, user_id),
+    CONSTRAINT shared_folders_folder_id_fkey FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+    CONSTRAINT shared_folders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shared_folder_groups (
+    folder_id UUID NOT NULL,
+    group_id UUID NOT NULL,
+    PRIMARY KEY (folder_id, group_id),
+    CONSTRAINT shared_folder_groups_folder_id_fkey FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+    CONSTRAINT shared_folder_groups_group_id_fkey FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS shared_folders_user_id_idx ON shared_folders (user_id);
+CREATE INDEX IF NOT EXISTS shared_folder_groups_group_id_idx ON shared_folder_groups (group_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_files_tags;
+DROP INDEX IF EXISTS idx_files_search_vector;
+ALTER TABLE files DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS files_search_vector(TEXT, TEXT, TEXT[]);
+ALTER TABLE files
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS description;
@@ -0,0 +1,24 @@
+ALTER TABLE files
+    ADD COLUMN IF NOT EXISTS description TEXT,
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- array_to_string chỉ là STABLE nên phải bọc lại thì mới dùng được trong generated column.
+-- Tên file được tách theo ký tự không phải chữ/số để "bao_cao-2024.pdf" khớp "bao", "cao", "2024", "pdf".
+-- Dùng config 'simple' (không stemming) vì tên và mô tả phần lớn là tiếng Việt.
+CREATE OR REPLACE FUNCTION files_search_vector(f_name TEXT, f_description TEXT, f_tags TEXT[])
+RETURNS tsvector
+LANGUAGE sql
+IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT
+        setweight(to_tsvector('simple', regexp_replace(COALESCE(f_name, ''), '[^[:alnum:]]+', ' ', 'g')), 'A') ||
+        setweight(to_tsvector('simple', array_to_string(COALESCE(f_tags, '{}'), ' ')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(f_description, '')), 'C')
+$$;
+
+ALTER TABLE files
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (files_search_vector(name, description, tags)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_files_search_vector ON files USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_files_tags ON files USING GIN (tags);
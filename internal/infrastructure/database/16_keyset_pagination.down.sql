@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS download_file_time_idx;
+DROP INDEX IF EXISTS files_user_available_to_idx;
+DROP INDEX IF EXISTS files_user_size_idx;
+DROP INDEX IF EXISTS files_user_name_idx;
+DROP INDEX IF EXISTS files_user_created_at_idx;
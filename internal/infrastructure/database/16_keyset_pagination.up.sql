@@ -0,0 +1,9 @@
+-- Index cho phân trang keyset của GET /files/my: (user_id, khóa sắp xếp, id) khớp đúng
+-- điều kiện "(key, id) < (cursor)" và ORDER BY key, id theo cả hai chiều.
+CREATE INDEX IF NOT EXISTS files_user_created_at_idx ON files (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS files_user_name_idx ON files (user_id, name, id);
+CREATE INDEX IF NOT EXISTS files_user_size_idx ON files (user_id, size, id);
+CREATE INDEX IF NOT EXISTS files_user_available_to_idx ON files (user_id, available_to, id);
+
+-- Lịch sử tải của một file, sắp xếp theo thời điểm tải.
+CREATE INDEX IF NOT EXISTS download_file_time_idx ON download (file_id, time, download_id);
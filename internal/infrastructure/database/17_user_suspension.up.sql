@@ -0,0 +1,3 @@
+-- Tài khoản bị admin tạm khóa: không đăng nhập được, JWT và API token đang có bị từ chối.
+-- NULL = đang hoạt động.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
@@ -0,0 +1,22 @@
+DROP PROCEDURE IF EXISTS proc_download(UUID, UUID, BOOLEAN, BIGINT);
+
+CREATE PROCEDURE proc_download(f_id UUID, u_id UUID)
+LANGUAGE SQL
+AS $$
+    UPDATE filestat 
+    SET 
+        download_count = download_count + 1
+    WHERE file_id = f_id;
+
+    UPDATE filestat
+    SET
+        user_download_count = user_download_count + 1
+    WHERE file_id = f_id AND NOT EXISTS (SELECT 1 FROM download WHERE user_id = u_id AND file_id = f_id);
+
+    INSERT INTO download (file_id, user_id) VALUES (f_id, u_id);
+$$;
+
+ALTER TABLE files DROP CONSTRAINT IF EXISTS files_max_downloads_check;
+ALTER TABLE files
+    DROP COLUMN IF EXISTS delete_on_limit,
+    DROP COLUMN IF EXISTS max_downloads;
@@ -0,0 +1,52 @@
+ALTER TABLE files
+    ADD COLUMN IF NOT EXISTS max_downloads INT,
+    ADD COLUMN IF NOT EXISTS delete_on_limit BOOLEAN DEFAULT FALSE;
+
+ALTER TABLE files
+    ADD CONSTRAINT files_max_downloads_check CHECK (max_downloads IS NULL OR max_downloads > 0);
+
+DROP PROCEDURE IF EXISTS proc_download(UUID, UUID);
+
+-- Lượt tải chỉ được ghi nhận khi còn slot. UPDATE trên filestat khóa dòng nên
+-- hai request đồng thời không thể cùng lấy slot cuối cùng.
+CREATE PROCEDURE proc_download(
+    f_id UUID,
+    u_id UUID,
+    INOUT granted BOOLEAN DEFAULT NULL,
+    INOUT remaining BIGINT DEFAULT NULL
+)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    lim INT;
+    used BIGINT;
+BEGIN
+    SELECT max_downloads INTO lim FROM files WHERE id = f_id;
+
+    UPDATE filestat
+    SET
+        download_count = download_count + 1
+    WHERE file_id = f_id AND (lim IS NULL OR download_count < lim)
+    RETURNING download_count INTO used;
+
+    IF NOT FOUND THEN
+        granted := FALSE;
+        remaining := 0;
+        RETURN;
+    END IF;
+
+    UPDATE filestat
+    SET
+        user_download_count = user_download_count + 1
+    WHERE file_id = f_id AND NOT EXISTS (SELECT 1 FROM download WHERE user_id = u_id AND file_id = f_id);
+
+    INSERT INTO download (file_id, user_id) VALUES (f_id, u_id);
+
+    granted := TRUE;
+    IF lim IS NULL THEN
+        remaining := NULL;
+    ELSE
+        remaining := lim - used;
+    END IF;
+END;
+$$;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS files_share_token_key;
@@ -0,0 +1,11 @@
+-- Token cũ được sinh bằng math/rand theo UnixNano nên có thể trùng nhau.
+-- Đổi tên các bản trùng (giữ bản tạo sớm nhất) trước khi thêm ràng buộc.
+UPDATE files f
+SET share_token = f.share_token || '-' || substr(md5(f.id::text), 1, 6)
+WHERE EXISTS (
+    SELECT 1 FROM files o
+    WHERE o.share_token = f.share_token
+      AND (o.created_at, o.id) < (f.created_at, f.id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS files_share_token_key ON files (share_token);
@@ -0,0 +1,3 @@
+-- Tăng mỗi khi share link thay đổi, signed URL mang version cũ sẽ hết hiệu lực.
+ALTER TABLE files
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
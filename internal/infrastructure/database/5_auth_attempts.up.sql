@@ -0,0 +1,11 @@
+-- Bộ đếm đoán sai (password file, login, TOTP) dùng chung giữa các replica.
+CREATE TABLE IF NOT EXISTS auth_attempts (
+    scope VARCHAR(32) NOT NULL, -- ip | account | share_token
+    key TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS auth_attempts_last_failure_at_idx ON auth_attempts (last_failure_at);
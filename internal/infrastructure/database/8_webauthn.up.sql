@@ -0,0 +1,27 @@
+-- Authenticator (passkey/security key) đã đăng ký, mỗi user có thể có nhiều cái.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    credential_id BYTEA NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    data JSONB NOT NULL, -- public key, sign count, flags, transports
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    CONSTRAINT webauthn_credentials_credential_id_key UNIQUE (credential_id),
+    CONSTRAINT webauthn_credentials_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+-- Challenge của ceremony đang dở, dùng một lần.
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    purpose VARCHAR(20) NOT NULL, -- register | mfa | passkey
+    user_id UUID,
+    cid VARCHAR(64),
+    name VARCHAR(100),
+    data JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webauthn_sessions_expires_at_idx ON webauthn_sessions (expires_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS invites;
+DROP TABLE IF EXISTS email_domain_rules;
+DROP TABLE IF EXISTS email_verifications;
+DROP INDEX IF EXISTS users_username_lower_key;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
@@ -0,0 +1,44 @@
+-- Tài khoản có sẵn coi như đã xác minh, tài khoản đăng ký mới phải xác minh email.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users ALTER COLUMN email_verified SET DEFAULT FALSE;
+
+-- Username không phân biệt hoa thường. Cần xử lý tay các username trùng trước khi migrate.
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_key ON users (LOWER(username));
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT email_verifications_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS email_verifications_user_id_idx ON email_verifications (user_id);
+
+-- Danh sách domain email do admin quản lý. "deny" luôn bị chặn, "allow" chỉ dùng ở chế độ đăng ký "domain".
+CREATE TABLE IF NOT EXISTS email_domain_rules (
+    domain VARCHAR(255) PRIMARY KEY,
+    rule VARCHAR(10) NOT NULL CHECK (rule IN ('allow', 'deny')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Trước đây hard-code trong validator email_advanced
+INSERT INTO email_domain_rules (domain, rule) VALUES
+    ('blacklist.com', 'deny'),
+    ('edu.vn', 'deny'),
+    ('abc.com', 'deny')
+ON CONFLICT (domain) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS invites (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    token_hash CHAR(64) NOT NULL,
+    email VARCHAR(255), -- NULL = ai có mã cũng dùng được
+    created_by UUID,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    used_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT invites_token_hash_key UNIQUE (token_hash),
+    CONSTRAINT invites_created_by_fkey FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT invites_used_by_fkey FOREIGN KEY (used_by) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -11,22 +11,41 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// Backend lưu trữ, chọn theo scheme của DATABASE_URL.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 var DB *sql.DB
 
+// Driver là backend của DB: DriverPostgres hoặc DriverSQLite.
+var Driver string
+
 func InitDB() error {
 	connStr := config.NewConfig().DSN()
 
 	var err error
-	DB, err = sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal("unable to use data source name", err)
+	if path, ok := SQLitePath(connStr); ok {
+		// SQLite cho cài đặt một node: schema được tạo/cập nhật ngay khi mở file.
+		Driver = DriverSQLite
+		DB, err = OpenSQLite(path)
+		if err != nil {
+			return fmt.Errorf("unable to open sqlite database: %w", err)
+		}
+	} else {
+		Driver = DriverPostgres
+		DB, err = sql.Open("postgres", connStr)
+		if err != nil {
+			log.Fatal("unable to use data source name", err)
+		}
+
+		DB.SetMaxIdleConns(3)                   // Số kết nối nhàn rỗi tối đa
+		DB.SetMaxOpenConns(30)                  // Số kết nối tôi đa
+		DB.SetConnMaxLifetime(30 * time.Minute) // Đóng kết nối sau 30 phút
+		DB.SetConnMaxIdleTime(5 * time.Minute)  // Đóng kết nối nhàn rỗi sau 5 phút
 	}
 
-	DB.SetMaxIdleConns(3)                   // Số kết nối nhàn rỗi tối đa
-	DB.SetMaxOpenConns(30)                  // Số kết nối tôi đa
-	DB.SetConnMaxLifetime(30 * time.Minute) // Đóng kết nối sau 30 phút
-	DB.SetConnMaxIdleTime(5 * time.Minute)  // Đóng kết nối nhàn rỗi sau 5 phút
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
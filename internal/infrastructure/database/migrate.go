@@ -1,23 +1,151 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-func RunMigrations(dsn string) {
-	m, err := migrate.New(
-		"file://internal/infrastructure/database/migrations",
-		dsn,
-	)
+// Migration Postgres (các file *.sql cạnh file này) được nhúng vào binary, không cần copy thư mục
+// migration khi deploy.
+//
+//go:embed *.sql
+var postgresMigrations embed.FS
+
+// migrationLockTimeout là thời gian tối đa chờ advisory lock khi instance khác đang chạy migration.
+const migrationLockTimeout = 5 * time.Minute
+
+// MigrationSource trả về migration nhúng của backend driver (DriverPostgres hoặc DriverSQLite).
+func MigrationSource(driver string) (source.Driver, error) {
+	if driver == DriverSQLite {
+		return iofs.New(sqliteMigrations, "sqlite")
+	}
+	return iofs.New(postgresMigrations, ".")
+}
+
+// RunMigrations áp dụng mọi migration Postgres chưa chạy trên db. Driver Postgres của migrate giữ
+// pg_advisory_lock trong suốt quá trình, nên khi nhiều instance cùng khởi động chỉ một instance chạy
+// migration, các instance còn lại chờ rồi nhận ErrNoChange.
+func RunMigrations(db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Dùng riêng một kết nối: đóng migrator chỉ trả kết nối này về pool chứ không đóng db.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migration init: %w", err)
+	}
+	target, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("migration init: %w", err)
+	}
+
+	m, err := newMigrate(DriverPostgres, target)
+	if err != nil {
+		target.Close()
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	version, _, _ := m.Version()
+	log.Printf("database migration completed, version %d", version)
+	return nil
+}
+
+// Migrator chạy migration nhúng trên một kết nối riêng tới DATABASE_URL, dùng cho cmd/migrate.
+type Migrator struct {
+	*migrate.Migrate
+	driver string
+}
+
+// NewMigrator mở kết nối tới dsn (Postgres hoặc sqlite://path). Gọi Close khi dùng xong.
+func NewMigrator(dsn string) (*Migrator, error) {
+	driver := DriverPostgres
+	var target migratedb.Driver
+
+	if path, ok := SQLitePath(dsn); ok {
+		driver = DriverSQLite
+		db, err := openSQLite(path)
+		if err != nil {
+			return nil, err
+		}
+		if target, err = migratesqlite.WithInstance(db, &migratesqlite.Config{}); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migration init: %w", err)
+		}
+	} else {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("migration init: %w", err)
+		}
+		if target, err = postgres.WithInstance(db, &postgres.Config{}); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migration init: %w", err)
+		}
+	}
+
+	m, err := newMigrate(driver, target)
+	if err != nil {
+		target.Close()
+		return nil, err
+	}
+	return &Migrator{Migrate: m, driver: driver}, nil
+}
+
+// Versions liệt kê version của mọi migration nhúng trong binary, tăng dần.
+func (m *Migrator) Versions() ([]uint, error) {
+	src, err := MigrationSource(m.driver)
 	if err != nil {
-		log.Fatal("migration init failed:", err)
+		return nil, err
 	}
+	defer src.Close()
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatal("migration failed:", err)
+	versions := []uint{}
+	version, err := src.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = src.Next(version)
 	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
 
-	log.Println("database migration completed")
+// Close đóng kết nối của migrator.
+func (m *Migrator) Close() error {
+	_, err := m.Migrate.Close()
+	return err
+}
+
+func newMigrate(driver string, target migratedb.Driver) (*migrate.Migrate, error) {
+	src, err := MigrationSource(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, driver, target)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("migration init: %w", err)
+	}
+	m.LockTimeout = migrationLockTimeout
+	return m, nil
 }
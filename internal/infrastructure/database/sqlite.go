@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"modernc.org/sqlite"
+)
+
+// SQLiteTimeLayout là định dạng lưu thời gian trong SQLite: UTC, luôn đủ 6 chữ số thập phân để so
+// sánh chuỗi cho đúng thứ tự thời gian. Khớp với DEFAULT strftime('%Y-%m-%d %H:%M:%f000', 'now').
+const SQLiteTimeLayout = "2006-01-02 15:04:05.000000"
+
+//go:embed sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLitePath trả về đường dẫn file trong DATABASE_URL dạng sqlite://path hoặc sqlite:path.
+// sqlite://:memory: là database trong RAM, mất khi tắt server.
+func SQLitePath(dsn string) (string, bool) {
+	for _, prefix := range []string{"sqlite://", "sqlite:"} {
+		if path, ok := strings.CutPrefix(dsn, prefix); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// OpenSQLite mở (hoặc tạo) database SQLite tại path và chạy migration của backend SQLite.
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// openSQLite mở database SQLite mà không chạy migration.
+func openSQLite(path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, errors.New("sqlite: empty database path")
+	}
+
+	memory := path == ":memory:"
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	if !memory {
+		dsn += "&_pragma=journal_mode(WAL)"
+	}
+
+	db := sql.OpenDB(&sqliteConnector{dsn: dsn, driver: &sqlite.Driver{}})
+	if memory {
+		// Mỗi kết nối tới :memory: là một database riêng, phải giữ đúng một kết nối suốt đời pool.
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
+
+	return db, nil
+}
+
+func migrateSQLite(db *sql.DB) error {
+	source, err := MigrationSource(DriverSQLite)
+	if err != nil {
+		return err
+	}
+
+	target, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return fmt.Errorf("sqlite migration init: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "sqlite", target)
+	if err != nil {
+		return fmt.Errorf("sqlite migration init: %w", err)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("sqlite migration failed: %w", err)
+	}
+	return nil
+}
+
+// sqliteConnector bọc driver SQLite để mọi tham số time.Time được ghi theo SQLiteTimeLayout
+// (mặc định driver ghi kiểu time.String(), không so sánh chuỗi được).
+type sqliteConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{Conn: conn}, nil
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type sqliteConn struct {
+	driver.Conn
+}
+
+// CheckNamedValue đổi time.Time (kể cả bọc trong *time.Time, sql.NullTime) thành chuỗi UTC,
+// các kiểu khác để database/sql chuyển đổi như bình thường.
+func (c *sqliteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value := nv.Value
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		value = v
+	}
+
+	value, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return driver.ErrSkip
+	}
+
+	if t, ok := value.(time.Time); ok {
+		nv.Value = t.UTC().Format(SQLiteTimeLayout)
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c *sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *sqliteConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *sqliteConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *sqliteConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
@@ -0,0 +1,29 @@
+DROP TABLE IF EXISTS shared_folder_groups;
+DROP TABLE IF EXISTS shared_folders;
+DROP TABLE IF EXISTS shared_groups;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS export_jobs;
+DROP TABLE IF EXISTS login_history;
+DROP TABLE IF EXISTS user_token_revocations;
+DROP TABLE IF EXISTS invites;
+DROP TABLE IF EXISTS email_domain_rules;
+DROP TABLE IF EXISTS email_verifications;
+DROP TABLE IF EXISTS webauthn_sessions;
+DROP TABLE IF EXISTS webauthn_credentials;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS oidc_states;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS auth_attempts;
+DROP TABLE IF EXISTS usersloginsession;
+DROP TABLE IF EXISTS jwt_blacklist;
+DROP TABLE IF EXISTS download;
+DROP TABLE IF EXISTS shared;
+DROP TABLE IF EXISTS filestat;
+DROP TRIGGER IF EXISTS files_fts_update;
+DROP TRIGGER IF EXISTS files_fts_delete;
+DROP TRIGGER IF EXISTS files_fts_insert;
+DROP TABLE IF EXISTS files_fts;
+DROP TABLE IF EXISTS files;
+DROP TABLE IF EXISTS folders;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,289 @@
+-- Schema cho backend SQLite (cài đặt một node, không cần Postgres). Tương đương các migration
+-- Postgres 1..16 gộp lại, với các khác biệt:
+--   * UUID lưu dạng TEXT, mặc định sinh UUID v4 bằng randomblob.
+--   * Thời gian lưu dạng TEXT UTC 'YYYY-MM-DD HH:MM:SS.ffffff' cố định độ dài để so sánh
+--     chuỗi đúng thứ tự thời gian; ứng dụng ghi cùng định dạng (database.sqliteConnector).
+--   * Mảng (tags, scopes) lưu dạng JSON text.
+--   * Tìm kiếm dùng bảng FTS5 files_fts thay cho tsvector.
+--   * proc_download được thay bằng transaction trong repository.
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    username TEXT NOT NULL,
+    password TEXT NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL,
+    enabletotp BOOLEAN DEFAULT FALSE,
+    secrettotp TEXT,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- lower() của SQLite chỉ đổi chữ ASCII, username có dấu vẫn phân biệt hoa thường.
+CREATE UNIQUE INDEX users_username_lower_key ON users (lower(username));
+
+CREATE TABLE folders (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id TEXT REFERENCES folders(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    path TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    updated_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE UNIQUE INDEX folders_sibling_name_key ON folders (user_id, COALESCE(parent_id, ''), lower(name));
+CREATE INDEX folders_path_idx ON folders (path);
+
+CREATE TABLE files (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE, -- NULL = anonymous upload
+    name TEXT NOT NULL,
+    password TEXT,
+    type TEXT,
+    size BIGINT,
+    is_public BOOLEAN DEFAULT FALSE,
+    created_at DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    available_from DATETIME,
+    available_to DATETIME,
+    enable_totp BOOLEAN DEFAULT FALSE,
+    share_token TEXT UNIQUE,
+    max_downloads INTEGER CHECK (max_downloads IS NULL OR max_downloads > 0),
+    delete_on_limit BOOLEAN DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1,
+    folder_id TEXT REFERENCES folders(id) ON DELETE SET NULL,
+    description TEXT,
+    tags TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX files_folder_id_idx ON files (folder_id);
+CREATE INDEX files_user_created_at_idx ON files (user_id, created_at, id);
+CREATE INDEX files_user_name_idx ON files (user_id, name, id);
+CREATE INDEX files_user_size_idx ON files (user_id, size, id);
+CREATE INDEX files_user_available_to_idx ON files (user_id, available_to, id);
+
+-- Chỉ mục tìm kiếm: tên (trọng số cao nhất), tags, mô tả. unicode61 tách từ theo ký tự không phải
+-- chữ/số như regexp_replace ở bản Postgres và không bỏ dấu, giống config 'simple'.
+CREATE VIRTUAL TABLE files_fts USING fts5 (
+    name, tags, description,
+    content = 'files',
+    tokenize = "unicode61 remove_diacritics 0"
+);
+
+CREATE TRIGGER files_fts_insert AFTER INSERT ON files BEGIN
+    INSERT INTO files_fts (rowid, name, tags, description) VALUES (new.rowid, new.name, new.tags, new.description);
+END;
+
+CREATE TRIGGER files_fts_delete AFTER DELETE ON files BEGIN
+    INSERT INTO files_fts (files_fts, rowid, name, tags, description) VALUES ('delete', old.rowid, old.name, old.tags, old.description);
+END;
+
+CREATE TRIGGER files_fts_update AFTER UPDATE OF name, tags, description ON files BEGIN
+    INSERT INTO files_fts (files_fts, rowid, name, tags, description) VALUES ('delete', old.rowid, old.name, old.tags, old.description);
+    INSERT INTO files_fts (rowid, name, tags, description) VALUES (new.rowid, new.name, new.tags, new.description);
+END;
+
+CREATE TABLE filestat (
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    download_count BIGINT DEFAULT 0,
+    user_download_count BIGINT DEFAULT 0
+);
+
+CREATE INDEX filestat_file_id_idx ON filestat (file_id);
+
+CREATE TABLE shared (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, file_id)
+);
+
+CREATE TABLE download (
+    download_id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    time DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE
+);
+
+CREATE INDEX download_file_time_idx ON download (file_id, time, download_id);
+
+CREATE TABLE jwt_blacklist (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token TEXT NOT NULL,
+    expired_at DATETIME NOT NULL
+);
+
+CREATE TABLE usersloginsession (
+    id TEXT PRIMARY KEY,
+    cid TEXT NOT NULL
+);
+
+CREATE TABLE auth_attempts (
+    scope TEXT NOT NULL, -- ip | account | share_token
+    key TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    locked_until DATETIME,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX auth_attempts_last_failure_at_idx ON auth_attempts (last_failure_at);
+
+CREATE TABLE api_tokens (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    scopes TEXT NOT NULL, -- JSON array
+    expires_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
+
+CREATE TABLE oidc_states (
+    state TEXT PRIMARY KEY,
+    provider TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX oidc_states_expires_at_idx ON oidc_states (expires_at);
+
+CREATE TABLE user_identities (
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    last_login_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+CREATE TABLE webauthn_credentials (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id BLOB NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    data BLOB NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    last_used_at DATETIME
+);
+
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+CREATE TABLE webauthn_sessions (
+    id TEXT PRIMARY KEY,
+    purpose TEXT NOT NULL, -- register | mfa | passkey
+    user_id TEXT,
+    cid TEXT,
+    name TEXT,
+    data BLOB NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX webauthn_sessions_expires_at_idx ON webauthn_sessions (expires_at);
+
+CREATE TABLE email_verifications (
+    token_hash TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
+
+CREATE TABLE email_domain_rules (
+    domain TEXT PRIMARY KEY,
+    rule TEXT NOT NULL CHECK (rule IN ('allow', 'deny')),
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+INSERT INTO email_domain_rules (domain, rule) VALUES
+    ('blacklist.com', 'deny'),
+    ('edu.vn', 'deny'),
+    ('abc.com', 'deny');
+
+CREATE TABLE invites (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    token_hash TEXT NOT NULL UNIQUE,
+    email TEXT,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    used_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE TABLE user_token_revocations (
+    user_id TEXT PRIMARY KEY,
+    revoked_at DATETIME NOT NULL
+);
+
+CREATE TABLE login_history (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method TEXT NOT NULL,
+    ip TEXT,
+    user_agent TEXT,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE INDEX login_history_user_id_idx ON login_history (user_id, created_at DESC);
+
+CREATE TABLE export_jobs (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending | running | ready | failed
+    error TEXT,
+    size BIGINT,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    completed_at DATETIME,
+    expires_at DATETIME
+);
+
+CREATE INDEX export_jobs_user_id_idx ON export_jobs (user_id, created_at DESC);
+
+CREATE TABLE groups (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    name TEXT NOT NULL,
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now'))
+);
+
+CREATE TABLE group_members (
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT 'member', -- owner | member
+    added_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f000', 'now')),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX group_members_user_id_idx ON group_members (user_id);
+
+CREATE TABLE shared_groups (
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, file_id)
+);
+
+CREATE INDEX shared_groups_file_id_idx ON shared_groups (file_id);
+
+CREATE TABLE shared_folders (
+    folder_id TEXT NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (folder_id, user_id)
+);
+
+CREATE TABLE shared_folder_groups (
+    folder_id TEXT NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (folder_id, group_id)
+);
+
+CREATE INDEX shared_folders_user_id_idx ON shared_folders (user_id);
+CREATE INDEX shared_folder_groups_group_id_idx ON shared_folder_groups (group_id);
@@ -0,0 +1,2 @@
+-- Tương đương migration Postgres 17: NULL = tài khoản đang hoạt động.
+ALTER TABLE users ADD COLUMN suspended_at TEXT;
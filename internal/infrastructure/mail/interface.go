@@ -0,0 +1,13 @@
+package mail
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // text/plain
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,21 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+// RFC 7636: verifier 43-128 ký tự trong bảng [A-Za-z0-9-._~].
+const codeVerifierLength = 64
+
+func NewCodeVerifier() (string, error) {
+	return utils.GenerateSecureString(codeVerifierLength, utils.TokenAlphabets[utils.DefaultTokenAlphabet])
+}
+
+// CodeChallengeS256 = BASE64URL(SHA256(verifier)), không padding.
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
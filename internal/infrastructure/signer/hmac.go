@@ -0,0 +1,33 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type HMACSigner struct {
+	key []byte
+}
+
+// Mặc định dùng chung secret với JWT nếu không cấu hình SIGNED_URL_SECRET riêng.
+var signedURLSecretKey = []byte(utils.GetEnv("SIGNED_URL_SECRET", utils.GetEnv("JWT_SECRET_KEY", "github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/signer")))
+
+func NewHMACSigner() URLSigner {
+	return &HMACSigner{key: signedURLSecretKey}
+}
+
+// Sign trả về chữ ký HMAC-SHA256 (base64url) của các phần được nối bằng "\n".
+func (s *HMACSigner) Sign(parts ...string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *HMACSigner) Verify(signature string, parts ...string) bool {
+	expected := s.Sign(parts...)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,6 @@
+package signer
+
+type URLSigner interface {
+	Sign(parts ...string) string
+	Verify(signature string, parts ...string) bool
+}
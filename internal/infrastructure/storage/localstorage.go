@@ -98,3 +98,56 @@ func (s *LocalStorage) DeleteFile(fileID string) *utils.ReturnStatus {
 	}
 	return nil
 }
+
+func (s *LocalStorage) SaveStream(filename string, src io.Reader) (int64, *utils.ReturnStatus) {
+	dst := filepath.Join(s.UploadDir, filename)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("failed to create directory: %s", err))
+	}
+
+	// Ghi ra file tạm rồi rename để không ai đọc được file đang ghi dở.
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("failed to create destination file: %s", err))
+	}
+
+	written, err := io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("failed to save file: %s", err))
+	}
+
+	return written, nil
+}
+
+func (s *LocalStorage) Stat(filename string) (int64, *utils.ReturnStatus) {
+	info, err := os.Stat(filepath.Join(s.UploadDir, filename))
+	if os.IsNotExist(err) {
+		return 0, utils.Response(utils.ErrCodeFileNotFound)
+	}
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("failed to stat file: %s", err))
+	}
+	return info.Size(), nil
+}
+
+func (s *LocalStorage) List() ([]string, *utils.ReturnStatus) {
+	entries, err := os.ReadDir(s.UploadDir)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("failed to list files: %s", err))
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
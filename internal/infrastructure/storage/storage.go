@@ -11,4 +11,11 @@ type Storage interface {
 	SaveFile(file *multipart.FileHeader, filename string) (string, *utils.ReturnStatus)
 	DeleteFile(filename string) *utils.ReturnStatus
 	GetFile(filename string) (io.Reader, *utils.ReturnStatus) // Cần cho Download
+	// SaveStream ghi nội dung từ src (vd. archive build dần qua io.Pipe), trả về số byte đã ghi.
+	// filename có thể chứa thư mục con, vd. "exports/<id>.zip".
+	SaveStream(filename string, src io.Reader) (int64, *utils.ReturnStatus)
+	// Stat trả về kích thước file, ErrCodeFileNotFound nếu không có.
+	Stat(filename string) (int64, *utils.ReturnStatus)
+	// List liệt kê tên các file ở thư mục gốc của storage (không gồm thư mục con như exports/).
+	List() ([]string, *utils.ReturnStatus)
 }
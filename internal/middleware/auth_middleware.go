@@ -2,22 +2,28 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
 var (
-	jwtService jwt.TokenService
-	authRepo   repository.AuthRepository
+	jwtService      jwt.TokenService
+	authRepo        repository.AuthRepository
+	apiTokenService service.APITokenService
 )
 
-func InitAuthMiddleware(service jwt.TokenService, repo repository.AuthRepository) {
+func InitAuthMiddleware(service jwt.TokenService, repo repository.AuthRepository, apiTokens service.APITokenService) {
 	jwtService = service
 	authRepo = repo
+	apiTokenService = apiTokens
 }
 
 func AuthMiddleware() gin.HandlerFunc {
@@ -33,6 +39,11 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		if strings.HasPrefix(tokenString, domain.APITokenPrefix) {
+			authenticateAPIToken(ctx, tokenString)
+			return
+		}
+
 		isBlacklisted, _ := authRepo.IsTokenBlacklisted(tokenString)
 		if isBlacklisted {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -50,6 +61,18 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		revoked, revokedErr := isUserTokenRevoked(claims)
+		if revokedErr.IsErr() {
+			abortRevocationUnavailable(ctx)
+			return
+		}
+		if revoked {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			return
+		}
+
 		ctx.Set("user", claims)
 		ctx.Set("userID", claims.UserID)
 		ctx.Next()
@@ -65,6 +88,11 @@ func AuthMiddlewareUpload() gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+		if strings.HasPrefix(tokenString, domain.APITokenPrefix) {
+			authenticateAPIToken(ctx, tokenString)
+			return
+		}
+
 		isBlacklisted, _ := authRepo.IsTokenBlacklisted(tokenString)
 		if isBlacklisted {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -82,8 +110,97 @@ func AuthMiddlewareUpload() gin.HandlerFunc {
 			return
 		}
 
+		revoked, revokedErr := isUserTokenRevoked(claims)
+		if revokedErr.IsErr() {
+			abortRevocationUnavailable(ctx)
+			return
+		}
+		if revoked {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			return
+		}
+
 		ctx.Set("user", claims)
 		ctx.Set("userID", claims.UserID)
 		ctx.Next()
 	}
 }
+
+// isUserTokenRevoked: mọi JWT của user bị thu hồi cùng lúc, ví dụ khi user tự xóa tài khoản.
+func isUserTokenRevoked(claims *jwt.Claims) (bool, *utils.ReturnStatus) {
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+	return authRepo.IsUserTokenRevoked(claims.UserID, claims.IssuedAt.Time)
+}
+
+// abortRevocationUnavailable: không kiểm tra được thu hồi thì từ chối (fail closed), nếu không token
+// của tài khoản đã xóa/bị khóa vẫn dùng được khi database lỗi.
+func abortRevocationUnavailable(ctx *gin.Context) {
+	ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "Service unavailable",
+		"message": "Unable to verify authentication token, please try again later",
+	})
+}
+
+// authenticateAPIToken xác thực personal access token. Claims được dựng từ user hiện tại
+// để các handler/AdminAuthMiddleware dùng chung được với phiên đăng nhập JWT.
+func authenticateAPIToken(ctx *gin.Context, raw string) {
+	if apiTokenService == nil {
+		utils.Response(utils.ErrCodeAPITokenInvalid).Export(ctx)
+		ctx.Abort()
+		return
+	}
+
+	token, user, err := apiTokenService.Authenticate(ctx, raw)
+	if err != nil {
+		err.Export(ctx)
+		ctx.Abort()
+		return
+	}
+
+	ctx.Set("user", &jwt.Claims{
+		UserID: user.Id,
+		Email:  user.Email,
+		Role:   user.Role,
+	})
+	ctx.Set("userID", user.Id)
+	ctx.Set("apiTokenID", token.Id)
+	ctx.Set("apiTokenScopes", token.Scopes)
+	ctx.Next()
+}
+
+// RequireScope chỉ áp dụng cho API token; phiên đăng nhập JWT có đầy đủ quyền.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, exists := ctx.Get("apiTokenScopes")
+		if !exists {
+			ctx.Next()
+			return
+		}
+
+		scopes, _ := value.([]string)
+		if !slices.Contains(scopes, scope) {
+			utils.ResponseArgs(utils.ErrCodeInsufficientScope, gin.H{"requiredScope": scope}).Export(ctx)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// SessionOnly chặn API token ở các route nhạy cảm (quản lý key, TOTP, logout).
+func SessionOnly() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, exists := ctx.Get("apiTokenID"); exists {
+			utils.Response(utils.ErrCodeSessionRequired).Export(ctx)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	rateLimitPolicy *config.SystemPolicy
+	rateLimitStore  = newBucketStore()
+)
+
+// InitRateLimitMiddleware giữ con trỏ tới SystemPolicy, thay đổi qua PATCH /admin/policy có hiệu lực ngay.
+func InitRateLimitMiddleware(policy *config.SystemPolicy) {
+	rateLimitPolicy = policy
+}
+
+// RateLimit giới hạn request theo policy name và caller. Phải đặt sau middleware xác thực
+// để user đã đăng nhập được đếm theo user ID thay vì IP.
+func RateLimit(name string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if rateLimitPolicy == nil {
+			ctx.Next()
+			return
+		}
+
+		identity, anonymous := callerIdentity(ctx)
+		rules := config.SnapshotPolicy(rateLimitPolicy).RateLimits
+
+		policyName := name
+		rule, ok := rules[name]
+		if anonymous {
+			if anonRule, exists := rules[name+"_anonymous"]; exists {
+				policyName, rule, ok = name+"_anonymous", anonRule, true
+			}
+		}
+		if !ok || rule.Limit <= 0 || rule.WindowSeconds <= 0 {
+			ctx.Next()
+			return
+		}
+
+		allowed, remaining, reset, retryAfter := rateLimitStore.take(policyName+"|"+identity, rule, time.Now())
+
+		ctx.Header("RateLimit-Policy", strconv.Itoa(rule.Limit)+";w="+strconv.Itoa(rule.WindowSeconds))
+		ctx.Header("RateLimit-Limit", strconv.Itoa(rule.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+
+		if !allowed {
+			utils.ResponseArgs(utils.ErrCodeTooManyRequests, gin.H{
+				"message":    "Rate limit exceeded. Please slow down.",
+				"retryAfter": ceilSeconds(retryAfter),
+			}).Export(ctx)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// callerIdentity: API token ID (mỗi key một bucket riêng), user ID nếu đã xác thực, ngược lại là IP.
+func callerIdentity(ctx *gin.Context) (string, bool) {
+	if tokenID, exists := ctx.Get("apiTokenID"); exists && tokenID != "" {
+		return "token:" + tokenID.(string), false
+	}
+	if userID, exists := ctx.Get("userID"); exists && userID != "" {
+		return "user:" + userID.(string), false
+	}
+
+	return "ip:" + ctx.ClientIP(), true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	capacity float64
+	perToken time.Duration
+}
+
+// full: bucket đã nạp đầy tại thời điểm now, xóa đi cũng không thay đổi kết quả.
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+float64(now.Sub(b.last))/float64(b.perToken) >= b.capacity
+}
+
+type bucketStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBucketStore() *bucketStore {
+	return &bucketStore{buckets: map[string]*bucket{}}
+}
+
+const bucketSweepInterval = 5 * time.Minute
+
+// take lấy một token; trả về (được phép?, số token còn lại, thời gian tới khi đầy lại, thời gian chờ tới token kế tiếp).
+func (s *bucketStore) take(key string, rule config.RateLimitRule, now time.Time) (bool, int, time.Duration, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	capacity := float64(rule.Limit)
+	perToken := time.Duration(rule.WindowSeconds) * time.Second / time.Duration(rule.Limit)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	b.capacity, b.perToken = capacity, perToken
+
+	// Nạp lại token theo thời gian đã trôi qua; policy có thể vừa bị hạ xuống nên cắt theo capacity.
+	b.tokens = min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	reset := time.Duration((capacity - b.tokens) * float64(perToken))
+	var retryAfter time.Duration
+	if !allowed {
+		retryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	return allowed, int(b.tokens), reset, retryAfter
+}
+
+// sweep định kỳ xóa các bucket đã nạp đầy, tránh map phình theo số IP.
+func (s *bucketStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < bucketSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if b.full(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
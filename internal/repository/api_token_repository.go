@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/lib/pq"
+)
+
+type apiTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAPITokenRepository(db *sql.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+const apiTokenColumns = `id, user_id, name, token_hash, prefix, scopes, expires_at, last_used_at, created_at`
+
+func scanAPIToken(row interface{ Scan(...any) error }, token *domain.APIToken) error {
+	var lastUsed sql.NullTime
+	err := row.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Name,
+		&token.TokenHash,
+		&token.Prefix,
+		pq.Array(&token.Scopes),
+		&token.ExpiresAt,
+		&lastUsed,
+		&token.CreatedAt,
+	)
+	if lastUsed.Valid {
+		token.LastUsedAt = &lastUsed.Time
+	}
+	return err
+}
+
+func (r *apiTokenRepository) Create(ctx context.Context, token *domain.APIToken) *utils.ReturnStatus {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, token.UserId, token.Name, token.TokenHash, token.Prefix, pq.Array(token.Scopes), token.ExpiresAt,
+	).Scan(&token.Id, &token.CreatedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *apiTokenRepository) ListByUser(ctx context.Context, userID string) ([]domain.APIToken, *utils.ReturnStatus) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	tokens := []domain.APIToken{}
+	for rows.Next() {
+		var token domain.APIToken
+		if err := scanAPIToken(rows, &token); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *apiTokenRepository) FindByHash(ctx context.Context, hash string) (*domain.APIToken, *utils.ReturnStatus) {
+	var token domain.APIToken
+	row := r.db.QueryRowContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = $1`, hash)
+	if err := scanAPIToken(row, &token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeAPITokenInvalid)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return &token, nil
+}
+
+func (r *apiTokenRepository) Delete(ctx context.Context, id string, userID string) *utils.ReturnStatus {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeAPITokenNotFound)
+	}
+
+	return nil
+}
+
+func (r *apiTokenRepository) TouchLastUsed(ctx context.Context, id string) *utils.ReturnStatus {
+	// Chỉ ghi tối đa mỗi phút một lần để CI gọi liên tục không sinh quá nhiều write.
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_tokens
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/lib/pq"
+)
+
+type attemptRepository struct {
+	db *sql.DB
+}
+
+func NewAttemptRepository(db *sql.DB) AttemptRepository {
+	return &attemptRepository{db: db}
+}
+
+func (r *attemptRepository) LockRemaining(ctx context.Context, keys []domain.AttemptKey) (time.Duration, *utils.ReturnStatus) {
+	scopes := make([]string, len(keys))
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		scopes[i] = string(k.Scope)
+		values[i] = k.Key
+	}
+
+	// Dùng NOW() của database để các replica lệch giờ vẫn thấy cùng một thời điểm mở khóa.
+	var remaining sql.NullFloat64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXTRACT(EPOCH FROM MAX(locked_until) - NOW())
+		FROM auth_attempts
+		WHERE (scope, key) IN (SELECT * FROM unnest($1::text[], $2::text[]))
+		  AND locked_until > NOW()
+	`, pq.Array(scopes), pq.Array(values)).Scan(&remaining)
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if !remaining.Valid {
+		return 0, nil
+	}
+
+	return time.Duration(remaining.Float64 * float64(time.Second)), nil
+}
+
+// reservedFailures là số lần sai sau khi tính thêm lần này: quay về 1 khi lần sai trước (và lần khóa
+// gần nhất) đã cũ hơn window.
+const reservedFailures = `CASE
+	WHEN a.last_failure_at < NOW() - make_interval(secs => $3)
+	 AND (a.locked_until IS NULL OR a.locked_until < NOW() - make_interval(secs => $3))
+	THEN 1
+	ELSE a.failures + 1
+END`
+
+func (r *attemptRepository) Reserve(ctx context.Context, key domain.AttemptKey, window time.Duration, threshold int, hold time.Duration) (int, *utils.ReturnStatus) {
+	// ON CONFLICT khóa dòng nên các request song song nhận số đếm lần lượt; khi khóa đã đặt thì
+	// WHERE chặn cập nhật và không trả về dòng nào.
+	var failures int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO auth_attempts AS a (scope, key, failures, last_failure_at, locked_until)
+		VALUES ($1, $2, 1, NOW(), CASE WHEN 1 >= $4 THEN NOW() + make_interval(secs => $5) END)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = `+reservedFailures+`,
+			last_failure_at = NOW(),
+			locked_until = CASE
+				WHEN `+reservedFailures+` >= $4 THEN NOW() + make_interval(secs => $5)
+				ELSE a.locked_until
+			END
+		WHERE a.locked_until IS NULL OR a.locked_until <= NOW()
+		RETURNING failures
+	`, string(key.Scope), key.Key, window.Seconds(), threshold, hold.Seconds()).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return failures, nil
+}
+
+func (r *attemptRepository) Release(ctx context.Context, key domain.AttemptKey, failures int) *utils.ReturnStatus {
+	// Khóa đặt từ lần Reserve cuối cùng chặn mọi lần sau, nên failures còn bằng số đếm của lần này
+	// nghĩa là khóa (nếu có) do chính lần này đặt.
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE auth_attempts
+		SET failures = failures - 1,
+			locked_until = CASE WHEN failures = $3 AND locked_until > NOW() THEN NULL ELSE locked_until END
+		WHERE scope = $1 AND key = $2 AND failures > 0
+	`, string(key.Scope), key.Key, failures)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *attemptRepository) Lock(ctx context.Context, key domain.AttemptKey, duration time.Duration) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE auth_attempts
+		SET locked_until = GREATEST(COALESCE(locked_until, NOW()), NOW() + make_interval(secs => $3))
+		WHERE scope = $1 AND key = $2
+	`, string(key.Scope), key.Key, duration.Seconds())
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *attemptRepository) Reset(ctx context.Context, key domain.AttemptKey) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM auth_attempts WHERE scope = $1 AND key = $2`, string(key.Scope), key.Key)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *attemptRepository) DeleteStale(ctx context.Context, olderThan time.Duration) (int64, *utils.ReturnStatus) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM auth_attempts
+		WHERE last_failure_at < NOW() - make_interval(secs => $1)
+		  AND (locked_until IS NULL OR locked_until < NOW())
+	`, olderThan.Seconds())
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -18,10 +19,17 @@ func NewAuthRepository(db *sql.DB) AuthRepository {
 }
 
 func (ur *authRepository) Create(user *domain.User) (*domain.User, *utils.ReturnStatus) {
-	row := ur.db.QueryRow("INSERT INTO users (id, username, password, email, role, enableTOTP, secretTOTP) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id", user.Id, user.Username, user.Password, user.Email, user.Role, user.EnableTOTP, user.SecretTOTP)
+	row := ur.db.QueryRow("INSERT INTO users (id, username, password, email, role, enableTOTP, secretTOTP, email_verified) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id", user.Id, user.Username, user.Password, user.Email, user.Role, user.EnableTOTP, user.SecretTOTP, user.EmailVerified)
 	err := row.Scan(&user.Id)
 	fmt.Println("Created user with ID:", user.Id)
 	if err != nil {
+		// Hai request đăng ký cùng lúc có thể cùng qua bước kiểm tra trùng ở service
+		if isUniqueViolation(err, "users_email_key") {
+			return nil, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+		}
+		if isUniqueViolation(err, "users_username_lower_key") {
+			return nil, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "username"})
+		}
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, fmt.Sprintf("failed to create user: %v", err))
 	}
 
@@ -75,3 +83,46 @@ func (r *authRepository) EnableTOTP(userID string) *utils.ReturnStatus {
 	_, err := r.db.Exec(`UPDATE users SET "enabletotp" = TRUE WHERE id = $1`, userID)
 	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
 }
+
+func (r *authRepository) DisableTOTP(userID string) *utils.ReturnStatus {
+	_, err := r.db.Exec(`UPDATE users SET "enabletotp" = FALSE, secrettotp = NULL WHERE id = $1`, userID)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// RevokeUserTokens vô hiệu mọi JWT của user cấp tại hoặc trước revokedAt.
+func (r *authRepository) RevokeUserTokens(ctx context.Context, userID string, revokedAt time.Time) *utils.ReturnStatus {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO user_token_revocations (user_id, revoked_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET revoked_at = GREATEST(user_token_revocations.revoked_at, EXCLUDED.revoked_at)
+	`, userID, revokedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// IsUserTokenRevoked: iat của JWT chỉ chính xác tới giây nên token cấp cùng giây với lúc thu hồi cũng bị từ chối.
+func (r *authRepository) IsUserTokenRevoked(userID string, issuedAt time.Time) (bool, *utils.ReturnStatus) {
+	var revoked bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_token_revocations WHERE user_id = $1 AND $2 <= revoked_at)
+	`, userID, issuedAt).Scan(&revoked)
+
+	return revoked, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// PurgeBlacklist: expired_at là TIMESTAMP không múi giờ, ghi theo giờ local của server như
+// claims.ExpiresAt, nên expiredBefore cũng phải là giờ local (time.Now()).
+func (r *authRepository) PurgeBlacklist(expiredBefore time.Time, revokedBefore time.Time) (int64, *utils.ReturnStatus) {
+	var purged int64
+	err := r.db.QueryRow(`
+		WITH tokens AS (
+			DELETE FROM jwt_blacklist WHERE expired_at < $1 RETURNING 1
+		), revocations AS (
+			DELETE FROM user_token_revocations WHERE revoked_at < $2 RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM tokens) + (SELECT COUNT(*) FROM revocations)
+	`, expiredBefore, revokedBefore).Scan(&purged)
+
+	return purged, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/lib/pq"
 )
 
 type FileRepository interface {
@@ -18,14 +19,22 @@ type FileRepository interface {
 	GetFileByID(ctx context.Context, id string) (*domain.File, *utils.ReturnStatus)
 	GetFileByToken(ctx context.Context, token string) (*domain.File, *utils.ReturnStatus)
 	DeleteFile(ctx context.Context, id string) *utils.ReturnStatus
-	GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) ([]domain.File, *utils.ReturnStatus)
-	GetTotalUserFiles(ctx context.Context, userID string) (int, *utils.ReturnStatus)
+	// Các hàm danh sách trả về một trang theo keyset và cursor của trang sau (nil = trang cuối).
+	GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) ([]domain.File, *domain.Cursor, *utils.ReturnStatus)
 	GetFileSummary(ctx context.Context, userID string) (*domain.FileSummary, *utils.ReturnStatus)
 	FindAll(ctx context.Context) ([]domain.File, *utils.ReturnStatus)
-	RegisterDownload(ctx context.Context, fileID string, userID string) *utils.ReturnStatus
-	GetFileDownloadHistory(ctx context.Context, fileID string) (*domain.FileDownloadHistory, *utils.ReturnStatus)
+	RegisterDownload(ctx context.Context, fileID string, userID string) (*int64, *utils.ReturnStatus)
+	UpdateShareLink(ctx context.Context, file *domain.File) *utils.ReturnStatus
+	GetFileDownloadHistory(ctx context.Context, fileID string, page domain.PageRequest) (*domain.FileDownloadHistory, *domain.Cursor, *utils.ReturnStatus)
 	GetFileStats(ctx context.Context, fileID string) (*domain.FileStat, *utils.ReturnStatus)
-	GetAccessibleFiles(ctx context.Context, userIDop string) ([]domain.File, *utils.ReturnStatus)
+	GetAccessibleFiles(ctx context.Context, userID string, page domain.PageRequest) ([]domain.File, *domain.Cursor, *utils.ReturnStatus)
+	ListFileIDsByOwner(ctx context.Context, userID string) ([]string, *utils.ReturnStatus)
+	TransferOwnership(ctx context.Context, fromUserID string, toUserID string) (int64, *utils.ReturnStatus)
+	GetUserDownloads(ctx context.Context, userID string) ([]domain.UserDownload, *utils.ReturnStatus)
+	ListByFolder(ctx context.Context, folderID string) ([]domain.File, *utils.ReturnStatus)
+	MoveToFolder(ctx context.Context, fileID string, folderID *string) *utils.ReturnStatus
+	UpdateMetadata(ctx context.Context, fileID string, description *string, tags []string) *utils.ReturnStatus
+	SearchFiles(ctx context.Context, userID string, params domain.FileSearchParams) ([]domain.FileSearchHit, *domain.Cursor, *utils.ReturnStatus)
 }
 
 type fileRepository struct {
@@ -53,16 +62,24 @@ func (r *fileRepository) CreateFile(ctx context.Context, file *domain.File) (*do
 		passwordHash = nil
 	}
 
+	// tags NOT NULL, pq.Array(nil) sẽ thành NULL
+	tags := file.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
 	query := `
 		INSERT INTO files (
 			id, user_id, name, type, size, password,
 			available_from, available_to, enable_totp,
-			share_token, created_at, is_public
+			share_token, created_at, is_public,
+			max_downloads, delete_on_limit, folder_id,
+			description, tags
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
-		) RETURNING id, created_at
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+		) RETURNING id, created_at, version
 	`
-	err := r.db.QueryRowContext(ctx, query,
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
 		file.Id,
 		userID,             // $2: user_id (UUID hoặc NULL)
 		file.FileName,      // $3: name
@@ -75,105 +92,103 @@ func (r *fileRepository) CreateFile(ctx context.Context, file *domain.File) (*do
 		file.ShareToken,    // $10: share_token
 		file.CreatedAt,     // $11: created_at,
 		file.IsPublic,      // $12: is_public,
-	).Scan(&file.Id, &file.CreatedAt)
+		file.MaxDownloads,  // $13: max_downloads (NULL = không giới hạn)
+		file.DeleteOnLimit, // $14: delete_on_limit
+		file.FolderId,      // $15: folder_id (NULL = thư mục gốc)
+		file.Description,   // $16: description
+		pq.Array(tags),     // $17: tags
+	).Scan(&file.Id, &file.CreatedAt, &file.Version)
 
 	if err != nil {
+		if isUniqueViolation(err, "files_share_token_key") {
+			return nil, utils.Response(utils.ErrCodeShareTokenTaken)
+		}
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
 
-	if _, err := r.db.Exec(`INSERT INTO filestat (file_id) VALUES ($1)`, file.Id); err != nil {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO filestat (file_id) VALUES ($1)`, file.Id); err != nil {
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
 
 	return file, nil
 }
 
-func (r *fileRepository) GetFileByID(ctx context.Context, id string) (*domain.File, *utils.ReturnStatus) {
-	query := `
-		SELECT
-			id, user_id, name, type, size, share_token,
-			password, available_from, available_to, enable_totp, created_at, is_public
-		FROM files
-		WHERE id = $1
-	`
-
-	var file domain.File
+// fileColumns là projection đầy đủ của một file, đọc kèm lượt tải (filestat) và chủ file (users)
+// trong cùng truy vấn để các danh sách không phải nạp lại từng file hay từng owner.
+const fileColumns = `
+	f.id, f.user_id, f.name, f.type, f.size, f.share_token,
+	f.password, f.available_from, f.available_to, f.enable_totp, f.created_at, f.is_public,
+	f.max_downloads, COALESCE(f.delete_on_limit, false), COALESCE(s.download_count, 0),
+	f.version, f.folder_id, f.description, f.tags,
+	u.username, u.email, u.role`
+
+const fileJoins = `
+	FROM files f
+	LEFT JOIN filestat s ON s.file_id = f.id
+	LEFT JOIN users u ON u.id = f.user_id`
+
+// fileRow giữ các giá trị nullable trong lúc scan fileColumns.
+type fileRow struct {
+	file                                 domain.File
+	ownerID, folderID, description       sql.NullString
+	passwordHash                         sql.NullString
+	maxDownloads                         sql.NullInt64
+	ownerUsername, ownerEmail, ownerRole sql.NullString
+}
 
-	var ownerID sql.NullString
-	var passwordHash sql.NullString
+// dest trả về các đích scan theo đúng thứ tự của fileColumns.
+func (r *fileRow) dest() []any {
+	f := &r.file
+	return []any{
+		&f.Id, &r.ownerID, &f.FileName, &f.MimeType, &f.FileSize, &f.ShareToken,
+		&r.passwordHash, &f.AvailableFrom, &f.AvailableTo, &f.EnableTOTP, &f.CreatedAt, &f.IsPublic,
+		&r.maxDownloads, &f.DeleteOnLimit, &f.DownloadCount,
+		&f.Version, &r.folderID, &r.description, pq.Array(&f.Tags),
+		&r.ownerUsername, &r.ownerEmail, &r.ownerRole,
+	}
+}
 
-	row := r.db.QueryRowContext(ctx, query, id)
+// result gán các giá trị nullable vào file sau khi scan.
+func (r *fileRow) result() *domain.File {
+	file := r.file
+
+	if r.ownerID.Valid {
+		file.OwnerId = &r.ownerID.String
+		file.Owner = &domain.FileOwner{
+			Id:       r.ownerID.String,
+			Username: r.ownerUsername.String,
+			Email:    r.ownerEmail.String,
+			Role:     r.ownerRole.String,
+		}
+	}
 
-	err := row.Scan(
-		&file.Id,
-		&ownerID,
-		&file.FileName,
-		&file.MimeType,
-		&file.FileSize,
-		&file.ShareToken,
-		&passwordHash,
-		&file.AvailableFrom,
-		&file.AvailableTo,
-		&file.EnableTOTP,
-		&file.CreatedAt,
-		&file.IsPublic,
-	)
+	if r.folderID.Valid {
+		file.FolderId = &r.folderID.String
+	}
 
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, utils.Response(utils.ErrCodeFileNotFound)
-		}
-		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	if r.description.Valid {
+		file.Description = &r.description.String
+	}
+	if file.Tags == nil {
+		file.Tags = []string{}
 	}
 
-	if ownerID.Valid {
-		file.OwnerId = &ownerID.String
-	} else {
-		file.OwnerId = nil
+	if r.maxDownloads.Valid {
+		limit := int(r.maxDownloads.Int64)
+		file.MaxDownloads = &limit
 	}
 
-	if passwordHash.Valid {
-		file.PasswordHash = &passwordHash.String
+	if r.passwordHash.Valid {
+		file.PasswordHash = &r.passwordHash.String
 		file.HasPassword = true
-	} else {
-		file.PasswordHash = nil
-		file.HasPassword = false
 	}
 
-	return &file, nil
+	return &file
 }
 
-func (r *fileRepository) GetFileByToken(ctx context.Context, token string) (*domain.File, *utils.ReturnStatus) {
-	query := `
-		SELECT
-			id, user_id, name, type, size, share_token,
-			password, available_from, available_to, enable_totp,
-			created_at, is_public
-		FROM files
-		WHERE share_token = $1
-	`
-
-	var file domain.File
-	var ownerID sql.NullString
-	var passwordHash sql.NullString
-
-	row := r.db.QueryRowContext(ctx, query, token)
-
-	err := row.Scan(
-		&file.Id,
-		&ownerID,
-		&file.FileName,
-		&file.MimeType,
-		&file.FileSize,
-		&file.ShareToken,
-		&passwordHash,
-		&file.AvailableFrom,
-		&file.AvailableTo,
-		&file.EnableTOTP,
-		&file.CreatedAt,
-		&file.IsPublic,
-	)
-
+func (r *fileRepository) getFile(ctx context.Context, condition string, arg any) (*domain.File, *utils.ReturnStatus) {
+	var row fileRow
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+fileColumns+fileJoins+` WHERE `+condition, arg).Scan(row.dest()...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, utils.Response(utils.ErrCodeFileNotFound)
@@ -181,21 +196,15 @@ func (r *fileRepository) GetFileByToken(ctx context.Context, token string) (*dom
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
 
-	if ownerID.Valid {
-		file.OwnerId = &ownerID.String
-	} else {
-		file.OwnerId = nil
-	}
+	return row.result(), nil
+}
 
-	if passwordHash.Valid {
-		file.PasswordHash = &passwordHash.String
-		file.HasPassword = true
-	} else {
-		file.PasswordHash = nil
-		file.HasPassword = false
-	}
+func (r *fileRepository) GetFileByID(ctx context.Context, id string) (*domain.File, *utils.ReturnStatus) {
+	return r.getFile(ctx, `f.id = $1`, id)
+}
 
-	return &file, nil
+func (r *fileRepository) GetFileByToken(ctx context.Context, token string) (*domain.File, *utils.ReturnStatus) {
+	return r.getFile(ctx, `f.share_token = $1`, token)
 }
 
 func (r *fileRepository) DeleteFile(ctx context.Context, id string) *utils.ReturnStatus {
@@ -204,7 +213,7 @@ func (r *fileRepository) DeleteFile(ctx context.Context, id string) *utils.Retur
         WHERE id = $1
     `
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
@@ -221,106 +230,82 @@ func (r *fileRepository) DeleteFile(ctx context.Context, id string) *utils.Retur
 	return nil
 }
 
-func (r *fileRepository) GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) ([]domain.File, *utils.ReturnStatus) {
-	// 1. Khởi tạo truy vấn cơ bản
-	baseQuery := `
-		SELECT
-			id, user_id, name, type, size, share_token,
-			available_from, available_to, enable_totp, created_at, is_public
-		FROM files
-		WHERE user_id = $1
-	`
-	args := []any{userID}
-	query := baseQuery
-	argCounter := 2
-
-	if strings.ToLower(params.Status) != "all" {
-		status := strings.ToLower(params.Status)
-
-		argCounter++
-
-		switch status {
-		case "active":
-			query += " AND available_from <= NOW() AND available_to > NOW()"
-		case "pending":
-			query += " AND available_from > NOW()"
-		case "expired":
-			query += " AND available_to <= NOW()"
-		default:
-			return nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid file status.")
-		}
+func (r *fileRepository) GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	keys, ok := fileKeysets[params.Page.SortBy]
+	if !ok {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
 	}
 
-	// 3. Thêm sắp xếp
-	safeSortBy := "created_at"
-	if params.SortBy == "fileName" {
-		safeSortBy = "name"
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
-	safeOrder := "DESC"
-	if strings.ToLower(params.Order) == "asc" {
-		safeOrder = "ASC"
+
+	// 1. Khởi tạo truy vấn cơ bản
+	query := `SELECT f.id, ` + keys.columns() + `,` + fileColumns + fileJoins + ` WHERE f.user_id = $1`
+
+	switch strings.ToLower(params.Status) {
+	case "all":
+	case "active":
+		query += " AND f.available_from <= NOW() AND f.available_to > NOW()"
+	case "pending":
+		query += " AND f.available_from > NOW()"
+	case "expired":
+		query += " AND f.available_to <= NOW()"
+	default:
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid file status.")
 	}
 
-	query += fmt.Sprintf(" ORDER BY %s %s", safeSortBy, safeOrder)
+	// 2. Lọc theo thư mục
+	switch params.FolderId {
+	case "":
+	case "root":
+		query += " AND f.folder_id IS NULL"
+	default:
+		query += " AND f.folder_id = " + arg(params.FolderId)
+	}
 
-	// 4. Thêm phân trang (Pagination)
-	offset := (params.Page - 1) * params.Limit
-	query += " LIMIT $2 OFFSET $3"
-	args = append(args, int64(params.Limit), int64(offset))
+	// 3. Phân trang keyset và sắp xếp
+	after, rerr := keys.after(params.Page, arg)
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+	if after != "" {
+		query += " AND " + after
+	}
+	query += keys.orderBy(params.Page, arg)
 
-	// 5. Thực thi truy vấn
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	// 4. Thực thi truy vấn
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		return nil, nil, cursorError(params.Page, err)
 	}
 	defer rows.Close()
 
 	now := time.Now()
 
+	var page []keysetRow
 	var files []domain.File
 	for rows.Next() {
-		var f domain.File
-		var ownerID sql.NullString // Cần để scan user_id
-
-		err := rows.Scan(
-			&f.Id, &ownerID, &f.FileName, &f.MimeType, &f.FileSize, &f.ShareToken,
-			&f.AvailableFrom, &f.AvailableTo, &f.EnableTOTP, &f.CreatedAt,
-			&f.IsPublic,
-		)
-
+		var f fileRow
+		row, err := keys.scan(rows, f.dest()...)
 		if err != nil {
-			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+			return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 		}
 
-		// Gán giá trị sau khi scan
-		if ownerID.Valid {
-			f.OwnerId = &ownerID.String
-		}
+		file := f.result()
+		file.Status = file.StatusAt(now)
 
-		f.Status = "active"
-
-		if now.Before(f.AvailableFrom) {
-			f.Status = "pending"
-		} else if now.After(f.AvailableTo) {
-			f.Status = "expired"
-		}
-
-		files = append(files, f)
+		page = append(page, row)
+		files = append(files, *file)
 	}
-
-	return files, nil
-}
-func (r *fileRepository) GetTotalUserFiles(ctx context.Context, userID string) (int, *utils.ReturnStatus) {
-	var total int
-
-	query := `SELECT COUNT(id) FROM files WHERE user_id = $1`
-
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&total)
-	if err != nil {
-		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	if err := rows.Err(); err != nil {
+		return nil, nil, cursorError(params.Page, err)
 	}
 
-	return total, nil
+	page, next := keys.next(params.Page, page)
+	return files[:len(page)], next, nil
 }
 func (r *fileRepository) GetFileSummary(ctx context.Context, userID string) (*domain.FileSummary, *utils.ReturnStatus) {
 	summary := &domain.FileSummary{}
@@ -331,7 +316,7 @@ func (r *fileRepository) GetFileSummary(ctx context.Context, userID string) (*do
           AND available_from <= NOW()
           AND available_to > NOW()
     `
-	err := r.db.QueryRowContext(ctx, activeQuery, userID).Scan(&summary.ActiveFiles) // Chỉ truyền $1
+	err := conn(ctx, r.db).QueryRowContext(ctx, activeQuery, userID).Scan(&summary.ActiveFiles) // Chỉ truyền $1
 	if err != nil {
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
@@ -341,7 +326,7 @@ func (r *fileRepository) GetFileSummary(ctx context.Context, userID string) (*do
         WHERE user_id = $1
           AND available_from > NOW()
     `
-	err = r.db.QueryRowContext(ctx, pendingQuery, userID).Scan(&summary.PendingFiles) // Chỉ truyền $1
+	err = conn(ctx, r.db).QueryRowContext(ctx, pendingQuery, userID).Scan(&summary.PendingFiles) // Chỉ truyền $1
 	if err != nil {
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
@@ -352,7 +337,7 @@ func (r *fileRepository) GetFileSummary(ctx context.Context, userID string) (*do
         WHERE user_id = $1
           AND available_to <= NOW()
     `
-	err = r.db.QueryRowContext(ctx, expiredQuery, userID).Scan(&summary.ExpiredFiles) // Chỉ truyền $1
+	err = conn(ctx, r.db).QueryRowContext(ctx, expiredQuery, userID).Scan(&summary.ExpiredFiles) // Chỉ truyền $1
 	if err != nil {
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
@@ -369,7 +354,7 @@ func (r *fileRepository) FindAll(ctx context.Context) ([]domain.File, *utils.Ret
         ORDER BY created_at DESC
     `
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
 	if err != nil {
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
@@ -425,53 +410,135 @@ func (r *fileRepository) FindAll(ctx context.Context) ([]domain.File, *utils.Ret
 	return files, nil
 }
 
-func (r *fileRepository) RegisterDownload(ctx context.Context, fileID string, userID string) *utils.ReturnStatus {
-	_, err := r.db.ExecContext(ctx, `CALL proc_download($1, $2)`, fileID, sql.Null[string]{V: userID, Valid: userID != ""})
+// RegisterDownload ghi nhận một lượt tải và trả về số lượt còn lại (nil nếu
+// file không giới hạn). Trả về ErrCodeDownloadLimitReached khi đã hết slot.
+func (r *fileRepository) RegisterDownload(ctx context.Context, fileID string, userID string) (*int64, *utils.ReturnStatus) {
+	var granted bool
+	var remaining sql.NullInt64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `CALL proc_download($1, $2, NULL, NULL)`,
+		fileID, sql.Null[string]{V: userID, Valid: userID != ""},
+	).Scan(&granted, &remaining)
 
 	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if !granted {
+		return nil, utils.Response(utils.ErrCodeDownloadLimitReached)
+	}
+
+	if remaining.Valid {
+		return &remaining.Int64, nil
+	}
+
+	return nil, nil
+}
+
+func (r *fileRepository) UpdateShareLink(ctx context.Context, file *domain.File) *utils.ReturnStatus {
+	// Tăng version để thu hồi các signed URL đã cấp cho link cũ.
+	query := `
+		UPDATE files
+		SET share_token = $2, max_downloads = $3, delete_on_limit = $4, version = version + 1
+		WHERE id = $1
+		RETURNING version
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, file.Id, file.ShareToken, file.MaxDownloads, file.DeleteOnLimit).Scan(&file.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.Response(utils.ErrCodeFileNotFound)
+		}
+		if isUniqueViolation(err, "files_share_token_key") {
+			return utils.Response(utils.ErrCodeShareTokenTaken)
+		}
 		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
 
 	return nil
 }
 
-func (r *fileRepository) GetFileDownloadHistory(ctx context.Context, fileID string) (*domain.FileDownloadHistory, *utils.ReturnStatus) {
+// downloadKeyset: lịch sử tải sắp xếp theo thời điểm tải (bảng download có alias d).
+var downloadKeyset = keyset{keys: []keysetKey{{"d.time", "timestamptz"}}, id: "d.download_id"}
+
+func (r *fileRepository) GetFileDownloadHistory(ctx context.Context, fileID string, page domain.PageRequest) (*domain.FileDownloadHistory, *domain.Cursor, *utils.ReturnStatus) {
 	file, err := r.GetFileByID(ctx, fileID)
 	if err != nil {
 		log.Println("File retrieval failure")
-		return nil, err
+		return nil, nil, err
 	}
 
 	history := domain.FileDownloadHistory{}
 	history.FileId = file.Id
 	history.FileName = file.FileName
+	history.History = []domain.Download{}
+
+	args := []any{file.Id}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
-	rows, derr := r.db.QueryContext(ctx, `SELECT download_id, user_id, time FROM download WHERE file_id = $1`, file.Id)
+	// Người tải được JOIN luôn, lượt tải ẩn danh hoặc của user đã xóa không có downloader.
+	query := `SELECT d.download_id, ` + downloadKeyset.columns() + `, d.user_id, d.time, u.username, u.email
+		FROM download d LEFT JOIN users u ON u.id = d.user_id
+		WHERE d.file_id = $1`
+	after, err := downloadKeyset.after(page, arg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if after != "" {
+		query += " AND " + after
+	}
+	query += downloadKeyset.orderBy(page, arg)
+
+	rows, derr := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if derr != nil {
 		log.Println("Download retrieval failure")
-		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, derr.Error())
+		return nil, nil, cursorError(page, derr)
 	}
 
+	defer rows.Close()
+
+	var downloads []keysetRow
 	for rows.Next() {
 		var time time.Time
-		var d_id string
-		var u_id string
-		if err := rows.Scan(&d_id, &u_id, &time); err != nil {
+		var u_id sql.NullString // lượt tải ẩn danh không có user_id
+		var username, email sql.NullString
+		row, err := downloadKeyset.scan(rows, &u_id, &time, &username, &email)
+		if err != nil {
 			log.Println("Row scan failure")
-			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+			return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+
+		var userID *string
+		if u_id.Valid {
+			userID = &u_id.String
 		}
 
+		var downloader *domain.Downloader
+		if email.Valid {
+			downloader = &domain.Downloader{Username: username.String, Email: email.String}
+		}
+
+		downloads = append(downloads, row)
 		history.History = append(history.History,
 			domain.Download{
-				DownloadId:        d_id,
-				UserId:            &u_id,
-				Downloader:        nil,
+				DownloadId:        row.id,
+				UserId:            userID,
+				Downloader:        downloader,
 				DownloadedAt:      time,
 				DownloadCompleted: true,
 			})
 	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, cursorError(page, err)
+	}
 
-	return &history, nil
+	downloads, next := downloadKeyset.next(page, downloads)
+	history.History = history.History[:len(downloads)]
+
+	return &history, next, nil
 }
 
 func (r *fileRepository) GetFileStats(ctx context.Context, fileID string) (*domain.FileStat, *utils.ReturnStatus) {
@@ -494,7 +561,7 @@ func (r *fileRepository) GetFileStats(ctx context.Context, fileID string) (*doma
 	stat := domain.FileStat{}
 	var ownerID sql.NullString
 	var lastDownloadTime sql.NullTime
-	row := r.db.QueryRowContext(ctx, query, fileID)
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, fileID)
 
 	err := row.Scan(
 		&stat.FileId,
@@ -521,40 +588,276 @@ func (r *fileRepository) GetFileStats(ctx context.Context, fileID string) (*doma
 	return &stat, nil
 }
 
-func (r *fileRepository) GetAccessibleFiles(ctx context.Context, userID string) ([]domain.File, *utils.ReturnStatus) {
+// sharedWithUserFiles trả về câu SELECT id các file được chia sẻ cho user userParam:
+// trực tiếp, qua nhóm (theo thành viên hiện tại) hoặc qua thư mục chứa file.
+func sharedWithUserFiles(userParam string) string {
+	return `
+		SELECT s.file_id FROM shared s WHERE s.user_id = ` + userParam + `
+		UNION
+		SELECT sg.file_id FROM shared_groups sg
+		JOIN group_members m ON m.group_id = sg.group_id
+		WHERE m.user_id = ` + userParam + `
+		UNION
+		SELECT ff.id FROM files ff
+		JOIN folders fo ON fo.id = ff.folder_id
+		WHERE ` + folderSharedWith(userParam) + `
+	`
+}
+
+func (r *fileRepository) GetAccessibleFiles(ctx context.Context, userID string, page domain.PageRequest) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	keys, ok := fileKeysets[page.SortBy]
+	if !ok {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
+	}
+
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
 	query := `
-		SELECT DISTINCT f.id
-		FROM files f JOIN shared s ON f.id = s.file_id
+		SELECT f.id, ` + keys.columns() + `,` + fileColumns + fileJoins + `
 		WHERE
 		(NOW() >= f.available_from AND NOW() < f.available_to)
-		AND $1 = s.user_id
-		;
+		AND f.id IN (` + sharedWithUserFiles("$1") + `)
 	`
+	after, rerr := keys.after(page, arg)
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+	if after != "" {
+		query += " AND " + after
+	}
+	query += keys.orderBy(page, arg)
 
-	var rows *sql.Rows = nil
-	var err error = nil
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, cursorError(page, err)
+	}
+	defer rows.Close()
 
-	rows, err = r.db.QueryContext(ctx, query, userID)
+	var matches []keysetRow
+	var out []domain.File
+	for rows.Next() {
+		var f fileRow
+		row, err := keys.scan(rows, f.dest()...)
+		if err != nil {
+			return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, err.Error())
+		}
+		matches = append(matches, row)
+		out = append(out, *f.result())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, cursorError(page, err)
+	}
+
+	matches, next := keys.next(page, matches)
+	return out[:len(matches)], next, nil
+}
 
+func (r *fileRepository) ListFileIDsByOwner(ctx context.Context, userID string) ([]string, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT id FROM files WHERE user_id = $1`, userID)
 	if err != nil {
-		return nil, utils.ResponseMsg(utils.ErrCodeInternal, err.Error())
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
+	defer rows.Close()
 
-	var out []domain.File
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+// TransferOwnership chuyển toàn bộ file của fromUserID sang toUserID, trả về số file đã chuyển.
+func (r *fileRepository) TransferOwnership(ctx context.Context, fromUserID string, toUserID string) (int64, *utils.ReturnStatus) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE files SET user_id = $2 WHERE user_id = $1`, fromUserID, toUserID)
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+// GetUserDownloads trả về các lượt tải do chính userID thực hiện, mới nhất trước.
+func (r *fileRepository) GetUserDownloads(ctx context.Context, userID string) ([]domain.UserDownload, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT d.file_id, f.name, d.time
+		FROM download d JOIN files f ON f.id = d.file_id
+		WHERE d.user_id = $1
+		ORDER BY d.time DESC
+	`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
 
+	downloads := []domain.UserDownload{}
 	for rows.Next() {
-		var fileID string
-		if err := rows.Scan(&fileID); err != nil {
-			return nil, utils.ResponseMsg(utils.ErrCodeInternal, err.Error())
+		var d domain.UserDownload
+		if err := rows.Scan(&d.FileId, &d.FileName, &d.DownloadedAt); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 		}
+		downloads = append(downloads, d)
+	}
 
-		file, err := r.GetFileByID(ctx, fileID)
-		if err != nil {
-			return nil, err
+	return downloads, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+// ListByFolder liệt kê file nằm trực tiếp trong folderID (không gồm thư mục con).
+func (r *fileRepository) ListByFolder(ctx context.Context, folderID string) ([]domain.File, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+fileColumns+fileJoins+` WHERE f.folder_id = $1 ORDER BY f.name`, folderID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	files := []domain.File{}
+	for rows.Next() {
+		var f fileRow
+		if err := rows.Scan(f.dest()...); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 		}
+		files = append(files, *f.result())
+	}
 
-		out = append(out, *file)
+	return files, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *fileRepository) MoveToFolder(ctx context.Context, fileID string, folderID *string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE files SET folder_id = $2 WHERE id = $1`, fileID, folderID)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	return nil
+}
+
+func (r *fileRepository) UpdateMetadata(ctx context.Context, fileID string, description *string, tags []string) *utils.ReturnStatus {
+	if tags == nil {
+		tags = []string{}
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE files SET description = $2, tags = $3 WHERE id = $1`, fileID, description, pq.Array(tags))
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	return nil
+}
+
+func (r *fileRepository) SearchFiles(ctx context.Context, userID string, params domain.FileSearchParams) ([]domain.FileSearchHit, *domain.Cursor, *utils.ReturnStatus) {
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// File của mình ở mọi trạng thái; file được chia sẻ chỉ khi đang trong thời gian hiệu lực, giống /files/available.
+	mine := `f.user_id = $1`
+	shared := `(f.user_id IS DISTINCT FROM $1 AND NOW() >= f.available_from AND NOW() < f.available_to
+		AND f.id IN (` + sharedWithUserFiles("$1") + `))`
+
+	conditions := []string{}
+	switch params.Scope {
+	case "mine":
+		conditions = append(conditions, mine)
+	case "shared":
+		conditions = append(conditions, shared)
+	default:
+		conditions = append(conditions, "("+mine+" OR "+shared+")")
+	}
+
+	rank := "0::real"
+	if params.Query != "" {
+		query := arg(params.Query)
+		conditions = append(conditions, "f.search_vector @@ to_tsquery('simple', "+query+")")
+		rank = "ts_rank_cd(f.search_vector, to_tsquery('simple', " + query + "))"
+	}
+
+	if len(params.Tags) > 0 {
+		conditions = append(conditions, "f.tags @> "+arg(pq.Array(params.Tags))+"::text[]")
+	}
+	if prefix, ok := strings.CutSuffix(params.MimeType, "/*"); ok {
+		conditions = append(conditions, "f.type LIKE "+arg(prefix+"/%"))
+	} else if params.MimeType != "" {
+		conditions = append(conditions, "f.type = "+arg(params.MimeType))
+	}
+	if params.MinSize != nil {
+		conditions = append(conditions, "f.size >= "+arg(*params.MinSize))
+	}
+	if params.MaxSize != nil {
+		conditions = append(conditions, "f.size <= "+arg(*params.MaxSize))
+	}
+	if params.From != nil {
+		conditions = append(conditions, "f.created_at >= "+arg(*params.From))
+	}
+	if params.To != nil {
+		conditions = append(conditions, "f.created_at < "+arg(*params.To))
+	}
+	if params.Owner != "" {
+		conditions = append(conditions, "u.email = "+arg(params.Owner))
+	}
+
+	// Độ liên quan giảm dần, cùng rank thì file mới hơn trước.
+	keys, ok := fileKeysets[params.Page.SortBy]
+	if params.Page.SortBy == domain.SORT_RELEVANCE {
+		keys, ok = keyset{keys: []keysetKey{{rank, "real"}, {"f.created_at", "timestamptz"}}, id: "f.id"}, true
+	}
+	if !ok {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
+	}
+
+	after, rerr := keys.after(params.Page, arg)
+	if rerr != nil {
+		return nil, nil, rerr
 	}
+	if after != "" {
+		conditions = append(conditions, after)
+	}
+
+	query := `SELECT f.id, ` + keys.columns() + `, ` + rank + ` AS rank,` + fileColumns + fileJoins + `
+		WHERE ` + strings.Join(conditions, " AND ") + keys.orderBy(params.Page, arg)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, cursorError(params.Page, err)
+	}
+	defer rows.Close()
+
+	page := []keysetRow{}
+	hits := []domain.FileSearchHit{}
+	for rows.Next() {
+		var f fileRow
+		var rank float64
+		row, err := keys.scan(rows, append([]any{&rank}, f.dest()...)...)
+		if err != nil {
+			return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		page = append(page, row)
+		hits = append(hits, domain.FileSearchHit{File: *f.result(), Rank: rank})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, cursorError(params.Page, err)
+	}
+
+	page, next := keys.next(params.Page, page)
+	hits = hits[:len(page)]
 
-	return out, nil
+	return hits, next, nil
 }
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/lib/pq"
+)
+
+type folderRepository struct {
+	db *sql.DB
+}
+
+func NewFolderRepository(db *sql.DB) FolderRepository {
+	return &folderRepository{db: db}
+}
+
+const folderColumns = `id, user_id, parent_id, name, path, created_at, updated_at`
+
+// folderSharedWith trả về điều kiện SQL "thư mục fo hoặc một thư mục cha của nó được chia sẻ
+// cho user userParam", trực tiếp hoặc qua nhóm. Tổ tiên lấy thẳng từ materialized path.
+func folderSharedWith(userParam string) string {
+	ancestors := `string_to_array(trim(both '/' from fo.path), '/')::uuid[]`
+	return `(
+		EXISTS (
+			SELECT 1 FROM shared_folders sf
+			WHERE sf.folder_id = ANY(` + ancestors + `) AND sf.user_id = ` + userParam + `
+		) OR EXISTS (
+			SELECT 1 FROM shared_folder_groups sg
+			JOIN group_members m ON m.group_id = sg.group_id
+			WHERE sg.folder_id = ANY(` + ancestors + `) AND m.user_id = ` + userParam + `
+		)
+	)`
+}
+
+func scanFolder(row interface{ Scan(...any) error }, folder *domain.Folder) error {
+	var parentID sql.NullString
+	err := row.Scan(&folder.Id, &folder.OwnerId, &parentID, &folder.Name, &folder.Path, &folder.CreatedAt, &folder.UpdatedAt)
+	if parentID.Valid {
+		folder.ParentId = &parentID.String
+	}
+	return err
+}
+
+func (r *folderRepository) list(ctx context.Context, query string, args ...any) ([]domain.Folder, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	folders := []domain.Folder{}
+	for rows.Next() {
+		var folder domain.Folder
+		if err := scanFolder(rows, &folder); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *folderRepository) Create(ctx context.Context, folder *domain.Folder) *utils.ReturnStatus {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO folders (id, user_id, parent_id, name, path)
+		VALUES ($1, $2, $3, $4, COALESCE((SELECT path FROM folders WHERE id = $3), '/') || $1::text || '/')
+		RETURNING path, created_at, updated_at
+	`, folder.Id, folder.OwnerId, folder.ParentId, folder.Name,
+	).Scan(&folder.Path, &folder.CreatedAt, &folder.UpdatedAt)
+
+	if isUniqueViolation(err, "folders_sibling_name_key") {
+		return utils.Response(utils.ErrCodeFolderNameTaken)
+	}
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *folderRepository) Find(ctx context.Context, id string) (*domain.Folder, *utils.ReturnStatus) {
+	var folder domain.Folder
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+folderColumns+` FROM folders WHERE id = $1`, id)
+	if err := scanFolder(row, &folder); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeFolderNotFound)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return &folder, nil
+}
+
+func (r *folderRepository) ListByOwner(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `SELECT `+folderColumns+` FROM folders WHERE user_id = $1 ORDER BY path`, userID)
+}
+
+func (r *folderRepository) ListChildren(ctx context.Context, parentID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `SELECT `+folderColumns+` FROM folders WHERE parent_id = $1 ORDER BY lower(name)`, parentID)
+}
+
+// ListSubtree trả về folder và mọi thư mục con cháu, sắp theo path (cha trước con).
+func (r *folderRepository) ListSubtree(ctx context.Context, folder *domain.Folder) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `SELECT `+folderColumns+` FROM folders WHERE path LIKE $1 || '%' ORDER BY path`, folder.Path)
+}
+
+func (r *folderRepository) Rename(ctx context.Context, id string, name string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE folders SET name = $2, updated_at = NOW() WHERE id = $1`, id, name)
+	if isUniqueViolation(err, "folders_sibling_name_key") {
+		return utils.Response(utils.ErrCodeFolderNameTaken)
+	}
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFolderNotFound)
+	}
+
+	return nil
+}
+
+func (r *folderRepository) Move(ctx context.Context, folder *domain.Folder, parent *domain.Folder) *utils.ReturnStatus {
+	newPath := "/" + folder.Id + "/"
+	var parentID any
+	if parent != nil {
+		newPath = parent.Path + folder.Id + "/"
+		parentID = parent.Id
+	}
+
+	// Một câu UPDATE cho cả cây con: thay tiền tố path cũ bằng path mới.
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE folders
+		SET path = $3 || substr(path, length($2) + 1),
+			parent_id = CASE WHEN id = $1 THEN $4::uuid ELSE parent_id END,
+			updated_at = CASE WHEN id = $1 THEN NOW() ELSE updated_at END
+		WHERE path LIKE $2 || '%'
+	`, folder.Id, folder.Path, newPath, parentID)
+	if isUniqueViolation(err, "folders_sibling_name_key") {
+		return utils.Response(utils.ErrCodeFolderNameTaken)
+	}
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	folder.Path = newPath
+	folder.ParentId = nil
+	if parent != nil {
+		folder.ParentId = &parent.Id
+	}
+	return nil
+}
+
+func (r *folderRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	// Thư mục con xóa theo ON DELETE CASCADE, file bên trong về thư mục gốc (ON DELETE SET NULL).
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM folders WHERE id = $1`, id)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFolderNotFound)
+	}
+
+	return nil
+}
+
+func (r *folderRepository) SetShares(ctx context.Context, folderID string, emails []string, groupIDs []string) *utils.ReturnStatus {
+	// pq.Array(nil) là NULL, "= ANY(NULL)" không khớp gì nên phải truyền mảng rỗng.
+	if emails == nil {
+		emails = []string{}
+	}
+	if groupIDs == nil {
+		groupIDs = []string{}
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		WITH recipients AS (
+			SELECT id FROM users WHERE email = ANY($2::text[])
+		), removed_users AS (
+			DELETE FROM shared_folders
+			WHERE folder_id = $1::uuid AND user_id NOT IN (SELECT id FROM recipients)
+		), added_users AS (
+			INSERT INTO shared_folders (folder_id, user_id)
+			SELECT $1::uuid, id FROM recipients
+			ON CONFLICT (folder_id, user_id) DO NOTHING
+		), removed_groups AS (
+			DELETE FROM shared_folder_groups
+			WHERE folder_id = $1::uuid AND NOT (group_id = ANY($3::uuid[]))
+		)
+		INSERT INTO shared_folder_groups (folder_id, group_id)
+		SELECT $1::uuid, g.id FROM groups g WHERE g.id = ANY($3::uuid[])
+		ON CONFLICT (folder_id, group_id) DO NOTHING
+	`, folderID, pq.Array(emails), pq.Array(groupIDs))
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *folderRepository) ListShares(ctx context.Context, folderID string) (*domain.FolderShares, *utils.ReturnStatus) {
+	shares := &domain.FolderShares{SharedWith: []string{}, SharedWithGroups: []string{}}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT u.email FROM shared_folders sf
+		JOIN users u ON u.id = sf.user_id
+		WHERE sf.folder_id = $1
+		ORDER BY u.email
+	`, folderID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		shares.SharedWith = append(shares.SharedWith, email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	err = conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT COALESCE(array_agg(group_id::text ORDER BY group_id), '{}') FROM shared_folder_groups WHERE folder_id = $1
+	`, folderID).Scan(pq.Array(&shares.SharedWithGroups))
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return shares, nil
+}
+
+func (r *folderRepository) ListSharedWithUser(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `
+		SELECT `+folderColumns+` FROM folders
+		WHERE id IN (
+			SELECT folder_id FROM shared_folders WHERE user_id = $1
+			UNION
+			SELECT sg.folder_id FROM shared_folder_groups sg
+			JOIN group_members m ON m.group_id = sg.group_id
+			WHERE m.user_id = $1
+		)
+		ORDER BY lower(name)
+	`, userID)
+}
+
+func (r *folderRepository) SharedWithUser(ctx context.Context, folderID string, userID string) (bool, *utils.ReturnStatus) {
+	var shared bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM folders fo WHERE fo.id = $1 AND `+folderSharedWith("$2")+`)
+	`, folderID, userID).Scan(&shared)
+
+	return shared, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type groupRepository struct {
+	db *sql.DB
+}
+
+func NewGroupRepository(db *sql.DB) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+const groupColumns = `g.id, g.name, g.description, g.created_at,
+	(SELECT COUNT(*) FROM group_members c WHERE c.group_id = g.id)`
+
+func scanGroup(row interface{ Scan(...any) error }, group *domain.Group, extra ...any) error {
+	var description sql.NullString
+	dest := append([]any{&group.Id, &group.Name, &description, &group.CreatedAt, &group.MemberCount}, extra...)
+	err := row.Scan(dest...)
+	if description.Valid {
+		group.Description = &description.String
+	}
+	return err
+}
+
+func (r *groupRepository) Create(ctx context.Context, group *domain.Group, ownerID string) *utils.ReturnStatus {
+	// Một câu lệnh duy nhất để không bao giờ có nhóm mồ côi không owner.
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		WITH g AS (
+			INSERT INTO groups (name, description) VALUES ($1, $2)
+			RETURNING id, created_at
+		), m AS (
+			INSERT INTO group_members (group_id, user_id, role)
+			SELECT id, $3, $4 FROM g
+		)
+		SELECT id, created_at FROM g
+	`, group.Name, group.Description, ownerID, domain.GROUP_OWNER,
+	).Scan(&group.Id, &group.CreatedAt)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	group.MemberCount = 1
+	group.MyRole = domain.GROUP_OWNER
+	return nil
+}
+
+func (r *groupRepository) Find(ctx context.Context, id string) (*domain.Group, *utils.ReturnStatus) {
+	var group domain.Group
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+groupColumns+` FROM groups g WHERE g.id = $1`, id)
+	if err := scanGroup(row, &group); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeGroupNotFound)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return &group, nil
+}
+
+func (r *groupRepository) ListByUser(ctx context.Context, userID string) ([]domain.Group, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+groupColumns+`, m.role
+		FROM groups g
+		JOIN group_members m ON m.group_id = g.id
+		WHERE m.user_id = $1
+		ORDER BY g.name, g.created_at
+	`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	groups := []domain.Group{}
+	for rows.Next() {
+		var group domain.Group
+		if err := scanGroup(rows, &group, &group.MyRole); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *groupRepository) Update(ctx context.Context, group *domain.Group) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE groups SET name = $2, description = $3 WHERE id = $1`,
+		group.Id, group.Name, group.Description)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeGroupNotFound)
+	}
+
+	return nil
+}
+
+func (r *groupRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	// group_members và shared_groups xóa theo ON DELETE CASCADE, quyền truy cập qua nhóm mất ngay.
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM groups WHERE id = $1`, id)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeGroupNotFound)
+	}
+
+	return nil
+}
+
+func (r *groupRepository) ListMembers(ctx context.Context, groupID string) ([]domain.GroupMember, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT u.id, u.username, u.email, m.role, m.added_at
+		FROM group_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = $1
+		ORDER BY m.role DESC, u.username
+	`, groupID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	members := []domain.GroupMember{}
+	for rows.Next() {
+		var member domain.GroupMember
+		if err := rows.Scan(&member.UserId, &member.Username, &member.Email, &member.Role, &member.AddedAt); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		members = append(members, member)
+	}
+
+	return members, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *groupRepository) FindRole(ctx context.Context, groupID string, userID string) (string, *utils.ReturnStatus) {
+	var role string
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT role FROM group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return role, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *groupRepository) AddMember(ctx context.Context, groupID string, userID string, role string) *utils.ReturnStatus {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO group_members (group_id, user_id, role) VALUES ($1, $2, $3)`, groupID, userID, role)
+	if isUniqueViolation(err, "") {
+		return utils.Response(utils.ErrCodeGroupMemberExists)
+	}
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *groupRepository) UpdateMemberRole(ctx context.Context, groupID string, userID string, role string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE group_members SET role = $3 WHERE group_id = $1 AND user_id = $2`, groupID, userID, role)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeGroupMemberNotFound)
+	}
+
+	return nil
+}
+
+func (r *groupRepository) RemoveMember(ctx context.Context, groupID string, userID string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeGroupMemberNotFound)
+	}
+
+	return nil
+}
+
+func (r *groupRepository) CountOwners(ctx context.Context, groupID string) (int, *utils.ReturnStatus) {
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM group_members WHERE group_id = $1 AND role = $2`, groupID, domain.GROUP_OWNER).Scan(&count)
+
+	return count, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
@@ -13,6 +14,17 @@ type UserRepository interface {
 	FindByCId(cid string, user *domain.UsersLoginSession) *utils.ReturnStatus
 	AddTimestamp(id string, cid string) *utils.ReturnStatus
 	DeleteTimestamp(id string) *utils.ReturnStatus
+	UsernameExists(username string) (bool, *utils.ReturnStatus)
+	EmailExists(email string) (bool, *utils.ReturnStatus)
+	UpdateUsername(ctx context.Context, id string, username string) *utils.ReturnStatus
+	UpdateEmail(ctx context.Context, id string, email string) *utils.ReturnStatus
+	ListAdmins() ([]domain.User, *utils.ReturnStatus)
+	// List trả về mọi user, sắp theo email.
+	List() ([]domain.User, *utils.ReturnStatus)
+	SetSuspended(id string, suspended bool) *utils.ReturnStatus
+	// Delete chạy trong transaction của ctx nếu có.
+	Delete(ctx context.Context, id string) *utils.ReturnStatus
+	Search(ctx context.Context, params domain.UserSearchParams) ([]domain.PublicProfile, *utils.ReturnStatus)
 }
 
 type AuthRepository interface {
@@ -22,4 +34,126 @@ type AuthRepository interface {
 	SaveSecret(userID string, secret string) *utils.ReturnStatus
 	GetSecret(userID string) (string, *utils.ReturnStatus)
 	EnableTOTP(userID string) *utils.ReturnStatus
+	// DisableTOTP tắt TOTP và xóa secret, user phải thiết lập lại từ đầu.
+	DisableTOTP(userID string) *utils.ReturnStatus
+	RevokeUserTokens(ctx context.Context, userID string, revokedAt time.Time) *utils.ReturnStatus
+	IsUserTokenRevoked(userID string, issuedAt time.Time) (bool, *utils.ReturnStatus)
+	// PurgeBlacklist xóa token trong blacklist đã hết hạn trước expiredBefore và mốc thu hồi
+	// cũ hơn revokedBefore, trả về tổng số dòng đã xóa.
+	PurgeBlacklist(expiredBefore time.Time, revokedBefore time.Time) (int64, *utils.ReturnStatus)
+}
+
+type AttemptRepository interface {
+	LockRemaining(ctx context.Context, keys []domain.AttemptKey) (time.Duration, *utils.ReturnStatus)
+	// Reserve tính trước một lần thử như một lần sai, trong cùng một câu lệnh với việc kiểm tra khóa, và
+	// khóa ngay hold khi số lần đạt threshold. Trả về số lần sai đã tính, hoặc 0 nếu key đang bị khóa.
+	Reserve(ctx context.Context, key domain.AttemptKey, window time.Duration, threshold int, hold time.Duration) (int, *utils.ReturnStatus)
+	// Release hoàn lại một lần Reserve có số đếm failures; mở khóa nếu chính lần đó đã đặt khóa.
+	Release(ctx context.Context, key domain.AttemptKey, failures int) *utils.ReturnStatus
+	Lock(ctx context.Context, key domain.AttemptKey, duration time.Duration) *utils.ReturnStatus
+	Reset(ctx context.Context, key domain.AttemptKey) *utils.ReturnStatus
+	DeleteStale(ctx context.Context, olderThan time.Duration) (int64, *utils.ReturnStatus)
+}
+
+type APITokenRepository interface {
+	Create(ctx context.Context, token *domain.APIToken) *utils.ReturnStatus
+	ListByUser(ctx context.Context, userID string) ([]domain.APIToken, *utils.ReturnStatus)
+	FindByHash(ctx context.Context, hash string) (*domain.APIToken, *utils.ReturnStatus)
+	Delete(ctx context.Context, id string, userID string) *utils.ReturnStatus
+	TouchLastUsed(ctx context.Context, id string) *utils.ReturnStatus
+}
+
+type OIDCRepository interface {
+	SaveState(ctx context.Context, state *domain.OIDCState) *utils.ReturnStatus
+	ConsumeState(ctx context.Context, state string, provider string) (*domain.OIDCState, *utils.ReturnStatus)
+	// FindIdentity trả về (nil, nil) khi identity chưa được liên kết.
+	FindIdentity(ctx context.Context, provider string, subject string) (*domain.UserIdentity, *utils.ReturnStatus)
+	LinkIdentity(ctx context.Context, identity *domain.UserIdentity) *utils.ReturnStatus
+	TouchIdentity(ctx context.Context, provider string, subject string, email string) *utils.ReturnStatus
+	UpdateUserRole(ctx context.Context, userID string, role string) *utils.ReturnStatus
+}
+
+type WebAuthnRepository interface {
+	ListByUser(ctx context.Context, userID string) ([]domain.WebAuthnCredential, *utils.ReturnStatus)
+	CountByUser(ctx context.Context, userID string) (int, *utils.ReturnStatus)
+	FindByCredentialID(ctx context.Context, credentialID []byte) (*domain.WebAuthnCredential, *utils.ReturnStatus)
+	Create(ctx context.Context, cred *domain.WebAuthnCredential) *utils.ReturnStatus
+	UpdateAfterLogin(ctx context.Context, id string, data []byte) *utils.ReturnStatus
+	Delete(ctx context.Context, id string, userID string) *utils.ReturnStatus
+	SaveSession(ctx context.Context, session *domain.WebAuthnSession) *utils.ReturnStatus
+	ConsumeSession(ctx context.Context, id string, purpose domain.WebAuthnPurpose) (*domain.WebAuthnSession, *utils.ReturnStatus)
+}
+
+type RegistrationRepository interface {
+	ListDomainRules(ctx context.Context) ([]domain.EmailDomainRule, *utils.ReturnStatus)
+	FindDomainRule(ctx context.Context, emailDomain string) (*domain.EmailDomainRule, *utils.ReturnStatus)
+	SaveDomainRule(ctx context.Context, rule *domain.EmailDomainRule) *utils.ReturnStatus
+	DeleteDomainRule(ctx context.Context, emailDomain string) *utils.ReturnStatus
+
+	CreateInvite(ctx context.Context, invite *domain.Invite) *utils.ReturnStatus
+	ListInvites(ctx context.Context) ([]domain.Invite, *utils.ReturnStatus)
+	DeleteInvite(ctx context.Context, id string) *utils.ReturnStatus
+	ClaimInvite(ctx context.Context, tokenHash string) (*domain.Invite, *utils.ReturnStatus)
+	ReleaseInvite(ctx context.Context, id string) *utils.ReturnStatus
+	CompleteInvite(ctx context.Context, id string, userID string) *utils.ReturnStatus
+
+	SaveVerification(ctx context.Context, userID string, email string, tokenHash string, expiresAt time.Time) *utils.ReturnStatus
+	ConsumeVerification(ctx context.Context, tokenHash string) (bool, *utils.ReturnStatus)
+}
+
+type ExportRepository interface {
+	Create(ctx context.Context, job *domain.ExportJob) *utils.ReturnStatus
+	Find(ctx context.Context, id string) (*domain.ExportJob, *utils.ReturnStatus)
+	// FindActive trả về job pending/running tạo sau since, (nil, nil) nếu không có.
+	FindActive(ctx context.Context, userID string, since time.Time) (*domain.ExportJob, *utils.ReturnStatus)
+	ListByUser(ctx context.Context, userID string) ([]domain.ExportJob, *utils.ReturnStatus)
+	MarkRunning(ctx context.Context, id string) *utils.ReturnStatus
+	MarkReady(ctx context.Context, id string, size int64, expiresAt time.Time) *utils.ReturnStatus
+	MarkFailed(ctx context.Context, id string, reason string) *utils.ReturnStatus
+	// ListExpired: job ready đã quá hạn tải và job lỗi/treo tạo trước before.
+	ListExpired(ctx context.Context, now time.Time, before time.Time) ([]domain.ExportJob, *utils.ReturnStatus)
+	Delete(ctx context.Context, id string) *utils.ReturnStatus
+}
+
+type LoginHistoryRepository interface {
+	Record(ctx context.Context, record *domain.LoginRecord) *utils.ReturnStatus
+	ListByUser(ctx context.Context, userID string) ([]domain.LoginRecord, *utils.ReturnStatus)
+}
+
+type GroupRepository interface {
+	// Create tạo nhóm và thêm ownerID làm owner đầu tiên.
+	Create(ctx context.Context, group *domain.Group, ownerID string) *utils.ReturnStatus
+	Find(ctx context.Context, id string) (*domain.Group, *utils.ReturnStatus)
+	ListByUser(ctx context.Context, userID string) ([]domain.Group, *utils.ReturnStatus)
+	Update(ctx context.Context, group *domain.Group) *utils.ReturnStatus
+	Delete(ctx context.Context, id string) *utils.ReturnStatus
+
+	ListMembers(ctx context.Context, groupID string) ([]domain.GroupMember, *utils.ReturnStatus)
+	// FindRole trả về vai trò của userID trong nhóm, chuỗi rỗng nếu không phải thành viên.
+	FindRole(ctx context.Context, groupID string, userID string) (string, *utils.ReturnStatus)
+	AddMember(ctx context.Context, groupID string, userID string, role string) *utils.ReturnStatus
+	UpdateMemberRole(ctx context.Context, groupID string, userID string, role string) *utils.ReturnStatus
+	RemoveMember(ctx context.Context, groupID string, userID string) *utils.ReturnStatus
+	CountOwners(ctx context.Context, groupID string) (int, *utils.ReturnStatus)
+}
+
+type FolderRepository interface {
+	// Create tính path theo thư mục cha, trùng tên trong cùng thư mục cha → ErrCodeFolderNameTaken.
+	Create(ctx context.Context, folder *domain.Folder) *utils.ReturnStatus
+	Find(ctx context.Context, id string) (*domain.Folder, *utils.ReturnStatus)
+	ListByOwner(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus)
+	ListChildren(ctx context.Context, parentID string) ([]domain.Folder, *utils.ReturnStatus)
+	ListSubtree(ctx context.Context, folder *domain.Folder) ([]domain.Folder, *utils.ReturnStatus)
+	Rename(ctx context.Context, id string, name string) *utils.ReturnStatus
+	// Move chuyển folder (cả cây con) vào parent, nil = thư mục gốc.
+	Move(ctx context.Context, folder *domain.Folder, parent *domain.Folder) *utils.ReturnStatus
+	Delete(ctx context.Context, id string) *utils.ReturnStatus
+
+	// SetShares thay toàn bộ danh sách người nhận của thư mục; email không có tài khoản bị bỏ qua.
+	SetShares(ctx context.Context, folderID string, emails []string, groupIDs []string) *utils.ReturnStatus
+	ListShares(ctx context.Context, folderID string) (*domain.FolderShares, *utils.ReturnStatus)
+	// ListSharedWithUser: thư mục được chia sẻ trực tiếp cho userID (hoặc qua nhóm).
+	ListSharedWithUser(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus)
+	// SharedWithUser báo thư mục hoặc một thư mục cha của nó được chia sẻ cho userID.
+	SharedWithUser(ctx context.Context, folderID string, userID string) (bool, *utils.ReturnStatus)
 }
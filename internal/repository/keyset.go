@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/lib/pq"
+)
+
+// keysetKey là một khóa sắp xếp: biểu thức SQL và kiểu để ép giá trị lấy từ cursor.
+type keysetKey struct {
+	expr string
+	cast string
+}
+
+// keyset mô tả phân trang keyset theo một kiểu sắp xếp. Giá trị khóa được SELECT dưới dạng text
+// rồi ép lại đúng kiểu khi so sánh, nên cursor giữ nguyên độ chính xác của timestamp/real.
+type keyset struct {
+	keys []keysetKey
+	id   string // cột duy nhất, phân định các dòng trùng khóa
+}
+
+// fileKeysets: khóa sắp xếp của danh sách file (bảng files có alias f).
+var fileKeysets = map[string]keyset{
+	domain.SORT_CREATED_AT: {keys: []keysetKey{{"f.created_at", "timestamptz"}}, id: "f.id"},
+	domain.SORT_NAME:       {keys: []keysetKey{{"f.name", "text"}}, id: "f.id"},
+	domain.SORT_SIZE:       {keys: []keysetKey{{"f.size", "bigint"}}, id: "f.id"},
+	domain.SORT_EXPIRY:     {keys: []keysetKey{{"f.available_to", "timestamptz"}}, id: "f.id"},
+}
+
+// columns trả về các cột "key::text" cần SELECT thêm để dựng cursor cho dòng cuối trang.
+func (k keyset) columns() string {
+	cols := []string{}
+	for _, key := range k.keys {
+		cols = append(cols, key.expr+"::text")
+	}
+	return strings.Join(cols, ", ")
+}
+
+// after trả về điều kiện "nằm sau cursor" (rỗng ở trang đầu). Mọi khóa cùng chiều sắp xếp
+// nên có thể so sánh theo bộ (row comparison) và dùng được index nhiều cột.
+func (k keyset) after(page domain.PageRequest, arg func(any) string) (string, *utils.ReturnStatus) {
+	if page.After == nil {
+		return "", nil
+	}
+	if len(page.After.Keys) != len(k.keys) {
+		return "", utils.Response(utils.ErrCodeCursorInvalid)
+	}
+
+	left, right := []string{}, []string{}
+	for i, key := range k.keys {
+		left = append(left, key.expr)
+		right = append(right, arg(page.After.Keys[i])+"::"+key.cast)
+	}
+	left = append(left, k.id)
+	right = append(right, arg(page.After.Id)+"::uuid")
+
+	op := "<"
+	if page.Order == domain.ORDER_ASC {
+		op = ">"
+	}
+	return "(" + strings.Join(left, ", ") + ") " + op + " (" + strings.Join(right, ", ") + ")", nil
+}
+
+// orderBy trả về ORDER BY ... LIMIT, lấy dư một dòng để biết còn trang sau hay không.
+func (k keyset) orderBy(page domain.PageRequest, arg func(any) string) string {
+	dir := " DESC"
+	if page.Order == domain.ORDER_ASC {
+		dir = " ASC"
+	}
+
+	cols := []string{}
+	for _, key := range k.keys {
+		cols = append(cols, key.expr+dir)
+	}
+	cols = append(cols, k.id+dir)
+
+	out := " ORDER BY " + strings.Join(cols, ", ")
+	if page.Limit > 0 {
+		out += " LIMIT " + arg(page.Limit+1)
+	}
+	return out
+}
+
+// keysetRow là id và giá trị khóa (text) của một dòng đã đọc.
+type keysetRow struct {
+	id   string
+	keys []string
+}
+
+// scan đọc một dòng dạng "SELECT id, <columns>, extra...".
+func (k keyset) scan(rows *sql.Rows, extra ...any) (keysetRow, error) {
+	row := keysetRow{keys: make([]string, len(k.keys))}
+	dest := []any{&row.id}
+	for i := range row.keys {
+		dest = append(dest, &row.keys[i])
+	}
+	return row, rows.Scan(append(dest, extra...)...)
+}
+
+// next cắt danh sách về đúng page.Limit dòng và trả về cursor của dòng cuối nếu còn trang sau.
+func (k keyset) next(page domain.PageRequest, rows []keysetRow) ([]keysetRow, *domain.Cursor) {
+	if page.Limit <= 0 || len(rows) <= page.Limit {
+		return rows, nil
+	}
+
+	rows = rows[:page.Limit]
+	last := rows[len(rows)-1]
+	return rows, &domain.Cursor{SortBy: page.SortBy, Order: page.Order, Keys: last.keys, Id: last.id}
+}
+
+// cursorError đổi lỗi ép kiểu giá trị cursor (class 22 của Postgres) thành ErrCodeCursorInvalid.
+func cursorError(page domain.PageRequest, err error) *utils.ReturnStatus {
+	var pqErr *pq.Error
+	if page.After != nil && errors.As(err, &pqErr) && pqErr.Code.Class() == "22" {
+		return utils.Response(utils.ErrCodeCursorInvalid)
+	}
+	return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+}
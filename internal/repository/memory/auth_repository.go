@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type authRepository struct {
+	store *Store
+}
+
+func NewAuthRepository(store *Store) repository.AuthRepository {
+	return &authRepository{store: store}
+}
+
+func (r *authRepository) Create(user *domain.User) (*domain.User, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	if field := r.store.data.conflict("", user.Username, user.Email); field != "" {
+		return nil, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": field})
+	}
+	if user.Id == "" {
+		user.Id = uuid.NewString()
+	}
+
+	stored := *user
+	stored.EnableWebAuthn = false
+	r.store.data.users[user.Id] = stored
+	return user, nil
+}
+
+func (r *authRepository) BlacklistToken(token string, expiredAt time.Time) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	r.store.data.blacklist[token] = expiredAt
+	return nil
+}
+
+func (r *authRepository) IsTokenBlacklisted(token string) (bool, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	_, ok := r.store.data.blacklist[token]
+	return ok, nil
+}
+
+func (r *authRepository) SaveSecret(userID string, secret string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	if user, ok := r.store.data.users[userID]; ok {
+		user.SecretTOTP = secret
+		r.store.data.users[userID] = user
+	}
+	return nil
+}
+
+func (r *authRepository) GetSecret(userID string) (string, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	user, ok := r.store.data.users[userID]
+	if !ok {
+		return "", utils.ResponseMsg(utils.ErrCodeDatabaseError, sql.ErrNoRows.Error())
+	}
+	return user.SecretTOTP, nil
+}
+
+func (r *authRepository) EnableTOTP(userID string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	if user, ok := r.store.data.users[userID]; ok {
+		user.EnableTOTP = true
+		r.store.data.users[userID] = user
+	}
+	return nil
+}
+
+func (r *authRepository) DisableTOTP(userID string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	if user, ok := r.store.data.users[userID]; ok {
+		user.EnableTOTP, user.SecretTOTP = false, ""
+		r.store.data.users[userID] = user
+	}
+	return nil
+}
+
+func (r *authRepository) RevokeUserTokens(ctx context.Context, userID string, revokedAt time.Time) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	if current, ok := r.store.data.revocations[userID]; !ok || revokedAt.After(current) {
+		r.store.data.revocations[userID] = revokedAt
+	}
+	return nil
+}
+
+func (r *authRepository) IsUserTokenRevoked(userID string, issuedAt time.Time) (bool, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	revokedAt, ok := r.store.data.revocations[userID]
+	return ok && !issuedAt.After(revokedAt), nil
+}
+
+func (r *authRepository) PurgeBlacklist(expiredBefore time.Time, revokedBefore time.Time) (int64, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	var purged int64
+	for token, expiredAt := range r.store.data.blacklist {
+		if expiredAt.Before(expiredBefore) {
+			delete(r.store.data.blacklist, token)
+			purged++
+		}
+	}
+	for userID, revokedAt := range r.store.data.revocations {
+		if revokedAt.Before(revokedBefore) {
+			delete(r.store.data.revocations, userID)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,537 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type fileRepository struct {
+	store *Store
+}
+
+func NewFileRepository(store *Store) repository.FileRepository {
+	return &fileRepository{store: store}
+}
+
+// file trả về bản sao của file kèm các giá trị mà backend SQL lấy bằng JOIN (owner, số lượt tải).
+func (d storeData) file(f domain.File) domain.File {
+	f.Owner = nil
+	if f.OwnerId != nil {
+		if u, ok := d.users[*f.OwnerId]; ok {
+			f.Owner = &domain.FileOwner{Id: u.Id, Username: u.Username, Email: u.Email, Role: u.Role}
+		}
+	}
+	f.DownloadCount = d.stats[f.Id].downloadCount
+	f.HasPassword = f.PasswordHash != nil
+	if f.Tags == nil {
+		f.Tags = []string{}
+	}
+	return f
+}
+
+func (d *storeData) deleteFile(id string) {
+	delete(d.files, id)
+	delete(d.stats, id)
+	delete(d.shared, id)
+	d.downloads = slices.DeleteFunc(d.downloads, func(dl download) bool { return dl.fileID == id })
+}
+
+// sharedWith báo fileID được chia sẻ trực tiếp cho userID.
+func (d storeData) sharedWith(fileID string, userID string) bool {
+	_, ok := d.shared[fileID][userID]
+	return ok
+}
+
+func (d storeData) tokenTaken(id string, token string) bool {
+	for _, f := range d.files {
+		if f.Id != id && f.ShareToken == token {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *fileRepository) CreateFile(ctx context.Context, file *domain.File) (*domain.File, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	if r.store.data.tokenTaken(file.Id, file.ShareToken) {
+		return nil, utils.Response(utils.ErrCodeShareTokenTaken)
+	}
+
+	file.Version = 1
+	stored := *file
+	stored.Tags = slices.Clone(file.Tags)
+	stored.Owner, stored.Shares, stored.SharedGroups = nil, nil, nil
+	r.store.data.files[file.Id] = stored
+	r.store.data.stats[file.Id] = fileStat{}
+
+	return file, nil
+}
+
+func (r *fileRepository) getFile(match func(domain.File) bool) (*domain.File, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	for _, f := range r.store.data.files {
+		if match(f) {
+			file := r.store.data.file(f)
+			return &file, nil
+		}
+	}
+	return nil, utils.Response(utils.ErrCodeFileNotFound)
+}
+
+func (r *fileRepository) GetFileByID(ctx context.Context, id string) (*domain.File, *utils.ReturnStatus) {
+	return r.getFile(func(f domain.File) bool { return f.Id == id })
+}
+
+func (r *fileRepository) GetFileByToken(ctx context.Context, token string) (*domain.File, *utils.ReturnStatus) {
+	return r.getFile(func(f domain.File) bool { return f.ShareToken == token })
+}
+
+func (r *fileRepository) DeleteFile(ctx context.Context, id string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	if _, ok := r.store.data.files[id]; !ok {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	r.store.data.deleteFile(id)
+	return nil
+}
+
+// fileSortKeys là các khóa sắp xếp của danh sách file, giống fileKeysets của backend SQL.
+var fileSortKeys = map[string]sortKey[domain.File]{
+	domain.SORT_CREATED_AT: timeKey(func(f domain.File) time.Time { return f.CreatedAt }),
+	domain.SORT_NAME:       textKey(func(f domain.File) string { return f.FileName }),
+	domain.SORT_SIZE:       intKey(func(f domain.File) int64 { return f.FileSize }),
+	domain.SORT_EXPIRY:     timeKey(func(f domain.File) time.Time { return f.AvailableTo }),
+}
+
+func fileID(f domain.File) string { return f.Id }
+
+// listFiles trả về một trang các file thỏa match.
+func (r *fileRepository) listFiles(page domain.PageRequest, match func(domain.File) bool) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	key, ok := fileSortKeys[page.SortBy]
+	if !ok {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
+	}
+
+	defer r.store.lock()()
+
+	files := []domain.File{}
+	for _, f := range r.store.data.files {
+		if match(f) {
+			files = append(files, r.store.data.file(f))
+		}
+	}
+
+	return paginate(files, page, []sortKey[domain.File]{key}, fileID)
+}
+
+func (r *fileRepository) GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	status := strings.ToLower(params.Status)
+	if !slices.Contains([]string{"all", "active", "pending", "expired"}, status) {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid file status.")
+	}
+
+	now := time.Now()
+	files, next, rerr := r.listFiles(params.Page, func(f domain.File) bool {
+		if f.OwnerId == nil || *f.OwnerId != userID {
+			return false
+		}
+		if status != "all" && string(f.StatusAt(now)) != status {
+			return false
+		}
+
+		switch params.FolderId {
+		case "":
+			return true
+		case "root":
+			return f.FolderId == nil
+		default:
+			return f.FolderId != nil && *f.FolderId == params.FolderId
+		}
+	})
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+
+	for i := range files {
+		files[i].Status = files[i].StatusAt(now)
+	}
+	return files, next, nil
+}
+
+func (r *fileRepository) GetFileSummary(ctx context.Context, userID string) (*domain.FileSummary, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	summary := &domain.FileSummary{}
+	now := time.Now()
+	for _, f := range r.store.data.files {
+		if f.OwnerId == nil || *f.OwnerId != userID {
+			continue
+		}
+		switch f.StatusAt(now) {
+		case domain.FILE_ACTIVE:
+			summary.ActiveFiles++
+		case domain.FILE_PENDING:
+			summary.PendingFiles++
+		case domain.FILE_EXPIRED:
+			summary.ExpiredFiles++
+		}
+	}
+
+	return summary, nil
+}
+
+func (r *fileRepository) FindAll(ctx context.Context) ([]domain.File, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	var files []domain.File
+	for _, f := range r.store.data.files {
+		files = append(files, r.store.data.file(f))
+	}
+	slices.SortFunc(files, func(a, b domain.File) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	return files, nil
+}
+
+func (r *fileRepository) RegisterDownload(ctx context.Context, fileID string, userID string) (*int64, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	d := &r.store.data
+	file, ok := d.files[fileID]
+	stat := d.stats[fileID]
+	if !ok || (file.MaxDownloads != nil && stat.downloadCount >= int64(*file.MaxDownloads)) {
+		return nil, utils.Response(utils.ErrCodeDownloadLimitReached)
+	}
+
+	var downloader *string
+	if userID != "" {
+		downloader = &userID
+	}
+	if !slices.ContainsFunc(d.downloads, func(dl download) bool {
+		return dl.fileID == fileID && downloader != nil && dl.userID != nil && *dl.userID == userID
+	}) {
+		stat.userDownloadCount++
+	}
+	stat.downloadCount++
+	d.stats[fileID] = stat
+	d.downloads = append(d.downloads, download{id: uuid.NewString(), fileID: fileID, userID: downloader, time: time.Now()})
+
+	if file.MaxDownloads == nil {
+		return nil, nil
+	}
+	remaining := int64(*file.MaxDownloads) - stat.downloadCount
+	return &remaining, nil
+}
+
+func (r *fileRepository) UpdateShareLink(ctx context.Context, file *domain.File) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	stored, ok := r.store.data.files[file.Id]
+	if !ok {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+	if r.store.data.tokenTaken(file.Id, file.ShareToken) {
+		return utils.Response(utils.ErrCodeShareTokenTaken)
+	}
+
+	stored.ShareToken, stored.MaxDownloads, stored.DeleteOnLimit = file.ShareToken, file.MaxDownloads, file.DeleteOnLimit
+	stored.Version++
+	r.store.data.files[file.Id] = stored
+	file.Version = stored.Version
+
+	return nil
+}
+
+var downloadSortKeys = []sortKey[download]{timeKey(func(d download) time.Time { return d.time })}
+
+func (r *fileRepository) GetFileDownloadHistory(ctx context.Context, fileID string, page domain.PageRequest) (*domain.FileDownloadHistory, *domain.Cursor, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	file, ok := r.store.data.files[fileID]
+	if !ok {
+		return nil, nil, utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	downloads := []download{}
+	for _, dl := range r.store.data.downloads {
+		if dl.fileID == fileID {
+			downloads = append(downloads, dl)
+		}
+	}
+	downloads, next, rerr := paginate(downloads, page, downloadSortKeys, func(d download) string { return d.id })
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+
+	history := domain.FileDownloadHistory{FileId: file.Id, FileName: file.FileName, History: []domain.Download{}}
+	for _, dl := range downloads {
+		entry := domain.Download{DownloadId: dl.id, UserId: dl.userID, DownloadedAt: dl.time, DownloadCompleted: true}
+		if dl.userID != nil {
+			if u, ok := r.store.data.users[*dl.userID]; ok {
+				entry.Downloader = &domain.Downloader{Username: u.Username, Email: u.Email}
+			}
+		}
+		history.History = append(history.History, entry)
+	}
+
+	return &history, next, nil
+}
+
+func (r *fileRepository) GetFileStats(ctx context.Context, fileID string) (*domain.FileStat, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	file, ok := r.store.data.files[fileID]
+	if !ok {
+		return nil, utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	stat := r.store.data.stats[fileID]
+	result := &domain.FileStat{
+		FileId:             file.Id,
+		FileName:           file.FileName,
+		TotalDownloadCount: int(stat.downloadCount),
+		UserDownloadCount:  stat.userDownloadCount,
+		CreatedAt:          file.CreatedAt,
+	}
+	for _, dl := range r.store.data.downloads {
+		if dl.fileID == fileID && dl.time.After(result.LastDownloadedAt) {
+			result.LastDownloadedAt = dl.time
+		}
+	}
+
+	return result, nil
+}
+
+func (r *fileRepository) GetAccessibleFiles(ctx context.Context, userID string, page domain.PageRequest) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	now := time.Now()
+	return r.listFiles(page, func(f domain.File) bool {
+		return f.StatusAt(now) == domain.FILE_ACTIVE && r.store.data.sharedWith(f.Id, userID)
+	})
+}
+
+func (r *fileRepository) ListFileIDsByOwner(ctx context.Context, userID string) ([]string, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	ids := []string{}
+	for _, f := range r.store.data.files {
+		if f.OwnerId != nil && *f.OwnerId == userID {
+			ids = append(ids, f.Id)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fileRepository) TransferOwnership(ctx context.Context, fromUserID string, toUserID string) (int64, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	var moved int64
+	for id, f := range r.store.data.files {
+		if f.OwnerId != nil && *f.OwnerId == fromUserID {
+			owner := toUserID
+			f.OwnerId = &owner
+			r.store.data.files[id] = f
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (r *fileRepository) GetUserDownloads(ctx context.Context, userID string) ([]domain.UserDownload, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	downloads := []domain.UserDownload{}
+	for _, dl := range r.store.data.downloads {
+		if dl.userID == nil || *dl.userID != userID {
+			continue
+		}
+		if f, ok := r.store.data.files[dl.fileID]; ok {
+			downloads = append(downloads, domain.UserDownload{FileId: f.Id, FileName: f.FileName, DownloadedAt: dl.time})
+		}
+	}
+	slices.SortStableFunc(downloads, func(a, b domain.UserDownload) int { return b.DownloadedAt.Compare(a.DownloadedAt) })
+
+	return downloads, nil
+}
+
+func (r *fileRepository) ListByFolder(ctx context.Context, folderID string) ([]domain.File, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	files := []domain.File{}
+	for _, f := range r.store.data.files {
+		if f.FolderId != nil && *f.FolderId == folderID {
+			files = append(files, r.store.data.file(f))
+		}
+	}
+	slices.SortFunc(files, func(a, b domain.File) int { return strings.Compare(a.FileName, b.FileName) })
+
+	return files, nil
+}
+
+func (r *fileRepository) update(fileID string, fn func(f *domain.File)) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	f, ok := r.store.data.files[fileID]
+	if !ok {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	fn(&f)
+	r.store.data.files[fileID] = f
+	return nil
+}
+
+func (r *fileRepository) MoveToFolder(ctx context.Context, fileID string, folderID *string) *utils.ReturnStatus {
+	return r.update(fileID, func(f *domain.File) { f.FolderId = folderID })
+}
+
+func (r *fileRepository) UpdateMetadata(ctx context.Context, fileID string, description *string, tags []string) *utils.ReturnStatus {
+	return r.update(fileID, func(f *domain.File) { f.Description, f.Tags = description, slices.Clone(tags) })
+}
+
+// searchTerms tách tsquery do service dựng ("bao:* & cao:*") thành các tiền tố cần khớp.
+func searchTerms(tsquery string) []string {
+	terms := []string{}
+	for _, term := range strings.Split(tsquery, "&") {
+		if term = strings.TrimSuffix(strings.TrimSpace(term), ":*"); term != "" {
+			terms = append(terms, strings.ToLower(term))
+		}
+	}
+	return terms
+}
+
+// searchRank tính điểm đơn giản thay cho ts_rank_cd: mỗi từ khớp tiền tố trong tên, tag, mô tả
+// được cộng trọng số 1.0, 0.4, 0.2. Trả về false nếu có từ không khớp ở đâu cả.
+func searchRank(f domain.File, terms []string) (float64, bool) {
+	words := func(s string) []string {
+		return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	}
+	description := ""
+	if f.Description != nil {
+		description = *f.Description
+	}
+	fields := []struct {
+		words  []string
+		weight float64
+	}{
+		{words(f.FileName), 1.0},
+		{words(strings.Join(f.Tags, " ")), 0.4},
+		{words(description), 0.2},
+	}
+
+	rank := 0.0
+	for _, term := range terms {
+		matched := false
+		for _, field := range fields {
+			for _, w := range field.words {
+				if strings.HasPrefix(w, term) {
+					rank += field.weight
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return 0, false
+		}
+	}
+	return rank, true
+}
+
+func (r *fileRepository) SearchFiles(ctx context.Context, userID string, params domain.FileSearchParams) ([]domain.FileSearchHit, *domain.Cursor, *utils.ReturnStatus) {
+	keys := []sortKey[domain.FileSearchHit]{}
+	if params.Page.SortBy == domain.SORT_RELEVANCE {
+		keys = append(keys,
+			realKey(func(h domain.FileSearchHit) float64 { return h.Rank }),
+			timeKey(func(h domain.FileSearchHit) time.Time { return h.File.CreatedAt }))
+	} else if key, ok := fileSortKeys[params.Page.SortBy]; ok {
+		keys = append(keys, sortKey[domain.FileSearchHit]{
+			value: func(h domain.FileSearchHit) any { return key.value(h.File) },
+			parse: key.parse,
+		})
+	} else {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
+	}
+
+	defer r.store.lock()()
+
+	terms := searchTerms(params.Query)
+	now := time.Now()
+	hits := []domain.FileSearchHit{}
+	for _, stored := range r.store.data.files {
+		f := r.store.data.file(stored)
+
+		mine := f.OwnerId != nil && *f.OwnerId == userID
+		shared := !mine && f.StatusAt(now) == domain.FILE_ACTIVE && r.store.data.sharedWith(f.Id, userID)
+		switch params.Scope {
+		case "mine":
+			if !mine {
+				continue
+			}
+		case "shared":
+			if !shared {
+				continue
+			}
+		default:
+			if !mine && !shared {
+				continue
+			}
+		}
+
+		if !matchesFilters(f, params) {
+			continue
+		}
+
+		rank := 0.0
+		if len(terms) > 0 {
+			var ok bool
+			if rank, ok = searchRank(f, terms); !ok {
+				continue
+			}
+		}
+		hits = append(hits, domain.FileSearchHit{File: f, Rank: rank})
+	}
+
+	return paginate(hits, params.Page, keys, func(h domain.FileSearchHit) string { return h.File.Id })
+}
+
+// matchesFilters kiểm tra các bộ lọc không phải từ khóa của FileSearchParams.
+func matchesFilters(f domain.File, params domain.FileSearchParams) bool {
+	for _, tag := range params.Tags {
+		if !slices.Contains(f.Tags, tag) {
+			return false
+		}
+	}
+	if prefix, ok := strings.CutSuffix(params.MimeType, "/*"); ok {
+		if !strings.HasPrefix(f.MimeType, prefix+"/") {
+			return false
+		}
+	} else if params.MimeType != "" && f.MimeType != params.MimeType {
+		return false
+	}
+	if params.MinSize != nil && f.FileSize < *params.MinSize {
+		return false
+	}
+	if params.MaxSize != nil && f.FileSize > *params.MaxSize {
+		return false
+	}
+	if params.From != nil && f.CreatedAt.Before(*params.From) {
+		return false
+	}
+	if params.To != nil && !f.CreatedAt.Before(*params.To) {
+		return false
+	}
+	if params.Owner != "" && (f.Owner == nil || f.Owner.Email != params.Owner) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type sharedRepository struct {
+	store *Store
+}
+
+func NewSharedRepository(store *Store) repository.SharedRepository {
+	return &sharedRepository{store: store}
+}
+
+func (r *sharedRepository) ShareFileWithUsers(ctx context.Context, fileID string, emails []string) (*domain.ShareResult, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	result := &domain.ShareResult{Resolved: []string{}, Unresolved: []string{}}
+	d := &r.store.data
+	for _, email := range emails {
+		if slices.Contains(result.Resolved, email) || slices.Contains(result.Unresolved, email) {
+			continue
+		}
+
+		userID := ""
+		for _, u := range d.users {
+			if u.Email == email {
+				userID = u.Id
+				break
+			}
+		}
+		if userID == "" {
+			result.Unresolved = append(result.Unresolved, email)
+			continue
+		}
+
+		result.Resolved = append(result.Resolved, email)
+		if d.shared[fileID] == nil {
+			d.shared[fileID] = map[string]time.Time{}
+		}
+		if _, ok := d.shared[fileID][userID]; !ok {
+			d.shared[fileID][userID] = time.Now()
+		}
+	}
+
+	return result, nil
+}
+
+func (r *sharedRepository) GetUsersSharedWith(ctx context.Context, fileID string) (*domain.Shared, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	users := []domain.User{}
+	for userID := range r.store.data.shared[fileID] {
+		if u, ok := r.store.data.users[userID]; ok {
+			users = append(users, u)
+		}
+	}
+	slices.SortFunc(users, func(a, b domain.User) int { return strings.Compare(a.Email, b.Email) })
+
+	share := &domain.Shared{FileId: fileID, UserIds: []string{}, Emails: []string{}}
+	for _, u := range users {
+		share.UserIds = append(share.UserIds, u.Id)
+		share.Emails = append(share.Emails, u.Email)
+	}
+	return share, nil
+}
+
+func (r *sharedRepository) GetFilesSharedWithUser(ctx context.Context, userID string) ([]domain.SharedFile, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	files := []domain.SharedFile{}
+	for _, stored := range r.store.data.files {
+		if !r.store.data.sharedWith(stored.Id, userID) {
+			continue
+		}
+
+		f := r.store.data.file(stored)
+		shared := domain.SharedFile{FileId: f.Id, FileName: f.FileName, CreatedAt: f.CreatedAt}
+		if f.Owner != nil {
+			shared.OwnerEmail = &f.Owner.Email
+		}
+		files = append(files, shared)
+	}
+	slices.SortFunc(files, func(a, b domain.SharedFile) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	return files, nil
+}
+
+// ShareFileWithGroups bỏ qua mọi group id vì store không có nhóm, giống backend SQL bỏ qua id
+// không tồn tại.
+func (r *sharedRepository) ShareFileWithGroups(ctx context.Context, fileID string, groupIDs []string) *utils.ReturnStatus {
+	return nil
+}
+
+func (r *sharedRepository) GetGroupsSharedWith(ctx context.Context, fileID string) ([]string, *utils.ReturnStatus) {
+	return []string{}, nil
+}
+
+func (r *sharedRepository) SharedViaGroup(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus) {
+	return false, nil
+}
+
+func (r *sharedRepository) SharedViaFolder(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus) {
+	return false, nil
+}
@@ -0,0 +1,237 @@
+// Package memory cài đặt UserRepository, AuthRepository, FileRepository và SharedRepository trên
+// map trong RAM, dùng cho unit test nhanh không cần database. Store không có nhóm và thư mục:
+// chia sẻ qua nhóm/thư mục luôn coi như không tồn tại.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+// Store là dữ liệu dùng chung của các repository trong package. Giá trị trong map không bao giờ
+// bị sửa tại chỗ (luôn ghi đè cả struct) nên snapshot chỉ cần copy map.
+type Store struct {
+	mu   sync.Mutex
+	data storeData
+}
+
+type storeData struct {
+	users       map[string]domain.User
+	sessions    map[string]string // user id → cid
+	blacklist   map[string]time.Time
+	revocations map[string]time.Time
+	files       map[string]domain.File
+	stats       map[string]fileStat
+	downloads   []download
+	shared      map[string]map[string]time.Time // file id → user id → thời điểm chia sẻ
+}
+
+type fileStat struct {
+	downloadCount     int64
+	userDownloadCount int
+}
+
+type download struct {
+	id     string
+	fileID string
+	userID *string
+	time   time.Time
+}
+
+func NewStore() *Store {
+	return &Store{data: storeData{
+		users:       map[string]domain.User{},
+		sessions:    map[string]string{},
+		blacklist:   map[string]time.Time{},
+		revocations: map[string]time.Time{},
+		files:       map[string]domain.File{},
+		stats:       map[string]fileStat{},
+		shared:      map[string]map[string]time.Time{},
+	}}
+}
+
+func (d storeData) clone() storeData {
+	shared := make(map[string]map[string]time.Time, len(d.shared))
+	for fileID, users := range d.shared {
+		shared[fileID] = maps.Clone(users)
+	}
+
+	return storeData{
+		users:       maps.Clone(d.users),
+		sessions:    maps.Clone(d.sessions),
+		blacklist:   maps.Clone(d.blacklist),
+		revocations: maps.Clone(d.revocations),
+		files:       maps.Clone(d.files),
+		stats:       maps.Clone(d.stats),
+		downloads:   slices.Clone(d.downloads),
+		shared:      shared,
+	}
+}
+
+func (s *Store) lock() func() {
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+type txKey struct{}
+
+type txManager struct {
+	store *Store
+}
+
+// NewTxManager trả về TxManager của store: rollback khôi phục snapshot chụp lúc mở transaction.
+// Không cô lập với các lệnh chạy song song ngoài transaction, chỉ hợp với test tuần tự.
+func NewTxManager(store *Store) repository.TxManager {
+	return &txManager{store: store}
+}
+
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) *utils.ReturnStatus) *utils.ReturnStatus {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	unlock := m.store.lock()
+	snapshot := m.store.data.clone()
+	unlock()
+
+	rollback := func() {
+		defer m.store.lock()()
+		m.store.data = snapshot
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if status := fn(context.WithValue(ctx, txKey{}, true)); status.IsErr() {
+		rollback()
+		return status
+	}
+	return nil
+}
+
+// sortKey là một khóa sắp xếp của phân trang keyset trong RAM, giá trị là time.Time, string,
+// int64 hoặc float64 và được ghi vào cursor dưới dạng text.
+type sortKey[T any] struct {
+	value func(T) any
+	parse func(string) (any, error)
+}
+
+func timeKey[T any](value func(T) time.Time) sortKey[T] {
+	return sortKey[T]{
+		value: func(row T) any { return value(row) },
+		parse: func(s string) (any, error) { return time.Parse(time.RFC3339Nano, s) },
+	}
+}
+
+func textKey[T any](value func(T) string) sortKey[T] {
+	return sortKey[T]{
+		value: func(row T) any { return value(row) },
+		parse: func(s string) (any, error) { return s, nil },
+	}
+}
+
+func intKey[T any](value func(T) int64) sortKey[T] {
+	return sortKey[T]{
+		value: func(row T) any { return value(row) },
+		parse: func(s string) (any, error) { return strconv.ParseInt(s, 10, 64) },
+	}
+}
+
+func realKey[T any](value func(T) float64) sortKey[T] {
+	return sortKey[T]{
+		value: func(row T) any { return value(row) },
+		parse: func(s string) (any, error) { return strconv.ParseFloat(s, 64) },
+	}
+}
+
+func formatKey(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return v.(string)
+}
+
+func compareKey(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	}
+	return strings.Compare(a.(string), b.(string))
+}
+
+// paginate sắp xếp rows theo keys rồi id, bỏ các dòng không nằm sau page.After và cắt còn
+// page.Limit dòng, giống keyset của các backend SQL.
+func paginate[T any](rows []T, page domain.PageRequest, keys []sortKey[T], id func(T) string) ([]T, *domain.Cursor, *utils.ReturnStatus) {
+	values := func(row T) []any {
+		out := make([]any, 0, len(keys)+1)
+		for _, key := range keys {
+			out = append(out, key.value(row))
+		}
+		return append(out, id(row))
+	}
+	compare := func(a, b []any) int {
+		for i := range a {
+			if c := compareKey(a[i], b[i]); c != 0 {
+				if page.Order == domain.ORDER_ASC {
+					return c
+				}
+				return -c
+			}
+		}
+		return 0
+	}
+
+	slices.SortStableFunc(rows, func(a, b T) int { return compare(values(a), values(b)) })
+
+	if page.After != nil {
+		if len(page.After.Keys) != len(keys) {
+			return nil, nil, utils.Response(utils.ErrCodeCursorInvalid)
+		}
+		cursor := make([]any, 0, len(keys)+1)
+		for i, key := range keys {
+			v, err := key.parse(page.After.Keys[i])
+			if err != nil {
+				return nil, nil, utils.Response(utils.ErrCodeCursorInvalid)
+			}
+			cursor = append(cursor, v)
+		}
+		cursor = append(cursor, page.After.Id)
+
+		rows = slices.DeleteFunc(rows, func(row T) bool { return compare(values(row), cursor) <= 0 })
+	}
+
+	if page.Limit <= 0 || len(rows) <= page.Limit {
+		return rows, nil, nil
+	}
+
+	rows = rows[:page.Limit]
+	last := values(rows[len(rows)-1])
+	next := &domain.Cursor{SortBy: page.SortBy, Order: page.Order, Id: id(rows[len(rows)-1])}
+	for _, v := range last[:len(keys)] {
+		next.Keys = append(next.Keys, formatKey(v))
+	}
+	return rows, next, nil
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{store: store}
+}
+
+// conflict trả về trường (email/username) đã bị user khác dùng, giống các ràng buộc UNIQUE của bảng users.
+func (d storeData) conflict(id string, username string, email string) string {
+	for _, u := range d.users {
+		if u.Id == id {
+			continue
+		}
+		if email != "" && u.Email == email {
+			return "email"
+		}
+		if username != "" && strings.EqualFold(u.Username, username) {
+			return "username"
+		}
+	}
+	return ""
+}
+
+func (r *userRepository) FindById(id string, user *domain.User) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	found, ok := r.store.data.users[id]
+	if !ok {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+
+	*user = found
+	return nil
+}
+
+func (r *userRepository) FindByEmail(email string, user *domain.User) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	for _, u := range r.store.data.users {
+		if u.Email == email {
+			*user = u
+			return nil
+		}
+	}
+	return utils.Response(utils.ErrCodeUserNotFound)
+}
+
+func (r *userRepository) FindByCId(cid string, user *domain.UsersLoginSession) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	for id, c := range r.store.data.sessions {
+		if c == cid {
+			user.Id, user.Cid = id, c
+			return nil
+		}
+	}
+	return utils.ResponseMsg(utils.ErrCodeInternal, sql.ErrNoRows.Error())
+}
+
+func (r *userRepository) AddTimestamp(id string, cid string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	r.store.data.sessions[id] = cid
+	return nil
+}
+
+func (r *userRepository) DeleteTimestamp(id string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	delete(r.store.data.sessions, id)
+	return nil
+}
+
+func (r *userRepository) UsernameExists(username string) (bool, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	return r.store.data.conflict("", username, "") != "", nil
+}
+
+func (r *userRepository) EmailExists(email string) (bool, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	return r.store.data.conflict("", "", email) != "", nil
+}
+
+func (r *userRepository) UpdateUsername(ctx context.Context, id string, username string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	if r.store.data.conflict(id, username, "") != "" {
+		return utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "username"})
+	}
+	if user, ok := r.store.data.users[id]; ok {
+		user.Username = username
+		r.store.data.users[id] = user
+	}
+	return nil
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, id string, email string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	if r.store.data.conflict(id, "", email) != "" {
+		return utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+	}
+	if user, ok := r.store.data.users[id]; ok {
+		user.Email, user.EmailVerified = email, true
+		r.store.data.users[id] = user
+	}
+	return nil
+}
+
+func (r *userRepository) ListAdmins() ([]domain.User, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	admins := []domain.User{}
+	for _, u := range r.store.data.users {
+		if u.Role == "admin" {
+			admins = append(admins, u)
+		}
+	}
+	slices.SortFunc(admins, func(a, b domain.User) int { return strings.Compare(a.Username, b.Username) })
+
+	return admins, nil
+}
+
+func (r *userRepository) List() ([]domain.User, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	users := []domain.User{}
+	for _, u := range r.store.data.users {
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b domain.User) int { return strings.Compare(a.Email, b.Email) })
+
+	return users, nil
+}
+
+func (r *userRepository) SetSuspended(id string, suspended bool) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	user, ok := r.store.data.users[id]
+	if !ok {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+	user.Suspended = suspended
+	r.store.data.users[id] = user
+	return nil
+}
+
+// Delete xóa user cùng file, lượt tải và chia sẻ của user như ON DELETE CASCADE.
+func (r *userRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	defer r.store.lock()()
+
+	d := &r.store.data
+	if _, ok := d.users[id]; !ok {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+
+	delete(d.users, id)
+	delete(d.sessions, id)
+	for fileID, f := range d.files {
+		if f.OwnerId != nil && *f.OwnerId == id {
+			d.deleteFile(fileID)
+		}
+	}
+	d.downloads = slices.DeleteFunc(d.downloads, func(dl download) bool { return dl.userID != nil && *dl.userID == id })
+	for _, users := range d.shared {
+		delete(users, id)
+	}
+	return nil
+}
+
+func (r *userRepository) Search(ctx context.Context, params domain.UserSearchParams) ([]domain.PublicProfile, *utils.ReturnStatus) {
+	defer r.store.lock()()
+
+	matches := []domain.User{}
+	for _, u := range r.store.data.users {
+		if u.Id == params.ExcludeId || !u.EmailVerified {
+			continue
+		}
+
+		email := strings.ToLower(u.Email)
+		if params.ExactEmail {
+			if email != params.Query {
+				continue
+			}
+		} else if !strings.HasPrefix(strings.ToLower(u.Username), params.Query) && !strings.HasPrefix(email, params.Query) {
+			continue
+		}
+
+		if _, emailDomain, _ := strings.Cut(email, "@"); params.Domain != "" && emailDomain != params.Domain {
+			continue
+		}
+		matches = append(matches, u)
+	}
+
+	slices.SortFunc(matches, func(a, b domain.User) int {
+		if c := strings.Compare(strings.ToLower(a.Username), strings.ToLower(b.Username)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Email, b.Email)
+	})
+	if len(matches) > params.Limit {
+		matches = matches[:params.Limit]
+	}
+
+	profiles := []domain.PublicProfile{}
+	for _, u := range matches {
+		profiles = append(profiles, domain.PublicProfile{Username: u.Username, Email: u.Email})
+	}
+	return profiles, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type oidcRepository struct {
+	db *sql.DB
+}
+
+func NewOIDCRepository(db *sql.DB) OIDCRepository {
+	return &oidcRepository{db: db}
+}
+
+func (r *oidcRepository) SaveState(ctx context.Context, state *domain.OIDCState) *utils.ReturnStatus {
+	// Dọn state bỏ dở luôn tại đây, bảng chỉ chứa vài phút dữ liệu nên không cần job riêng.
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_states WHERE expires_at < NOW()`); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO oidc_states (state, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, state.State, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// ConsumeState lấy và xóa state trong cùng một câu lệnh, một state chỉ dùng được một lần.
+func (r *oidcRepository) ConsumeState(ctx context.Context, state string, provider string) (*domain.OIDCState, *utils.ReturnStatus) {
+	result := &domain.OIDCState{}
+	err := r.db.QueryRowContext(ctx, `
+		DELETE FROM oidc_states
+		WHERE state = $1 AND provider = $2
+		RETURNING state, provider, nonce, code_verifier, expires_at
+	`, state, provider).Scan(&result.State, &result.Provider, &result.Nonce, &result.CodeVerifier, &result.ExpiresAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, utils.Response(utils.ErrCodeOIDCStateInvalid)
+	}
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return result, nil
+}
+
+func (r *oidcRepository) FindIdentity(ctx context.Context, provider string, subject string) (*domain.UserIdentity, *utils.ReturnStatus) {
+	identity := &domain.UserIdentity{}
+	var email sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT provider, subject, user_id, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`, provider, subject).Scan(&identity.Provider, &identity.Subject, &identity.UserId, &email, &identity.CreatedAt, &identity.LastLoginAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	identity.Email = email.String
+
+	return identity, nil
+}
+
+func (r *oidcRepository) LinkIdentity(ctx context.Context, identity *domain.UserIdentity) *utils.ReturnStatus {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, last_login_at
+	`, identity.Provider, identity.Subject, identity.UserId, identity.Email).Scan(&identity.CreatedAt, &identity.LastLoginAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *oidcRepository) TouchIdentity(ctx context.Context, provider string, subject string, email string) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE user_identities
+		SET last_login_at = NOW(), email = $3
+		WHERE provider = $1 AND subject = $2
+	`, provider, subject, email)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *oidcRepository) UpdateUserRole(ctx context.Context, userID string, role string) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET role = $2 WHERE id = $1`, userID, role)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// isUniqueViolation báo lỗi vi phạm ràng buộc UNIQUE (mã 23505) của Postgres.
+// constraint rỗng nghĩa là chấp nhận mọi ràng buộc.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return false
+	}
+
+	return constraint == "" || pqErr.Constraint == constraint
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type registrationRepository struct {
+	db *sql.DB
+}
+
+func NewRegistrationRepository(db *sql.DB) RegistrationRepository {
+	return &registrationRepository{db: db}
+}
+
+func (r *registrationRepository) ListDomainRules(ctx context.Context) ([]domain.EmailDomainRule, *utils.ReturnStatus) {
+	rows, err := r.db.QueryContext(ctx, `SELECT domain, rule, created_at FROM email_domain_rules ORDER BY rule, domain`)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	rules := []domain.EmailDomainRule{}
+	for rows.Next() {
+		var rule domain.EmailDomainRule
+		if err := rows.Scan(&rule.Domain, &rule.Rule, &rule.CreatedAt); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+// FindDomainRule trả về nil, nil khi domain không có trong danh sách.
+func (r *registrationRepository) FindDomainRule(ctx context.Context, emailDomain string) (*domain.EmailDomainRule, *utils.ReturnStatus) {
+	rule := &domain.EmailDomainRule{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT domain, rule, created_at FROM email_domain_rules WHERE domain = $1
+	`, emailDomain).Scan(&rule.Domain, &rule.Rule, &rule.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return rule, nil
+}
+
+func (r *registrationRepository) SaveDomainRule(ctx context.Context, rule *domain.EmailDomainRule) *utils.ReturnStatus {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO email_domain_rules (domain, rule)
+		VALUES ($1, $2)
+		ON CONFLICT (domain) DO UPDATE SET rule = EXCLUDED.rule
+		RETURNING created_at
+	`, rule.Domain, rule.Rule).Scan(&rule.CreatedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *registrationRepository) DeleteDomainRule(ctx context.Context, emailDomain string) *utils.ReturnStatus {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM email_domain_rules WHERE domain = $1`, emailDomain)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeEmailDomainRuleNotFound)
+	}
+
+	return nil
+}
+
+const inviteColumns = `id, token_hash, COALESCE(email, ''), COALESCE(created_by::text, ''), expires_at, used_at, COALESCE(used_by::text, ''), created_at`
+
+func scanInvite(row interface{ Scan(...any) error }, invite *domain.Invite) error {
+	var usedAt sql.NullTime
+	err := row.Scan(
+		&invite.Id,
+		&invite.TokenHash,
+		&invite.Email,
+		&invite.CreatedBy,
+		&invite.ExpiresAt,
+		&usedAt,
+		&invite.UsedBy,
+		&invite.CreatedAt,
+	)
+	if usedAt.Valid {
+		invite.UsedAt = &usedAt.Time
+	}
+	return err
+}
+
+func (r *registrationRepository) CreateInvite(ctx context.Context, invite *domain.Invite) *utils.ReturnStatus {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO invites (token_hash, email, created_by, expires_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, '')::uuid, $4)
+		RETURNING id, created_at
+	`, invite.TokenHash, invite.Email, invite.CreatedBy, invite.ExpiresAt).Scan(&invite.Id, &invite.CreatedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *registrationRepository) ListInvites(ctx context.Context) ([]domain.Invite, *utils.ReturnStatus) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+inviteColumns+` FROM invites ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	invites := []domain.Invite{}
+	for rows.Next() {
+		var invite domain.Invite
+		if err := scanInvite(rows, &invite); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *registrationRepository) DeleteInvite(ctx context.Context, id string) *utils.ReturnStatus {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM invites WHERE id = $1`, id)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeInviteNotFound)
+	}
+
+	return nil
+}
+
+// ClaimInvite đánh dấu invite đã dùng trong cùng câu lệnh kiểm tra, hai lượt đăng ký
+// cùng lúc không thể dùng chung một mã.
+func (r *registrationRepository) ClaimInvite(ctx context.Context, tokenHash string) (*domain.Invite, *utils.ReturnStatus) {
+	invite := &domain.Invite{}
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE invites SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING `+inviteColumns, tokenHash)
+
+	if err := scanInvite(row, invite); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeInviteInvalid)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return invite, nil
+}
+
+// ReleaseInvite trả lại invite khi tạo tài khoản thất bại sau ClaimInvite.
+func (r *registrationRepository) ReleaseInvite(ctx context.Context, id string) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `UPDATE invites SET used_at = NULL WHERE id = $1 AND used_by IS NULL`, id)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *registrationRepository) CompleteInvite(ctx context.Context, id string, userID string) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `UPDATE invites SET used_by = $2 WHERE id = $1`, id, userID)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// SaveVerification thay mọi link xác minh cũ của user bằng link mới. email rỗng = xác minh
+// email hiện tại, ngược lại email của user được đổi thành email này khi mở link.
+func (r *registrationRepository) SaveVerification(ctx context.Context, userID string, email string, tokenHash string, expiresAt time.Time) *utils.ReturnStatus {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM email_verifications WHERE user_id = $1`, userID); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO email_verifications (token_hash, user_id, email, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+	`, tokenHash, userID, email, expiresAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// ConsumeVerification xóa token, đổi sang email mới (nếu có) và đánh dấu email đã xác minh
+// trong một câu lệnh. Trả về false nếu token không tồn tại hoặc đã hết hạn.
+func (r *registrationRepository) ConsumeVerification(ctx context.Context, tokenHash string) (bool, *utils.ReturnStatus) {
+	result, err := r.db.ExecContext(ctx, `
+		WITH token AS (
+			DELETE FROM email_verifications
+			WHERE token_hash = $1
+			RETURNING user_id, email, expires_at
+		)
+		UPDATE users SET email = COALESCE(token.email, users.email), email_verified = TRUE
+		FROM token
+		WHERE users.id = token.user_id AND token.expires_at > NOW()
+	`, tokenHash)
+	if err != nil {
+		// Email mới đã được tài khoản khác dùng trong lúc chờ xác minh
+		if isUniqueViolation(err, "users_email_key") {
+			return false, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+		}
+		return false, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
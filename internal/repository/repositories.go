@@ -0,0 +1,42 @@
+package repository
+
+import "database/sql"
+
+// Repositories gom mọi repository của một backend lưu trữ, app chọn backend theo DATABASE_URL
+// rồi truyền bộ này cho các module.
+type Repositories struct {
+	User         UserRepository
+	Auth         AuthRepository
+	File         FileRepository
+	Shared       SharedRepository
+	Group        GroupRepository
+	Folder       FolderRepository
+	Attempt      AttemptRepository
+	APIToken     APITokenRepository
+	Registration RegistrationRepository
+	Export       ExportRepository
+	LoginHistory LoginHistoryRepository
+	OIDC         OIDCRepository
+	WebAuthn     WebAuthnRepository
+	Tx           TxManager
+}
+
+// NewPostgresRepositories dựng các repository chạy trên Postgres.
+func NewPostgresRepositories(db *sql.DB) *Repositories {
+	return &Repositories{
+		User:         NewSQLUserRepository(db),
+		Auth:         NewAuthRepository(db),
+		File:         NewFileRepository(db),
+		Shared:       NewSharedRepository(db),
+		Group:        NewGroupRepository(db),
+		Folder:       NewFolderRepository(db),
+		Attempt:      NewAttemptRepository(db),
+		APIToken:     NewAPITokenRepository(db),
+		Registration: NewRegistrationRepository(db),
+		Export:       NewExportRepository(db),
+		LoginHistory: NewLoginHistoryRepository(db),
+		OIDC:         NewOIDCRepository(db),
+		WebAuthn:     NewWebAuthnRepository(db),
+		Tx:           NewTxManager(db),
+	}
+}
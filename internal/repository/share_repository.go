@@ -3,17 +3,24 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"log"
-	"strings"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/lib/pq"
 )
 
 type SharedRepository interface {
-	ShareFileWithUsers(ctx context.Context, fileID string, emails []string) *utils.ReturnStatus
+	// ShareFileWithUsers trả về các email đã/không khớp tài khoản nào.
+	ShareFileWithUsers(ctx context.Context, fileID string, emails []string) (*domain.ShareResult, *utils.ReturnStatus)
 	GetUsersSharedWith(ctx context.Context, fileID string) (*domain.Shared, *utils.ReturnStatus)
+	GetFilesSharedWithUser(ctx context.Context, userID string) ([]domain.SharedFile, *utils.ReturnStatus)
+	ShareFileWithGroups(ctx context.Context, fileID string, groupIDs []string) *utils.ReturnStatus
+	GetGroupsSharedWith(ctx context.Context, fileID string) ([]string, *utils.ReturnStatus)
+	// SharedViaGroup báo userID có thuộc một nhóm được chia sẻ fileID hay không.
+	SharedViaGroup(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus)
+	// SharedViaFolder báo fileID nằm trong một thư mục được chia sẻ cho userID.
+	SharedViaFolder(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus)
 }
 
 type sharedRepository struct {
@@ -24,81 +31,195 @@ func NewSharedRepository(db *sql.DB) SharedRepository {
 	return &sharedRepository{db: db}
 }
 
-func (r *sharedRepository) ShareFileWithUsers(ctx context.Context, fileID string, emails []string) *utils.ReturnStatus {
+// ShareFileWithUsers chia sẻ fileID cho các tài khoản có email trong emails. Email không khớp
+// tài khoản nào không làm lỗi cả lô mà được trả về trong Unresolved.
+func (r *sharedRepository) ShareFileWithUsers(ctx context.Context, fileID string, emails []string) (*domain.ShareResult, *utils.ReturnStatus) {
+	result := &domain.ShareResult{Resolved: []string{}, Unresolved: []string{}}
 	if len(emails) == 0 {
-		return nil
-	}
-
-	var emailstrings []string
-	for _, e := range emails {
-		emailstrings = append(emailstrings, fmt.Sprintf("'%s'", e))
+		return result, nil
 	}
 
-	userIDQuery := fmt.Sprintf(`SELECT id FROM users WHERE email IN (%s);`, strings.Join(emailstrings, ", "))
-
-	userIDsRaw, err := r.db.QueryContext(ctx, userIDQuery)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT id, email FROM users WHERE email = ANY($1::text[])`, pq.Array(emails))
 	if err != nil {
-		log.Println("Email retrieval failure")
-		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
+	defer rows.Close()
 
-	var queryValues []string
-	for userIDsRaw.Next() {
-		var userid_tmp string
-		if err := userIDsRaw.Scan(&userid_tmp); err != nil {
-			log.Println("Email scan failure")
-			return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	userIDs := []string{}
+	found := map[string]bool{}
+	for rows.Next() {
+		var id, email string
+		if err := rows.Scan(&id, &email); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 		}
+		userIDs = append(userIDs, id)
+		found[email] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
 
-		queryValues = append(queryValues, fmt.Sprintf("('%s', '%s')", userid_tmp, fileID))
+	// Giữ thứ tự và bỏ trùng theo danh sách client gửi lên.
+	seen := map[string]bool{}
+	for _, email := range emails {
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		if found[email] {
+			result.Resolved = append(result.Resolved, email)
+		} else {
+			result.Unresolved = append(result.Unresolved, email)
+		}
 	}
 
-	if len(queryValues) == 0 {
-		return nil
+	if len(userIDs) == 0 {
+		return result, nil
 	}
 
-	query := fmt.Sprintf(`
+	_, err = conn(ctx, r.db).ExecContext(ctx, `
 		INSERT INTO shared (user_id, file_id)
-		VALUES %s
+		SELECT unnest($1::uuid[]), $2
 		ON CONFLICT (user_id, file_id) DO NOTHING
-	`, strings.Join(queryValues, ", "))
-
-	if _, err := r.db.ExecContext(ctx, query); err != nil {
-		log.Println("INSERT failure")
-		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	`, pq.Array(userIDs), fileID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
 
-	return nil
+	return result, nil
 }
 
 func (r *sharedRepository) GetUsersSharedWith(ctx context.Context, fileID string) (*domain.Shared, *utils.ReturnStatus) {
-	// SELECT * FROM shared_with WHERE file_id = $1
-
 	query := `
-		SELECT user_id FROM shared WHERE file_id = $1
+		SELECT s.user_id, u.email
+		FROM shared s JOIN users u ON u.id = s.user_id
+		WHERE s.file_id = $1
+		ORDER BY u.email
 	`
 
 	share := domain.Shared{
 		FileId:  fileID,
 		UserIds: make([]string, 0, 10),
+		Emails:  make([]string, 0, 10),
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, fileID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, fileID)
 	if err != nil {
 		log.Println(err)
 		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var userid_tmp string
+		var userid_tmp, email string
 
-		if err := rows.Scan(&userid_tmp); err != nil {
+		if err := rows.Scan(&userid_tmp, &email); err != nil {
 			log.Println(err)
 			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
 		}
 
 		share.UserIds = append(share.UserIds, userid_tmp)
+		share.Emails = append(share.Emails, email)
+	}
+
+	return &share, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+// GetFilesSharedWithUser liệt kê mọi file được chia sẻ cho userID (trực tiếp, qua nhóm hoặc thư mục), kể cả file đã hết hạn.
+func (r *sharedRepository) GetFilesSharedWithUser(ctx context.Context, userID string) ([]domain.SharedFile, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT f.id, f.name, u.email, f.created_at
+		FROM files f
+		LEFT JOIN users u ON u.id = f.user_id
+		WHERE f.id IN (
+			SELECT s.file_id FROM shared s WHERE s.user_id = $1
+			UNION
+			SELECT sg.file_id FROM shared_groups sg
+			JOIN group_members m ON m.group_id = sg.group_id
+			WHERE m.user_id = $1
+			UNION
+			SELECT ff.id FROM files ff
+			JOIN folders fo ON fo.id = ff.folder_id
+			WHERE `+folderSharedWith("$1")+`
+		)
+		ORDER BY f.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	files := []domain.SharedFile{}
+	for rows.Next() {
+		var f domain.SharedFile
+		var ownerEmail sql.NullString
+		if err := rows.Scan(&f.FileId, &f.FileName, &ownerEmail, &f.CreatedAt); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		if ownerEmail.Valid {
+			f.OwnerEmail = &ownerEmail.String
+		}
+		files = append(files, f)
 	}
 
-	return &share, nil
+	return files, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *sharedRepository) ShareFileWithGroups(ctx context.Context, fileID string, groupIDs []string) *utils.ReturnStatus {
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO shared_groups (group_id, file_id)
+		SELECT g.id, $1 FROM groups g WHERE g.id = ANY($2::uuid[])
+		ON CONFLICT (group_id, file_id) DO NOTHING
+	`, fileID, pq.Array(groupIDs))
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *sharedRepository) GetGroupsSharedWith(ctx context.Context, fileID string) ([]string, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT group_id FROM shared_groups WHERE file_id = $1 ORDER BY group_id`, fileID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	groupIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		groupIDs = append(groupIDs, id)
+	}
+
+	return groupIDs, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *sharedRepository) SharedViaGroup(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus) {
+	var shared bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM shared_groups sg
+			JOIN group_members m ON m.group_id = sg.group_id
+			WHERE sg.file_id = $1 AND m.user_id = $2
+		)
+	`, fileID, userID).Scan(&shared)
+
+	return shared, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *sharedRepository) SharedViaFolder(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus) {
+	var shared bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM files f
+			JOIN folders fo ON fo.id = f.folder_id
+			WHERE f.id = $1 AND `+folderSharedWith("$2")+`
+		)
+	`, fileID, userID).Scan(&shared)
+
+	return shared, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
 }
@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type authRepository struct {
+	db *sql.DB
+}
+
+func NewAuthRepository(db *sql.DB) repository.AuthRepository {
+	return &authRepository{db: db}
+}
+
+func (r *authRepository) Create(user *domain.User) (*domain.User, *utils.ReturnStatus) {
+	err := r.db.QueryRow(`
+		INSERT INTO users (id, username, password, email, role, enabletotp, secrettotp, email_verified)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, user.Id, user.Username, user.Password, user.Email, user.Role, user.EnableTOTP, user.SecretTOTP, user.EmailVerified).Scan(&user.Id)
+	if err != nil {
+		if isUniqueViolation(err, "users.email") {
+			return nil, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+		}
+		if isUniqueViolation(err, "users_username_lower_key") {
+			return nil, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "username"})
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return user, nil
+}
+
+func (r *authRepository) BlacklistToken(token string, expiredAt time.Time) *utils.ReturnStatus {
+	_, err := r.db.Exec("INSERT INTO jwt_blacklist (token, expired_at) VALUES ($1, $2)", token, expiredAt)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *authRepository) IsTokenBlacklisted(token string) (bool, *utils.ReturnStatus) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM jwt_blacklist WHERE token = $1)", token).Scan(&exists)
+	return exists, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *authRepository) SaveSecret(userID string, secret string) *utils.ReturnStatus {
+	_, err := r.db.Exec(`UPDATE users SET secrettotp = $1 WHERE id = $2`, secret, userID)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *authRepository) GetSecret(userID string) (string, *utils.ReturnStatus) {
+	var secret string
+	err := r.db.QueryRow(`SELECT secrettotp FROM users WHERE id = $1`, userID).Scan(&secret)
+	if err != nil {
+		return "", utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+	}
+
+	return secret, nil
+}
+
+func (r *authRepository) EnableTOTP(userID string) *utils.ReturnStatus {
+	_, err := r.db.Exec(`UPDATE users SET enabletotp = TRUE WHERE id = $1`, userID)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *authRepository) DisableTOTP(userID string) *utils.ReturnStatus {
+	_, err := r.db.Exec(`UPDATE users SET enabletotp = FALSE, secrettotp = NULL WHERE id = $1`, userID)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *authRepository) RevokeUserTokens(ctx context.Context, userID string, revokedAt time.Time) *utils.ReturnStatus {
+	// max() hai đối số của SQLite tương đương GREATEST, so sánh được vì thời gian cùng định dạng.
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO user_token_revocations (user_id, revoked_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET revoked_at = max(user_token_revocations.revoked_at, excluded.revoked_at)
+	`, userID, revokedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *authRepository) IsUserTokenRevoked(userID string, issuedAt time.Time) (bool, *utils.ReturnStatus) {
+	var revoked bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_token_revocations WHERE user_id = $1 AND $2 <= revoked_at)
+	`, userID, issuedAt).Scan(&revoked)
+
+	return revoked, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// PurgeBlacklist: hai bảng độc lập nên không cần transaction, lỗi giữa chừng chỉ làm lần dọn sau
+// xóa tiếp phần còn lại.
+func (r *authRepository) PurgeBlacklist(expiredBefore time.Time, revokedBefore time.Time) (int64, *utils.ReturnStatus) {
+	tokens, err := r.db.Exec(`DELETE FROM jwt_blacklist WHERE expired_at < $1`, expiredBefore)
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	revocations, err := r.db.Exec(`DELETE FROM user_token_revocations WHERE revoked_at < $1`, revokedBefore)
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	purgedTokens, _ := tokens.RowsAffected()
+	purgedRevocations, _ := revocations.RowsAffected()
+	return purgedTokens + purgedRevocations, nil
+}
@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type exportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) repository.ExportRepository {
+	return &exportRepository{db: db}
+}
+
+const exportJobColumns = `id, user_id, status, error, size, created_at, completed_at, expires_at`
+
+func scanExportJob(row interface{ Scan(...any) error }, job *domain.ExportJob) error {
+	var reason sql.NullString
+	var size sql.NullInt64
+	var completedAt, expiresAt sql.NullTime
+	err := row.Scan(
+		&job.Id,
+		&job.UserId,
+		&job.Status,
+		&reason,
+		&size,
+		&job.CreatedAt,
+		&completedAt,
+		&expiresAt,
+	)
+	if reason.Valid {
+		job.Error = &reason.String
+	}
+	if size.Valid {
+		job.Size = &size.Int64
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		job.ExpiresAt = &expiresAt.Time
+	}
+	return err
+}
+
+func (r *exportRepository) queryJobs(ctx context.Context, query string, args ...any) ([]domain.ExportJob, *utils.ReturnStatus) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	jobs := []domain.ExportJob{}
+	for rows.Next() {
+		var job domain.ExportJob
+		if err := scanExportJob(rows, &job); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *exportRepository) Create(ctx context.Context, job *domain.ExportJob) *utils.ReturnStatus {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO export_jobs (user_id, status)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, job.UserId, job.Status).Scan(&job.Id, &job.CreatedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *exportRepository) Find(ctx context.Context, id string) (*domain.ExportJob, *utils.ReturnStatus) {
+	var job domain.ExportJob
+	row := r.db.QueryRowContext(ctx, `SELECT `+exportJobColumns+` FROM export_jobs WHERE id = $1`, id)
+	if err := scanExportJob(row, &job); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeExportNotFound)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	return &job, nil
+}
+
+func (r *exportRepository) FindActive(ctx context.Context, userID string, since time.Time) (*domain.ExportJob, *utils.ReturnStatus) {
+	var job domain.ExportJob
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+exportJobColumns+` FROM export_jobs
+		WHERE user_id = $1 AND status IN ('pending', 'running') AND created_at > $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID, since)
+	if err := scanExportJob(row, &job); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	return &job, nil
+}
+
+func (r *exportRepository) ListByUser(ctx context.Context, userID string) ([]domain.ExportJob, *utils.ReturnStatus) {
+	return r.queryJobs(ctx, `SELECT `+exportJobColumns+` FROM export_jobs WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+}
+
+func (r *exportRepository) MarkRunning(ctx context.Context, id string) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `UPDATE export_jobs SET status = 'running' WHERE id = $1`, id)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *exportRepository) MarkReady(ctx context.Context, id string, size int64, expiresAt time.Time) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE export_jobs
+		SET status = 'ready', size = $2, completed_at = `+now+`, expires_at = $3, error = NULL
+		WHERE id = $1
+	`, id, size, expiresAt)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *exportRepository) MarkFailed(ctx context.Context, id string, reason string) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE export_jobs SET status = 'failed', error = $2, completed_at = `+now+` WHERE id = $1
+	`, id, reason)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *exportRepository) ListExpired(ctx context.Context, now time.Time, before time.Time) ([]domain.ExportJob, *utils.ReturnStatus) {
+	return r.queryJobs(ctx, `
+		SELECT `+exportJobColumns+` FROM export_jobs
+		WHERE (status = 'ready' AND expires_at <= $1)
+		   OR (status <> 'ready' AND created_at <= $2)
+	`, now, before)
+}
+
+func (r *exportRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM export_jobs WHERE id = $1`, id)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
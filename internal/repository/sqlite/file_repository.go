@@ -0,0 +1,702 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type fileRepository struct {
+	db *sql.DB
+}
+
+func NewFileRepository(db *sql.DB) repository.FileRepository {
+	return &fileRepository{db: db}
+}
+
+func (r *fileRepository) CreateFile(ctx context.Context, file *domain.File) (*domain.File, *utils.ReturnStatus) {
+	var userID any
+	if file.OwnerId != nil {
+		userID = *file.OwnerId
+	}
+
+	var passwordHash any
+	if file.PasswordHash != nil {
+		passwordHash = *file.PasswordHash
+	}
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO files (
+			id, user_id, name, type, size, password,
+			available_from, available_to, enable_totp,
+			share_token, created_at, is_public,
+			max_downloads, delete_on_limit, folder_id,
+			description, tags
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+		) RETURNING id, created_at, version
+	`,
+		file.Id, userID, file.FileName, file.MimeType, file.FileSize, passwordHash,
+		file.AvailableFrom, file.AvailableTo, file.EnableTOTP,
+		file.ShareToken, file.CreatedAt, file.IsPublic,
+		file.MaxDownloads, file.DeleteOnLimit, file.FolderId,
+		file.Description, jsonStrings(file.Tags),
+	).Scan(&file.Id, &file.CreatedAt, &file.Version)
+
+	if err != nil {
+		if isUniqueViolation(err, "files.share_token") {
+			return nil, utils.Response(utils.ErrCodeShareTokenTaken)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO filestat (file_id) VALUES ($1)`, file.Id); err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return file, nil
+}
+
+const fileColumns = `
+	f.id, f.user_id, f.name, f.type, f.size, f.share_token,
+	f.password, f.available_from, f.available_to, f.enable_totp, f.created_at, f.is_public,
+	f.max_downloads, COALESCE(f.delete_on_limit, FALSE), COALESCE(s.download_count, 0),
+	f.version, f.folder_id, f.description, f.tags,
+	u.username, u.email, u.role`
+
+const fileJoins = `
+	FROM files f
+	LEFT JOIN filestat s ON s.file_id = f.id
+	LEFT JOIN users u ON u.id = f.user_id`
+
+type fileRow struct {
+	file                                 domain.File
+	ownerID, folderID, description       sql.NullString
+	passwordHash                         sql.NullString
+	maxDownloads                         sql.NullInt64
+	ownerUsername, ownerEmail, ownerRole sql.NullString
+}
+
+func (r *fileRow) dest() []any {
+	f := &r.file
+	return []any{
+		&f.Id, &r.ownerID, &f.FileName, &f.MimeType, &f.FileSize, &f.ShareToken,
+		&r.passwordHash, &f.AvailableFrom, &f.AvailableTo, &f.EnableTOTP, &f.CreatedAt, &f.IsPublic,
+		&r.maxDownloads, &f.DeleteOnLimit, &f.DownloadCount,
+		&f.Version, &r.folderID, &r.description, (*jsonStrings)(&f.Tags),
+		&r.ownerUsername, &r.ownerEmail, &r.ownerRole,
+	}
+}
+
+func (r *fileRow) result() *domain.File {
+	file := r.file
+
+	if r.ownerID.Valid {
+		file.OwnerId = &r.ownerID.String
+		file.Owner = &domain.FileOwner{
+			Id:       r.ownerID.String,
+			Username: r.ownerUsername.String,
+			Email:    r.ownerEmail.String,
+			Role:     r.ownerRole.String,
+		}
+	}
+	if r.folderID.Valid {
+		file.FolderId = &r.folderID.String
+	}
+	if r.description.Valid {
+		file.Description = &r.description.String
+	}
+	if file.Tags == nil {
+		file.Tags = []string{}
+	}
+	if r.maxDownloads.Valid {
+		limit := int(r.maxDownloads.Int64)
+		file.MaxDownloads = &limit
+	}
+	if r.passwordHash.Valid {
+		file.PasswordHash = &r.passwordHash.String
+		file.HasPassword = true
+	}
+
+	return &file
+}
+
+func (r *fileRepository) getFile(ctx context.Context, condition string, arg any) (*domain.File, *utils.ReturnStatus) {
+	var row fileRow
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+fileColumns+fileJoins+` WHERE `+condition, arg).Scan(row.dest()...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeFileNotFound)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return row.result(), nil
+}
+
+func (r *fileRepository) GetFileByID(ctx context.Context, id string) (*domain.File, *utils.ReturnStatus) {
+	return r.getFile(ctx, `f.id = $1`, id)
+}
+
+func (r *fileRepository) GetFileByToken(ctx context.Context, token string) (*domain.File, *utils.ReturnStatus) {
+	return r.getFile(ctx, `f.share_token = $1`, token)
+}
+
+func (r *fileRepository) DeleteFile(ctx context.Context, id string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM files WHERE id = $1`, id)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	return nil
+}
+
+// listFiles chạy một truy vấn danh sách file theo keyset, dùng chung cho GetMyFiles và GetAccessibleFiles.
+func (r *fileRepository) listFiles(ctx context.Context, keys keyset, page domain.PageRequest, query string, args []any) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	var matches []keysetRow
+	files := []domain.File{}
+	for rows.Next() {
+		var f fileRow
+		row, err := keys.scan(rows, f.dest()...)
+		if err != nil {
+			return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		matches = append(matches, row)
+		files = append(files, *f.result())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	matches, next := keys.next(page, matches)
+	return files[:len(matches)], next, nil
+}
+
+func (r *fileRepository) GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	keys, ok := fileKeysets[params.Page.SortBy]
+	if !ok {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
+	}
+
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `SELECT f.id, ` + keys.columns() + `,` + fileColumns + fileJoins + ` WHERE f.user_id = $1`
+
+	switch strings.ToLower(params.Status) {
+	case "all":
+	case "active":
+		query += " AND f.available_from <= " + now + " AND f.available_to > " + now
+	case "pending":
+		query += " AND f.available_from > " + now
+	case "expired":
+		query += " AND f.available_to <= " + now
+	default:
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid file status.")
+	}
+
+	switch params.FolderId {
+	case "":
+	case "root":
+		query += " AND f.folder_id IS NULL"
+	default:
+		query += " AND f.folder_id = " + arg(params.FolderId)
+	}
+
+	after, rerr := keys.after(params.Page, arg)
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+	if after != "" {
+		query += " AND " + after
+	}
+	query += keys.orderBy(params.Page, arg)
+
+	files, next, rerr := r.listFiles(ctx, keys, params.Page, query, args)
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+
+	t := time.Now()
+	for i := range files {
+		files[i].Status = files[i].StatusAt(t)
+	}
+	return files, next, nil
+}
+
+func (r *fileRepository) GetFileSummary(ctx context.Context, userID string) (*domain.FileSummary, *utils.ReturnStatus) {
+	summary := &domain.FileSummary{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE available_from <= `+now+` AND available_to > `+now+`),
+			COUNT(*) FILTER (WHERE available_from > `+now+`),
+			COUNT(*) FILTER (WHERE available_to <= `+now+`)
+		FROM files WHERE user_id = $1
+	`, userID).Scan(&summary.ActiveFiles, &summary.PendingFiles, &summary.ExpiredFiles)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return summary, nil
+}
+
+func (r *fileRepository) FindAll(ctx context.Context) ([]domain.File, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, user_id, name, type, size, share_token,
+			password, available_from, available_to, enable_totp, created_at, is_public
+		FROM files
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	var files []domain.File
+	for rows.Next() {
+		var f domain.File
+		var ownerID, passwordHash sql.NullString
+		err := rows.Scan(&f.Id, &ownerID, &f.FileName, &f.MimeType, &f.FileSize, &f.ShareToken,
+			&passwordHash, &f.AvailableFrom, &f.AvailableTo, &f.EnableTOTP, &f.CreatedAt, &f.IsPublic)
+		if err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+
+		if ownerID.Valid {
+			f.OwnerId = &ownerID.String
+		}
+		if passwordHash.Valid {
+			f.HasPassword = true
+			f.PasswordHash = &passwordHash.String
+		}
+		files = append(files, f)
+	}
+
+	return files, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+// RegisterDownload làm đúng việc của proc_download bên Postgres trong một transaction. SQLite chỉ
+// có một writer (_txlock=immediate) nên hai request không thể cùng lấy slot cuối.
+func (r *fileRepository) RegisterDownload(ctx context.Context, fileID string, userID string) (*int64, *utils.ReturnStatus) {
+	var remaining *int64
+	status := withinTx(ctx, r.db, func(ctx context.Context) *utils.ReturnStatus {
+		var used int64
+		var limit sql.NullInt64
+		err := conn(ctx, r.db).QueryRowContext(ctx, `
+			UPDATE filestat
+			SET download_count = download_count + 1
+			WHERE file_id = $1
+			  AND ((SELECT max_downloads FROM files WHERE id = $1) IS NULL
+			       OR download_count < (SELECT max_downloads FROM files WHERE id = $1))
+			RETURNING download_count, (SELECT max_downloads FROM files WHERE id = $1)
+		`, fileID).Scan(&used, &limit)
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.Response(utils.ErrCodeDownloadLimitReached)
+		}
+		if err != nil {
+			return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+
+		downloader := sql.Null[string]{V: userID, Valid: userID != ""}
+		if _, err := conn(ctx, r.db).ExecContext(ctx, `
+			UPDATE filestat
+			SET user_download_count = user_download_count + 1
+			WHERE file_id = $1 AND NOT EXISTS (SELECT 1 FROM download WHERE user_id = $2 AND file_id = $1)
+		`, fileID, downloader); err != nil {
+			return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+
+		if _, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO download (file_id, user_id) VALUES ($1, $2)`, fileID, downloader); err != nil {
+			return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+
+		if limit.Valid {
+			left := limit.Int64 - used
+			remaining = &left
+		}
+		return nil
+	})
+
+	return remaining, status
+}
+
+func (r *fileRepository) UpdateShareLink(ctx context.Context, file *domain.File) *utils.ReturnStatus {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE files
+		SET share_token = $2, max_downloads = $3, delete_on_limit = $4, version = version + 1
+		WHERE id = $1
+		RETURNING version
+	`, file.Id, file.ShareToken, file.MaxDownloads, file.DeleteOnLimit).Scan(&file.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.Response(utils.ErrCodeFileNotFound)
+		}
+		if isUniqueViolation(err, "files.share_token") {
+			return utils.Response(utils.ErrCodeShareTokenTaken)
+		}
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return nil
+}
+
+func (r *fileRepository) GetFileDownloadHistory(ctx context.Context, fileID string, page domain.PageRequest) (*domain.FileDownloadHistory, *domain.Cursor, *utils.ReturnStatus) {
+	file, status := r.GetFileByID(ctx, fileID)
+	if status != nil {
+		return nil, nil, status
+	}
+
+	history := domain.FileDownloadHistory{FileId: file.Id, FileName: file.FileName, History: []domain.Download{}}
+
+	args := []any{file.Id}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `SELECT d.download_id, ` + downloadKeyset.columns() + `, d.user_id, d.time, u.username, u.email
+		FROM download d LEFT JOIN users u ON u.id = d.user_id
+		WHERE d.file_id = $1`
+	after, status := downloadKeyset.after(page, arg)
+	if status != nil {
+		return nil, nil, status
+	}
+	if after != "" {
+		query += " AND " + after
+	}
+	query += downloadKeyset.orderBy(page, arg)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	var downloads []keysetRow
+	for rows.Next() {
+		var downloadedAt time.Time
+		var downloaderID, username, email sql.NullString
+		row, err := downloadKeyset.scan(rows, &downloaderID, &downloadedAt, &username, &email)
+		if err != nil {
+			return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+
+		download := domain.Download{DownloadId: row.id, DownloadedAt: downloadedAt, DownloadCompleted: true}
+		if downloaderID.Valid {
+			download.UserId = &downloaderID.String
+		}
+		if email.Valid {
+			download.Downloader = &domain.Downloader{Username: username.String, Email: email.String}
+		}
+
+		downloads = append(downloads, row)
+		history.History = append(history.History, download)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	downloads, next := downloadKeyset.next(page, downloads)
+	history.History = history.History[:len(downloads)]
+
+	return &history, next, nil
+}
+
+func (r *fileRepository) GetFileStats(ctx context.Context, fileID string) (*domain.FileStat, *utils.ReturnStatus) {
+	stat := domain.FileStat{}
+	var ownerID sql.NullString
+	var lastDownload nullTime
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT f.id, f.user_id, f.name,
+			COALESCE(s.download_count, 0), COALESCE(s.user_download_count, 0), f.created_at,
+			(SELECT MAX(d.time) FROM download d WHERE d.file_id = f.id)
+		FROM files f
+		LEFT JOIN filestat s ON s.file_id = f.id
+		WHERE f.id = $1
+	`, fileID).Scan(&stat.FileId, &ownerID, &stat.FileName, &stat.TotalDownloadCount, &stat.UserDownloadCount, &stat.CreatedAt, &lastDownload)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeFileNotFound)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if lastDownload.Valid {
+		stat.LastDownloadedAt = lastDownload.Time
+	}
+
+	return &stat, nil
+}
+
+// sharedWithUserFiles giống bản Postgres: file chia sẻ trực tiếp, qua nhóm hoặc qua thư mục.
+func sharedWithUserFiles(userParam string) string {
+	return `
+		SELECT s.file_id FROM shared s WHERE s.user_id = ` + userParam + `
+		UNION
+		SELECT sg.file_id FROM shared_groups sg
+		JOIN group_members m ON m.group_id = sg.group_id
+		WHERE m.user_id = ` + userParam + `
+		UNION
+		SELECT ff.id FROM files ff
+		JOIN folders fo ON fo.id = ff.folder_id
+		WHERE ` + folderSharedWith(userParam) + `
+	`
+}
+
+func (r *fileRepository) GetAccessibleFiles(ctx context.Context, userID string, page domain.PageRequest) ([]domain.File, *domain.Cursor, *utils.ReturnStatus) {
+	keys, ok := fileKeysets[page.SortBy]
+	if !ok {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
+	}
+
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `SELECT f.id, ` + keys.columns() + `,` + fileColumns + fileJoins + `
+		WHERE ` + now + ` >= f.available_from AND ` + now + ` < f.available_to
+		AND f.id IN (` + sharedWithUserFiles("$1") + `)`
+	after, rerr := keys.after(page, arg)
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+	if after != "" {
+		query += " AND " + after
+	}
+	query += keys.orderBy(page, arg)
+
+	return r.listFiles(ctx, keys, page, query, args)
+}
+
+func (r *fileRepository) ListFileIDsByOwner(ctx context.Context, userID string) ([]string, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT id FROM files WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *fileRepository) TransferOwnership(ctx context.Context, fromUserID string, toUserID string) (int64, *utils.ReturnStatus) {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE files SET user_id = $2 WHERE user_id = $1`, fromUserID, toUserID)
+	if err != nil {
+		return 0, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+func (r *fileRepository) GetUserDownloads(ctx context.Context, userID string) ([]domain.UserDownload, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT d.file_id, f.name, d.time
+		FROM download d JOIN files f ON f.id = d.file_id
+		WHERE d.user_id = $1
+		ORDER BY d.time DESC
+	`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	downloads := []domain.UserDownload{}
+	for rows.Next() {
+		var d domain.UserDownload
+		if err := rows.Scan(&d.FileId, &d.FileName, &d.DownloadedAt); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		downloads = append(downloads, d)
+	}
+
+	return downloads, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *fileRepository) ListByFolder(ctx context.Context, folderID string) ([]domain.File, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+fileColumns+fileJoins+` WHERE f.folder_id = $1 ORDER BY f.name`, folderID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	files := []domain.File{}
+	for rows.Next() {
+		var f fileRow
+		if err := rows.Scan(f.dest()...); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		files = append(files, *f.result())
+	}
+
+	return files, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *fileRepository) MoveToFolder(ctx context.Context, fileID string, folderID *string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE files SET folder_id = $2 WHERE id = $1`, fileID, folderID)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	return nil
+}
+
+func (r *fileRepository) UpdateMetadata(ctx context.Context, fileID string, description *string, tags []string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE files SET description = $2, tags = $3 WHERE id = $1`, fileID, description, jsonStrings(tags))
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFileNotFound)
+	}
+
+	return nil
+}
+
+// ftsQuery đổi tsquery do service dựng ("bao:* & cao:*") sang cú pháp FTS5 ("bao"* "cao"*):
+// mọi từ đều phải khớp tiền tố.
+func ftsQuery(tsquery string) string {
+	terms := []string{}
+	for _, term := range strings.Split(tsquery, "&") {
+		term = strings.TrimSuffix(strings.TrimSpace(term), ":*")
+		if term != "" {
+			terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+func (r *fileRepository) SearchFiles(ctx context.Context, userID string, params domain.FileSearchParams) ([]domain.FileSearchHit, *domain.Cursor, *utils.ReturnStatus) {
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	mine := `f.user_id = $1`
+	shared := `(f.user_id IS NOT $1 AND ` + now + ` >= f.available_from AND ` + now + ` < f.available_to
+		AND f.id IN (` + sharedWithUserFiles("$1") + `))`
+
+	conditions := []string{}
+	switch params.Scope {
+	case "mine":
+		conditions = append(conditions, mine)
+	case "shared":
+		conditions = append(conditions, shared)
+	default:
+		conditions = append(conditions, "("+mine+" OR "+shared+")")
+	}
+
+	// bm25 càng nhỏ càng liên quan, đổi dấu để rank giảm dần như ts_rank_cd. Trọng số theo thứ tự
+	// cột name, tags, description tương ứng setweight A, B, C.
+	hits, rank := "", "0.0"
+	if params.Query != "" {
+		hits = `JOIN (
+			SELECT rowid, -bm25(files_fts, 1.0, 0.4, 0.2) AS rank FROM files_fts WHERE files_fts MATCH ` + arg(ftsQuery(params.Query)) + `
+		) h ON h.rowid = f.rowid`
+		rank = "h.rank"
+	}
+
+	for _, tag := range params.Tags {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM json_each(f.tags) WHERE value = "+arg(tag)+")")
+	}
+	if prefix, ok := strings.CutSuffix(params.MimeType, "/*"); ok {
+		conditions = append(conditions, "f.type LIKE "+arg(prefix+"/%"))
+	} else if params.MimeType != "" {
+		conditions = append(conditions, "f.type = "+arg(params.MimeType))
+	}
+	if params.MinSize != nil {
+		conditions = append(conditions, "f.size >= "+arg(*params.MinSize))
+	}
+	if params.MaxSize != nil {
+		conditions = append(conditions, "f.size <= "+arg(*params.MaxSize))
+	}
+	if params.From != nil {
+		conditions = append(conditions, "f.created_at >= "+arg(*params.From))
+	}
+	if params.To != nil {
+		conditions = append(conditions, "f.created_at < "+arg(*params.To))
+	}
+	if params.Owner != "" {
+		conditions = append(conditions, "u.email = "+arg(params.Owner))
+	}
+
+	keys, ok := fileKeysets[params.Page.SortBy]
+	if params.Page.SortBy == domain.SORT_RELEVANCE {
+		keys, ok = keyset{keys: []keysetKey{{rank, keyReal}, {"f.created_at", keyTime}}, id: "f.id"}, true
+	}
+	if !ok {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Invalid sort key.")
+	}
+
+	after, rerr := keys.after(params.Page, arg)
+	if rerr != nil {
+		return nil, nil, rerr
+	}
+	if after != "" {
+		conditions = append(conditions, after)
+	}
+
+	query := `SELECT f.id, ` + keys.columns() + `, ` + rank + ` AS rank,` + fileColumns + fileJoins + ` ` + hits + `
+		WHERE ` + strings.Join(conditions, " AND ") + keys.orderBy(params.Page, arg)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	page := []keysetRow{}
+	results := []domain.FileSearchHit{}
+	for rows.Next() {
+		var f fileRow
+		var score float64
+		row, err := keys.scan(rows, append([]any{&score}, f.dest()...)...)
+		if err != nil {
+			return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		page = append(page, row)
+		results = append(results, domain.FileSearchHit{File: *f.result(), Rank: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	page, next := keys.next(params.Page, page)
+	return results[:len(page)], next, nil
+}
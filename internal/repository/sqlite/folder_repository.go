@@ -0,0 +1,245 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type folderRepository struct {
+	db *sql.DB
+}
+
+func NewFolderRepository(db *sql.DB) repository.FolderRepository {
+	return &folderRepository{db: db}
+}
+
+const folderColumns = `id, user_id, parent_id, name, path, created_at, updated_at`
+
+// folderSharedWith: thư mục fo hoặc một thư mục cha của nó được chia sẻ cho userParam. Không có
+// mảng uuid nên tổ tiên được nhận ra bằng "/<id>/" xuất hiện trong materialized path.
+func folderSharedWith(userParam string) string {
+	return `(
+		EXISTS (
+			SELECT 1 FROM shared_folders sf
+			WHERE instr(fo.path, '/' || sf.folder_id || '/') > 0 AND sf.user_id = ` + userParam + `
+		) OR EXISTS (
+			SELECT 1 FROM shared_folder_groups sg
+			JOIN group_members m ON m.group_id = sg.group_id
+			WHERE instr(fo.path, '/' || sg.folder_id || '/') > 0 AND m.user_id = ` + userParam + `
+		)
+	)`
+}
+
+func scanFolder(row interface{ Scan(...any) error }, folder *domain.Folder) error {
+	var parentID sql.NullString
+	err := row.Scan(&folder.Id, &folder.OwnerId, &parentID, &folder.Name, &folder.Path, &folder.CreatedAt, &folder.UpdatedAt)
+	if parentID.Valid {
+		folder.ParentId = &parentID.String
+	}
+	return err
+}
+
+func (r *folderRepository) list(ctx context.Context, query string, args ...any) ([]domain.Folder, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	folders := []domain.Folder{}
+	for rows.Next() {
+		var folder domain.Folder
+		if err := scanFolder(rows, &folder); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *folderRepository) Create(ctx context.Context, folder *domain.Folder) *utils.ReturnStatus {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO folders (id, user_id, parent_id, name, path)
+		VALUES ($1, $2, $3, $4, COALESCE((SELECT path FROM folders WHERE id = $3), '/') || $1 || '/')
+		RETURNING path, created_at, updated_at
+	`, folder.Id, folder.OwnerId, folder.ParentId, folder.Name,
+	).Scan(&folder.Path, &folder.CreatedAt, &folder.UpdatedAt)
+
+	if isUniqueViolation(err, "folders_sibling_name_key") {
+		return utils.Response(utils.ErrCodeFolderNameTaken)
+	}
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *folderRepository) Find(ctx context.Context, id string) (*domain.Folder, *utils.ReturnStatus) {
+	var folder domain.Folder
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+folderColumns+` FROM folders WHERE id = $1`, id)
+	if err := scanFolder(row, &folder); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeFolderNotFound)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return &folder, nil
+}
+
+func (r *folderRepository) ListByOwner(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `SELECT `+folderColumns+` FROM folders WHERE user_id = $1 ORDER BY path`, userID)
+}
+
+func (r *folderRepository) ListChildren(ctx context.Context, parentID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `SELECT `+folderColumns+` FROM folders WHERE parent_id = $1 ORDER BY lower(name)`, parentID)
+}
+
+// ListSubtree dùng substr thay cho LIKE vì LIKE của SQLite không phân biệt hoa thường.
+func (r *folderRepository) ListSubtree(ctx context.Context, folder *domain.Folder) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `SELECT `+folderColumns+` FROM folders WHERE substr(path, 1, length($1)) = $1 ORDER BY path`, folder.Path)
+}
+
+func (r *folderRepository) Rename(ctx context.Context, id string, name string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE folders SET name = $2, updated_at = `+now+` WHERE id = $1`, id, name)
+	if isUniqueViolation(err, "folders_sibling_name_key") {
+		return utils.Response(utils.ErrCodeFolderNameTaken)
+	}
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFolderNotFound)
+	}
+
+	return nil
+}
+
+func (r *folderRepository) Move(ctx context.Context, folder *domain.Folder, parent *domain.Folder) *utils.ReturnStatus {
+	newPath := "/" + folder.Id + "/"
+	var parentID any
+	if parent != nil {
+		newPath = parent.Path + folder.Id + "/"
+		parentID = parent.Id
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE folders
+		SET path = $3 || substr(path, length($2) + 1),
+			parent_id = CASE WHEN id = $1 THEN $4 ELSE parent_id END,
+			updated_at = CASE WHEN id = $1 THEN `+now+` ELSE updated_at END
+		WHERE substr(path, 1, length($2)) = $2
+	`, folder.Id, folder.Path, newPath, parentID)
+	if isUniqueViolation(err, "folders_sibling_name_key") {
+		return utils.Response(utils.ErrCodeFolderNameTaken)
+	}
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	folder.Path = newPath
+	folder.ParentId = nil
+	if parent != nil {
+		folder.ParentId = &parent.Id
+	}
+	return nil
+}
+
+func (r *folderRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM folders WHERE id = $1`, id)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeFolderNotFound)
+	}
+
+	return nil
+}
+
+// SetShares: SQLite không có CTE ghi dữ liệu nên bốn bước của bản Postgres chạy trong một transaction.
+func (r *folderRepository) SetShares(ctx context.Context, folderID string, emails []string, groupIDs []string) *utils.ReturnStatus {
+	statements := []struct {
+		query string
+		arg   jsonStrings
+	}{
+		{`DELETE FROM shared_folders WHERE folder_id = $1
+			AND user_id NOT IN (SELECT id FROM users WHERE email IN (SELECT value FROM json_each($2)))`, emails},
+		{`INSERT INTO shared_folders (folder_id, user_id)
+			SELECT $1, id FROM users WHERE email IN (SELECT value FROM json_each($2))
+			ON CONFLICT (folder_id, user_id) DO NOTHING`, emails},
+		{`DELETE FROM shared_folder_groups WHERE folder_id = $1
+			AND group_id NOT IN (SELECT value FROM json_each($2))`, groupIDs},
+		{`INSERT INTO shared_folder_groups (folder_id, group_id)
+			SELECT $1, g.id FROM groups g WHERE g.id IN (SELECT value FROM json_each($2))
+			ON CONFLICT (folder_id, group_id) DO NOTHING`, groupIDs},
+	}
+
+	return withinTx(ctx, r.db, func(ctx context.Context) *utils.ReturnStatus {
+		for _, stmt := range statements {
+			if _, err := conn(ctx, r.db).ExecContext(ctx, stmt.query, folderID, stmt.arg); err != nil {
+				return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+func (r *folderRepository) ListShares(ctx context.Context, folderID string) (*domain.FolderShares, *utils.ReturnStatus) {
+	shares := &domain.FolderShares{SharedWith: []string{}, SharedWithGroups: []string{}}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT u.email, NULL FROM shared_folders sf
+		JOIN users u ON u.id = sf.user_id
+		WHERE sf.folder_id = $1
+		UNION ALL
+		SELECT NULL, group_id FROM shared_folder_groups WHERE folder_id = $1
+		ORDER BY 1, 2
+	`, folderID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var email, groupID sql.NullString
+		if err := rows.Scan(&email, &groupID); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		if email.Valid {
+			shares.SharedWith = append(shares.SharedWith, email.String)
+		} else {
+			shares.SharedWithGroups = append(shares.SharedWithGroups, groupID.String)
+		}
+	}
+
+	return shares, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *folderRepository) ListSharedWithUser(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return r.list(ctx, `
+		SELECT `+folderColumns+` FROM folders
+		WHERE id IN (
+			SELECT folder_id FROM shared_folders WHERE user_id = $1
+			UNION
+			SELECT sg.folder_id FROM shared_folder_groups sg
+			JOIN group_members m ON m.group_id = sg.group_id
+			WHERE m.user_id = $1
+		)
+		ORDER BY lower(name)
+	`, userID)
+}
+
+func (r *folderRepository) SharedWithUser(ctx context.Context, folderID string, userID string) (bool, *utils.ReturnStatus) {
+	var shared bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM folders fo WHERE fo.id = $1 AND `+folderSharedWith("$2")+`)
+	`, folderID, userID).Scan(&shared)
+
+	return shared, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
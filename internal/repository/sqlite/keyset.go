@@ -0,0 +1,164 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// keyKind là kiểu giá trị của một khóa sắp xếp, quyết định cách ghi vào cursor và đọc lại.
+type keyKind int
+
+const (
+	keyTime keyKind = iota
+	keyText
+	keyInt
+	keyReal
+)
+
+type keysetKey struct {
+	expr string
+	kind keyKind
+}
+
+// keyset mô tả phân trang keyset như bản Postgres. SQLite không có ép kiểu báo lỗi nên giá trị
+// cursor được kiểm tra và đổi kiểu ngay trong Go; cursor sai định dạng → ErrCodeCursorInvalid.
+type keyset struct {
+	keys []keysetKey
+	id   string
+}
+
+var fileKeysets = map[string]keyset{
+	domain.SORT_CREATED_AT: {keys: []keysetKey{{"f.created_at", keyTime}}, id: "f.id"},
+	domain.SORT_NAME:       {keys: []keysetKey{{"f.name", keyText}}, id: "f.id"},
+	domain.SORT_SIZE:       {keys: []keysetKey{{"f.size", keyInt}}, id: "f.id"},
+	domain.SORT_EXPIRY:     {keys: []keysetKey{{"f.available_to", keyTime}}, id: "f.id"},
+}
+
+var downloadKeyset = keyset{keys: []keysetKey{{"d.time", keyTime}}, id: "d.download_id"}
+
+func (k keyset) columns() string {
+	cols := []string{}
+	for _, key := range k.keys {
+		cols = append(cols, key.expr)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func (k keyset) after(page domain.PageRequest, arg func(any) string) (string, *utils.ReturnStatus) {
+	if page.After == nil {
+		return "", nil
+	}
+	if len(page.After.Keys) != len(k.keys) {
+		return "", utils.Response(utils.ErrCodeCursorInvalid)
+	}
+	if _, err := uuid.Parse(page.After.Id); err != nil {
+		return "", utils.Response(utils.ErrCodeCursorInvalid)
+	}
+
+	left, right := []string{}, []string{}
+	for i, key := range k.keys {
+		value, err := key.parse(page.After.Keys[i])
+		if err != nil {
+			return "", utils.Response(utils.ErrCodeCursorInvalid)
+		}
+		left = append(left, key.expr)
+		right = append(right, arg(value))
+	}
+	left = append(left, k.id)
+	right = append(right, arg(page.After.Id))
+
+	op := "<"
+	if page.Order == domain.ORDER_ASC {
+		op = ">"
+	}
+	return "(" + strings.Join(left, ", ") + ") " + op + " (" + strings.Join(right, ", ") + ")", nil
+}
+
+func (k keyset) orderBy(page domain.PageRequest, arg func(any) string) string {
+	dir := " DESC"
+	if page.Order == domain.ORDER_ASC {
+		dir = " ASC"
+	}
+
+	cols := []string{}
+	for _, key := range k.keys {
+		cols = append(cols, key.expr+dir)
+	}
+	cols = append(cols, k.id+dir)
+
+	out := " ORDER BY " + strings.Join(cols, ", ")
+	if page.Limit > 0 {
+		out += " LIMIT " + arg(page.Limit+1)
+	}
+	return out
+}
+
+type keysetRow struct {
+	id   string
+	keys []string
+}
+
+// scan đọc một dòng dạng "SELECT id, <columns>, extra..." và ghi giá trị khóa thành chuỗi cursor.
+func (k keyset) scan(rows *sql.Rows, extra ...any) (keysetRow, error) {
+	row := keysetRow{keys: make([]string, len(k.keys))}
+	values := make([]any, len(k.keys))
+	dest := []any{&row.id}
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return row, err
+	}
+
+	for i, key := range k.keys {
+		row.keys[i] = key.format(values[i])
+	}
+	return row, nil
+}
+
+func (k keyset) next(page domain.PageRequest, rows []keysetRow) ([]keysetRow, *domain.Cursor) {
+	if page.Limit <= 0 || len(rows) <= page.Limit {
+		return rows, nil
+	}
+
+	rows = rows[:page.Limit]
+	last := rows[len(rows)-1]
+	return rows, &domain.Cursor{SortBy: page.SortBy, Order: page.Order, Keys: last.keys, Id: last.id}
+}
+
+// format ghi giá trị khóa thành chuỗi đọc lại được chính xác (float theo biểu diễn ngắn nhất).
+func (key keysetKey) format(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(database.SQLiteTimeLayout)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func (key keysetKey) parse(value string) (any, error) {
+	switch key.kind {
+	case keyTime:
+		return time.Parse(database.SQLiteTimeLayout, value)
+	case keyInt:
+		return strconv.ParseInt(value, 10, 64)
+	case keyReal:
+		return strconv.ParseFloat(value, 64)
+	}
+	return value, nil
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type loginHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewLoginHistoryRepository(db *sql.DB) repository.LoginHistoryRepository {
+	return &loginHistoryRepository{db: db}
+}
+
+func (r *loginHistoryRepository) Record(ctx context.Context, record *domain.LoginRecord) *utils.ReturnStatus {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO login_history (user_id, method, ip, user_agent)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, record.UserId, record.Method, record.IP, record.UserAgent).Scan(&record.Id, &record.CreatedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *loginHistoryRepository) ListByUser(ctx context.Context, userID string) ([]domain.LoginRecord, *utils.ReturnStatus) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, method, COALESCE(ip, ''), COALESCE(user_agent, ''), created_at
+		FROM login_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	records := []domain.LoginRecord{}
+	for rows.Next() {
+		var record domain.LoginRecord
+		if err := rows.Scan(&record.Id, &record.UserId, &record.Method, &record.IP, &record.UserAgent, &record.CreatedAt); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		records = append(records, record)
+	}
+
+	return records, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
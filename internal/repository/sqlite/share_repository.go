@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type sharedRepository struct {
+	db *sql.DB
+}
+
+func NewSharedRepository(db *sql.DB) repository.SharedRepository {
+	return &sharedRepository{db: db}
+}
+
+// ShareFileWithUsers giống bản Postgres; danh sách email/id truyền vào dạng JSON array và đọc
+// bằng json_each thay cho ANY($1::text[]) / unnest.
+func (r *sharedRepository) ShareFileWithUsers(ctx context.Context, fileID string, emails []string) (*domain.ShareResult, *utils.ReturnStatus) {
+	result := &domain.ShareResult{Resolved: []string{}, Unresolved: []string{}}
+	if len(emails) == 0 {
+		return result, nil
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT id, email FROM users WHERE email IN (SELECT value FROM json_each($1))`, jsonStrings(emails))
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	found := map[string]bool{}
+	for rows.Next() {
+		var id, email string
+		if err := rows.Scan(&id, &email); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		userIDs = append(userIDs, id)
+		found[email] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	seen := map[string]bool{}
+	for _, email := range emails {
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		if found[email] {
+			result.Resolved = append(result.Resolved, email)
+		} else {
+			result.Unresolved = append(result.Unresolved, email)
+		}
+	}
+
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO shared (user_id, file_id)
+		SELECT value, $2 FROM json_each($1) WHERE true -- WHERE để SQLite không đọc ON CONFLICT thành điều kiện JOIN
+		ON CONFLICT (user_id, file_id) DO NOTHING
+	`, jsonStrings(userIDs), fileID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return result, nil
+}
+
+func (r *sharedRepository) GetUsersSharedWith(ctx context.Context, fileID string) (*domain.Shared, *utils.ReturnStatus) {
+	share := domain.Shared{FileId: fileID, UserIds: []string{}, Emails: []string{}}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT s.user_id, u.email
+		FROM shared s JOIN users u ON u.id = s.user_id
+		WHERE s.file_id = $1
+		ORDER BY u.email
+	`, fileID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, email string
+		if err := rows.Scan(&userID, &email); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		share.UserIds = append(share.UserIds, userID)
+		share.Emails = append(share.Emails, email)
+	}
+
+	return &share, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *sharedRepository) GetFilesSharedWithUser(ctx context.Context, userID string) ([]domain.SharedFile, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT f.id, f.name, u.email, f.created_at
+		FROM files f
+		LEFT JOIN users u ON u.id = f.user_id
+		WHERE f.id IN (`+sharedWithUserFiles("$1")+`)
+		ORDER BY f.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	files := []domain.SharedFile{}
+	for rows.Next() {
+		var f domain.SharedFile
+		var ownerEmail sql.NullString
+		if err := rows.Scan(&f.FileId, &f.FileName, &ownerEmail, &f.CreatedAt); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		if ownerEmail.Valid {
+			f.OwnerEmail = &ownerEmail.String
+		}
+		files = append(files, f)
+	}
+
+	return files, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *sharedRepository) ShareFileWithGroups(ctx context.Context, fileID string, groupIDs []string) *utils.ReturnStatus {
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO shared_groups (group_id, file_id)
+		SELECT g.id, $1 FROM groups g WHERE g.id IN (SELECT value FROM json_each($2))
+		ON CONFLICT (group_id, file_id) DO NOTHING
+	`, fileID, jsonStrings(groupIDs))
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *sharedRepository) GetGroupsSharedWith(ctx context.Context, fileID string) ([]string, *utils.ReturnStatus) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT group_id FROM shared_groups WHERE file_id = $1 ORDER BY group_id`, fileID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	groupIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		groupIDs = append(groupIDs, id)
+	}
+
+	return groupIDs, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *sharedRepository) SharedViaGroup(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus) {
+	var shared bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM shared_groups sg
+			JOIN group_members m ON m.group_id = sg.group_id
+			WHERE sg.file_id = $1 AND m.user_id = $2
+		)
+	`, fileID, userID).Scan(&shared)
+
+	return shared, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *sharedRepository) SharedViaFolder(ctx context.Context, fileID string, userID string) (bool, *utils.ReturnStatus) {
+	var shared bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM files f
+			JOIN folders fo ON fo.id = f.folder_id
+			WHERE f.id = $1 AND `+folderSharedWith("$2")+`
+		)
+	`, fileID, userID).Scan(&shared)
+
+	return shared, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
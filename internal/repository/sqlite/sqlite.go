@@ -0,0 +1,124 @@
+// Package sqlite cài đặt các repository trên SQLite cho cài đặt một node không cần Postgres.
+// Schema nằm ở internal/infrastructure/database/sqlite, thời gian lưu theo database.SQLiteTimeLayout.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	modernc "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// NewRepositories dựng các repository chạy trên SQLite. Transaction dùng chung TxManager của
+// package repository vì cùng là *sql.Tx.
+func NewRepositories(db *sql.DB) *repository.Repositories {
+	return &repository.Repositories{
+		User:         NewUserRepository(db),
+		Auth:         NewAuthRepository(db),
+		File:         NewFileRepository(db),
+		Shared:       NewSharedRepository(db),
+		Group:        NewGroupRepository(db),
+		Folder:       NewFolderRepository(db),
+		Attempt:      NewAttemptRepository(db),
+		APIToken:     NewAPITokenRepository(db),
+		Registration: NewRegistrationRepository(db),
+		Export:       NewExportRepository(db),
+		LoginHistory: NewLoginHistoryRepository(db),
+		OIDC:         NewOIDCRepository(db),
+		WebAuthn:     NewWebAuthnRepository(db),
+		Tx:           repository.NewTxManager(db),
+	}
+}
+
+// conn trả về transaction đang mở trong ctx nếu có, giống repository.conn.
+func conn(ctx context.Context, db *sql.DB) repository.DBTX {
+	return repository.Conn(ctx, db)
+}
+
+// withinTx chạy fn trong transaction, dùng cho các thao tác mà bản Postgres làm bằng một câu
+// lệnh (CTE ghi dữ liệu, procedure) nhưng SQLite phải tách thành nhiều câu.
+func withinTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) *utils.ReturnStatus) *utils.ReturnStatus {
+	return repository.NewTxManager(db).WithinTx(ctx, fn)
+}
+
+// now tương đương NOW() của Postgres, theo đúng database.SQLiteTimeLayout.
+const now = `strftime('%Y-%m-%d %H:%M:%f000', 'now')`
+
+// nowPlus trả về biểu thức "NOW() + seconds giây"; seconds là placeholder hoặc biểu thức số, có thể âm.
+func nowPlus(seconds string) string {
+	return `strftime('%Y-%m-%d %H:%M:%f000', 'now', ` + seconds + ` || ' seconds')`
+}
+
+// isUniqueViolation báo lỗi vi phạm UNIQUE/PRIMARY KEY. target là "bảng.cột" với ràng buộc trên
+// cột, hoặc tên index với index biểu thức; rỗng nghĩa là chấp nhận mọi ràng buộc.
+func isUniqueViolation(err error, target string) bool {
+	var sqliteErr *modernc.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	if code := sqliteErr.Code(); code != sqlite3.SQLITE_CONSTRAINT_UNIQUE && code != sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return false
+	}
+
+	return target == "" || strings.Contains(sqliteErr.Error(), target)
+}
+
+// jsonStrings lưu []string dưới dạng JSON array, thay cho cột TEXT[] của Postgres.
+type jsonStrings []string
+
+func (s jsonStrings) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	out, err := json.Marshal([]string(s))
+	return string(out), err
+}
+
+func (s *jsonStrings) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(s))
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(s))
+	}
+	return fmt.Errorf("sqlite: cannot scan %T into []string", src)
+}
+
+// nullTime đọc thời gian từ biểu thức (MAX, subquery...): SQLite chỉ trả về time.Time cho cột
+// khai báo DATETIME, còn biểu thức thì là chuỗi.
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *nullTime) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		t.Time, t.Valid = time.Time{}, false
+		return nil
+	case time.Time:
+		t.Time, t.Valid = v, true
+		return nil
+	case string:
+		parsed, err := time.Parse(database.SQLiteTimeLayout, v)
+		if err != nil {
+			return err
+		}
+		t.Time, t.Valid = parsed, true
+		return nil
+	}
+	return fmt.Errorf("sqlite: cannot scan %T into time", src)
+}
@@ -0,0 +1,197 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type userRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) repository.UserRepository {
+	return &userRepository{db: db}
+}
+
+const userColumns = `id, username, password, email, role, enabletotp, COALESCE(secrettotp, ''), email_verified, suspended_at IS NOT NULL`
+
+func scanUser(row interface{ Scan(...any) error }, user *domain.User) error {
+	return row.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.EnableTOTP, &user.SecretTOTP, &user.EmailVerified, &user.Suspended)
+}
+
+func (r *userRepository) findBy(column string, value string, user *domain.User) *utils.ReturnStatus {
+	err := scanUser(r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE "+column+" = $1", value), user)
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+
+	return utils.ErrIfExists(utils.ErrCodeInternal, err)
+}
+
+func (r *userRepository) FindById(id string, user *domain.User) *utils.ReturnStatus {
+	return r.findBy("id", id, user)
+}
+
+func (r *userRepository) FindByEmail(email string, user *domain.User) *utils.ReturnStatus {
+	return r.findBy("email", email, user)
+}
+
+func (r *userRepository) FindByCId(cid string, user *domain.UsersLoginSession) *utils.ReturnStatus {
+	err := r.db.QueryRow("SELECT id, cid FROM usersloginsession WHERE cid = $1", cid).Scan(&user.Id, &user.Cid)
+	return utils.ErrIfExists(utils.ErrCodeInternal, err)
+}
+
+func (r *userRepository) AddTimestamp(id string, cid string) *utils.ReturnStatus {
+	_, err := r.db.Exec(`
+		INSERT INTO usersloginsession (id, cid) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET cid = excluded.cid
+	`, id, cid)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *userRepository) DeleteTimestamp(id string) *utils.ReturnStatus {
+	_, err := r.db.Exec(`DELETE FROM usersloginsession WHERE id = $1`, id)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *userRepository) UsernameExists(username string) (bool, *utils.ReturnStatus) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE lower(username) = lower($1))", username).Scan(&exists)
+	return exists, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *userRepository) EmailExists(email string) (bool, *utils.ReturnStatus) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", email).Scan(&exists)
+	return exists, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *userRepository) UpdateUsername(ctx context.Context, id string, username string) *utils.ReturnStatus {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET username = $1 WHERE id = $2", username, id)
+	if isUniqueViolation(err, "users_username_lower_key") {
+		return utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "username"})
+	}
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, id string, email string) *utils.ReturnStatus {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET email = $1, email_verified = TRUE WHERE id = $2", email, id)
+	if isUniqueViolation(err, "users.email") {
+		return utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+	}
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *userRepository) ListAdmins() ([]domain.User, *utils.ReturnStatus) {
+	rows, err := r.db.Query("SELECT " + userColumns + " FROM users WHERE role = 'admin' ORDER BY username")
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	admins := []domain.User{}
+	for rows.Next() {
+		var user domain.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		admins = append(admins, user)
+	}
+
+	return admins, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *userRepository) List() ([]domain.User, *utils.ReturnStatus) {
+	rows, err := r.db.Query("SELECT " + userColumns + " FROM users ORDER BY email")
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		var user domain.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		users = append(users, user)
+	}
+
+	return users, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *userRepository) SetSuspended(id string, suspended bool) *utils.ReturnStatus {
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET suspended_at = CASE WHEN $2 THEN COALESCE(suspended_at, `+now+`) END
+		WHERE id = $1
+	`, id, suspended)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	db := conn(ctx, r.db)
+	if _, err := db.ExecContext(ctx, "DELETE FROM usersloginsession WHERE id = $1", id); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	result, err := db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+	return nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (r *userRepository) Search(ctx context.Context, params domain.UserSearchParams) ([]domain.PublicProfile, *utils.ReturnStatus) {
+	match := `(lower(username) LIKE $2 ESCAPE '\' OR lower(email) LIKE $2 ESCAPE '\')`
+	pattern := escapeLike(params.Query) + "%"
+	if params.ExactEmail {
+		match = `lower(email) = $2`
+		pattern = params.Query
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT username, email FROM users
+		WHERE id <> $1 AND email_verified AND `+match+`
+		  AND ($3 = '' OR substr(lower(email), instr(email, '@') + 1) = $3)
+		ORDER BY lower(username), email
+		LIMIT $4
+	`, params.ExcludeId, pattern, params.Domain, params.Limit)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	profiles := []domain.PublicProfile{}
+	for rows.Next() {
+		var profile domain.PublicProfile
+		if err := rows.Scan(&profile.Username, &profile.Email); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
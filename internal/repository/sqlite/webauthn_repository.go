@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type webAuthnRepository struct {
+	db *sql.DB
+}
+
+func NewWebAuthnRepository(db *sql.DB) repository.WebAuthnRepository {
+	return &webAuthnRepository{db: db}
+}
+
+const webAuthnCredentialColumns = `id, user_id, credential_id, name, data, created_at, last_used_at`
+
+func scanWebAuthnCredential(row interface{ Scan(...any) error }, cred *domain.WebAuthnCredential) error {
+	var lastUsed sql.NullTime
+	err := row.Scan(&cred.Id, &cred.UserId, &cred.CredentialID, &cred.Name, &cred.Data, &cred.CreatedAt, &lastUsed)
+	if lastUsed.Valid {
+		cred.LastUsedAt = &lastUsed.Time
+	}
+	return err
+}
+
+func (r *webAuthnRepository) ListByUser(ctx context.Context, userID string) ([]domain.WebAuthnCredential, *utils.ReturnStatus) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webAuthnCredentialColumns+` FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	creds := []domain.WebAuthnCredential{}
+	for rows.Next() {
+		var cred domain.WebAuthnCredential
+		if err := scanWebAuthnCredential(rows, &cred); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		creds = append(creds, cred)
+	}
+
+	return creds, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (r *webAuthnRepository) CountByUser(ctx context.Context, userID string) (int, *utils.ReturnStatus) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = $1`, userID).Scan(&count)
+	return count, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *webAuthnRepository) FindByCredentialID(ctx context.Context, credentialID []byte) (*domain.WebAuthnCredential, *utils.ReturnStatus) {
+	var cred domain.WebAuthnCredential
+	row := r.db.QueryRowContext(ctx, `SELECT `+webAuthnCredentialColumns+` FROM webauthn_credentials WHERE credential_id = $1`, credentialID)
+	if err := scanWebAuthnCredential(row, &cred); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.Response(utils.ErrCodeWebAuthnCredentialNotFound)
+		}
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	return &cred, nil
+}
+
+func (r *webAuthnRepository) Create(ctx context.Context, cred *domain.WebAuthnCredential) *utils.ReturnStatus {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO webauthn_credentials (user_id, credential_id, name, data)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, cred.UserId, cred.CredentialID, cred.Name, cred.Data).Scan(&cred.Id, &cred.CreatedAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// UpdateAfterLogin lưu lại sign counter/flags mới sau mỗi lần xác thực thành công.
+func (r *webAuthnRepository) UpdateAfterLogin(ctx context.Context, id string, data []byte) *utils.ReturnStatus {
+	_, err := r.db.ExecContext(ctx, `UPDATE webauthn_credentials SET data = $2, last_used_at = `+now+` WHERE id = $1`, id, data)
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (r *webAuthnRepository) Delete(ctx context.Context, id string, userID string) *utils.ReturnStatus {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeWebAuthnCredentialNotFound)
+	}
+
+	return nil
+}
+
+func (r *webAuthnRepository) SaveSession(ctx context.Context, session *domain.WebAuthnSession) *utils.ReturnStatus {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM webauthn_sessions WHERE expires_at < `+now); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO webauthn_sessions (id, purpose, user_id, cid, name, data, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+	`, session.Id, session.Purpose, session.UserId, session.Cid, session.Name, session.Data, session.ExpiresAt)
+
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// ConsumeSession lấy và xóa session trong một câu lệnh; session hết hạn coi như không tồn tại.
+func (r *webAuthnRepository) ConsumeSession(ctx context.Context, id string, purpose domain.WebAuthnPurpose) (*domain.WebAuthnSession, *utils.ReturnStatus) {
+	session := &domain.WebAuthnSession{}
+	var userID, cid, name sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		DELETE FROM webauthn_sessions
+		WHERE id = $1 AND purpose = $2
+		RETURNING id, purpose, user_id, cid, name, data, expires_at
+	`, id, purpose).Scan(&session.Id, &session.Purpose, &userID, &cid, &name, &session.Data, &session.ExpiresAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, utils.Response(utils.ErrCodeWebAuthnSessionInvalid)
+	}
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	session.UserId, session.Cid, session.Name = userID.String, cid.String, name.String
+
+	return session, nil
+}
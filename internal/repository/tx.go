@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+// DBTX là phần chung của *sql.DB và *sql.Tx mà repository dùng để chạy query.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// conn trả về transaction đang mở trong ctx (do TxManager.WithinTx gắn vào), nếu không có thì dùng db.
+// Nhờ vậy nhiều repository cùng tham gia một transaction mà không phải đổi chữ ký hàm.
+func conn(ctx context.Context, db *sql.DB) DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// Conn là conn cho các backend ở package khác (sqlite) để cùng tham gia transaction của TxManager.
+func Conn(ctx context.Context, db *sql.DB) DBTX {
+	return conn(ctx, db)
+}
+
+// TxManager chạy một nhóm thao tác trên nhiều repository như một đơn vị (unit of work).
+type TxManager interface {
+	// WithinTx mở transaction, gắn vào ctx truyền cho fn, commit khi fn trả về nil và rollback
+	// khi fn lỗi hoặc panic. Gọi lồng nhau thì dùng lại transaction bên ngoài.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) *utils.ReturnStatus) *utils.ReturnStatus
+}
+
+type sqlTxManager struct {
+	db *sql.DB
+}
+
+func NewTxManager(db *sql.DB) TxManager {
+	return &sqlTxManager{db: db}
+}
+
+func (m *sqlTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) *utils.ReturnStatus) *utils.ReturnStatus {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if status := fn(context.WithValue(ctx, txKey{}, tx)); status.IsErr() {
+		tx.Rollback()
+		return status
+	}
+
+	if err := tx.Commit(); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"strings"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
@@ -17,10 +20,20 @@ func NewSQLUserRepository(DB *sql.DB) UserRepository {
 	}
 }
 
+// Liệt kê cột thay vì SELECT * để thêm cột vào bảng users không làm hỏng Scan.
+const userColumns = `id, username, password, email, role, enabletotp, COALESCE(secrettotp, ''), email_verified, suspended_at IS NOT NULL`
+
+func scanUser(row interface{ Scan(...any) error }, user *domain.User) error {
+	return row.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.EnableTOTP, &user.SecretTOTP, &user.EmailVerified, &user.Suspended)
+}
+
 func (ur *SQLUserRepository) FindById(id string, user *domain.User) *utils.ReturnStatus {
-	row := ur.db.QueryRow("SELECT * FROM users WHERE id = $1", id)
-	err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.EnableTOTP, &user.SecretTOTP)
+	row := ur.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1", id)
+	err := scanUser(row, user)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
 	if err != nil {
 		return utils.ErrIfExists(utils.ErrCodeInternal, err)
 	}
@@ -29,8 +42,12 @@ func (ur *SQLUserRepository) FindById(id string, user *domain.User) *utils.Retur
 }
 
 func (ur *SQLUserRepository) FindByEmail(email string, user *domain.User) *utils.ReturnStatus {
-	row := ur.db.QueryRow("SELECT * FROM users WHERE email = $1", email)
-	err := row.Scan(&user.Id, &user.Username, &user.Password, &user.Email, &user.Role, &user.EnableTOTP, &user.SecretTOTP)
+	row := ur.db.QueryRow("SELECT "+userColumns+" FROM users WHERE email = $1", email)
+	err := scanUser(row, user)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
 	if err != nil {
 		return utils.ErrIfExists(utils.ErrCodeInternal, err)
 	}
@@ -66,4 +83,144 @@ func (ur *SQLUserRepository) DeleteTimestamp(id string) *utils.ReturnStatus {
 	`, id)
 
 	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
-}
\ No newline at end of file
+}
+
+func (ur *SQLUserRepository) UsernameExists(username string) (bool, *utils.ReturnStatus) {
+	var exists bool
+	err := ur.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))", username).Scan(&exists)
+	return exists, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (ur *SQLUserRepository) EmailExists(email string) (bool, *utils.ReturnStatus) {
+	var exists bool
+	err := ur.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", email).Scan(&exists)
+	return exists, utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (ur *SQLUserRepository) UpdateUsername(ctx context.Context, id string, username string) *utils.ReturnStatus {
+	_, err := conn(ctx, ur.db).ExecContext(ctx, "UPDATE users SET username = $1 WHERE id = $2", username, id)
+	if isUniqueViolation(err, "users_username_lower_key") {
+		return utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "username"})
+	}
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+// UpdateEmail đổi email ngay (không qua link xác minh), dùng khi policy không yêu cầu xác minh email.
+func (ur *SQLUserRepository) UpdateEmail(ctx context.Context, id string, email string) *utils.ReturnStatus {
+	_, err := conn(ctx, ur.db).ExecContext(ctx, "UPDATE users SET email = $1, email_verified = TRUE WHERE id = $2", email, id)
+	if isUniqueViolation(err, "users_email_key") {
+		return utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+	}
+	return utils.ErrIfExists(utils.ErrCodeDatabaseError, err)
+}
+
+func (ur *SQLUserRepository) ListAdmins() ([]domain.User, *utils.ReturnStatus) {
+	rows, err := ur.db.Query("SELECT " + userColumns + " FROM users WHERE role = 'admin' ORDER BY username")
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	admins := []domain.User{}
+	for rows.Next() {
+		var user domain.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		admins = append(admins, user)
+	}
+
+	return admins, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+func (ur *SQLUserRepository) List() ([]domain.User, *utils.ReturnStatus) {
+	rows, err := ur.db.Query("SELECT " + userColumns + " FROM users ORDER BY email")
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	users := []domain.User{}
+	for rows.Next() {
+		var user domain.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		users = append(users, user)
+	}
+
+	return users, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
+
+// SetSuspended khóa/mở khóa tài khoản, giữ nguyên thời điểm khóa nếu đã bị khóa từ trước.
+func (ur *SQLUserRepository) SetSuspended(id string, suspended bool) *utils.ReturnStatus {
+	result, err := ur.db.Exec(`
+		UPDATE users
+		SET suspended_at = CASE WHEN $2 THEN COALESCE(suspended_at, NOW()) END
+		WHERE id = $1
+	`, id, suspended)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+	return nil
+}
+
+// Delete xóa user; file, chia sẻ, API token, passkey... của user bị xóa theo (ON DELETE CASCADE).
+// usersLoginSession không có khóa ngoại nên CID đang chờ bước 2 được xóa riêng.
+func (ur *SQLUserRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	db := conn(ctx, ur.db)
+	if _, err := db.ExecContext(ctx, "DELETE FROM usersLoginSession WHERE id = $1", id); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	result, err := db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return utils.Response(utils.ErrCodeUserNotFound)
+	}
+	return nil
+}
+
+// escapeLike thoát ký tự đặc biệt của LIKE để query của user được so khớp nguyên văn.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Search tìm user đã xác minh email theo tiền tố username/email (hoặc đúng email khi ExactEmail).
+func (ur *SQLUserRepository) Search(ctx context.Context, params domain.UserSearchParams) ([]domain.PublicProfile, *utils.ReturnStatus) {
+	match := `(lower(username) LIKE $2 ESCAPE '\' OR lower(email) LIKE $2 ESCAPE '\')`
+	pattern := escapeLike(params.Query) + "%"
+	if params.ExactEmail {
+		match = `lower(email) = $2`
+		pattern = params.Query
+	}
+
+	rows, err := ur.db.QueryContext(ctx, `
+		SELECT username, email FROM users
+		WHERE id <> $1 AND email_verified AND `+match+`
+		  AND ($3 = '' OR split_part(lower(email), '@', 2) = $3)
+		ORDER BY lower(username), email
+		LIMIT $4
+	`, params.ExcludeId, pattern, params.Domain, params.Limit)
+	if err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+	}
+	defer rows.Close()
+
+	profiles := []domain.PublicProfile{}
+	for rows.Next() {
+		var profile domain.PublicProfile
+		if err := rows.Scan(&profile.Username, &profile.Email); err != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, err.Error())
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, utils.ErrIfExists(utils.ErrCodeDatabaseError, rows.Err())
+}
@@ -2,32 +2,43 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
 )
 
 type adminService struct {
 	cfg      *config.Config            // Lưu tham chiếu đến cấu hình
 	fileRepo repository.FileRepository // <-- THÊM: Để truy vấn file
 	storage  storage.Storage           // <-- THÊM: Để xóa file vật lý
+	guard    BruteForceGuard
+	exports  ExportService
 }
 
-func NewAdminService(cfg *config.Config, fr repository.FileRepository, s storage.Storage) AdminService {
+func NewAdminService(cfg *config.Config, fr repository.FileRepository, s storage.Storage, g BruteForceGuard, e ExportService) AdminService {
 	return &adminService{
 		cfg:      cfg,
 		fileRepo: fr,
 		storage:  s,
+		guard:    g,
+		exports:  e,
 	}
 }
 
 func (s *adminService) GetSystemPolicy(ctx context.Context) (*config.SystemPolicy, *utils.ReturnStatus) {
-	return s.cfg.Policy, nil
+	policy := config.SnapshotPolicy(s.cfg.Policy)
+	return &policy, nil
 }
 
 func toInt(value any) (int, bool) {
@@ -48,7 +59,7 @@ func toInt(value any) (int, bool) {
 
 func (s *adminService) UpdateSystemPolicy(ctx context.Context, updates map[string]any) (*config.SystemPolicy, *utils.ReturnStatus) {
 
-	currentPolicy := *s.cfg.Policy
+	currentPolicy := config.SnapshotPolicy(s.cfg.Policy)
 
 	// 1. MaxFileSizeMB
 	if val, exists := updates[utils.CamelToSnake("MaxFileSizeMB")]; exists {
@@ -105,52 +116,228 @@ func (s *adminService) UpdateSystemPolicy(ctx context.Context, updates map[strin
 			if v < 0 {
 				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Password length cannot be negative")
 			}
-			if v > 128 { // Ví dụ: Mật khẩu quá dài không cần thiết
-				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Password min length cannot exceed 128 characters")
+			if v > validation.PasswordMaxBytes { // bcrypt chỉ dùng 72 byte đầu
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("Password min length cannot exceed %d characters", validation.PasswordMaxBytes))
 			}
 			currentPolicy.RequirePasswordMinLength = v
 		}
 	}
 
+	// 6. ShareTokenLength
+	if val, exists := updates[utils.CamelToSnake("ShareTokenLength")]; exists {
+		if v, ok := toInt(val); ok {
+			if v < 8 {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Share token length must be at least 8 characters")
+			}
+			if v > 64 {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Share token length cannot exceed 64 characters")
+			}
+			currentPolicy.ShareTokenLength = v
+		}
+	}
+
+	// 7. ShareTokenAlphabet
+	if val, exists := updates[utils.CamelToSnake("ShareTokenAlphabet")]; exists {
+		if v, ok := val.(string); ok {
+			if _, known := utils.TokenAlphabets[v]; !known {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Unknown share token alphabet")
+			}
+			currentPolicy.ShareTokenAlphabet = v
+		}
+	}
+
+	// 8. SignedURLMaxTTLMinutes
+	if val, exists := updates[utils.CamelToSnake("SignedURLMaxTTLMinutes")]; exists {
+		if v, ok := toInt(val); ok {
+			if v < 1 || v > 1440 {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Signed URL max TTL must be between 1 and 1440 minutes")
+			}
+			currentPolicy.SignedURLMaxTTLMinutes = v
+		}
+	}
+
+	// 9. RegistrationMode
+	if val, exists := updates[utils.CamelToSnake("RegistrationMode")]; exists {
+		if v, ok := val.(string); ok {
+			if !slices.Contains(domain.RegistrationModes, v) {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Unknown registration mode")
+			}
+			currentPolicy.RegistrationMode = v
+		}
+	}
+
+	// 10. RequireEmailVerification
+	if val, exists := updates[utils.CamelToSnake("RequireEmailVerification")]; exists {
+		if v, ok := val.(bool); ok {
+			currentPolicy.RequireEmailVerification = v
+		}
+	}
+
+	// 11. PasswordRequiredClasses
+	if val, exists := updates[utils.CamelToSnake("PasswordRequiredClasses")]; exists {
+		if v, ok := val.([]string); ok {
+			classes := []string{}
+			for _, class := range v {
+				if !slices.Contains(validation.PasswordClasses, class) {
+					return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("Unknown password character class: %s", class))
+				}
+				if !slices.Contains(classes, class) {
+					classes = append(classes, class)
+				}
+			}
+			currentPolicy.PasswordRequiredClasses = classes
+		}
+	}
+
+	// 12. PasswordCheckBreached
+	if val, exists := updates[utils.CamelToSnake("PasswordCheckBreached")]; exists {
+		if v, ok := val.(bool); ok {
+			currentPolicy.PasswordCheckBreached = v
+		}
+	}
+
+	// 13. RateLimits
+	if val, exists := updates[utils.CamelToSnake("RateLimits")]; exists {
+		if v, ok := val.(map[string]dto.RateLimitRule); ok {
+			// Map mới thay cho map cũ: middleware đọc map của bản snapshot ngoài lock nên không được sửa tại chỗ.
+			limits := maps.Clone(currentPolicy.RateLimits)
+			known := config.DefaultRateLimits()
+			for name, rule := range v {
+				if _, exists := known[name]; !exists {
+					return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("Unknown rate limit policy: %s", name))
+				}
+				if rule.Limit < 1 || rule.WindowSeconds < 1 {
+					return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Rate limit and window must be > 0")
+				}
+				limits[name] = config.RateLimitRule{Limit: rule.Limit, WindowSeconds: rule.WindowSeconds}
+			}
+			currentPolicy.RateLimits = limits
+		}
+	}
+
+	// 14. UserDirectoryMode
+	if val, exists := updates[utils.CamelToSnake("UserDirectoryMode")]; exists {
+		if v, ok := val.(string); ok {
+			if !slices.Contains(domain.UserDirectoryModes, v) {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Unknown user directory mode")
+			}
+			currentPolicy.UserDirectoryMode = v
+		}
+	}
+
+	// 15. UserSearchMaxResults
+	if val, exists := updates[utils.CamelToSnake("UserSearchMaxResults")]; exists {
+		if v, ok := toInt(val); ok {
+			if v < 1 || v > 50 {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "User search max results must be between 1 and 50")
+			}
+			currentPolicy.UserSearchMaxResults = v
+		}
+	}
+
 	if currentPolicy.DefaultValidityDays > currentPolicy.MaxValidityDays {
 		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Default validity days cannot be greater than max validity days")
 	}
 
-	*s.cfg.Policy = currentPolicy
+	config.ReplacePolicy(s.cfg.Policy, currentPolicy)
 
-	return s.cfg.Policy, nil
+	return &currentPolicy, nil
 }
 
 func (s *adminService) CleanupExpiredFiles(ctx context.Context) (int, *utils.ReturnStatus) {
-	// Giả định FileRepository có hàm FindAll để lấy TẤT CẢ files
-	files, err := s.fileRepo.FindAll(ctx)
+	files, err := s.expiredFiles(ctx)
 	if err.IsErr() {
 		return 0, err
 	}
 
-	now := time.Now().UTC()
 	deletedCount := 0
 
-	// Duyệt qua tất cả các file
+	// Duyệt qua các file đã hết hạn
+	for _, file := range files {
+		if err := s.storage.DeleteFile(file.Id); err.IsErr() {
+			// Log lỗi nhưng tiếp tục sang file tiếp theo
+			log.Printf("Cleanup Error: Failed to delete physical file %s: %v, ignoring...", file.Id, err)
+			continue
+		}
+
+		if err := s.fileRepo.DeleteFile(ctx, file.Id); err.IsErr() {
+			// Log lỗi nhưng tiếp tục
+			log.Printf("Cleanup Error: Failed to delete metadata for file %s: %v", file.Id, err)
+			continue
+		}
+
+		deletedCount++
+	}
+
+	if purged, err := s.guard.Purge(ctx); err.IsErr() {
+		log.Printf("Cleanup Error: Failed to purge stale auth attempts: %v", err)
+	} else if purged > 0 {
+		log.Printf("Cleanup: purged %d stale auth attempt counters", purged)
+	}
+
+	if purged, err := s.exports.PurgeExpired(ctx); err.IsErr() {
+		log.Printf("Cleanup Error: Failed to purge expired data exports: %v", err)
+	} else if purged > 0 {
+		log.Printf("Cleanup: purged %d expired data exports", purged)
+	}
+
+	return deletedCount, nil
+}
+
+func (s *adminService) PreviewCleanup(ctx context.Context) ([]domain.File, *utils.ReturnStatus) {
+	return s.expiredFiles(ctx)
+}
+
+// expiredFiles: các file đã quá ngày hết hạn, CleanupExpiredFiles và dry run dùng chung.
+func (s *adminService) expiredFiles(ctx context.Context) ([]domain.File, *utils.ReturnStatus) {
+	files, err := s.fileRepo.FindAll(ctx)
+	if err.IsErr() {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expired := []domain.File{}
 	for _, file := range files {
-		// 1. Kiểm tra ngày hết hạn
 		if file.AvailableTo.Before(now) {
+			expired = append(expired, file)
+		}
+	}
+	return expired, nil
+}
 
-			if err := s.storage.DeleteFile(file.Id); err.IsErr() {
-				// Log lỗi nhưng tiếp tục sang file tiếp theo
-				log.Printf("Cleanup Error: Failed to delete physical file %s: %v, ignoring...", file.Id, err)
-				continue
-			}
+// CheckStorage đối chiếu metadata với storage. Liệt kê storage trước khi đọc metadata để file vừa
+// upload xong giữa hai bước không bị báo nhầm là mồ côi.
+func (s *adminService) CheckStorage(ctx context.Context) (*domain.StorageReport, *utils.ReturnStatus) {
+	stored, err := s.storage.List()
+	if err.IsErr() {
+		return nil, err
+	}
 
-			if err := s.fileRepo.DeleteFile(ctx, file.Id); err.IsErr() {
-				// Log lỗi nhưng tiếp tục
-				log.Printf("Cleanup Error: Failed to delete metadata for file %s: %v", file.Id, err)
-				continue
-			}
+	files, err := s.fileRepo.FindAll(ctx)
+	if err.IsErr() {
+		return nil, err
+	}
 
-			deletedCount++
+	report := &domain.StorageReport{Checked: len(files), Missing: []domain.File{}, SizeMismatch: []domain.File{}, Orphans: []string{}}
+	known := make(map[string]bool, len(files))
+	for _, file := range files {
+		known[file.Id] = true
+
+		size, err := s.storage.Stat(file.Id)
+		switch {
+		case err.IsErr() && err.Error() == utils.ErrCodeFileNotFound:
+			report.Missing = append(report.Missing, file)
+		case err.IsErr():
+			return nil, err
+		case size != file.FileSize:
+			report.SizeMismatch = append(report.SizeMismatch, file)
 		}
 	}
 
-	return deletedCount, nil
+	for _, name := range stored {
+		if !known[name] {
+			report.Orphans = append(report.Orphans, name)
+		}
+	}
+	return report, nil
 }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+const (
+	apiTokenLength        = 40
+	apiTokenDisplayLength = 8
+	defaultAPITokenTTL    = 90 * 24 * time.Hour
+)
+
+type apiTokenService struct {
+	tokenRepo repository.APITokenRepository
+	userRepo  repository.UserRepository
+}
+
+func NewAPITokenService(tokenRepo repository.APITokenRepository, userRepo repository.UserRepository) APITokenService {
+	return &apiTokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// HashAPIToken: DB chỉ giữ SHA-256 của token. Token đủ entropy nên không cần salt/bcrypt,
+// và hash xác định cho phép tra cứu trực tiếp theo index. Dùng chung cho mã mời và link xác minh email.
+func HashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *apiTokenService) CreateToken(ctx context.Context, userID string, req *dto.CreateAPITokenRequest) (string, *domain.APIToken, *utils.ReturnStatus) {
+	user := &domain.User{}
+	if err := s.userRepo.FindById(userID, user); err != nil {
+		return "", nil, err
+	}
+
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	// Key không được có nhiều quyền hơn chủ sở hữu.
+	if slices.Contains(scopes, domain.SCOPE_ADMIN) && strings.ToLower(user.Role) != "admin" {
+		return "", nil, utils.Response(utils.ErrCodeAPITokenScope)
+	}
+
+	secret, err := utils.GenerateSecureString(apiTokenLength, utils.TokenAlphabets[utils.DefaultTokenAlphabet])
+	if err != nil {
+		return "", nil, utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate API token")
+	}
+	raw := domain.APITokenPrefix + secret
+
+	ttl := defaultAPITokenTTL
+	if req.ExpiresInDays != nil {
+		ttl = time.Duration(*req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	token := &domain.APIToken{
+		UserId:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: HashAPIToken(raw),
+		Prefix:    raw[:len(domain.APITokenPrefix)+apiTokenDisplayLength],
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return "", nil, err
+	}
+
+	return raw, token, nil
+}
+
+func (s *apiTokenService) ListTokens(ctx context.Context, userID string) ([]domain.APIToken, *utils.ReturnStatus) {
+	return s.tokenRepo.ListByUser(ctx, userID)
+}
+
+func (s *apiTokenService) RevokeToken(ctx context.Context, userID string, tokenID string) *utils.ReturnStatus {
+	return s.tokenRepo.Delete(ctx, tokenID, userID)
+}
+
+func (s *apiTokenService) Authenticate(ctx context.Context, raw string) (*domain.APIToken, *domain.User, *utils.ReturnStatus) {
+	if !strings.HasPrefix(raw, domain.APITokenPrefix) {
+		return nil, nil, utils.Response(utils.ErrCodeAPITokenInvalid)
+	}
+
+	token, err := s.tokenRepo.FindByHash(ctx, HashAPIToken(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	if token.Expired() {
+		return nil, nil, utils.Response(utils.ErrCodeAPITokenInvalid)
+	}
+
+	// Role lấy theo user hiện tại, admin bị hạ quyền thì key "admin" cũng mất tác dụng.
+	user := &domain.User{}
+	if err := s.userRepo.FindById(token.UserId, user); err != nil {
+		return nil, nil, utils.Response(utils.ErrCodeAPITokenInvalid)
+	}
+	if err := checkActive(user); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.tokenRepo.TouchLastUsed(ctx, token.Id); err != nil {
+		return nil, nil, err
+	}
+
+	return token, user, nil
+}
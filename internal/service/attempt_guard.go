@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// attemptPolicy: sau Threshold lần sai trong Window, khóa BaseLockout rồi gấp đôi
+// sau mỗi lần sai tiếp theo, tối đa MaxLockout.
+type attemptPolicy struct {
+	Threshold   int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+var attemptPolicies = map[domain.AttemptScope]attemptPolicy{
+	// IP dùng chung cho mọi endpoint, ngưỡng cao hơn vì nhiều người có thể chung NAT.
+	domain.ATTEMPT_IP:          {Threshold: 20, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour},
+	domain.ATTEMPT_ACCOUNT:     {Threshold: 5, Window: 15 * time.Minute, BaseLockout: 30 * time.Second, MaxLockout: 30 * time.Minute},
+	domain.ATTEMPT_SHARE_TOKEN: {Threshold: 10, Window: 15 * time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour},
+}
+
+func (p attemptPolicy) lockout(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+
+	exp := failures - p.Threshold
+	if exp > 30 {
+		return p.MaxLockout
+	}
+
+	return min(p.BaseLockout*time.Duration(1<<exp), p.MaxLockout)
+}
+
+type bruteForceGuard struct {
+	attemptRepo repository.AttemptRepository
+}
+
+func NewBruteForceGuard(ar repository.AttemptRepository) BruteForceGuard {
+	return &bruteForceGuard{
+		attemptRepo: ar,
+	}
+}
+
+// AttemptReservation là các lần thử Reserve đã tính trước, giữ lại số đếm để Release hoàn đúng lần đó.
+type AttemptReservation struct {
+	keys     []domain.AttemptKey
+	failures []int
+}
+
+func (g *bruteForceGuard) Reserve(ctx context.Context, keys ...domain.AttemptKey) (*AttemptReservation, *utils.ReturnStatus) {
+	reservation := &AttemptReservation{}
+	for _, key := range keys {
+		policy, ok := attemptPolicies[key.Scope]
+		if !ok {
+			continue
+		}
+
+		failures, err := g.attemptRepo.Reserve(ctx, key, policy.Window, policy.Threshold, policy.BaseLockout)
+		if err.IsErr() {
+			g.Release(ctx, reservation)
+			return nil, err
+		}
+		if failures == 0 {
+			g.Release(ctx, reservation)
+			return nil, g.tooManyRequests(ctx, key)
+		}
+
+		reservation.keys = append(reservation.keys, key)
+		reservation.failures = append(reservation.failures, failures)
+
+		// Reserve đã khóa BaseLockout khi chạm ngưỡng, ở đây chỉ kéo dài cho các lần vượt ngưỡng.
+		if lockout := policy.lockout(failures); lockout > policy.BaseLockout {
+			if err := g.attemptRepo.Lock(ctx, key, lockout); err.IsErr() {
+				log.Printf("Brute-force guard: failed to lock %s/%s: %v", key.Scope, key.Key, err)
+			}
+		}
+	}
+
+	return reservation, nil
+}
+
+func (g *bruteForceGuard) Release(ctx context.Context, reservations ...*AttemptReservation) {
+	for _, reservation := range reservations {
+		if reservation == nil {
+			continue
+		}
+		for i, key := range reservation.keys {
+			if err := g.attemptRepo.Release(ctx, key, reservation.failures[i]); err.IsErr() {
+				log.Printf("Brute-force guard: failed to release %s/%s: %v", key.Scope, key.Key, err)
+			}
+		}
+		reservation.keys, reservation.failures = nil, nil
+	}
+}
+
+func (g *bruteForceGuard) tooManyRequests(ctx context.Context, key domain.AttemptKey) *utils.ReturnStatus {
+	remaining, err := g.attemptRepo.LockRemaining(ctx, []domain.AttemptKey{key})
+	if err.IsErr() {
+		return err
+	}
+
+	// Khóa có thể vừa hết hạn giữa hai câu lệnh, vẫn báo client thử lại sau ít nhất 1 giây.
+	return utils.ResponseArgs(utils.ErrCodeTooManyRequests, gin.H{
+		"retryAfter": max(int(math.Ceil(remaining.Seconds())), 1),
+	})
+}
+
+func (g *bruteForceGuard) Succeed(ctx context.Context, keys ...domain.AttemptKey) {
+	for _, key := range keys {
+		if err := g.attemptRepo.Reset(ctx, key); err.IsErr() {
+			log.Printf("Brute-force guard: failed to reset %s/%s: %v", key.Scope, key.Key, err)
+		}
+	}
+}
+
+func (g *bruteForceGuard) Purge(ctx context.Context) (int64, *utils.ReturnStatus) {
+	var longest time.Duration
+	for _, policy := range attemptPolicies {
+		longest = max(longest, policy.Window+policy.MaxLockout)
+	}
+
+	return g.attemptRepo.DeleteStale(ctx, longest)
+}
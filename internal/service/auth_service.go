@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"log"
 	"strings"
 
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
@@ -19,18 +22,76 @@ import (
 type authService struct {
 	userRepo     repository.UserRepository
 	authRepo     repository.AuthRepository
+	webAuthnRepo repository.WebAuthnRepository
+	registration RegistrationService
 	tokenService jwt.TokenService
+	guard        BruteForceGuard
+	policy       *config.SystemPolicy
+	loginHistory repository.LoginHistoryRepository
 }
 
-func NewAuthService(userRepo repository.UserRepository, authRepo repository.AuthRepository, tokenService jwt.TokenService) AuthService {
+func NewAuthService(userRepo repository.UserRepository, authRepo repository.AuthRepository, webAuthnRepo repository.WebAuthnRepository, registration RegistrationService, tokenService jwt.TokenService, guard BruteForceGuard, policy *config.SystemPolicy, loginHistory repository.LoginHistoryRepository) AuthService {
 	return &authService{
 		userRepo:     userRepo,
 		authRepo:     authRepo,
+		webAuthnRepo: webAuthnRepo,
+		registration: registration,
 		tokenService: tokenService,
+		guard:        guard,
+		policy:       policy,
+		loginHistory: loginHistory,
 	}
 }
 
-func (us *authService) CreateUser(username, password, email string) (*domain.User, *utils.ReturnStatus) {
+// RecordLogin ghi một lần đăng nhập thành công vào lịch sử; lỗi chỉ được log để không chặn đăng nhập.
+func (us *authService) RecordLogin(ctx context.Context, userID, method, clientIP, userAgent string) {
+	record := &domain.LoginRecord{UserId: userID, Method: method, IP: clientIP, UserAgent: userAgent}
+	if err := us.loginHistory.Record(ctx, record); err != nil {
+		log.Printf("Failed to record login for user %s: %v", userID, err)
+	}
+}
+
+func (us *authService) CreateUser(ctx context.Context, username, password, email, inviteToken string) (*domain.User, *utils.ReturnStatus) {
+	username = strings.TrimSpace(username)
+	email = utils.NormalizeString(email)
+
+	// Kiểm tra password trước khi giữ chỗ mã mời
+	if err := checkPassword(us.policy, "password", password); err != nil {
+		return nil, err
+	}
+
+	ticket, err := us.registration.Admit(ctx, email, inviteToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := us.createUser(username, password, email, ticket.EmailVerified)
+	if err != nil {
+		us.registration.Release(ctx, ticket)
+		return nil, err
+	}
+
+	us.registration.Complete(ctx, ticket, user)
+	return user, nil
+}
+
+func (us *authService) createUser(username, password, email string, emailVerified bool) (*domain.User, *utils.ReturnStatus) {
+	return createAccount(us.userRepo, us.authRepo, username, password, email, "user", emailVerified)
+}
+
+// createAccount kiểm tra trùng email/username rồi lưu user với password đã hash. Dùng chung cho
+// đăng ký và tạo admin từ CLI; password phải được kiểm tra theo policy trước đó.
+func createAccount(userRepo repository.UserRepository, authRepo repository.AuthRepository, username, password, email, role string, emailVerified bool) (*domain.User, *utils.ReturnStatus) {
+	if taken, err := userRepo.EmailExists(email); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+	}
+	if taken, err := userRepo.UsernameExists(username); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "username"})
+	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -40,24 +101,31 @@ func (us *authService) CreateUser(username, password, email string) (*domain.Use
 	if err != nil {
 		return nil, utils.ResponseMsg(utils.ErrCodeInternal, "failed to create UserID")
 	}
-	//TODO: add username and email uniqueness check
 	user := &domain.User{
-		Id:         hashedUserID.String(),
-		Username:   username,
-		Password:   string(hashedPassword),
-		Email:      email,
-		Role:       "user",
-		EnableTOTP: false,
-		SecretTOTP: "",
-	}
-	return us.authRepo.Create(user)
+		Id:            hashedUserID.String(),
+		Username:      username,
+		Password:      string(hashedPassword),
+		Email:         email,
+		Role:          role,
+		EnableTOTP:    false,
+		SecretTOTP:    "",
+		EmailVerified: emailVerified,
+	}
+	return authRepo.Create(user)
 }
 
-func (as *authService) Login(email, password string) (*domain.User, string, *utils.ReturnStatus) {
+func (as *authService) Login(ctx context.Context, email, password, clientIP string) (*domain.User, string, *utils.ReturnStatus) {
 	email = utils.NormalizeString(email)
-	user := &domain.User{}
-	err := as.userRepo.FindByEmail(email, user)
+
+	// Đếm cả email không tồn tại để không lộ tài khoản nào có thật. Lần thử được tính là sai ngay từ
+	// đầu và chỉ hoàn lại khi password đúng.
+	reservation, err := as.guard.Reserve(ctx, domain.IPAttempt(clientIP), domain.AccountAttempt(email))
 	if err != nil {
+		return nil, "", err
+	}
+
+	user := &domain.User{}
+	if err := as.userRepo.FindByEmail(email, user); err != nil {
 		fmt.Println("Login failed: User not found")
 		return nil, "", utils.Response(utils.ErrCodeLoginInvalid)
 	}
@@ -65,8 +133,24 @@ func (as *authService) Login(email, password string) (*domain.User, string, *uti
 	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
 		return nil, "", utils.Response(utils.ErrCodeLoginInvalid)
 	}
+	as.guard.Release(ctx, reservation)
+
+	// Kiểm tra sau password để không lộ trạng thái tài khoản cho người không biết password
+	if !user.EmailVerified {
+		return nil, "", utils.Response(utils.ErrCodeEmailNotVerified)
+	}
+	if err := checkActive(user); err != nil {
+		return nil, "", err
+	}
+
+	// Có authenticator đã đăng ký -> cũng yêu cầu bước 2 như TOTP
+	passkeys, countErr := as.webAuthnRepo.CountByUser(ctx, user.Id)
+	if countErr != nil {
+		return nil, "", countErr
+	}
+	user.EnableWebAuthn = passkeys > 0
 
-	if user.EnableTOTP {
+	if user.EnableTOTP || user.EnableWebAuthn {
 		cid, err := uuid.NewUUID()
 		if err != nil {
 			return nil, "", utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate CID")
@@ -81,14 +165,22 @@ func (as *authService) Login(email, password string) (*domain.User, string, *uti
 	accessToken, gen_err := as.tokenService.GenerateAccessToken(*user)
 
 	if gen_err != nil {
-		fmt.Println("*utils.ReturnStatus generating access token:", err)
+		fmt.Println("*utils.ReturnStatus generating access token:", gen_err)
 		return nil, "", utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("Failed to generate access token: %s", gen_err.Error()))
 	}
 
+	// Chỉ reset bộ đếm khi đăng nhập hoàn tất: nếu reset ngay sau password, người biết password có thể
+	// xóa lockout của bước 2 bằng cách đăng nhập lại giữa các lần đoán mã.
+	as.guard.Succeed(ctx, domain.AccountAttempt(email))
 	return user, accessToken, nil
 
 }
-func (as *authService) LoginTOTP(cid, totpCode string) (*domain.User, string, *utils.ReturnStatus) {
+func (as *authService) LoginTOTP(ctx context.Context, cid, totpCode, clientIP string) (*domain.User, string, *utils.ReturnStatus) {
+	ipReservation, err := as.guard.Reserve(ctx, domain.IPAttempt(clientIP))
+	if err != nil {
+		return nil, "", err
+	}
+
 	// Find session
 	sess := &domain.UsersLoginSession{}
 	if err := as.userRepo.FindByCId(cid, sess); err != nil {
@@ -98,46 +190,83 @@ func (as *authService) LoginTOTP(cid, totpCode string) (*domain.User, string, *u
 	// Find user
 	user := &domain.User{}
 	if err := as.userRepo.FindById(sess.Id, user); err != nil {
+		as.guard.Release(ctx, ipReservation)
 		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Invalid ID")
 	}
 
-	// Validate TOTP
-	if !totp.Validate(totpCode, user.SecretTOTP) {
-		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Invalid or expired TOTP code")
+	// Mã TOTP sai được tính chung vào bộ đếm của tài khoản với password sai.
+	accountAttempt := domain.AccountAttempt(user.Email)
+	accountReservation, err := as.guard.Reserve(ctx, accountAttempt)
+	if err != nil {
+		as.guard.Release(ctx, ipReservation)
+		return nil, "", err
 	}
 
-	// Parse UUID & check expiration
-	CID, err := uuid.Parse(cid)
-	if err != nil {
-		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Invalid CID format")
+	// Tài khoản chỉ có passkey (hoặc vừa bị admin reset TOTP) có secret rỗng, mà mã của secret rỗng
+	// thì ai cũng tính được -> chỉ chấp nhận mã khi TOTP thực sự được bật.
+	if !user.EnableTOTP || user.SecretTOTP == "" {
+		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "TOTP is not enabled for this account")
 	}
 
-	ts := CID.Time()
-	now, _, err := uuid.GetTime()
-	if err != nil {
-		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Failed to get current time")
+	// Validate TOTP
+	if !totp.Validate(totpCode, user.SecretTOTP) {
+		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Invalid or expired TOTP code")
 	}
+	as.guard.Release(ctx, ipReservation, accountReservation)
 
 	// Always delete timestamp first
 	if err := as.userRepo.DeleteTimestamp(user.Id); err != nil {
 		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Delete timestamp failed")
 	}
 
-	// Check expiration (5 minutes)
-	if int64(now-ts) > 300*10_000_000 {
-		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "CID has expired")
+	if err := checkCIDExpiry(cid); err != nil {
+		return nil, "", err
+	}
+
+	// Admin có thể đã khóa tài khoản giữa hai bước đăng nhập
+	if err := checkActive(user); err != nil {
+		return nil, "", err
 	}
 
 	// Generate access token
-	accessToken, err := as.tokenService.GenerateAccessToken(*user)
-	if err != nil {
+	accessToken, genErr := as.tokenService.GenerateAccessToken(*user)
+	if genErr != nil {
 		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized,
-			fmt.Sprintf("Failed to generate access token: %s", err))
+			fmt.Sprintf("Failed to generate access token: %s", genErr))
 	}
 
+	as.guard.Succeed(ctx, accountAttempt)
 	return user, accessToken, nil
 }
 
+// checkActive chặn cấp token cho tài khoản bị admin tạm khóa. Gọi ở mọi đường đăng nhập:
+// JWT cũ đã bị thu hồi lúc khóa nên chỉ cần chặn token mới.
+func checkActive(user *domain.User) *utils.ReturnStatus {
+	if user.Suspended {
+		return utils.Response(utils.ErrCodeAccountSuspended)
+	}
+	return nil
+}
+
+// checkCIDExpiry: CID là UUID v1 nên thời điểm tạo nằm ngay trong CID, hết hạn sau 5 phút.
+func checkCIDExpiry(cid string) *utils.ReturnStatus {
+	CID, err := uuid.Parse(cid)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeUnauthorized, "Invalid CID format")
+	}
+
+	ts := CID.Time()
+	now, _, err := uuid.GetTime()
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeUnauthorized, "Failed to get current time")
+	}
+
+	if int64(now-ts) > 300*10_000_000 {
+		return utils.ResponseMsg(utils.ErrCodeUnauthorized, "CID has expired")
+	}
+
+	return nil
+}
 
 func (as *authService) Logout(ctx *gin.Context) *utils.ReturnStatus {
 	authHeader := ctx.GetHeader("Authorization")
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/signer"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+const (
+	exportTTL        = 24 * time.Hour // thời hạn của link tải archive
+	exportStaleAfter = time.Hour      // job pending/running lâu hơn coi như bị bỏ dở (vd. server restart)
+	exportPageSize   = 100
+)
+
+type exportService struct {
+	cfg          *config.Config
+	repo         repository.ExportRepository
+	userRepo     repository.UserRepository
+	fileRepo     repository.FileRepository
+	sharedRepo   repository.SharedRepository
+	loginHistory repository.LoginHistoryRepository
+	storage      storage.Storage
+	signer       signer.URLSigner
+}
+
+func NewExportService(cfg *config.Config, repo repository.ExportRepository, userRepo repository.UserRepository, fileRepo repository.FileRepository, sharedRepo repository.SharedRepository, loginHistory repository.LoginHistoryRepository, storage storage.Storage, signer signer.URLSigner) ExportService {
+	return &exportService{
+		cfg:          cfg,
+		repo:         repo,
+		userRepo:     userRepo,
+		fileRepo:     fileRepo,
+		sharedRepo:   sharedRepo,
+		loginHistory: loginHistory,
+		storage:      storage,
+		signer:       signer,
+	}
+}
+
+// exportObjectName là key của archive trong storage.
+func exportObjectName(jobID string) string {
+	return "exports/" + jobID + ".zip"
+}
+
+func exportURLParts(jobID string, userID string, expiresAt int64) []string {
+	return []string{"export", jobID, userID, strconv.FormatInt(expiresAt, 10)}
+}
+
+// StartExport tạo job mới và build archive ở goroutine riêng. Nếu user đã có job đang chạy thì trả về job đó.
+func (s *exportService) StartExport(ctx context.Context, userID string) (*domain.ExportJob, *utils.ReturnStatus) {
+	active, err := s.repo.FindActive(ctx, userID, time.Now().Add(-exportStaleAfter))
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return active, nil
+	}
+
+	job := &domain.ExportJob{UserId: userID, Status: domain.EXPORT_PENDING}
+	if err := s.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Không dùng ctx của request: job tiếp tục chạy sau khi response đã trả về.
+	go s.run(context.Background(), *job)
+
+	return job, nil
+}
+
+func (s *exportService) GetExport(ctx context.Context, userID string, jobID string) (*domain.ExportJob, string, *utils.ReturnStatus) {
+	job, err := s.repo.Find(ctx, jobID)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.UserId != userID {
+		return nil, "", utils.Response(utils.ErrCodeExportNotFound)
+	}
+
+	if job.Status != domain.EXPORT_READY {
+		return job, "", nil
+	}
+	if job.ExpiresAt == nil || time.Now().After(*job.ExpiresAt) {
+		return nil, "", utils.Response(utils.ErrCodeExportNotFound)
+	}
+
+	// Link hết hạn cùng lúc với archive nên có thể cấp lại nhiều lần.
+	expiresAt := job.ExpiresAt.Unix()
+	query := url.Values{}
+	query.Set("exp", strconv.FormatInt(expiresAt, 10))
+	query.Set("sig", s.signer.Sign(exportURLParts(job.Id, job.UserId, expiresAt)...))
+
+	return job, s.cfg.PublicURL("user/export/"+job.Id+"/download") + "?" + query.Encode(), nil
+}
+
+// DownloadExport trả về reader của archive (*os.File, người gọi phải đóng qua io.Closer).
+func (s *exportService) DownloadExport(ctx context.Context, jobID string, query *dto.ExportDownloadQuery) (*domain.ExportJob, io.Reader, *utils.ReturnStatus) {
+	job, err := s.repo.Find(ctx, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !s.signer.Verify(query.Signature, exportURLParts(job.Id, job.UserId, query.ExpiresAt)...) {
+		return nil, nil, utils.Response(utils.ErrCodeSignedURLInvalid)
+	}
+	if time.Now().Unix() > query.ExpiresAt {
+		return nil, nil, utils.Response(utils.ErrCodeSignedURLExpired)
+	}
+	if job.Status != domain.EXPORT_READY {
+		return nil, nil, utils.Response(utils.ErrCodeExportNotReady)
+	}
+
+	reader, err := s.storage.GetFile(exportObjectName(job.Id))
+	if err != nil {
+		return nil, nil, utils.Response(utils.ErrCodeExportNotFound)
+	}
+
+	return job, reader, nil
+}
+
+// DeleteUserExports xóa mọi archive của user, gọi khi user xóa tài khoản.
+func (s *exportService) DeleteUserExports(ctx context.Context, userID string) {
+	jobs, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		log.Printf("Export: failed to list exports of user %s: %v", userID, err)
+		return
+	}
+
+	for _, job := range jobs {
+		s.deleteJob(ctx, job)
+	}
+}
+
+// PurgeExpired xóa archive đã hết hạn tải và các job lỗi/bị bỏ dở, trả về số job đã xóa.
+func (s *exportService) PurgeExpired(ctx context.Context) (int, *utils.ReturnStatus) {
+	now := time.Now()
+	jobs, err := s.repo.ListExpired(ctx, now, now.Add(-exportStaleAfter))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, job := range jobs {
+		if s.deleteJob(ctx, job) {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *exportService) deleteJob(ctx context.Context, job domain.ExportJob) bool {
+	if job.Status == domain.EXPORT_READY {
+		if err := s.storage.DeleteFile(exportObjectName(job.Id)); err.IsErr() && err.Error() != utils.ErrCodeFileNotFound {
+			log.Printf("Export: failed to delete archive %s: %v", job.Id, err)
+			return false
+		}
+	}
+	if err := s.repo.Delete(ctx, job.Id); err != nil {
+		log.Printf("Export: failed to delete job %s: %v", job.Id, err)
+		return false
+	}
+	return true
+}
+
+// run build archive và stream thẳng vào storage qua io.Pipe, không giữ cả archive trong bộ nhớ.
+func (s *exportService) run(ctx context.Context, job domain.ExportJob) {
+	if err := s.repo.MarkRunning(ctx, job.Id); err != nil {
+		log.Printf("Export: failed to start job %s: %v", job.Id, err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.writeArchive(ctx, job.UserId, pw))
+	}()
+
+	size, err := s.storage.SaveStream(exportObjectName(job.Id), pr)
+	// Giải phóng goroutine ghi nếu storage dừng đọc giữa chừng.
+	pr.Close()
+
+	if err != nil {
+		log.Printf("Export: job %s failed: %v", job.Id, err)
+		if err := s.repo.MarkFailed(ctx, job.Id, "Failed to build the export archive"); err != nil {
+			log.Printf("Export: failed to mark job %s as failed: %v", job.Id, err)
+		}
+		return
+	}
+
+	expiresAt := time.Now().Add(exportTTL).Truncate(time.Second)
+	if err := s.repo.MarkReady(ctx, job.Id, size, expiresAt); err != nil {
+		log.Printf("Export: failed to mark job %s as ready: %v", job.Id, err)
+	}
+}
+
+func exportErr(step string, err *utils.ReturnStatus) error {
+	return fmt.Errorf("%s: %s", step, err.Error())
+}
+
+func writeJSONEntry(zw *zip.Writer, name string, v any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type exportedFile struct {
+	Id            string    `json:"id"`
+	FileName      string    `json:"fileName"`
+	Description   *string   `json:"description"`
+	Tags          []string  `json:"tags"`
+	Path          string    `json:"path,omitempty"` // vị trí nội dung trong archive, trống nếu không còn trong storage
+	MimeType      string    `json:"mimeType"`
+	FileSize      int64     `json:"fileSize"`
+	ShareLink     string    `json:"shareLink"`
+	IsPublic      bool      `json:"isPublic"`
+	HasPassword   bool      `json:"hasPassword"`
+	AvailableFrom time.Time `json:"availableFrom"`
+	AvailableTo   time.Time `json:"availableTo"`
+	MaxDownloads  *int      `json:"maxDownloads"`
+	DownloadCount int64     `json:"downloadCount"`
+	Status        string    `json:"status"`
+	SharedWith    []string  `json:"sharedWith"`
+	SharedGroups  []string  `json:"sharedWithGroups"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type exportedShare struct {
+	FileId       string   `json:"fileId"`
+	FileName     string   `json:"fileName"`
+	SharedWith   []string `json:"sharedWith"`
+	SharedGroups []string `json:"sharedWithGroups"`
+}
+
+type exportedDownload struct {
+	FileId       string    `json:"fileId"`
+	FileName     string    `json:"fileName"`
+	Downloader   *string   `json:"downloader"` // email, nil = tải ẩn danh
+	DownloadedAt time.Time `json:"downloadedAt"`
+}
+
+// archiveFileName chỉ giữ phần tên file để tên do user đặt không thoát ra ngoài thư mục của file.
+func archiveFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+func (s *exportService) writeArchive(ctx context.Context, userID string, w io.Writer) error {
+	user := &domain.User{}
+	if err := s.userRepo.FindById(userID, user); err != nil {
+		return exportErr("load profile", err)
+	}
+
+	zw := zip.NewWriter(w)
+
+	profile := domain.UserResponse{
+		Id:         user.Id,
+		Username:   user.Username,
+		Email:      user.Email,
+		Role:       user.Role,
+		EnableTOTP: user.EnableTOTP,
+	}
+	if err := writeJSONEntry(zw, "profile.json", profile); err != nil {
+		return err
+	}
+
+	manifest := []exportedFile{}
+	sharedByMe := []exportedShare{}
+	downloadsOfMyFiles := []exportedDownload{}
+
+	page := domain.PageRequest{SortBy: domain.SORT_CREATED_AT, Order: domain.ORDER_ASC, Limit: exportPageSize}
+	for {
+		files, next, err := s.fileRepo.GetMyFiles(ctx, userID, domain.ListFileParams{
+			Status: "all",
+			Page:   page,
+		})
+		if err != nil {
+			return exportErr("list files", err)
+		}
+
+		for _, f := range files {
+			file, err := s.fileRepo.GetFileByID(ctx, f.Id)
+			if err != nil {
+				return exportErr("load file "+f.Id, err)
+			}
+
+			entry := exportedFile{
+				Id:            file.Id,
+				FileName:      file.FileName,
+				Description:   file.Description,
+				Tags:          file.Tags,
+				MimeType:      file.MimeType,
+				FileSize:      file.FileSize,
+				ShareLink:     s.cfg.PublicURL("files/" + url.PathEscape(file.ShareToken)),
+				IsPublic:      file.IsPublic,
+				HasPassword:   file.HasPassword,
+				AvailableFrom: file.AvailableFrom,
+				AvailableTo:   file.AvailableTo,
+				MaxDownloads:  file.MaxDownloads,
+				DownloadCount: file.DownloadCount,
+				Status:        string(f.Status),
+				SharedWith:    []string{},
+				CreatedAt:     file.CreatedAt,
+			}
+
+			if reader, err := s.storage.GetFile(file.Id); err != nil {
+				log.Printf("Export: content of file %s is missing from storage: %v", file.Id, err)
+			} else {
+				entry.Path = "files/" + file.Id + "/" + archiveFileName(file.FileName)
+				dst, zerr := zw.Create(entry.Path)
+				if zerr == nil {
+					_, zerr = io.Copy(dst, reader)
+				}
+				if closer, ok := reader.(io.Closer); ok {
+					closer.Close()
+				}
+				if zerr != nil {
+					return zerr
+				}
+			}
+
+			shared, err := s.sharedRepo.GetUsersSharedWith(ctx, file.Id)
+			if err != nil {
+				return exportErr("list shares of "+file.Id, err)
+			}
+			entry.SharedWith = append(entry.SharedWith, shared.Emails...)
+			if entry.SharedGroups, err = s.sharedRepo.GetGroupsSharedWith(ctx, file.Id); err != nil {
+				return exportErr("list group shares of "+file.Id, err)
+			}
+			if len(entry.SharedWith) > 0 || len(entry.SharedGroups) > 0 {
+				sharedByMe = append(sharedByMe, exportedShare{FileId: file.Id, FileName: file.FileName, SharedWith: entry.SharedWith, SharedGroups: entry.SharedGroups})
+			}
+
+			history, _, err := s.fileRepo.GetFileDownloadHistory(ctx, file.Id, domain.PageRequest{SortBy: domain.SORT_DOWNLOADED_AT, Order: domain.ORDER_ASC})
+			if err != nil {
+				return exportErr("load download history of "+file.Id, err)
+			}
+			for _, d := range history.History {
+				download := exportedDownload{FileId: file.Id, FileName: file.FileName, DownloadedAt: d.DownloadedAt}
+				if d.Downloader != nil {
+					download.Downloader = &d.Downloader.Email
+				}
+				downloadsOfMyFiles = append(downloadsOfMyFiles, download)
+			}
+
+			manifest = append(manifest, entry)
+		}
+
+		if next == nil {
+			break
+		}
+		page.After = next
+	}
+
+	if err := writeJSONEntry(zw, "files/manifest.json", manifest); err != nil {
+		return err
+	}
+
+	sharedWithMe, err := s.sharedRepo.GetFilesSharedWithUser(ctx, userID)
+	if err != nil {
+		return exportErr("list files shared with user", err)
+	}
+	if err := writeJSONEntry(zw, "shares.json", map[string]any{
+		"sharedByMe":   sharedByMe,
+		"sharedWithMe": sharedWithMe,
+	}); err != nil {
+		return err
+	}
+
+	myDownloads, err := s.fileRepo.GetUserDownloads(ctx, userID)
+	if err != nil {
+		return exportErr("list downloads", err)
+	}
+	if err := writeJSONEntry(zw, "downloads.json", map[string]any{
+		"downloadsOfMyFiles": downloadsOfMyFiles,
+		"myDownloads":        myDownloads,
+	}); err != nil {
+		return err
+	}
+
+	logins, err := s.loginHistory.ListByUser(ctx, userID)
+	if err != nil {
+		return exportErr("list login history", err)
+	}
+	if err := writeJSONEntry(zw, "logins.json", logins); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
@@ -1,18 +1,27 @@
 package service
 
 import (
+	"archive/zip"
 	"context"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
+	"net/url"
+	"path"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"time"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/signer"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
 
@@ -27,19 +36,71 @@ type fileService struct {
 	fileRepo   repository.FileRepository
 	sharedRepo repository.SharedRepository
 	userRepo   repository.UserRepository // Cần để tìm User ID từ Email
+	groupRepo  repository.GroupRepository
+	folderRepo repository.FolderRepository
+	txManager  repository.TxManager
 	storage    storage.Storage
+	signer     signer.URLSigner
+	guard      BruteForceGuard
 }
 
-func NewFileService(cfg *config.Config, fr repository.FileRepository, sr repository.SharedRepository, ur repository.UserRepository, s storage.Storage) FileService {
+func NewFileService(cfg *config.Config, fr repository.FileRepository, sr repository.SharedRepository, ur repository.UserRepository, gr repository.GroupRepository, fo repository.FolderRepository, tm repository.TxManager, s storage.Storage, us signer.URLSigner, g BruteForceGuard) FileService {
 	return &fileService{
 		cfg:        cfg,
 		fileRepo:   fr,
 		sharedRepo: sr,
 		userRepo:   ur,
+		groupRepo:  gr,
+		folderRepo: fo,
+		txManager:  tm,
 		storage:    s,
+		signer:     us,
+		guard:      g,
 	}
 }
 
+const (
+	maxShareTokenAttempts = 5
+	defaultSignedURLTTL   = 5 * time.Minute
+	maxFileTags           = 20
+	maxFileTagLength      = 50
+)
+
+// searchTermRegex tách từ khóa tìm kiếm thành các từ, bỏ mọi ký tự có nghĩa trong cú pháp tsquery.
+var searchTermRegex = regexp.MustCompile(`[\p{L}\p{M}\p{N}]+`)
+
+// Các slug trùng với route tĩnh dưới /files sẽ không bao giờ truy cập được.
+var reservedShareSlugs = []string{"upload", "available", "my", "info", "stats", "download-history", "signed", "archive", "search"}
+
+func validateShareSlug(slug string) *utils.ReturnStatus {
+	// Slug dạng UUID sẽ bị hiểu nhầm thành file ID ở GET /files/:shareToken.
+	if uuid.Validate(slug) == nil || slices.Contains(reservedShareSlugs, slug) {
+		return utils.Response(utils.ErrCodeShareSlugInvalid)
+	}
+	return nil
+}
+
+// newShareToken sinh share token theo độ dài và bảng ký tự trong SystemPolicy.
+func (s *fileService) newShareToken() (string, *utils.ReturnStatus) {
+	policy := s.cfg.Policy
+
+	alphabet, ok := utils.TokenAlphabets[policy.ShareTokenAlphabet]
+	if !ok {
+		alphabet = utils.TokenAlphabets[utils.DefaultTokenAlphabet]
+	}
+
+	token, err := utils.GenerateSecureString(policy.ShareTokenLength, alphabet)
+	if err != nil {
+		return "", utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("failed to generate share token: %s", err))
+	}
+
+	return token, nil
+}
+
+func (s *fileService) shareLink(token string) string {
+	return s.cfg.PublicURL("files/" + url.PathEscape(token))
+}
+
 // Hàm tính toán thời gian hiệu lực
 func (s *fileService) calculateValidityPeriod(req *dto.UploadRequest) (time.Time, time.Time, int, *utils.ReturnStatus) {
 	now := time.Now().UTC()
@@ -86,6 +147,34 @@ func (s *fileService) calculateValidityPeriod(req *dto.UploadRequest) (time.Time
 	return availableFrom, availableTo, validityDays, nil
 }
 
+// splitSharedWith tách sharedWith thành email (đã chuẩn hóa như lúc đăng ký) và id nhóm (UUID).
+// Chỉ được chia sẻ cho nhóm mà người chia sẻ đang là thành viên.
+func splitSharedWith(ctx context.Context, groupRepo repository.GroupRepository, entries []string, ownerID *string) ([]string, []string, *utils.ReturnStatus) {
+	var emails, groupIDs []string
+	for _, entry := range entries {
+		if uuid.Validate(entry) != nil {
+			emails = append(emails, utils.NormalizeString(entry))
+			continue
+		}
+
+		if ownerID == nil {
+			return nil, nil, utils.Response(utils.ErrCodeFilePrivateNeedsAuth)
+		}
+		role, err := groupRepo.FindRole(ctx, entry, *ownerID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if role == "" {
+			return nil, nil, utils.ResponseArgs(utils.ErrCodeShareGroupInvalid, gin.H{"groupId": entry})
+		}
+		if !slices.Contains(groupIDs, entry) {
+			groupIDs = append(groupIDs, entry)
+		}
+	}
+
+	return emails, groupIDs, nil
+}
+
 func (s *fileService) UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, req *dto.UploadRequest, ownerID *string) (*domain.File, *utils.ReturnStatus) {
 	// Kiểm tra kích thước file (Sử dụng MaxFileSizeMB từ Policy)
 	if fileHeader.Size > int64(s.cfg.Policy.MaxFileSizeMB)*1024*1024 {
@@ -98,12 +187,40 @@ func (s *fileService) UploadFile(ctx context.Context, fileHeader *multipart.File
 		return nil, err
 	}
 
+	shareEmails, shareGroups, err := splitSharedWith(ctx, s.groupRepo, req.SharedWith, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.FolderId != nil {
+		if err := s.checkFolderOwner(ctx, *req.FolderId, ownerID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 2. Chuẩn bị File Metadata
 	fileUUID := uuid.New().String()
-	shareToken := utils.GenerateRandomString(16) // Hàm tạo token ngẫu nhiên 16 ký tự
+
+	var shareToken string
+	if req.Slug != nil {
+		if err := validateShareSlug(*req.Slug); err != nil {
+			return nil, err
+		}
+		shareToken = *req.Slug
+	} else if shareToken, err = s.newShareToken(); err != nil {
+		return nil, err
+	}
 
 	var passwordHash *string
 	if req.Password != nil && *req.Password != "" {
+		if err := checkPassword(s.cfg.Policy, "password", *req.Password); err != nil {
+			return nil, err
+		}
 		hashed, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return nil, utils.ResponseMsg(utils.ErrCodeInternal, err.Error())
@@ -112,6 +229,11 @@ func (s *fileService) UploadFile(ctx context.Context, fileHeader *multipart.File
 		passwordHash = &hashStr
 	}
 
+	var maxDownloads *int
+	if req.MaxDownloads != nil && *req.MaxDownloads > 0 {
+		maxDownloads = req.MaxDownloads
+	}
+
 	storageFileName := fileUUID
 	newFile := &domain.File{
 		Id:            fileUUID,
@@ -128,6 +250,11 @@ func (s *fileService) UploadFile(ctx context.Context, fileHeader *multipart.File
 		AvailableFrom: availableFrom,
 		AvailableTo:   availableTo,
 		ValidityDays:  validityDays,
+		MaxDownloads:  maxDownloads,
+		DeleteOnLimit: req.DeleteOnLimit && maxDownloads != nil,
+		FolderId:      req.FolderId,
+		Description:   normalizeDescription(req.Description),
+		Tags:          tags,
 		CreatedAt:     time.Now().UTC(),
 	}
 
@@ -137,25 +264,254 @@ func (s *fileService) UploadFile(ctx context.Context, fileHeader *multipart.File
 		return nil, err
 	}
 
-	// 4. Lưu Metadata vào DB
-	savedFile, err := s.fileRepo.CreateFile(ctx, newFile)
+	// 4. Lưu metadata, filestat và danh sách chia sẻ trong cùng một transaction
+	savedFile, err := s.createFile(ctx, newFile, shareEmails, shareGroups)
+	// Token ngẫu nhiên hiếm khi trùng, chỉ sinh lại khi không phải slug do user chọn.
+	for attempt := 1; err.IsErr() && err.Error() == utils.ErrCodeShareTokenTaken && req.Slug == nil && attempt < maxShareTokenAttempts; attempt++ {
+		if newFile.ShareToken, err = s.newShareToken(); err != nil {
+			break
+		}
+		savedFile, err = s.createFile(ctx, newFile, shareEmails, shareGroups)
+	}
 	if err.IsErr() {
-		// QUAN TRỌNG: Nếu lưu DB lỗi, phải xóa file đã lưu vật lý!
+		// QUAN TRỌNG: transaction đã rollback, phải xóa file đã lưu vật lý!
 		s.storage.DeleteFile(newFile.StorageName)
 		return nil, err
 	}
 
-	// 5. Xử lý SharedWith
-	if req.SharedWith != nil {
-		if err := s.sharedRepo.ShareFileWithUsers(ctx, savedFile.Id, req.SharedWith); err != nil {
+	savedFile.ShareLink = s.shareLink(savedFile.ShareToken)
+	if len(shareGroups) > 0 {
+		savedFile.SharedGroups = shareGroups
+	}
+
+	return savedFile, nil
+}
+
+// createFile ghi file và chia sẻ ban đầu như một đơn vị: lỗi ở bất kỳ bước nào thì không còn file dở dang.
+// Mỗi lần gọi là một transaction riêng vì lỗi trùng share token làm hỏng transaction đang mở.
+func (s *fileService) createFile(ctx context.Context, file *domain.File, shareEmails []string, shareGroups []string) (*domain.File, *utils.ReturnStatus) {
+	var saved *domain.File
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) *utils.ReturnStatus {
+		var err *utils.ReturnStatus
+		if saved, err = s.fileRepo.CreateFile(ctx, file); err != nil {
+			return err
+		}
+		if len(shareEmails) > 0 {
+			if saved.Shares, err = s.sharedRepo.ShareFileWithUsers(ctx, saved.Id, shareEmails); err != nil {
+				return err
+			}
+		}
+		if len(shareGroups) > 0 {
+			if err := s.sharedRepo.ShareFileWithGroups(ctx, saved.Id, shareGroups); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// checkFolderOwner: file chỉ được đặt vào thư mục của chính chủ file.
+func (s *fileService) checkFolderOwner(ctx context.Context, folderID string, ownerID *string) *utils.ReturnStatus {
+	if ownerID == nil {
+		return utils.Response(utils.ErrCodeUploadBearerRequired)
+	}
+
+	folder, err := s.folderRepo.Find(ctx, folderID)
+	if err != nil {
+		return err
+	}
+	if folder.OwnerId != *ownerID {
+		return utils.Response(utils.ErrCodeFolderNotFound)
+	}
+
+	return nil
+}
+
+// MoveFile chuyển file sang thư mục khác của chủ file, folderID nil = thư mục gốc.
+func (s *fileService) MoveFile(ctx context.Context, fileID string, userID string, folderID *string) (*domain.File, *utils.ReturnStatus) {
+	file, err := s.fileRepo.GetFileByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	requester := domain.User{}
+	if err := s.userRepo.FindById(userID, &requester); err != nil {
+		return nil, err
+	}
+	if requester.Role != "admin" && (file.OwnerId == nil || *file.OwnerId != userID) {
+		return nil, utils.Response(utils.ErrCodeCantAccessResource)
+	}
+
+	if folderID != nil {
+		if err := s.checkFolderOwner(ctx, *folderID, file.OwnerId); err != nil {
 			return nil, err
 		}
 	}
 
-	return savedFile, nil
+	if err := s.fileRepo.MoveToFolder(ctx, file.Id, folderID); err != nil {
+		return nil, err
+	}
+
+	file.FolderId = folderID
+	file.ShareLink = s.shareLink(file.ShareToken)
+	file.Status = file.StatusAt(time.Now())
+	return file, nil
 }
 
-func (s *fileService) GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) (interface{}, *utils.ReturnStatus) {
+// normalizeTags đưa tag về chữ thường, bỏ tag rỗng/trùng; "a, b" trong một field được tách thành hai tag.
+func normalizeTags(raw []string) ([]string, *utils.ReturnStatus) {
+	tags := []string{}
+	for _, entry := range raw {
+		for _, tag := range strings.Split(entry, ",") {
+			tag = utils.NormalizeString(tag)
+			if tag == "" || slices.Contains(tags, tag) {
+				continue
+			}
+			if utf8.RuneCountInString(tag) > maxFileTagLength {
+				return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("Tags can be at most %d characters", maxFileTagLength))
+			}
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(tags) > maxFileTags {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("A file can have at most %d tags", maxFileTags))
+	}
+
+	return tags, nil
+}
+
+// normalizeDescription: mô tả chỉ gồm khoảng trắng được lưu là NULL.
+func normalizeDescription(description *string) *string {
+	if description == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*description)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// searchQuery chuyển từ khóa người dùng nhập thành tsquery khớp tiền tố mọi từ: "bao cao" → "bao:* & cao:*".
+func searchQuery(q string) string {
+	terms := []string{}
+	for _, term := range searchTermRegex.FindAllString(strings.ToLower(q), -1) {
+		terms = append(terms, term+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// UpdateFileMetadata đổi mô tả và tag của file, chỉ owner hoặc admin.
+func (s *fileService) UpdateFileMetadata(ctx context.Context, fileID string, userID string, req *dto.UpdateFileMetadataRequest) (*domain.File, *utils.ReturnStatus) {
+	file, err := s.fileRepo.GetFileByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	requester := domain.User{}
+	if err := s.userRepo.FindById(userID, &requester); err != nil {
+		return nil, err
+	}
+	if requester.Role != "admin" && (file.OwnerId == nil || *file.OwnerId != userID) {
+		return nil, utils.Response(utils.ErrCodeCantAccessResource)
+	}
+
+	if req.Description != nil {
+		file.Description = normalizeDescription(req.Description)
+	}
+	if req.Tags != nil {
+		if file.Tags, err = normalizeTags(*req.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.fileRepo.UpdateMetadata(ctx, file.Id, file.Description, file.Tags); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (s *fileService) SearchFiles(ctx context.Context, userID string, query *dto.FileSearchQuery) ([]dto.FileSearchResult, *domain.Pagination, *utils.ReturnStatus) {
+	if query.MinSize != nil && query.MaxSize != nil && *query.MinSize > *query.MaxSize {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "minSize must not be greater than maxSize")
+	}
+	if query.From != nil && query.To != nil && query.From.After(*query.To) {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "from must not be after to")
+	}
+
+	tags, err := normalizeTags([]string{query.Tags})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := domain.FileSearchParams{
+		Query:    searchQuery(query.Q),
+		Scope:    query.Scope,
+		Tags:     tags,
+		MimeType: utils.NormalizeString(query.MimeType),
+		MinSize:  query.MinSize,
+		MaxSize:  query.MaxSize,
+		From:     query.From,
+		To:       query.To,
+		Owner:    utils.NormalizeString(query.Owner),
+	}
+
+	// Có từ khóa thì mặc định xếp theo độ liên quan, không thì theo thời gian upload.
+	defaultSort := domain.SORT_CREATED_AT
+	if params.Query != "" {
+		defaultSort = domain.SORT_RELEVANCE
+	}
+	if params.Page, err = pageRequest(query.PageQuery, domain.SearchSorts, defaultSort); err != nil {
+		return nil, nil, err
+	}
+
+	hits, next, err := s.fileRepo.SearchFiles(ctx, userID, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	results := []dto.FileSearchResult{}
+	for _, hit := range hits {
+		file := hit.File
+		results = append(results, dto.FileSearchResult{
+			Id:          file.Id,
+			FileName:    file.FileName,
+			Description: file.Description,
+			Tags:        file.Tags,
+			MimeType:    file.MimeType,
+			FileSize:    file.FileSize,
+			Owner:       ownerEmail(&file),
+			IsOwner:     file.OwnerId != nil && *file.OwnerId == userID,
+			HasPassword: file.HasPassword,
+			ShareToken:  file.ShareToken,
+			ShareLink:   s.shareLink(file.ShareToken),
+			Status:      string(file.StatusAt(now)),
+			CreatedAt:   file.CreatedAt,
+			Rank:        hit.Rank,
+		})
+	}
+
+	pagination := domain.NewPagination(params.Page, next)
+	return results, &pagination, nil
+}
+
+func (s *fileService) GetMyFiles(ctx context.Context, userID string, query *dto.ListMyFilesQuery) (interface{}, *utils.ReturnStatus) {
+	// "fileName" là tên cũ của sortBy=name
+	if query.SortBy == "fileName" {
+		query.SortBy = domain.SORT_NAME
+	}
+	page, err := pageRequest(query.PageQuery, domain.FileSorts, domain.SORT_CREATED_AT)
+	if err != nil {
+		return nil, err
+	}
+
 	// Lấy danh sách file của user đó
 	fileSummary, err := s.fileRepo.GetFileSummary(ctx, userID)
 	if err.IsErr() {
@@ -163,42 +519,35 @@ func (s *fileService) GetMyFiles(ctx context.Context, userID string, params doma
 		// Trong trường hợp này, ta sẽ trả về lỗi
 		return nil, err
 	}
-	totalFiles, err := s.fileRepo.GetTotalUserFiles(ctx, userID)
-	if err.IsErr() {
-		return nil, err
-	}
-	files, err := s.fileRepo.GetMyFiles(ctx, userID, params)
+	files, next, err := s.fileRepo.GetMyFiles(ctx, userID, domain.ListFileParams{
+		Status:   query.Status,
+		FolderId: query.FolderId,
+		Page:     page,
+	})
 	if err.IsErr() {
 		return nil, err
 	}
-	totalPages := 0
-	if params.Limit > 0 {
-		totalPages = (totalFiles + params.Limit - 1) / params.Limit
-	}
-
-	pagination := gin.H{
-		"currentPage": params.Page,
-		"totalPages":  totalPages,
-		"totalFiles":  totalFiles,
-		"limit":       params.Limit,
-	}
 
 	out := []gin.H{}
 
 	for _, f := range files {
 		out = append(out, gin.H{
-			"id":         f.Id,
-			"fileName":   f.FileName,
-			"shareToken": f.ShareToken,
-			"status":     f.Status,
-			"createdAt":  f.CreatedAt,
+			"id":          f.Id,
+			"fileName":    f.FileName,
+			"fileSize":    f.FileSize,
+			"shareToken":  f.ShareToken,
+			"shareLink":   s.shareLink(f.ShareToken),
+			"status":      f.Status,
+			"folderId":    f.FolderId,
+			"availableTo": f.AvailableTo,
+			"createdAt":   f.CreatedAt,
 		})
 	}
 
 	// 5. Trả về kết quả với dữ liệu thực tế
 	return gin.H{
 		"files":      out,
-		"pagination": pagination,  // Dữ liệu phân trang thực tế
+		"pagination": domain.NewPagination(page, next),
 		"summary":    fileSummary, // Dữ liệu summary thực tế
 	}, nil
 }
@@ -256,6 +605,7 @@ func (s *fileService) getFileInfo(ctx context.Context, id string, userID string,
 
 	now := time.Now()
 
+	file.ShareLink = s.shareLink(file.ShareToken)
 	file.Status = domain.FILE_ACTIVE
 
 	if now.Before(file.AvailableFrom) {
@@ -272,11 +622,13 @@ func (s *fileService) getFileInfo(ctx context.Context, id string, userID string,
 	}
 
 	isAdmin := requester.Role == "admin"
-	owner_ := domain.User{}
 	var owner *domain.User = nil
-	if file.OwnerId != nil {
-		if s.userRepo.FindById(*file.OwnerId, &owner_) == nil {
-			owner = &owner_
+	if file.Owner != nil {
+		owner = &domain.User{
+			Id:       file.Owner.Id,
+			Username: file.Owner.Username,
+			Email:    file.Owner.Email,
+			Role:     file.Owner.Role,
 		}
 	}
 
@@ -285,13 +637,26 @@ func (s *fileService) getFileInfo(ctx context.Context, id string, userID string,
 		return nil, nil, nil, err
 	}
 	if !isAdmin {
-		if verbose && *file.OwnerId != userID {
+		if verbose && (file.OwnerId == nil || *file.OwnerId != userID) {
 			return nil, nil, nil, utils.Response(utils.ErrCodeGetForbidden)
 		}
 
 		if !file.IsPublic && *file.OwnerId != userID {
 			if !slices.Contains(shareds.UserIds, userID) {
-				return nil, nil, nil, utils.Response(utils.ErrCodeGetForbidden)
+				// Thành viên nhóm được tính tại thời điểm truy cập, rời nhóm là mất quyền ngay.
+				viaGroup, err := s.sharedRepo.SharedViaGroup(ctx, file.Id, userID)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				viaFolder := false
+				if !viaGroup && file.FolderId != nil {
+					if viaFolder, err = s.sharedRepo.SharedViaFolder(ctx, file.Id, userID); err != nil {
+						return nil, nil, nil, err
+					}
+				}
+				if !viaGroup && !viaFolder {
+					return nil, nil, nil, utils.Response(utils.ErrCodeGetForbidden)
+				}
 			}
 		}
 
@@ -314,22 +679,19 @@ func (s *fileService) getFileInfo(ctx context.Context, id string, userID string,
 					},
 				)
 			}
+
+			if file.DownloadLimitReached() {
+				return nil, nil, nil, utils.Response(utils.ErrCodeDownloadLimitReached)
+			}
 		}
 
 	}
 
-	outShared := []string{}
-	for _, id := range shareds.UserIds {
-		sharedowner := domain.User{}
-		serr := s.userRepo.FindById(id, &sharedowner)
-		if serr != nil {
-			return nil, nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, "Failed to retrieve emails of shared users")
-		}
-
-		outShared = append(outShared, sharedowner.Email)
+	if file.SharedGroups, err = s.sharedRepo.GetGroupsSharedWith(ctx, file.Id); err != nil {
+		return nil, nil, nil, err
 	}
 
-	return file, owner, outShared, nil
+	return file, owner, shareds.Emails, nil
 }
 
 func (s *fileService) GetFileInfo(ctx context.Context, token string, userID string, verbose bool) (*domain.File, *domain.User, []string, *utils.ReturnStatus) {
@@ -340,88 +702,508 @@ func (s *fileService) GetFileInfoID(ctx context.Context, id string, userID strin
 	return s.getFileInfo(ctx, id, userID, false, verbose)
 }
 
-func (s *fileService) DownloadFile(ctx context.Context, token string, userID string, password string) (*domain.File, io.Reader, *utils.ReturnStatus) {
+func (s *fileService) DownloadFile(ctx context.Context, token string, userID string, password string, clientIP string) (*domain.File, io.Reader, *utils.ReturnStatus) {
 	fileInfo, _, _, err := s.getFileInfo(ctx, token, userID, true, false)
 
 	if err.IsErr() {
 		return nil, nil, err
 	}
 
-	if fileInfo.HasPassword {
-		if password == "" {
-			return nil, nil, utils.Response(utils.ErrCodeDownloadPasswordInvalid)
-		}
+	if err := s.checkFilePassword(ctx, fileInfo, password, clientIP); err != nil {
+		return nil, nil, err
+	}
 
-		if bcrypt.CompareHashAndPassword([]byte(*fileInfo.PasswordHash), []byte(password)) != nil {
-			return nil, nil, utils.Response(utils.ErrCodeDownloadPasswordInvalid)
-		}
+	return s.serveDownload(ctx, fileInfo, userID)
+}
+
+// checkFilePassword so khớp password của file, giới hạn số lần đoán sai theo IP và share token.
+func (s *fileService) checkFilePassword(ctx context.Context, file *domain.File, password string, clientIP string) *utils.ReturnStatus {
+	if !file.HasPassword {
+		return nil
 	}
 
-	fileReader, err := s.storage.GetFile(fileInfo.Id)
+	if password == "" {
+		return utils.Response(utils.ErrCodeDownloadPasswordInvalid)
+	}
+
+	reservation, err := s.guard.Reserve(ctx, domain.IPAttempt(clientIP), domain.ShareTokenAttempt(file.ShareToken))
+	if err != nil {
+		return err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(*file.PasswordHash), []byte(password)) != nil {
+		return utils.Response(utils.ErrCodeDownloadPasswordInvalid)
+	}
+
+	s.guard.Release(ctx, reservation)
+	s.guard.Succeed(ctx, domain.ShareTokenAttempt(file.ShareToken))
+
+	return nil
+}
+
+// serveDownload ghi nhận lượt tải rồi mở file; gọi sau khi đã qua mọi bước kiểm tra quyền.
+func (s *fileService) serveDownload(ctx context.Context, fileInfo *domain.File, userID string) (*domain.File, io.Reader, *utils.ReturnStatus) {
+	// Giữ slot trước khi đọc file để hai request không cùng lấy lượt cuối.
+	remaining, err := s.fileRepo.RegisterDownload(ctx, fileInfo.Id, userID)
 	if err.IsErr() {
 		return nil, nil, err
 	}
 
-	if err := s.fileRepo.RegisterDownload(ctx, fileInfo.Id, userID); err.IsErr() {
+	fileReader, err := s.storage.GetFile(fileInfo.Id)
+	if err.IsErr() {
 		return nil, nil, err
 	}
 
+	if remaining != nil && *remaining == 0 && fileInfo.DeleteOnLimit {
+		return s.burnAfterReading(ctx, fileInfo, fileReader)
+	}
+
 	return fileInfo, fileReader, nil
 }
 
-func (s *fileService) GetFileDownloadHistory(ctx context.Context, fileID string, userID string, pagenum, limit int) (*domain.FileDownloadHistory, *utils.ReturnStatus) {
-	file, err := s.fileRepo.GetFileByID(ctx, fileID)
+// signedURLParts là nội dung được ký cho một direct download URL. IP để trống khi URL không gắn IP.
+func signedURLParts(fileID string, version int, expiresAt int64, userID string, clientIP string) []string {
+	return []string{"download", fileID, strconv.Itoa(version), strconv.FormatInt(expiresAt, 10), userID, clientIP}
+}
+
+func (s *fileService) CreateSignedURL(ctx context.Context, token string, userID string, password string, clientIP string, req *dto.SignedURLRequest) (string, time.Time, *utils.ReturnStatus) {
+	file, _, _, err := s.getFileInfo(ctx, token, userID, true, false)
 	if err.IsErr() {
-		return nil, err
+		return "", time.Time{}, err
 	}
-	var requester domain.User
-	if uErr := s.userRepo.FindById(userID, &requester); uErr != nil {
-		return nil, uErr
+
+	if err := s.checkFilePassword(ctx, file, password, clientIP); err != nil {
+		return "", time.Time{}, err
 	}
-	isAdmin := requester.Role == "admin"
 
-	isOwner := file.OwnerId != nil && *file.OwnerId == userID
-	if !isAdmin && !isOwner {
-		log.Println("Not the owner")
-		return nil, utils.Response(utils.ErrCodeHistoryForbidden)
+	ttl := defaultSignedURLTTL
+	if req.ExpiresIn != nil {
+		ttl = time.Duration(*req.ExpiresIn) * time.Second
+	}
+	if maxTTL := time.Duration(s.cfg.Policy.SignedURLMaxTTLMinutes) * time.Minute; ttl > maxTTL {
+		return "", time.Time{}, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("expiresIn cannot exceed %d minutes", s.cfg.Policy.SignedURLMaxTTLMinutes))
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	boundIP := ""
+	if req.BindIP {
+		boundIP = clientIP
+	}
+
+	query := url.Values{}
+	query.Set("v", strconv.Itoa(file.Version))
+	query.Set("exp", strconv.FormatInt(expiresAt.Unix(), 10))
+	if userID != "" {
+		query.Set("u", userID)
+	}
+	if req.BindIP {
+		query.Set("ip", "1")
 	}
-	history, err := s.fileRepo.GetFileDownloadHistory(ctx, fileID)
+	query.Set("sig", s.signer.Sign(signedURLParts(file.Id, file.Version, expiresAt.Unix(), userID, boundIP)...))
+
+	return s.cfg.PublicURL("files/signed/"+file.Id) + "?" + query.Encode(), expiresAt.UTC(), nil
+}
+
+func (s *fileService) DownloadSigned(ctx context.Context, fileID string, query *dto.SignedDownloadQuery, clientIP string) (*domain.File, io.Reader, *utils.ReturnStatus) {
+	boundIP := ""
+	if query.BindIP {
+		boundIP = clientIP
+	}
+
+	if !s.signer.Verify(query.Signature, signedURLParts(fileID, query.Version, query.ExpiresAt, query.UserID, boundIP)...) {
+		return nil, nil, utils.Response(utils.ErrCodeSignedURLInvalid)
+	}
+
+	if time.Now().Unix() > query.ExpiresAt {
+		return nil, nil, utils.Response(utils.ErrCodeSignedURLExpired)
+	}
+
+	// Kiểm tra lại trạng thái file (hết hạn, whitelist, lượt tải) với quyền của người đã xin URL.
+	fileInfo, _, _, err := s.getFileInfo(ctx, fileID, query.UserID, false, false)
 	if err.IsErr() {
-		return nil, err
+		return nil, nil, err
 	}
-	history.Pagination = domain.Pagination{
-		CurrentPage:  pagenum,
-		TotalPages:   (len(history.History) + limit) / limit,
-		TotalRecords: len(history.History),
-		Limit:        limit,
+
+	// Share link đã bị đổi hoặc thu hồi sau khi URL được cấp.
+	if fileInfo.Version != query.Version {
+		return nil, nil, utils.Response(utils.ErrCodeSignedURLInvalid)
 	}
 
-	start := (len(history.History) / limit) * pagenum
-	end := min(start+limit, len(history.History))
-	history.History = history.History[start:end]
+	return s.serveDownload(ctx, fileInfo, query.UserID)
+}
+
+// burnAfterReading xóa metadata ngay khi lượt tải cuối cùng đã được dùng; file vật lý được stream
+// cho người tải và chỉ bị xóa khi reader được đóng.
+func (s *fileService) burnAfterReading(ctx context.Context, file *domain.File, reader io.Reader) (*domain.File, io.Reader, *utils.ReturnStatus) {
+	if err := s.fileRepo.DeleteFile(ctx, file.Id); err.IsErr() {
+		log.Printf("Burn after reading: failed to delete metadata for file %s: %v", file.Id, err)
+	}
 
-	for i := range history.History {
-		u := &history.History[i]
+	return file, &burnReader{Reader: reader, storage: s.storage, fileID: file.Id}, nil
+}
+
+// burnReader xóa file vật lý khỏi storage khi được đóng.
+type burnReader struct {
+	io.Reader
+	storage storage.Storage
+	fileID  string
+	once    sync.Once
+}
 
-		if u.UserId == nil {
+func (r *burnReader) Close() error {
+	var closeErr error
+	r.once.Do(func() {
+		if closer, ok := r.Reader.(io.Closer); ok {
+			closeErr = closer.Close()
+		}
+		if err := r.storage.DeleteFile(r.fileID); err.IsErr() {
+			log.Printf("Burn after reading: failed to delete physical file %s: %v", r.fileID, err)
+		}
+	})
+	return closeErr
+}
+
+// maxArchiveFiles giới hạn số file trong một archive để một request không giữ kết nối quá lâu.
+const maxArchiveFiles = 500
+
+// archiveCandidate là một file được chọn cho archive. explicit = chọn trực tiếp qua id/share token
+// (không tải được thì từ chối cả request), ngược lại được chọn qua thư mục/bộ lọc (không tải được thì bỏ qua).
+type archiveCandidate struct {
+	ident    string
+	isToken  bool
+	dir      string // thư mục trong ZIP, rỗng hoặc kết thúc bằng "/"
+	explicit bool
+}
+
+func (s *fileService) PrepareArchive(ctx context.Context, req *dto.ArchiveRequest, userID string, clientIP string) (*domain.Archive, *utils.ReturnStatus) {
+	candidates := []archiveCandidate{}
+	for _, id := range req.FileIds {
+		candidates = append(candidates, archiveCandidate{ident: id, explicit: true})
+	}
+	for _, token := range req.ShareTokens {
+		candidates = append(candidates, archiveCandidate{ident: token, isToken: true, explicit: true})
+	}
+
+	name := "files.zip"
+	if req.FolderId != nil {
+		folder, inFolder, err := s.folderArchiveCandidates(ctx, *req.FolderId, userID)
+		if err != nil {
+			return nil, err
+		}
+		name = archiveFileName(folder.Name) + ".zip"
+		candidates = append(candidates, inFolder...)
+	}
+	if req.Filter != nil {
+		filtered, err := s.filterArchiveCandidates(ctx, req.Filter, userID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, filtered...)
+	}
+
+	if len(candidates) == 0 {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Select at least one file, a folder or a filter")
+	}
+	if len(candidates) > maxArchiveFiles {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("An archive can contain at most %d files", maxArchiveFiles))
+	}
+
+	archive := &domain.Archive{Name: name, Entries: []domain.ArchiveEntry{}}
+	seen := map[string]bool{}
+	usedPaths := map[string]bool{}
+	for _, c := range candidates {
+		// Cùng các bước kiểm tra như DownloadFile: whitelist/nhóm/thư mục, thời gian hiệu lực, lượt tải, password.
+		file, _, _, err := s.getFileInfo(ctx, c.ident, userID, c.isToken, false)
+		// Owner không bị chặn bởi getFileInfo khi hết lượt tải, nhưng proc_download vẫn từ chối.
+		if err == nil && file.DownloadLimitReached() {
+			err = utils.Response(utils.ErrCodeDownloadLimitReached)
+		}
+		if err == nil {
+			err = s.checkFilePassword(ctx, file, archivePassword(req.Passwords, c.ident, file), clientIP)
+		}
+		if err != nil {
+			if err.Error() == utils.ErrCodeDatabaseError {
+				return nil, err
+			}
+			if c.explicit {
+				return nil, utils.ResponseArgs(utils.ErrCodeArchiveFileRejected, gin.H{"file": c.ident, "reason": err.Error()})
+			}
+			archive.Skipped++
 			continue
 		}
 
-		if *u.UserId == "" {
+		if seen[file.Id] {
 			continue
 		}
+		seen[file.Id] = true
+		archive.Entries = append(archive.Entries, domain.ArchiveEntry{
+			Path: uniqueArchivePath(usedPaths, c.dir, file.FileName),
+			File: file,
+		})
+	}
+
+	if len(archive.Entries) == 0 {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "None of the selected files can be downloaded")
+	}
+
+	return archive, nil
+}
+
+// folderArchiveCandidates chọn mọi file trong cây thư mục; đường dẫn trong ZIP tính từ thư mục được chọn.
+func (s *fileService) folderArchiveCandidates(ctx context.Context, folderID string, userID string) (*domain.Folder, []archiveCandidate, *utils.ReturnStatus) {
+	if userID == "" {
+		return nil, nil, utils.Response(utils.ErrCodeBearerInvalid)
+	}
+
+	folder, err := s.folderRepo.Find(ctx, folderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if folder.OwnerId != userID {
+		requester := domain.User{}
+		if err := s.userRepo.FindById(userID, &requester); err != nil {
+			return nil, nil, err
+		}
+		if requester.Role != "admin" {
+			shared, err := s.folderRepo.SharedWithUser(ctx, folder.Id, userID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !shared {
+				return nil, nil, utils.Response(utils.ErrCodeFolderNotFound)
+			}
+		}
+	}
+
+	folders, err := s.folderRepo.ListSubtree(ctx, folder)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		user := domain.User{}
-		err := s.userRepo.FindById(*u.UserId, &user)
+	names := map[string]string{}
+	for _, f := range folders {
+		names[f.Id] = archiveFileName(f.Name)
+	}
+
+	// Path của thư mục con luôn bắt đầu bằng path của folder, bỏ phần tổ tiên phía trên folder.
+	ancestors := len(folder.Path) - len(folder.Id) - 1
+	candidates := []archiveCandidate{}
+	for _, f := range folders {
+		dir := ""
+		for _, id := range strings.Split(strings.Trim(f.Path[ancestors:], "/"), "/") {
+			dir += names[id] + "/"
+		}
+
+		files, err := s.fileRepo.ListByFolder(ctx, f.Id)
 		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range files {
+			candidates = append(candidates, archiveCandidate{ident: file.Id, dir: dir})
+		}
+	}
+
+	return folder, candidates, nil
+}
+
+// filterArchiveCandidates chọn file của user theo cùng bộ lọc với GET /files/my.
+func (s *fileService) filterArchiveCandidates(ctx context.Context, filter *dto.ArchiveFilter, userID string) ([]archiveCandidate, *utils.ReturnStatus) {
+	if userID == "" {
+		return nil, utils.Response(utils.ErrCodeBearerInvalid)
+	}
+
+	if filter.FolderId != "" && filter.FolderId != "root" && uuid.Validate(filter.FolderId) != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Invalid folderId")
+	}
+
+	status := filter.Status
+	if status == "" {
+		status = "all"
+	}
+
+	// Lấy dư một file để PrepareArchive nhận ra bộ lọc vượt quá giới hạn.
+	files, _, err := s.fileRepo.GetMyFiles(ctx, userID, domain.ListFileParams{
+		Status:   status,
+		FolderId: filter.FolderId,
+		Page:     domain.PageRequest{SortBy: domain.SORT_CREATED_AT, Order: domain.ORDER_ASC, Limit: maxArchiveFiles + 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []archiveCandidate{}
+	for _, file := range files {
+		candidates = append(candidates, archiveCandidate{ident: file.Id})
+	}
+
+	return candidates, nil
+}
+
+// archivePassword tra password theo đúng phần tử trong request, rồi theo id và share token của file.
+func archivePassword(passwords map[string]string, ident string, file *domain.File) string {
+	for _, key := range []string{ident, file.Id, file.ShareToken} {
+		if password, ok := passwords[key]; ok {
+			return password
+		}
+	}
+	return ""
+}
+
+// uniqueArchivePath đặt tên file trong ZIP, thêm " (2)", " (3)"... khi trùng tên trong cùng thư mục.
+func uniqueArchivePath(used map[string]bool, dir string, name string) string {
+	name = archiveFileName(name)
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := dir + name
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s%s (%d)%s", dir, base, i, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+
+	return candidate
+}
+
+// WriteArchive stream ZIP trực tiếp ra w, không dùng file tạm. Mỗi file được ghi nhận lượt tải
+// (proc_download) ngay trước khi ghi vào ZIP, giống một lần DownloadFile.
+func (s *fileService) WriteArchive(ctx context.Context, archive *domain.Archive, userID string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, entry := range archive.Entries {
+		_, reader, err := s.serveDownload(ctx, entry.File, userID)
+		if err != nil {
+			// Response đã bắt đầu nên không đổi được status: bỏ file (vd. request khác vừa lấy lượt tải cuối).
+			log.Printf("Archive: skipping file %s: %s", entry.File.Id, err.Error())
+			continue
+		}
+
+		dst, zerr := zw.CreateHeader(&zip.FileHeader{
+			Name:     entry.Path,
+			Method:   zip.Deflate,
+			Modified: entry.File.CreatedAt,
+		})
+		if zerr == nil {
+			_, zerr = io.Copy(dst, reader)
+		}
+		if closer, ok := reader.(io.Closer); ok {
+			closer.Close()
+		}
+		if zerr != nil {
+			return zerr
+		}
+	}
+
+	return zw.Close()
+}
+
+func (s *fileService) UpdateShareLink(ctx context.Context, fileID string, userID string, req *dto.UpdateShareLinkRequest) (*domain.File, *utils.ReturnStatus) {
+	file, err := s.fileRepo.GetFileByID(ctx, fileID)
+	if err.IsErr() {
+		return nil, err
+	}
+
+	var requester domain.User
+	if errStatus := s.userRepo.FindById(userID, &requester); errStatus != nil {
+		return nil, errStatus
+	}
+
+	isOwner := file.OwnerId != nil && *file.OwnerId == userID
+	if !isOwner && requester.Role != "admin" {
+		return nil, utils.Response(utils.ErrCodeCantAccessResource)
+	}
+
+	if req.MaxDownloads != nil {
+		if *req.MaxDownloads > 0 {
+			file.MaxDownloads = req.MaxDownloads
+		} else {
+			file.MaxDownloads = nil
+		}
+	}
+	if req.DeleteOnLimit != nil {
+		file.DeleteOnLimit = *req.DeleteOnLimit
+	}
+	if file.MaxDownloads == nil {
+		file.DeleteOnLimit = false
+	}
+
+	if req.Slug != nil {
+		if err := validateShareSlug(*req.Slug); err != nil {
+			return nil, err
+		}
+		file.ShareToken = *req.Slug
+	} else if req.RegenerateToken {
+		if file.ShareToken, err = s.newShareToken(); err != nil {
 			return nil, err
 		}
+	}
+
+	err = s.fileRepo.UpdateShareLink(ctx, file)
+	for attempt := 1; err.IsErr() && err.Error() == utils.ErrCodeShareTokenTaken && req.Slug == nil && req.RegenerateToken && attempt < maxShareTokenAttempts; attempt++ {
+		if file.ShareToken, err = s.newShareToken(); err != nil {
+			break
+		}
+		err = s.fileRepo.UpdateShareLink(ctx, file)
+	}
+	if err.IsErr() {
+		return nil, err
+	}
+
+	file.ShareLink = s.shareLink(file.ShareToken)
 
-		u.Downloader = &domain.Downloader{
-			Username: user.Username,
-			Email:    user.Email,
+	return file, nil
+}
+
+func (s *fileService) GetShareQRCode(ctx context.Context, ident string, userID string, format string, size int) ([]byte, string, *utils.ReturnStatus) {
+	file, _, _, err := s.getFileInfo(ctx, ident, userID, uuid.Validate(ident) != nil, true)
+	if err.IsErr() {
+		return nil, "", err
+	}
+
+	switch format {
+	case "png":
+		png, qrErr := utils.QRCodePNG(file.ShareLink, size)
+		if qrErr != nil {
+			return nil, "", utils.ResponseMsg(utils.ErrCodeInternal, qrErr.Error())
+		}
+		return png, "image/png", nil
+	case "svg":
+		svg, qrErr := utils.QRCodeSVG(file.ShareLink, size)
+		if qrErr != nil {
+			return nil, "", utils.ResponseMsg(utils.ErrCodeInternal, qrErr.Error())
 		}
+		return svg, "image/svg+xml", nil
+	default:
+		return nil, "", utils.ResponseMsg(utils.ErrCodeBadRequest, "format must be one of: png, svg")
+	}
+}
+
+func (s *fileService) GetFileDownloadHistory(ctx context.Context, fileID string, userID string, query *dto.PageQuery) (*domain.FileDownloadHistory, *utils.ReturnStatus) {
+	page, err := pageRequest(*query, []string{domain.SORT_DOWNLOADED_AT}, domain.SORT_DOWNLOADED_AT)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.fileRepo.GetFileByID(ctx, fileID)
+	if err.IsErr() {
+		return nil, err
+	}
+	var requester domain.User
+	if uErr := s.userRepo.FindById(userID, &requester); uErr != nil {
+		return nil, uErr
 	}
+	isAdmin := requester.Role == "admin"
+
+	isOwner := file.OwnerId != nil && *file.OwnerId == userID
+	if !isAdmin && !isOwner {
+		log.Println("Not the owner")
+		return nil, utils.Response(utils.ErrCodeHistoryForbidden)
+	}
+	history, next, err := s.fileRepo.GetFileDownloadHistory(ctx, fileID, page)
+	if err.IsErr() {
+		return nil, err
+	}
+	history.Pagination = domain.NewPagination(page, next)
 
 	return history, nil
 }
@@ -450,34 +1232,38 @@ func (s *fileService) GetFileStats(ctx context.Context, fileID, userID string) (
 	return s.fileRepo.GetFileStats(ctx, fileID)
 }
 
-func (s *fileService) GetAccessibleFiles(ctx context.Context, userID string) ([]dto.AccessibleFile, *utils.ReturnStatus) {
-	files, err := s.fileRepo.GetAccessibleFiles(ctx, userID)
-
+func (s *fileService) GetAccessibleFiles(ctx context.Context, userID string, query *dto.PageQuery) ([]dto.AccessibleFile, *domain.Pagination, *utils.ReturnStatus) {
+	page, err := pageRequest(*query, domain.FileSorts, domain.SORT_CREATED_AT)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var out []dto.AccessibleFile
+	files, next, err := s.fileRepo.GetAccessibleFiles(ctx, userID, page)
 
-	for _, file := range files {
-		var user domain.User
-		var email *string
+	if err != nil {
+		return nil, nil, err
+	}
 
-		if file.OwnerId != nil {
-			if err := s.userRepo.FindById(*file.OwnerId, &user); err != nil {
-				return nil, err
-			}
-			email = &user.Email
-		}
+	out := []dto.AccessibleFile{}
 
+	for _, file := range files {
 		out = append(out, dto.AccessibleFile{
 			FileId:      file.Id,
 			FileName:    file.FileName,
-			OwnerEmail:  email,
+			OwnerEmail:  ownerEmail(&file),
 			HasPassword: file.HasPassword,
 			ShareToken:  file.ShareToken,
 		})
 	}
 
-	return out, nil
+	pagination := domain.NewPagination(page, next)
+	return out, &pagination, nil
+}
+
+// ownerEmail trả về email chủ file đã JOIN sẵn, nil với upload ẩn danh.
+func ownerEmail(file *domain.File) *string {
+	if file.Owner == nil {
+		return nil
+	}
+	return &file.Owner.Email
 }
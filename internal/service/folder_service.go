@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type folderService struct {
+	cfg        *config.Config
+	folderRepo repository.FolderRepository
+	fileRepo   repository.FileRepository
+	groupRepo  repository.GroupRepository
+	userRepo   repository.UserRepository
+}
+
+func NewFolderService(cfg *config.Config, folderRepo repository.FolderRepository, fileRepo repository.FileRepository, groupRepo repository.GroupRepository, userRepo repository.UserRepository) FolderService {
+	return &folderService{
+		cfg:        cfg,
+		folderRepo: folderRepo,
+		fileRepo:   fileRepo,
+		groupRepo:  groupRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// access trả về thư mục và quyền quản lý của userID: owner/admin được sửa,
+// người được chia sẻ (trực tiếp hoặc qua thư mục cha) chỉ được xem. Người ngoài nhận 404.
+func (s *folderService) access(ctx context.Context, userID string, folderID string) (*domain.Folder, bool, *utils.ReturnStatus) {
+	folder, err := s.folderRepo.Find(ctx, folderID)
+	if err != nil {
+		return nil, false, err
+	}
+	if folder.OwnerId == userID {
+		return folder, true, nil
+	}
+
+	requester := domain.User{}
+	if err := s.userRepo.FindById(userID, &requester); err != nil {
+		return nil, false, err
+	}
+	if requester.Role == "admin" {
+		return folder, true, nil
+	}
+
+	shared, err := s.folderRepo.SharedWithUser(ctx, folderID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !shared {
+		return nil, false, utils.Response(utils.ErrCodeFolderNotFound)
+	}
+
+	return folder, false, nil
+}
+
+func (s *folderService) manage(ctx context.Context, userID string, folderID string) (*domain.Folder, *utils.ReturnStatus) {
+	folder, canManage, err := s.access(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManage {
+		return nil, utils.Response(utils.ErrCodeFolderForbidden)
+	}
+
+	return folder, nil
+}
+
+func folderName(name string) (string, *utils.ReturnStatus) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", utils.ResponseMsg(utils.ErrCodeBadRequest, "Folder name is required")
+	}
+	return name, nil
+}
+
+func (s *folderService) CreateFolder(ctx context.Context, userID string, req *dto.CreateFolderRequest) (*domain.Folder, *utils.ReturnStatus) {
+	name, err := folderName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ParentId != nil {
+		parent, err := s.folderRepo.Find(ctx, *req.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		if parent.OwnerId != userID {
+			return nil, utils.Response(utils.ErrCodeFolderNotFound)
+		}
+	}
+
+	folder := &domain.Folder{
+		Id:       uuid.New().String(),
+		OwnerId:  userID,
+		ParentId: req.ParentId,
+		Name:     name,
+	}
+	if err := s.folderRepo.Create(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (s *folderService) ListFolders(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return s.folderRepo.ListByOwner(ctx, userID)
+}
+
+func (s *folderService) ListSharedFolders(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus) {
+	return s.folderRepo.ListSharedWithUser(ctx, userID)
+}
+
+func (s *folderService) GetFolder(ctx context.Context, userID string, folderID string) (*domain.FolderContents, *utils.ReturnStatus) {
+	folder, canManage, err := s.access(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := s.folderRepo.ListChildren(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.fileRepo.ListByFolder(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Người được chia sẻ chỉ thấy file đang trong thời gian hiệu lực, giống /files/available.
+	now := time.Now()
+	visible := []domain.File{}
+	for _, file := range files {
+		file.Status = file.StatusAt(now)
+		if !canManage && file.Status != domain.FILE_ACTIVE {
+			continue
+		}
+		file.ShareLink = s.cfg.PublicURL("files/" + url.PathEscape(file.ShareToken))
+		visible = append(visible, file)
+	}
+
+	contents := &domain.FolderContents{Folder: folder, Folders: children, Files: visible}
+	if canManage {
+		if contents.Shares, err = s.folderRepo.ListShares(ctx, folderID); err != nil {
+			return nil, err
+		}
+	}
+
+	return contents, nil
+}
+
+func (s *folderService) RenameFolder(ctx context.Context, userID string, folderID string, name string) (*domain.Folder, *utils.ReturnStatus) {
+	folder, err := s.manage(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if folder.Name, err = folderName(name); err != nil {
+		return nil, err
+	}
+	if err := s.folderRepo.Rename(ctx, folderID, folder.Name); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (s *folderService) MoveFolder(ctx context.Context, userID string, folderID string, parentID *string) (*domain.Folder, *utils.ReturnStatus) {
+	folder, err := s.manage(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *domain.Folder
+	if parentID != nil {
+		if parent, err = s.folderRepo.Find(ctx, *parentID); err != nil {
+			return nil, err
+		}
+		// Chỉ di chuyển trong cây thư mục của cùng một chủ sở hữu.
+		if parent.OwnerId != folder.OwnerId {
+			return nil, utils.Response(utils.ErrCodeFolderNotFound)
+		}
+		if folder.Contains(parent) {
+			return nil, utils.Response(utils.ErrCodeFolderMoveInvalid)
+		}
+	}
+
+	if err := s.folderRepo.Move(ctx, folder, parent); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (s *folderService) DeleteFolder(ctx context.Context, userID string, folderID string) *utils.ReturnStatus {
+	if _, err := s.manage(ctx, userID, folderID); err != nil {
+		return err
+	}
+
+	return s.folderRepo.Delete(ctx, folderID)
+}
+
+func (s *folderService) ShareFolder(ctx context.Context, userID string, folderID string, sharedWith []string) (*domain.FolderShares, *utils.ReturnStatus) {
+	if _, err := s.manage(ctx, userID, folderID); err != nil {
+		return nil, err
+	}
+
+	emails, groupIDs, err := splitSharedWith(ctx, s.groupRepo, sharedWith, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.folderRepo.SetShares(ctx, folderID, emails, groupIDs); err != nil {
+		return nil, err
+	}
+
+	return s.folderRepo.ListShares(ctx, folderID)
+}
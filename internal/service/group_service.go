@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+type groupService struct {
+	groupRepo repository.GroupRepository
+	userRepo  repository.UserRepository
+}
+
+func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository) GroupService {
+	return &groupService{
+		groupRepo: groupRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// access trả về nhóm và vai trò của userID trong nhóm. Admin được coi như owner của mọi nhóm.
+// Người ngoài nhóm nhận 404 để không dò được nhóm nào tồn tại.
+func (s *groupService) access(ctx context.Context, userID string, groupID string) (*domain.Group, string, *utils.ReturnStatus) {
+	group, err := s.groupRepo.Find(ctx, groupID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	role, err := s.groupRepo.FindRole(ctx, groupID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	group.MyRole = role
+	if role != "" {
+		return group, role, nil
+	}
+
+	requester := domain.User{}
+	if err := s.userRepo.FindById(userID, &requester); err != nil {
+		return nil, "", err
+	}
+	if requester.Role == "admin" {
+		return group, domain.GROUP_OWNER, nil
+	}
+
+	return nil, "", utils.Response(utils.ErrCodeGroupNotFound)
+}
+
+func (s *groupService) manage(ctx context.Context, userID string, groupID string) (*domain.Group, *utils.ReturnStatus) {
+	group, role, err := s.access(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if role != domain.GROUP_OWNER {
+		return nil, utils.Response(utils.ErrCodeGroupForbidden)
+	}
+
+	return group, nil
+}
+
+// keepOwner chặn thao tác làm nhóm không còn owner nào.
+func (s *groupService) keepOwner(ctx context.Context, groupID string, memberID string) *utils.ReturnStatus {
+	role, err := s.groupRepo.FindRole(ctx, groupID, memberID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return utils.Response(utils.ErrCodeGroupMemberNotFound)
+	}
+	if role != domain.GROUP_OWNER {
+		return nil
+	}
+
+	owners, err := s.groupRepo.CountOwners(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return utils.Response(utils.ErrCodeLastGroupOwner)
+	}
+
+	return nil
+}
+
+func (s *groupService) CreateGroup(ctx context.Context, userID string, req *dto.CreateGroupRequest) (*domain.Group, *utils.ReturnStatus) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Group name is required")
+	}
+
+	group := &domain.Group{Name: name, Description: req.Description}
+	if err := s.groupRepo.Create(ctx, group, userID); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (s *groupService) ListGroups(ctx context.Context, userID string) ([]domain.Group, *utils.ReturnStatus) {
+	return s.groupRepo.ListByUser(ctx, userID)
+}
+
+func (s *groupService) GetGroup(ctx context.Context, userID string, groupID string) (*domain.Group, []domain.GroupMember, *utils.ReturnStatus) {
+	group, _, err := s.access(ctx, userID, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	members, err := s.groupRepo.ListMembers(ctx, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return group, members, nil
+}
+
+func (s *groupService) UpdateGroup(ctx context.Context, userID string, groupID string, req *dto.UpdateGroupRequest) (*domain.Group, *utils.ReturnStatus) {
+	group, err := s.manage(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Group name is required")
+		}
+		group.Name = name
+	}
+	if req.Description != nil {
+		group.Description = req.Description
+	}
+
+	if err := s.groupRepo.Update(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (s *groupService) DeleteGroup(ctx context.Context, userID string, groupID string) *utils.ReturnStatus {
+	if _, err := s.manage(ctx, userID, groupID); err != nil {
+		return err
+	}
+
+	return s.groupRepo.Delete(ctx, groupID)
+}
+
+func (s *groupService) AddMember(ctx context.Context, userID string, groupID string, req *dto.AddGroupMemberRequest) ([]domain.GroupMember, *utils.ReturnStatus) {
+	if _, err := s.manage(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	member := domain.User{}
+	if err := s.userRepo.FindByEmail(utils.NormalizeString(req.Email), &member); err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = domain.GROUP_MEMBER
+	}
+	if err := s.groupRepo.AddMember(ctx, groupID, member.Id, role); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.ListMembers(ctx, groupID)
+}
+
+func (s *groupService) UpdateMember(ctx context.Context, userID string, groupID string, memberID string, role string) ([]domain.GroupMember, *utils.ReturnStatus) {
+	if _, err := s.manage(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	if role != domain.GROUP_OWNER {
+		if err := s.keepOwner(ctx, groupID, memberID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.groupRepo.UpdateMemberRole(ctx, groupID, memberID, role); err != nil {
+		return nil, err
+	}
+
+	return s.groupRepo.ListMembers(ctx, groupID)
+}
+
+// RemoveMember: owner xóa được mọi thành viên, thành viên thường chỉ tự rời nhóm.
+func (s *groupService) RemoveMember(ctx context.Context, userID string, groupID string, memberID string) *utils.ReturnStatus {
+	if memberID == userID {
+		if _, _, err := s.access(ctx, userID, groupID); err != nil {
+			return err
+		}
+	} else if _, err := s.manage(ctx, userID, groupID); err != nil {
+		return err
+	}
+
+	if err := s.keepOwner(ctx, groupID, memberID); err != nil {
+		return err
+	}
+
+	return s.groupRepo.RemoveMember(ctx, groupID, memberID)
+}
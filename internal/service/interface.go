@@ -4,12 +4,14 @@ import (
 	"context"
 	"io"
 	"mime/multipart"
+	"time"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
 )
 
 type TOTPSetupResponse struct {
@@ -20,31 +22,155 @@ type TOTPSetupResponse struct {
 type UserService interface {
 	GetUserById(id string) (*domain.UserResponse, *utils.ReturnStatus)
 	GetUserByEmail(email string) (*domain.UserResponse, *utils.ReturnStatus)
+	GetProfile(ctx context.Context, requesterID string, userID string) (*domain.UserResponse, *utils.ReturnStatus)
+	SearchUsers(ctx context.Context, requesterID string, query string) ([]domain.PublicProfile, *utils.ReturnStatus)
+	UpdateProfile(ctx context.Context, userID string, req *dto.UpdateProfileRequest) (*domain.UserResponse, bool, *utils.ReturnStatus)
+	DeleteAccount(ctx context.Context, userID string, authenticatedAt time.Time, req *dto.DeleteAccountRequest, clientIP string) *utils.ReturnStatus
+}
+
+// APITokenService quản lý personal access token (pat_...) cho script/CI.
+type APITokenService interface {
+	CreateToken(ctx context.Context, userID string, req *dto.CreateAPITokenRequest) (string, *domain.APIToken, *utils.ReturnStatus)
+	ListTokens(ctx context.Context, userID string) ([]domain.APIToken, *utils.ReturnStatus)
+	RevokeToken(ctx context.Context, userID string, tokenID string) *utils.ReturnStatus
+	Authenticate(ctx context.Context, raw string) (*domain.APIToken, *domain.User, *utils.ReturnStatus)
+}
+
+// OIDCService đăng nhập SSO qua OpenID Connect (authorization code + PKCE).
+type OIDCService interface {
+	Providers() []string
+	BeginLogin(ctx context.Context, provider string) (authorizationURL string, err *utils.ReturnStatus)
+	CompleteLogin(ctx context.Context, provider, code, state string) (*domain.User, string, *utils.ReturnStatus)
+}
+
+// WebAuthnService: đăng ký passkey/security key, dùng làm second factor trong Login -> CID
+// hoặc đăng nhập không cần password.
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, userID string, name string) (string, *protocol.CredentialCreation, *utils.ReturnStatus)
+	FinishRegistration(ctx context.Context, userID string, sessionID string, credential []byte) (*domain.WebAuthnCredential, *utils.ReturnStatus)
+	ListCredentials(ctx context.Context, userID string) ([]domain.WebAuthnCredential, *utils.ReturnStatus)
+	DeleteCredential(ctx context.Context, userID string, credentialID string) *utils.ReturnStatus
+	BeginSecondFactor(ctx context.Context, cid string) (string, *protocol.CredentialAssertion, *utils.ReturnStatus)
+	FinishSecondFactor(ctx context.Context, cid string, sessionID string, credential []byte, clientIP string) (*domain.User, string, *utils.ReturnStatus)
+	BeginPasskeyLogin(ctx context.Context) (string, *protocol.CredentialAssertion, *utils.ReturnStatus)
+	FinishPasskeyLogin(ctx context.Context, sessionID string, credential []byte, clientIP string) (*domain.User, string, *utils.ReturnStatus)
+	BeginReauthentication(ctx context.Context, userID string) (string, *protocol.CredentialAssertion, *utils.ReturnStatus)
+	VerifyReauthentication(ctx context.Context, userID string, sessionID string, credential []byte) *utils.ReturnStatus
 }
 
 type AuthService interface {
-	CreateUser(username, password, email string) (*domain.User, *utils.ReturnStatus)
-	Login(email, password string) (user *domain.User, accessToken string, err *utils.ReturnStatus)
+	CreateUser(ctx context.Context, username, password, email, inviteToken string) (*domain.User, *utils.ReturnStatus)
+	Login(ctx context.Context, email, password, clientIP string) (user *domain.User, accessToken string, err *utils.ReturnStatus)
 	SetupTOTP(userID string) (*TOTPSetupResponse, *utils.ReturnStatus)
 	VerifyTOTP(userID string, code string) (bool, *utils.ReturnStatus)
 	Logout(ctx *gin.Context) *utils.ReturnStatus
-	LoginTOTP(email, totpCode string) (*domain.User, string, *utils.ReturnStatus)
+	LoginTOTP(ctx context.Context, cid, totpCode, clientIP string) (*domain.User, string, *utils.ReturnStatus)
+	RecordLogin(ctx context.Context, userID, method, clientIP, userAgent string)
 }
 
 type FileService interface {
 	UploadFile(ctx context.Context, fileHeader *multipart.FileHeader, req *dto.UploadRequest, ownerID *string) (*domain.File, *utils.ReturnStatus)
-	GetMyFiles(ctx context.Context, userID string, params domain.ListFileParams) (interface{}, *utils.ReturnStatus)
+	GetMyFiles(ctx context.Context, userID string, query *dto.ListMyFilesQuery) (interface{}, *utils.ReturnStatus)
 	DeleteFile(ctx context.Context, fileID string, userID string) *utils.ReturnStatus
 	GetFileInfo(ctx context.Context, token string, userID string, verbose bool) (*domain.File, *domain.User, []string, *utils.ReturnStatus)
 	GetFileInfoID(ctx context.Context, token string, userID string, verbose bool) (*domain.File, *domain.User, []string, *utils.ReturnStatus)
-	DownloadFile(ctx context.Context, token string, userID string, password string) (*domain.File, io.Reader, *utils.ReturnStatus)
-	GetFileDownloadHistory(ctx context.Context, fileID string, userID string, pagenum, limit int) (*domain.FileDownloadHistory, *utils.ReturnStatus)
+	DownloadFile(ctx context.Context, token string, userID string, password string, clientIP string) (*domain.File, io.Reader, *utils.ReturnStatus)
+	GetFileDownloadHistory(ctx context.Context, fileID string, userID string, query *dto.PageQuery) (*domain.FileDownloadHistory, *utils.ReturnStatus)
 	GetFileStats(ctx context.Context, fileID string, userID string) (*domain.FileStat, *utils.ReturnStatus)
-	GetAccessibleFiles(ctx context.Context, userID string) ([]dto.AccessibleFile, *utils.ReturnStatus)
+	GetAccessibleFiles(ctx context.Context, userID string, query *dto.PageQuery) ([]dto.AccessibleFile, *domain.Pagination, *utils.ReturnStatus)
+	UpdateShareLink(ctx context.Context, fileID string, userID string, req *dto.UpdateShareLinkRequest) (*domain.File, *utils.ReturnStatus)
+	GetShareQRCode(ctx context.Context, ident string, userID string, format string, size int) ([]byte, string, *utils.ReturnStatus)
+	CreateSignedURL(ctx context.Context, token string, userID string, password string, clientIP string, req *dto.SignedURLRequest) (string, time.Time, *utils.ReturnStatus)
+	DownloadSigned(ctx context.Context, fileID string, query *dto.SignedDownloadQuery, clientIP string) (*domain.File, io.Reader, *utils.ReturnStatus)
+	MoveFile(ctx context.Context, fileID string, userID string, folderID *string) (*domain.File, *utils.ReturnStatus)
+	UpdateFileMetadata(ctx context.Context, fileID string, userID string, req *dto.UpdateFileMetadataRequest) (*domain.File, *utils.ReturnStatus)
+	SearchFiles(ctx context.Context, userID string, query *dto.FileSearchQuery) ([]dto.FileSearchResult, *domain.Pagination, *utils.ReturnStatus)
+	// PrepareArchive kiểm tra quyền mọi file được chọn trước khi gửi byte nào, WriteArchive stream ZIP.
+	PrepareArchive(ctx context.Context, req *dto.ArchiveRequest, userID string, clientIP string) (*domain.Archive, *utils.ReturnStatus)
+	WriteArchive(ctx context.Context, archive *domain.Archive, userID string, w io.Writer) error
+}
+
+// BruteForceGuard đếm số lần đoán sai theo IP, tài khoản và share token,
+// khóa tạm thời với thời gian tăng dần khi vượt ngưỡng.
+// Mỗi lần thử được Reserve (tính như một lần sai) trước khi so khớp, để các request song song
+// không cùng lọt qua ngưỡng; đoán đúng thì Release hoàn lại, đăng nhập xong thì Succeed reset.
+type BruteForceGuard interface {
+	Reserve(ctx context.Context, keys ...domain.AttemptKey) (*AttemptReservation, *utils.ReturnStatus)
+	Release(ctx context.Context, reservations ...*AttemptReservation)
+	Succeed(ctx context.Context, keys ...domain.AttemptKey)
+	Purge(ctx context.Context) (int64, *utils.ReturnStatus)
+}
+
+// ExportService: export dữ liệu cá nhân (GDPR) thành archive ZIP, build nền và tải qua signed link.
+type ExportService interface {
+	StartExport(ctx context.Context, userID string) (*domain.ExportJob, *utils.ReturnStatus)
+	GetExport(ctx context.Context, userID string, jobID string) (*domain.ExportJob, string, *utils.ReturnStatus)
+	DownloadExport(ctx context.Context, jobID string, query *dto.ExportDownloadQuery) (*domain.ExportJob, io.Reader, *utils.ReturnStatus)
+	DeleteUserExports(ctx context.Context, userID string)
+	PurgeExpired(ctx context.Context) (int, *utils.ReturnStatus)
+}
+
+// GroupService quản lý nhóm người dùng để chia sẻ file cho cả team.
+type GroupService interface {
+	CreateGroup(ctx context.Context, userID string, req *dto.CreateGroupRequest) (*domain.Group, *utils.ReturnStatus)
+	ListGroups(ctx context.Context, userID string) ([]domain.Group, *utils.ReturnStatus)
+	GetGroup(ctx context.Context, userID string, groupID string) (*domain.Group, []domain.GroupMember, *utils.ReturnStatus)
+	UpdateGroup(ctx context.Context, userID string, groupID string, req *dto.UpdateGroupRequest) (*domain.Group, *utils.ReturnStatus)
+	DeleteGroup(ctx context.Context, userID string, groupID string) *utils.ReturnStatus
+	AddMember(ctx context.Context, userID string, groupID string, req *dto.AddGroupMemberRequest) ([]domain.GroupMember, *utils.ReturnStatus)
+	UpdateMember(ctx context.Context, userID string, groupID string, memberID string, role string) ([]domain.GroupMember, *utils.ReturnStatus)
+	RemoveMember(ctx context.Context, userID string, groupID string, memberID string) *utils.ReturnStatus
+}
+
+// FolderService quản lý cây thư mục của user và chia sẻ cả thư mục.
+type FolderService interface {
+	CreateFolder(ctx context.Context, userID string, req *dto.CreateFolderRequest) (*domain.Folder, *utils.ReturnStatus)
+	ListFolders(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus)
+	ListSharedFolders(ctx context.Context, userID string) ([]domain.Folder, *utils.ReturnStatus)
+	GetFolder(ctx context.Context, userID string, folderID string) (*domain.FolderContents, *utils.ReturnStatus)
+	RenameFolder(ctx context.Context, userID string, folderID string, name string) (*domain.Folder, *utils.ReturnStatus)
+	MoveFolder(ctx context.Context, userID string, folderID string, parentID *string) (*domain.Folder, *utils.ReturnStatus)
+	DeleteFolder(ctx context.Context, userID string, folderID string) *utils.ReturnStatus
+	ShareFolder(ctx context.Context, userID string, folderID string, sharedWith []string) (*domain.FolderShares, *utils.ReturnStatus)
 }
 
 type AdminService interface {
 	GetSystemPolicy(ctx context.Context) (*config.SystemPolicy, *utils.ReturnStatus)
 	UpdateSystemPolicy(ctx context.Context, updates map[string]any) (*config.SystemPolicy, *utils.ReturnStatus)
 	CleanupExpiredFiles(ctx context.Context) (int, *utils.ReturnStatus)
+	// PreviewCleanup trả về các file CleanupExpiredFiles sẽ xóa, không xóa gì (dry run).
+	PreviewCleanup(ctx context.Context) ([]domain.File, *utils.ReturnStatus)
+	CheckStorage(ctx context.Context) (*domain.StorageReport, *utils.ReturnStatus)
+}
+
+// UserAdminService: thao tác quản trị tài khoản dùng cho cmd/fsadmin. ident là id hoặc email của user.
+type UserAdminService interface {
+	// BootstrapAdmin tạo tài khoản admin đầu tiên, từ chối nếu hệ thống đã có admin.
+	BootstrapAdmin(ctx context.Context, username, email, password string) (*domain.User, *utils.ReturnStatus)
+	ListUsers(ctx context.Context) ([]domain.User, *utils.ReturnStatus)
+	// SetSuspended khóa/mở khóa tài khoản; khóa thì thu hồi luôn mọi JWT đang dùng.
+	SetSuspended(ctx context.Context, ident string, suspended bool) (*domain.User, *utils.ReturnStatus)
+	// ResetTwoFactor tắt TOTP, removePasskeys xóa cả passkey; trả về số passkey đã xóa.
+	ResetTwoFactor(ctx context.Context, ident string, removePasskeys bool) (*domain.User, int, *utils.ReturnStatus)
+	// PurgeTokenBlacklist dọn blacklist JWT và mốc thu hồi mà mọi token liên quan đều đã hết hạn.
+	PurgeTokenBlacklist(ctx context.Context) (int64, *utils.ReturnStatus)
+}
+
+type RegistrationService interface {
+	Admit(ctx context.Context, email string, inviteToken string) (*domain.SignupTicket, *utils.ReturnStatus)
+	Release(ctx context.Context, ticket *domain.SignupTicket)
+	Complete(ctx context.Context, ticket *domain.SignupTicket, user *domain.User)
+	CheckEmailDomain(ctx context.Context, email string) *utils.ReturnStatus
+
+	SendVerification(ctx context.Context, user *domain.User) *utils.ReturnStatus
+	RequestEmailChange(ctx context.Context, user *domain.User, newEmail string) *utils.ReturnStatus
+	VerifyEmail(ctx context.Context, token string) *utils.ReturnStatus
+	ResendVerification(ctx context.Context, email string) *utils.ReturnStatus
+
+	ListDomainRules(ctx context.Context) ([]domain.EmailDomainRule, *utils.ReturnStatus)
+	SetDomainRule(ctx context.Context, emailDomain string, rule string) (*domain.EmailDomainRule, *utils.ReturnStatus)
+	DeleteDomainRule(ctx context.Context, emailDomain string) *utils.ReturnStatus
+	CreateInvite(ctx context.Context, createdBy string, email string, expiresInDays *int) (*domain.Invite, string, *utils.ReturnStatus)
+	ListInvites(ctx context.Context) ([]domain.Invite, *utils.ReturnStatus)
+	DeleteInvite(ctx context.Context, id string) *utils.ReturnStatus
 }
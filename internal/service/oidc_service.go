@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/oidc"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcStateLength = 32
+	oidcStateTTL    = 10 * time.Minute
+)
+
+type oidcProvider struct {
+	client oidc.Provider
+	cfg    config.OIDCProviderConfig
+}
+
+type oidcService struct {
+	providers    map[string]oidcProvider
+	userRepo     repository.UserRepository
+	authRepo     repository.AuthRepository
+	oidcRepo     repository.OIDCRepository
+	registration RegistrationService
+	tokenService jwt.TokenService
+}
+
+func NewOIDCService(cfgs []config.OIDCProviderConfig, userRepo repository.UserRepository, authRepo repository.AuthRepository, oidcRepo repository.OIDCRepository, registration RegistrationService, tokenService jwt.TokenService) OIDCService {
+	providers := map[string]oidcProvider{}
+	for _, cfg := range cfgs {
+		providers[cfg.Name] = oidcProvider{client: oidc.NewProvider(cfg), cfg: cfg}
+	}
+
+	return &oidcService{
+		providers:    providers,
+		userRepo:     userRepo,
+		authRepo:     authRepo,
+		oidcRepo:     oidcRepo,
+		registration: registration,
+		tokenService: tokenService,
+	}
+}
+
+func (s *oidcService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *oidcService) BeginLogin(ctx context.Context, providerName string) (string, *utils.ReturnStatus) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", utils.Response(utils.ErrCodeOIDCProviderNotFound)
+	}
+
+	alphabet := utils.TokenAlphabets[utils.DefaultTokenAlphabet]
+	state, err := utils.GenerateSecureString(oidcStateLength, alphabet)
+	if err != nil {
+		return "", utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate login state")
+	}
+	nonce, err := utils.GenerateSecureString(oidcStateLength, alphabet)
+	if err != nil {
+		return "", utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate login nonce")
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate PKCE verifier")
+	}
+
+	authURL, err := provider.client.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		log.Printf("OIDC: provider %s unavailable: %v", providerName, err)
+		return "", utils.ResponseMsg(utils.ErrCodeOIDCFailed, "Identity provider is unavailable")
+	}
+
+	if err := s.oidcRepo.SaveState(ctx, &domain.OIDCState{
+		State:        state,
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+func (s *oidcService) CompleteLogin(ctx context.Context, providerName, code, state string) (*domain.User, string, *utils.ReturnStatus) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, "", utils.Response(utils.ErrCodeOIDCProviderNotFound)
+	}
+
+	// 1. State dùng một lần, gắn với đúng provider
+	loginState, err := s.oidcRepo.ConsumeState(ctx, state, providerName)
+	if err != nil {
+		return nil, "", err
+	}
+	if time.Now().After(loginState.ExpiresAt) {
+		return nil, "", utils.Response(utils.ErrCodeOIDCStateInvalid)
+	}
+
+	// 2. Đổi code lấy ID token (kèm PKCE verifier), kiểm tra chữ ký, issuer, audience, nonce
+	identity, exchangeErr := provider.client.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if exchangeErr != nil {
+		log.Printf("OIDC: login via %s failed: %v", providerName, exchangeErr)
+		return nil, "", utils.ResponseMsg(utils.ErrCodeOIDCFailed, "Could not verify the response from the identity provider")
+	}
+
+	// 3. Tìm hoặc tạo tài khoản local
+	user, err := s.resolveUser(ctx, provider.cfg, identity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 4. Đồng bộ role theo claim của IdP
+	if provider.cfg.RoleClaim != "" {
+		role := mapRole(identity.Claims[provider.cfg.RoleClaim], provider.cfg.AdminValues)
+		if role != user.Role {
+			if err := s.oidcRepo.UpdateUserRole(ctx, user.Id, role); err != nil {
+				return nil, "", err
+			}
+			user.Role = role
+		}
+	}
+
+	if err := checkActive(user); err != nil {
+		return nil, "", err
+	}
+
+	accessToken, genErr := s.tokenService.GenerateAccessToken(*user)
+	if genErr != nil {
+		return nil, "", utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("Failed to generate access token: %s", genErr.Error()))
+	}
+
+	return user, accessToken, nil
+}
+
+// resolveUser: identity đã liên kết -> user đó; chưa liên kết -> ghép với tài khoản
+// cùng email (chỉ khi IdP đã xác minh email) hoặc tạo tài khoản mới.
+func (s *oidcService) resolveUser(ctx context.Context, cfg config.OIDCProviderConfig, identity *oidc.Identity) (*domain.User, *utils.ReturnStatus) {
+	email := utils.NormalizeString(identity.Email)
+
+	linked, err := s.oidcRepo.FindIdentity(ctx, cfg.Name, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		user := &domain.User{}
+		if err := s.userRepo.FindById(linked.UserId, user); err != nil {
+			return nil, err
+		}
+		if err := s.oidcRepo.TouchIdentity(ctx, cfg.Name, identity.Subject, email); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if email == "" {
+		return nil, utils.ResponseMsg(utils.ErrCodeOIDCFailed, "Identity provider did not return an email address")
+	}
+
+	user := &domain.User{}
+	if s.userRepo.FindByEmail(email, user) == nil {
+		if !identity.EmailVerified {
+			return nil, utils.Response(utils.ErrCodeOIDCEmailUnverified)
+		}
+	} else {
+		if !cfg.AllowSignup {
+			return nil, utils.Response(utils.ErrCodeOIDCSignupDisabled)
+		}
+		// ALLOW_SIGNUP thay cho mã mời, nhưng danh sách domain vẫn áp dụng
+		if err := s.registration.CheckEmailDomain(ctx, email); err != nil {
+			return nil, err
+		}
+
+		username, err := s.uniqueUsername(oidcUsername(identity, email))
+		if err != nil {
+			return nil, err
+		}
+
+		id, uuidErr := uuid.NewRandom()
+		if uuidErr != nil {
+			return nil, utils.ResponseMsg(utils.ErrCodeInternal, "failed to create UserID")
+		}
+		// Password rỗng: bcrypt không bao giờ khớp, tài khoản chỉ đăng nhập được qua SSO.
+		created, createErr := s.authRepo.Create(&domain.User{
+			Id:            id.String(),
+			Username:      username,
+			Email:         email,
+			Role:          "user",
+			EmailVerified: identity.EmailVerified,
+		})
+		if createErr != nil {
+			return nil, createErr
+		}
+		user = created
+	}
+
+	if err := s.oidcRepo.LinkIdentity(ctx, &domain.UserIdentity{
+		Provider: cfg.Name,
+		Subject:  identity.Subject,
+		UserId:   user.Id,
+		Email:    email,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func oidcUsername(identity *oidc.Identity, email string) string {
+	for _, candidate := range []string{identity.PreferredUsername, identity.Name} {
+		if candidate = strings.TrimSpace(candidate); candidate != "" {
+			return candidate
+		}
+	}
+	return strings.SplitN(email, "@", 2)[0]
+}
+
+// uniqueUsername thêm hậu tố số khi username từ IdP đã có người dùng (alice -> alice2, alice3...).
+func (s *oidcService) uniqueUsername(base string) (string, *utils.ReturnStatus) {
+	username := base
+	for i := 2; ; i++ {
+		taken, err := s.userRepo.UsernameExists(username)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return username, nil
+		}
+		username = fmt.Sprintf("%s%d", base, i)
+	}
+}
+
+// mapRole: claim có thể là chuỗi ("admin") hoặc mảng (groups: ["staff", "fs-admins"]).
+func mapRole(claim any, adminValues []string) string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(strings.ReplaceAll(v, ",", " "))
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	for _, value := range values {
+		if slices.Contains(adminValues, value) {
+			return "admin"
+		}
+	}
+	return "user"
+}
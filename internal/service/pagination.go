@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+// pageRequest kiểm tra sortBy, order và cursor của một endpoint danh sách. Sắp xếp theo tên mặc định
+// tăng dần, các khóa khác giảm dần. Cursor phải được sinh với đúng cách sắp xếp của request,
+// nếu không vị trí keyset không còn ý nghĩa.
+func pageRequest(query dto.PageQuery, sorts []string, defaultSort string) (domain.PageRequest, *utils.ReturnStatus) {
+	page := domain.PageRequest{
+		SortBy: query.SortBy,
+		Order:  strings.ToLower(query.Order),
+		Limit:  query.Limit,
+	}
+
+	if page.SortBy == "" {
+		page.SortBy = defaultSort
+	}
+	if !slices.Contains(sorts, page.SortBy) {
+		return page, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("sortBy must be one of: %s", strings.Join(sorts, ", ")))
+	}
+
+	if page.Order == "" {
+		page.Order = domain.ORDER_DESC
+		if page.SortBy == domain.SORT_NAME {
+			page.Order = domain.ORDER_ASC
+		}
+	}
+
+	if query.Cursor != "" {
+		cursor, err := domain.DecodeCursor(query.Cursor)
+		if err != nil || cursor.SortBy != page.SortBy || cursor.Order != page.Order {
+			return page, utils.Response(utils.ErrCodeCursorInvalid)
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/validation"
+)
+
+// checkPassword áp dụng password policy hiện hành (admin có thể đổi lúc chạy) cho password
+// tài khoản và password file. field là tên trường trả về cho client.
+func checkPassword(policy *config.SystemPolicy, field, password string) *utils.ReturnStatus {
+	violations := validation.PasswordPolicy{
+		MinLength:       policy.RequirePasswordMinLength,
+		RequiredClasses: policy.PasswordRequiredClasses,
+		RejectBreached:  policy.PasswordCheckBreached,
+	}.Check(password)
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return utils.ResponseArgs(utils.ErrCodePasswordPolicy, map[string]any{
+		"field":      field,
+		"violations": violations,
+	})
+}
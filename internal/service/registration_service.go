@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/mail"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+)
+
+const (
+	registrationTokenLength = 32
+	inviteDefaultDays       = 7
+	emailVerificationTTL    = 24 * time.Hour
+)
+
+type registrationService struct {
+	policy   *config.SystemPolicy
+	mailCfg  config.MailConfig
+	repo     repository.RegistrationRepository
+	userRepo repository.UserRepository
+	mailer   mail.Mailer
+}
+
+func NewRegistrationService(cfg *config.Config, repo repository.RegistrationRepository, userRepo repository.UserRepository, mailer mail.Mailer) RegistrationService {
+	return &registrationService{
+		policy:   cfg.Policy,
+		mailCfg:  cfg.Mail,
+		repo:     repo,
+		userRepo: userRepo,
+		mailer:   mailer,
+	}
+}
+
+// Admit kiểm tra một lượt đăng ký theo registration mode và danh sách domain.
+// Mã mời do admin cấp được ưu tiên hơn allow/deny list.
+func (s *registrationService) Admit(ctx context.Context, email string, inviteToken string) (*domain.SignupTicket, *utils.ReturnStatus) {
+	ticket := &domain.SignupTicket{EmailVerified: !s.policy.RequireEmailVerification}
+
+	if inviteToken = strings.TrimSpace(inviteToken); inviteToken != "" {
+		invite, err := s.repo.ClaimInvite(ctx, HashAPIToken(inviteToken))
+		if err != nil {
+			return nil, err
+		}
+		if invite.Email != "" && invite.Email != email {
+			s.release(ctx, invite)
+			return nil, utils.Response(utils.ErrCodeInviteInvalid)
+		}
+
+		ticket.Invite = invite
+		// Mã mời đã được gửi tới chính email này nên không cần xác minh lại
+		if invite.Email != "" {
+			ticket.EmailVerified = true
+		}
+		return ticket, nil
+	}
+
+	if s.policy.RegistrationMode == domain.REGISTRATION_INVITE {
+		return nil, utils.Response(utils.ErrCodeRegistrationInviteRequired)
+	}
+
+	if err := s.CheckEmailDomain(ctx, email); err != nil {
+		return nil, err
+	}
+
+	return ticket, nil
+}
+
+func (s *registrationService) Release(ctx context.Context, ticket *domain.SignupTicket) {
+	if ticket != nil && ticket.Invite != nil {
+		s.release(ctx, ticket.Invite)
+	}
+}
+
+func (s *registrationService) release(ctx context.Context, invite *domain.Invite) {
+	if err := s.repo.ReleaseInvite(ctx, invite.Id); err != nil {
+		log.Printf("Registration: failed to release invite %s: %v", invite.Id, err.Error())
+	}
+}
+
+// Complete chạy sau khi tạo user: gắn invite với user và gửi email xác minh nếu cần.
+// Lỗi gửi mail chỉ ghi log, user có thể yêu cầu gửi lại.
+func (s *registrationService) Complete(ctx context.Context, ticket *domain.SignupTicket, user *domain.User) {
+	if ticket.Invite != nil {
+		if err := s.repo.CompleteInvite(ctx, ticket.Invite.Id, user.Id); err != nil {
+			log.Printf("Registration: failed to record invite %s usage: %v", ticket.Invite.Id, err.Error())
+		}
+	}
+
+	if !user.EmailVerified {
+		if err := s.SendVerification(ctx, user); err != nil {
+			log.Printf("Registration: failed to send verification email to %s: %v", user.Email, err.Error())
+		}
+	}
+}
+
+// CheckEmailDomain: domain trong deny list luôn bị chặn, ở chế độ "domain" phải nằm trong allow list.
+func (s *registrationService) CheckEmailDomain(ctx context.Context, email string) *utils.ReturnStatus {
+	emailDomain := emailDomainOf(email)
+
+	rule, err := s.repo.FindDomainRule(ctx, emailDomain)
+	if err != nil {
+		return err
+	}
+
+	denied := rule != nil && rule.Rule == domain.DOMAIN_RULE_DENY
+	notAllowed := s.policy.RegistrationMode == domain.REGISTRATION_DOMAIN && (rule == nil || rule.Rule != domain.DOMAIN_RULE_ALLOW)
+	if denied || notAllowed {
+		return utils.ResponseArgs(utils.ErrCodeEmailDomainNotAllowed, map[string]any{"domain": emailDomain})
+	}
+
+	return nil
+}
+
+func (s *registrationService) SendVerification(ctx context.Context, user *domain.User) *utils.ReturnStatus {
+	link, err := s.newVerificationLink(ctx, user.Id, "")
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"Please confirm your email address by opening the link below:\n%s\n\n"+
+		"The link expires in 24 hours. If you did not create an account, you can ignore this email.\n",
+		user.Username, link)
+
+	if err := s.mailer.Send(ctx, mail.Message{To: user.Email, Subject: "Verify your email address", Body: body}); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("Failed to send verification email: %v", err))
+	}
+
+	return nil
+}
+
+// RequestEmailChange gửi link xác minh tới email mới; email của tài khoản chỉ đổi khi link được mở.
+// Email cũ nhận thông báo để chủ tài khoản phát hiện nếu không phải họ yêu cầu.
+func (s *registrationService) RequestEmailChange(ctx context.Context, user *domain.User, newEmail string) *utils.ReturnStatus {
+	link, err := s.newVerificationLink(ctx, user.Id, newEmail)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\n"+
+		"Please confirm your new email address by opening the link below:\n%s\n\n"+
+		"The link expires in 24 hours. Your account keeps using the current address until then.\n",
+		user.Username, link)
+	if err := s.mailer.Send(ctx, mail.Message{To: newEmail, Subject: "Confirm your new email address", Body: body}); err != nil {
+		return utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("Failed to send verification email: %v", err))
+	}
+
+	notice := fmt.Sprintf("Hello %s,\n\n"+
+		"A request was made to change the email address of your File Sharing account to %s.\n"+
+		"If this was not you, change your password and contact an administrator.\n",
+		user.Username, newEmail)
+	if err := s.mailer.Send(ctx, mail.Message{To: user.Email, Subject: "Email change requested", Body: notice}); err != nil {
+		log.Printf("Registration: failed to send email change notice to %s: %v", user.Email, err)
+	}
+
+	return nil
+}
+
+func (s *registrationService) newVerificationLink(ctx context.Context, userID string, email string) (string, *utils.ReturnStatus) {
+	token, err := utils.GenerateSecureString(registrationTokenLength, utils.TokenAlphabets[utils.DefaultTokenAlphabet])
+	if err != nil {
+		return "", utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate verification token")
+	}
+
+	if err := s.repo.SaveVerification(ctx, userID, email, HashAPIToken(token), time.Now().Add(emailVerificationTTL)); err != nil {
+		return "", err
+	}
+
+	link := s.mailCfg.VerifyEmailURL
+	if strings.Contains(link, "?") {
+		link += "&token=" + url.QueryEscape(token)
+	} else {
+		link += "?token=" + url.QueryEscape(token)
+	}
+	return link, nil
+}
+
+func (s *registrationService) VerifyEmail(ctx context.Context, token string) *utils.ReturnStatus {
+	verified, err := s.repo.ConsumeVerification(ctx, HashAPIToken(strings.TrimSpace(token)))
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return utils.Response(utils.ErrCodeEmailVerificationInvalid)
+	}
+
+	return nil
+}
+
+// ResendVerification luôn thành công với email không tồn tại hoặc đã xác minh
+// để endpoint không dùng được để dò tài khoản.
+func (s *registrationService) ResendVerification(ctx context.Context, email string) *utils.ReturnStatus {
+	user := &domain.User{}
+	if err := s.userRepo.FindByEmail(utils.NormalizeString(email), user); err != nil || user.EmailVerified {
+		return nil
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+func (s *registrationService) ListDomainRules(ctx context.Context) ([]domain.EmailDomainRule, *utils.ReturnStatus) {
+	return s.repo.ListDomainRules(ctx)
+}
+
+func (s *registrationService) SetDomainRule(ctx context.Context, emailDomain string, rule string) (*domain.EmailDomainRule, *utils.ReturnStatus) {
+	emailDomain = utils.NormalizeString(strings.TrimPrefix(strings.TrimSpace(emailDomain), "@"))
+	if emailDomain == "" || strings.ContainsAny(emailDomain, "@ /") {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "Invalid email domain")
+	}
+
+	result := &domain.EmailDomainRule{Domain: emailDomain, Rule: rule}
+	if err := s.repo.SaveDomainRule(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *registrationService) DeleteDomainRule(ctx context.Context, emailDomain string) *utils.ReturnStatus {
+	return s.repo.DeleteDomainRule(ctx, utils.NormalizeString(strings.TrimPrefix(emailDomain, "@")))
+}
+
+// CreateInvite trả về mã mời dạng plaintext, chỉ hiển thị một lần. Nếu có email,
+// mã được gửi tới email đó và chỉ email đó dùng được.
+func (s *registrationService) CreateInvite(ctx context.Context, createdBy string, email string, expiresInDays *int) (*domain.Invite, string, *utils.ReturnStatus) {
+	days := inviteDefaultDays
+	if expiresInDays != nil {
+		days = *expiresInDays
+	}
+
+	token, err := utils.GenerateSecureString(registrationTokenLength, utils.TokenAlphabets[utils.DefaultTokenAlphabet])
+	if err != nil {
+		return nil, "", utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate invitation")
+	}
+
+	invite := &domain.Invite{
+		TokenHash: HashAPIToken(token),
+		Email:     utils.NormalizeString(email),
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(time.Duration(days) * 24 * time.Hour),
+	}
+	if err := s.repo.CreateInvite(ctx, invite); err != nil {
+		return nil, "", err
+	}
+
+	if invite.Email != "" {
+		body := fmt.Sprintf("You have been invited to create a File Sharing account.\n\n"+
+			"Invitation code: %s\n\n"+
+			"Register with this email address and enter the code above. The invitation expires on %s.\n",
+			token, invite.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"))
+		if err := s.mailer.Send(ctx, mail.Message{To: invite.Email, Subject: "You're invited to File Sharing", Body: body}); err != nil {
+			log.Printf("Registration: failed to send invite email to %s: %v", invite.Email, err)
+		}
+	}
+
+	return invite, token, nil
+}
+
+func (s *registrationService) ListInvites(ctx context.Context) ([]domain.Invite, *utils.ReturnStatus) {
+	return s.repo.ListInvites(ctx)
+}
+
+func (s *registrationService) DeleteInvite(ctx context.Context, id string) *utils.ReturnStatus {
+	return s.repo.DeleteInvite(ctx, id)
+}
+
+func emailDomainOf(email string) string {
+	return utils.NormalizeString(email[strings.LastIndex(email, "@")+1:])
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type userAdminService struct {
+	policy       *config.SystemPolicy
+	userRepo     repository.UserRepository
+	authRepo     repository.AuthRepository
+	webAuthnRepo repository.WebAuthnRepository
+}
+
+func NewUserAdminService(policy *config.SystemPolicy, userRepo repository.UserRepository, authRepo repository.AuthRepository, webAuthnRepo repository.WebAuthnRepository) UserAdminService {
+	return &userAdminService{
+		policy:       policy,
+		userRepo:     userRepo,
+		authRepo:     authRepo,
+		webAuthnRepo: webAuthnRepo,
+	}
+}
+
+func (s *userAdminService) BootstrapAdmin(ctx context.Context, username, email, password string) (*domain.User, *utils.ReturnStatus) {
+	username = strings.TrimSpace(username)
+	email = utils.NormalizeString(email)
+	if username == "" {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "username is required")
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, "invalid email address")
+	}
+
+	admins, err := s.userRepo.ListAdmins()
+	if err != nil {
+		return nil, err
+	}
+	if len(admins) > 0 {
+		return nil, utils.ResponseMsg(utils.ErrCodeConflict, "an admin account already exists")
+	}
+
+	if err := checkPassword(s.policy, "password", password); err != nil {
+		return nil, err
+	}
+
+	// Admin do người vận hành tạo trực tiếp, không cần qua link xác minh email
+	return createAccount(s.userRepo, s.authRepo, username, password, email, "admin", true)
+}
+
+func (s *userAdminService) ListUsers(ctx context.Context) ([]domain.User, *utils.ReturnStatus) {
+	return s.userRepo.List()
+}
+
+func (s *userAdminService) SetSuspended(ctx context.Context, ident string, suspended bool) (*domain.User, *utils.ReturnStatus) {
+	user, err := s.findUser(ident)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetSuspended(user.Id, suspended); err != nil {
+		return nil, err
+	}
+	if suspended {
+		if err := s.authRepo.RevokeUserTokens(ctx, user.Id, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Suspended = suspended
+	return user, nil
+}
+
+func (s *userAdminService) ResetTwoFactor(ctx context.Context, ident string, removePasskeys bool) (*domain.User, int, *utils.ReturnStatus) {
+	user, err := s.findUser(ident)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.authRepo.DisableTOTP(user.Id); err != nil {
+		return nil, 0, err
+	}
+	user.EnableTOTP, user.SecretTOTP = false, ""
+
+	removed := 0
+	if removePasskeys {
+		credentials, err := s.webAuthnRepo.ListByUser(ctx, user.Id)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, cred := range credentials {
+			if err := s.webAuthnRepo.Delete(ctx, cred.Id, user.Id); err != nil {
+				return nil, removed, err
+			}
+			removed++
+		}
+	}
+
+	// Bước 2 đang chờ (CID) được tạo trước khi reset không còn giá trị
+	if err := s.userRepo.DeleteTimestamp(user.Id); err != nil {
+		return nil, removed, err
+	}
+
+	return user, removed, nil
+}
+
+// PurgeTokenBlacklist: JWT sống tối đa AccessTokenTTL nên mốc thu hồi cũ hơn thế không còn chặn
+// token nào.
+func (s *userAdminService) PurgeTokenBlacklist(ctx context.Context) (int64, *utils.ReturnStatus) {
+	now := time.Now()
+	return s.authRepo.PurgeBlacklist(now, now.Add(-jwt.AccessTokenTTL))
+}
+
+func (s *userAdminService) findUser(ident string) (*domain.User, *utils.ReturnStatus) {
+	user := &domain.User{}
+	if _, err := uuid.Parse(ident); err == nil {
+		return user, s.userRepo.FindById(ident, user)
+	}
+	return user, s.userRepo.FindByEmail(utils.NormalizeString(ident), user)
+}
@@ -1,18 +1,48 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type userService struct {
-	userRepo repository.UserRepository
+	policy       *config.SystemPolicy
+	userRepo     repository.UserRepository
+	authRepo     repository.AuthRepository
+	fileRepo     repository.FileRepository
+	storage      storage.Storage
+	registration RegistrationService
+	guard        BruteForceGuard
+	exports      ExportService
+	webAuthn     WebAuthnService
+	txManager    repository.TxManager
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
+func NewUserService(cfg *config.Config, repo repository.UserRepository, authRepo repository.AuthRepository, fileRepo repository.FileRepository, storage storage.Storage, registration RegistrationService, guard BruteForceGuard, exports ExportService, webAuthn WebAuthnService, tm repository.TxManager) UserService {
 	return &userService{
-		userRepo: repo,
+		policy:       cfg.Policy,
+		userRepo:     repo,
+		authRepo:     authRepo,
+		fileRepo:     fileRepo,
+		storage:      storage,
+		registration: registration,
+		guard:        guard,
+		exports:      exports,
+		webAuthn:     webAuthn,
+		txManager:    tm,
 	}
 }
 
@@ -32,6 +62,62 @@ func (us *userService) GetUserById(id string) (*domain.UserResponse, *utils.Retu
 	return resp, nil
 }
 
+// GetProfile: user chỉ xem được profile của chính mình, admin xem được mọi user.
+func (us *userService) GetProfile(ctx context.Context, requesterID string, userID string) (*domain.UserResponse, *utils.ReturnStatus) {
+	if userID == "" || userID == requesterID {
+		return us.GetUserById(requesterID)
+	}
+
+	// Role lấy từ DB thay vì JWT để admin bị hạ quyền mất quyền xem ngay.
+	requester := &domain.User{}
+	if err := us.userRepo.FindById(requesterID, requester); err != nil {
+		return nil, err
+	}
+	if requester.Role != "admin" {
+		return nil, utils.Response(utils.ErrCodeCantAccessResource)
+	}
+
+	return us.GetUserById(userID)
+}
+
+// userSearchMinQuery: số ký tự tối thiểu của query, tránh liệt kê cả danh bạ bằng 1 ký tự.
+const userSearchMinQuery = 2
+
+// SearchUsers gợi ý người nhận khi chia sẻ file, phạm vi theo policy UserDirectoryMode.
+func (us *userService) SearchUsers(ctx context.Context, requesterID string, query string) ([]domain.PublicProfile, *utils.ReturnStatus) {
+	requester := &domain.User{}
+	if err := us.userRepo.FindById(requesterID, requester); err != nil {
+		return nil, err
+	}
+
+	mode := us.policy.UserDirectoryMode
+	if requester.Role == "admin" {
+		mode = domain.USER_DIRECTORY_ALL
+	}
+	if mode == domain.USER_DIRECTORY_OFF {
+		return nil, utils.Response(utils.ErrCodeUserSearchDisabled)
+	}
+
+	query = utils.NormalizeString(query)
+	if utf8.RuneCountInString(query) < userSearchMinQuery {
+		return nil, utils.ResponseMsg(utils.ErrCodeBadRequest, fmt.Sprintf("Query must be at least %d characters", userSearchMinQuery))
+	}
+
+	params := domain.UserSearchParams{
+		Query:     query,
+		ExcludeId: requester.Id,
+		Limit:     us.policy.UserSearchMaxResults,
+	}
+	switch mode {
+	case domain.USER_DIRECTORY_EXACT:
+		params.ExactEmail = true
+	case domain.USER_DIRECTORY_DOMAIN:
+		params.Domain = emailDomainOf(requester.Email)
+	}
+
+	return us.userRepo.Search(ctx, params)
+}
+
 func (us *userService) GetUserByEmail(email string) (*domain.UserResponse, *utils.ReturnStatus) {
 	user := &domain.User{}
 	err := us.userRepo.FindByEmail(email, user)
@@ -47,3 +133,209 @@ func (us *userService) GetUserByEmail(email string) (*domain.UserResponse, *util
 	}
 	return resp, nil
 }
+
+// UpdateProfile đổi username/email của chính user. Khi policy yêu cầu xác minh email,
+// email mới chỉ có hiệu lực sau khi mở link xác minh (emailPending = true).
+// Cả hai trường được kiểm tra trước rồi mới ghi trong một transaction: request lỗi thì không đổi gì.
+func (us *userService) UpdateProfile(ctx context.Context, userID string, req *dto.UpdateProfileRequest) (*domain.UserResponse, bool, *utils.ReturnStatus) {
+	user := &domain.User{}
+	if err := us.userRepo.FindById(userID, user); err != nil {
+		return nil, false, err
+	}
+
+	username := user.Username
+	if req.Username != nil {
+		username = strings.TrimSpace(*req.Username)
+		if username == "" {
+			return nil, false, utils.ResponseMsg(utils.ErrCodeBadRequest, "Username cannot be empty")
+		}
+		// Chỉ đổi hoa thường của chính username hiện tại thì không cần kiểm tra trùng
+		if !strings.EqualFold(username, user.Username) {
+			if taken, err := us.userRepo.UsernameExists(username); err != nil {
+				return nil, false, err
+			} else if taken {
+				return nil, false, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "username"})
+			}
+		}
+	}
+
+	email := user.Email
+	if req.Email != nil {
+		email = utils.NormalizeString(*req.Email)
+		if email != user.Email {
+			if taken, err := us.userRepo.EmailExists(email); err != nil {
+				return nil, false, err
+			} else if taken {
+				return nil, false, utils.ResponseArgs(utils.ErrCodeUserConflict, map[string]any{"field": "email"})
+			}
+			if err := us.registration.CheckEmailDomain(ctx, email); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	emailPending := false
+	err := us.txManager.WithinTx(ctx, func(ctx context.Context) *utils.ReturnStatus {
+		if username != user.Username {
+			if err := us.userRepo.UpdateUsername(ctx, user.Id, username); err != nil {
+				return err
+			}
+			user.Username = username
+		}
+
+		if email == user.Email {
+			return nil
+		}
+		if us.policy.RequireEmailVerification {
+			emailPending = true
+			return us.registration.RequestEmailChange(ctx, user, email)
+		}
+		if err := us.userRepo.UpdateEmail(ctx, user.Id, email); err != nil {
+			return err
+		}
+		user.Email = email
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &domain.UserResponse{
+		Id:         user.Id,
+		Username:   user.Username,
+		Email:      user.Email,
+		Role:       user.Role,
+		EnableTOTP: user.EnableTOTP,
+	}, emailPending, nil
+}
+
+// accountReauthWindow: tài khoản SSO không có password phải vừa đăng nhập lại trong khoảng này mới được xóa.
+const accountReauthWindow = 5 * time.Minute
+
+// DeleteAccount xóa tài khoản của chính user sau khi xác thực lại bằng password và yếu tố thứ hai
+// mà tài khoản có (TOTP hoặc passkey). Tài khoản tạo qua SSO không có password thì thay bằng passkey
+// hoặc phiên vừa đăng nhập lại qua SSO (authenticatedAt là lúc cấp phiên hiện tại).
+// File của user bị xóa hoặc chuyển cho một admin; mọi token của user bị thu hồi.
+func (us *userService) DeleteAccount(ctx context.Context, userID string, authenticatedAt time.Time, req *dto.DeleteAccountRequest, clientIP string) *utils.ReturnStatus {
+	user := &domain.User{}
+	if err := us.userRepo.FindById(userID, user); err != nil {
+		return err
+	}
+
+	// Đoán password/TOTP ở đây được tính chung với đăng nhập
+	reservation, err := us.guard.Reserve(ctx, domain.IPAttempt(clientIP), domain.AccountAttempt(user.Email))
+	if err != nil {
+		return err
+	}
+	passkeys, err := us.webAuthn.ListCredentials(ctx, user.Id)
+	if err != nil {
+		us.guard.Release(ctx, reservation)
+		return err
+	}
+	if user.Password != "" {
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+			return utils.Response(utils.ErrCodeCurrentPasswordInvalid)
+		}
+	} else if len(passkeys) == 0 && time.Since(authenticatedAt) > accountReauthWindow {
+		// Passkey (nếu có) được kiểm tra ở checkSecondFactor
+		us.guard.Release(ctx, reservation)
+		return utils.Response(utils.ErrCodeReauthRequired)
+	}
+	if err := us.checkSecondFactor(ctx, user, len(passkeys) > 0, req); err != nil {
+		return err
+	}
+	us.guard.Release(ctx, reservation)
+	us.guard.Succeed(ctx, domain.AccountAttempt(user.Email))
+
+	admins, err := us.userRepo.ListAdmins()
+	if err != nil {
+		return err
+	}
+	if user.Role == "admin" && len(admins) <= 1 {
+		return utils.Response(utils.ErrCodeLastAdmin)
+	}
+
+	var target *domain.User
+	if req.Files == dto.AccountFilesTransfer {
+		if target, err = us.transferTarget(user, req.TransferTo, admins); err != nil {
+			return err
+		}
+	}
+
+	// Archive export nằm trong storage, phải xóa trước khi job bị xóa theo user. Archive chỉ là bản
+	// sao dữ liệu, nếu bước sau lỗi user tạo lại được.
+	us.exports.DeleteUserExports(ctx, user.Id)
+
+	// Thu hồi token, chuyển file và xóa user trong một transaction: lỗi giữa chừng thì tài khoản còn
+	// nguyên, không bị xóa dở. API token và passkey bị xóa theo user (ON DELETE CASCADE).
+	var fileIDs []string
+	err = us.txManager.WithinTx(ctx, func(ctx context.Context) *utils.ReturnStatus {
+		if err := us.authRepo.RevokeUserTokens(ctx, user.Id, time.Now()); err != nil {
+			return err
+		}
+
+		if target != nil {
+			moved, err := us.fileRepo.TransferOwnership(ctx, user.Id, target.Id)
+			if err != nil {
+				return err
+			}
+			log.Printf("Account: transferred %d files of user %s to admin %s", moved, user.Id, target.Id)
+		} else {
+			ids, err := us.fileRepo.ListFileIDsByOwner(ctx, user.Id)
+			if err != nil {
+				return err
+			}
+			fileIDs = ids
+		}
+
+		return us.userRepo.Delete(ctx, user.Id)
+	})
+	if err != nil {
+		return err
+	}
+
+	// File vật lý chỉ xóa sau khi commit; metadata đã bị xóa theo user, lỗi chỉ ghi log
+	for _, id := range fileIDs {
+		if err := us.storage.DeleteFile(id); err.IsErr() {
+			log.Printf("Account: failed to delete physical file %s: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// checkSecondFactor đòi yếu tố thứ hai mà tài khoản thực sự có. Có cả TOTP và passkey thì dùng cái
+// nào cũng được, giống bước 2 của Login.
+func (us *userService) checkSecondFactor(ctx context.Context, user *domain.User, hasPasskeys bool, req *dto.DeleteAccountRequest) *utils.ReturnStatus {
+	hasTOTP := user.EnableTOTP && user.SecretTOTP != ""
+
+	switch {
+	case hasTOTP && req.TOTPCode != "":
+		if !totp.Validate(req.TOTPCode, user.SecretTOTP) {
+			return utils.Response(utils.ErrCodeTOTPCodeInvalid)
+		}
+		return nil
+	case hasPasskeys && req.WebAuthnSessionID != "":
+		return us.webAuthn.VerifyReauthentication(ctx, user.Id, req.WebAuthnSessionID, req.WebAuthnCredential)
+	case hasTOTP:
+		return utils.Response(utils.ErrCodeTOTPCodeInvalid)
+	case hasPasskeys:
+		return utils.Response(utils.ErrCodeWebAuthnFailed)
+	}
+	return nil
+}
+
+// transferTarget: admin được chỉ định bằng email, hoặc admin đầu tiên (theo username) khác user.
+func (us *userService) transferTarget(user *domain.User, email string, admins []domain.User) (*domain.User, *utils.ReturnStatus) {
+	email = utils.NormalizeString(email)
+	for i := range admins {
+		if admins[i].Id == user.Id {
+			continue
+		}
+		if email == "" || admins[i].Email == email {
+			return &admins[i], nil
+		}
+	}
+
+	return nil, utils.Response(utils.ErrCodeTransferTargetInvalid)
+}
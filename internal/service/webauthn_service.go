@@ -0,0 +1,507 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	webAuthnSessionIDLength = 32
+	webAuthnSessionTTL      = 5 * time.Minute
+)
+
+// webAuthnUser gắn domain.User với các authenticator đã đăng ký để dùng với thư viện webauthn.
+// User handle là 16 byte UUID của user, không chứa thông tin cá nhân.
+type webAuthnUser struct {
+	user        *domain.User
+	records     []domain.WebAuthnCredential
+	credentials []webauthn.Credential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	id, _ := uuid.Parse(u.user.Id)
+	return id[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// record tìm bản ghi DB ứng với credential ID do authenticator trả về.
+func (u *webAuthnUser) record(credentialID []byte) *domain.WebAuthnCredential {
+	for i := range u.records {
+		if bytes.Equal(u.records[i].CredentialID, credentialID) {
+			return &u.records[i]
+		}
+	}
+	return nil
+}
+
+type webAuthnService struct {
+	webAuthn     *webauthn.WebAuthn
+	repo         repository.WebAuthnRepository
+	userRepo     repository.UserRepository
+	tokenService jwt.TokenService
+	guard        BruteForceGuard
+}
+
+func NewWebAuthnService(cfg config.WebAuthnConfig, repo repository.WebAuthnRepository, userRepo repository.UserRepository, tokenService jwt.TokenService, guard BruteForceGuard) WebAuthnService {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+	})
+	if err != nil {
+		// Không chặn server khởi động, các endpoint WebAuthn sẽ trả 503.
+		log.Printf("WebAuthn disabled: %v", err)
+	}
+
+	return &webAuthnService{
+		webAuthn:     wa,
+		repo:         repo,
+		userRepo:     userRepo,
+		tokenService: tokenService,
+		guard:        guard,
+	}
+}
+
+func (s *webAuthnService) BeginRegistration(ctx context.Context, userID string, name string) (string, *protocol.CredentialCreation, *utils.ReturnStatus) {
+	if s.webAuthn == nil {
+		return "", nil, utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Resident key "preferred" để authenticator lưu passkey, dùng được cho đăng nhập không cần password.
+	creation, session, waErr := s.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.credentials).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if waErr != nil {
+		return "", nil, utils.ResponseMsg(utils.ErrCodeInternal, waErr.Error())
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fmt.Sprintf("Authenticator %d", len(user.credentials)+1)
+	}
+
+	sessionID, err := s.saveSession(ctx, &domain.WebAuthnSession{
+		Purpose: domain.WEBAUTHN_REGISTER,
+		UserId:  userID,
+		Name:    name,
+	}, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sessionID, creation, nil
+}
+
+func (s *webAuthnService) FinishRegistration(ctx context.Context, userID string, sessionID string, credential []byte) (*domain.WebAuthnCredential, *utils.ReturnStatus) {
+	if s.webAuthn == nil {
+		return nil, utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	record, session, err := s.consumeSession(ctx, sessionID, domain.WEBAUTHN_REGISTER)
+	if err != nil {
+		return nil, err
+	}
+	if record.UserId != userID {
+		return nil, utils.Response(utils.ErrCodeWebAuthnSessionInvalid)
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, parseErr := protocol.ParseCredentialCreationResponseBytes(credential)
+	if parseErr != nil {
+		return nil, webAuthnError(parseErr)
+	}
+
+	created, waErr := s.webAuthn.CreateCredential(user, *session, parsed)
+	if waErr != nil {
+		return nil, webAuthnError(waErr)
+	}
+
+	data, marshalErr := json.Marshal(created)
+	if marshalErr != nil {
+		return nil, utils.ResponseMsg(utils.ErrCodeInternal, marshalErr.Error())
+	}
+
+	cred := &domain.WebAuthnCredential{
+		UserId:       userID,
+		CredentialID: created.ID,
+		Name:         record.Name,
+		Data:         data,
+	}
+	if err := s.repo.Create(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	return cred, nil
+}
+
+func (s *webAuthnService) ListCredentials(ctx context.Context, userID string) ([]domain.WebAuthnCredential, *utils.ReturnStatus) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *webAuthnService) DeleteCredential(ctx context.Context, userID string, credentialID string) *utils.ReturnStatus {
+	return s.repo.Delete(ctx, credentialID, userID)
+}
+
+// BeginSecondFactor: bước 2 của Login khi user đã có authenticator, thay cho mã TOTP.
+func (s *webAuthnService) BeginSecondFactor(ctx context.Context, cid string) (string, *protocol.CredentialAssertion, *utils.ReturnStatus) {
+	if s.webAuthn == nil {
+		return "", nil, utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	sess := &domain.UsersLoginSession{}
+	if err := s.userRepo.FindByCId(cid, sess); err != nil {
+		return "", nil, utils.ResponseMsg(utils.ErrCodeUnauthorized, "Wrong CID")
+	}
+
+	user, err := s.loadUser(ctx, sess.Id)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(user.credentials) == 0 {
+		return "", nil, utils.Response(utils.ErrCodeWebAuthnCredentialNotFound)
+	}
+
+	assertion, session, waErr := s.webAuthn.BeginLogin(user)
+	if waErr != nil {
+		return "", nil, utils.ResponseMsg(utils.ErrCodeInternal, waErr.Error())
+	}
+
+	sessionID, err := s.saveSession(ctx, &domain.WebAuthnSession{
+		Purpose: domain.WEBAUTHN_MFA,
+		UserId:  sess.Id,
+		Cid:     cid,
+	}, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sessionID, assertion, nil
+}
+
+func (s *webAuthnService) FinishSecondFactor(ctx context.Context, cid string, sessionID string, credential []byte, clientIP string) (*domain.User, string, *utils.ReturnStatus) {
+	if s.webAuthn == nil {
+		return nil, "", utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	ipReservation, err := s.guard.Reserve(ctx, domain.IPAttempt(clientIP))
+	if err != nil {
+		return nil, "", err
+	}
+
+	record, session, err := s.consumeSession(ctx, sessionID, domain.WEBAUTHN_MFA)
+	if err != nil {
+		s.guard.Release(ctx, ipReservation)
+		return nil, "", err
+	}
+
+	sess := &domain.UsersLoginSession{}
+	if err := s.userRepo.FindByCId(cid, sess); err != nil || record.Cid != cid || record.UserId != sess.Id {
+		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Wrong CID")
+	}
+
+	user, err := s.loadUser(ctx, sess.Id)
+	if err != nil {
+		s.guard.Release(ctx, ipReservation)
+		return nil, "", err
+	}
+
+	accountAttempt := domain.AccountAttempt(user.user.Email)
+	accountReservation, err := s.guard.Reserve(ctx, accountAttempt)
+	if err != nil {
+		s.guard.Release(ctx, ipReservation)
+		return nil, "", err
+	}
+
+	if err := s.validateLogin(ctx, user, session, credential); err != nil {
+		return nil, "", err
+	}
+	s.guard.Release(ctx, ipReservation, accountReservation)
+
+	// CID dùng một lần, giống LoginTOTP
+	if err := s.userRepo.DeleteTimestamp(user.user.Id); err != nil {
+		return nil, "", utils.ResponseMsg(utils.ErrCodeUnauthorized, "Delete timestamp failed")
+	}
+	if err := checkCIDExpiry(cid); err != nil {
+		return nil, "", err
+	}
+
+	issued, accessToken, err := s.issueToken(user.user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Reset bộ đếm tài khoản chỉ khi đăng nhập đã hoàn tất, giống LoginTOTP
+	s.guard.Succeed(ctx, accountAttempt)
+	return issued, accessToken, nil
+}
+
+// BeginPasskeyLogin: đăng nhập không cần password, authenticator tự chọn passkey (discoverable credential).
+func (s *webAuthnService) BeginPasskeyLogin(ctx context.Context) (string, *protocol.CredentialAssertion, *utils.ReturnStatus) {
+	if s.webAuthn == nil {
+		return "", nil, utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	// Passkey thay cả password nên bắt buộc user verification (PIN/vân tay).
+	assertion, session, waErr := s.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if waErr != nil {
+		return "", nil, utils.ResponseMsg(utils.ErrCodeInternal, waErr.Error())
+	}
+
+	sessionID, err := s.saveSession(ctx, &domain.WebAuthnSession{Purpose: domain.WEBAUTHN_PASSKEY}, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sessionID, assertion, nil
+}
+
+func (s *webAuthnService) FinishPasskeyLogin(ctx context.Context, sessionID string, credential []byte, clientIP string) (*domain.User, string, *utils.ReturnStatus) {
+	if s.webAuthn == nil {
+		return nil, "", utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	reservation, err := s.guard.Reserve(ctx, domain.IPAttempt(clientIP))
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, session, err := s.consumeSession(ctx, sessionID, domain.WEBAUTHN_PASSKEY)
+	if err != nil {
+		s.guard.Release(ctx, reservation)
+		return nil, "", err
+	}
+
+	parsed, parseErr := protocol.ParseCredentialRequestResponseBytes(credential)
+	if parseErr != nil {
+		return nil, "", webAuthnError(parseErr)
+	}
+
+	var user *webAuthnUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, uuidErr := uuid.FromBytes(userHandle)
+		if uuidErr != nil {
+			return nil, uuidErr
+		}
+		loaded, loadErr := s.loadUser(ctx, userID.String())
+		if loadErr != nil {
+			return nil, fmt.Errorf("unknown user handle")
+		}
+		user = loaded
+		return loaded, nil
+	}
+
+	_, updated, waErr := s.webAuthn.ValidatePasskeyLogin(handler, *session, parsed)
+	if waErr != nil || updated.Authenticator.CloneWarning {
+		return nil, "", webAuthnError(waErr)
+	}
+	s.guard.Release(ctx, reservation)
+
+	if err := s.saveCredentialUsage(ctx, user, updated); err != nil {
+		return nil, "", err
+	}
+
+	return s.issueToken(user.user)
+}
+
+// BeginReauthentication: user đang đăng nhập xác nhận lại bằng authenticator trước thao tác nhạy cảm
+// (xóa tài khoản), thay cho mã TOTP với tài khoản chỉ dùng passkey.
+func (s *webAuthnService) BeginReauthentication(ctx context.Context, userID string) (string, *protocol.CredentialAssertion, *utils.ReturnStatus) {
+	if s.webAuthn == nil {
+		return "", nil, utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(user.credentials) == 0 {
+		return "", nil, utils.Response(utils.ErrCodeWebAuthnCredentialNotFound)
+	}
+
+	assertion, session, waErr := s.webAuthn.BeginLogin(user)
+	if waErr != nil {
+		return "", nil, utils.ResponseMsg(utils.ErrCodeInternal, waErr.Error())
+	}
+
+	sessionID, err := s.saveSession(ctx, &domain.WebAuthnSession{
+		Purpose: domain.WEBAUTHN_REAUTH,
+		UserId:  userID,
+	}, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sessionID, assertion, nil
+}
+
+// VerifyReauthentication kiểm tra assertion của BeginReauthentication; session dùng một lần và phải
+// thuộc đúng user đang đăng nhập. Brute-force do bên gọi tính.
+func (s *webAuthnService) VerifyReauthentication(ctx context.Context, userID string, sessionID string, credential []byte) *utils.ReturnStatus {
+	if s.webAuthn == nil {
+		return utils.Response(utils.ErrCodeWebAuthnNotConfigured)
+	}
+
+	record, session, err := s.consumeSession(ctx, sessionID, domain.WEBAUTHN_REAUTH)
+	if err != nil {
+		return err
+	}
+	if record.UserId != userID {
+		return utils.Response(utils.ErrCodeWebAuthnSessionInvalid)
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.validateLogin(ctx, user, session, credential)
+}
+
+func (s *webAuthnService) validateLogin(ctx context.Context, user *webAuthnUser, session *webauthn.SessionData, credential []byte) *utils.ReturnStatus {
+	parsed, parseErr := protocol.ParseCredentialRequestResponseBytes(credential)
+	if parseErr != nil {
+		return webAuthnError(parseErr)
+	}
+
+	updated, waErr := s.webAuthn.ValidateLogin(user, *session, parsed)
+	if waErr != nil {
+		return webAuthnError(waErr)
+	}
+	// Sign counter đi lùi: có thể authenticator đã bị sao chép.
+	if updated.Authenticator.CloneWarning {
+		return webAuthnError(nil)
+	}
+
+	return s.saveCredentialUsage(ctx, user, updated)
+}
+
+func (s *webAuthnService) saveCredentialUsage(ctx context.Context, user *webAuthnUser, updated *webauthn.Credential) *utils.ReturnStatus {
+	record := user.record(updated.ID)
+	if record == nil {
+		return utils.Response(utils.ErrCodeWebAuthnCredentialNotFound)
+	}
+
+	data, err := json.Marshal(updated)
+	if err != nil {
+		return utils.ResponseMsg(utils.ErrCodeInternal, err.Error())
+	}
+
+	return s.repo.UpdateAfterLogin(ctx, record.Id, data)
+}
+
+func (s *webAuthnService) issueToken(user *domain.User) (*domain.User, string, *utils.ReturnStatus) {
+	if err := checkActive(user); err != nil {
+		return nil, "", err
+	}
+
+	accessToken, err := s.tokenService.GenerateAccessToken(*user)
+	if err != nil {
+		return nil, "", utils.ResponseMsg(utils.ErrCodeInternal, fmt.Sprintf("Failed to generate access token: %s", err))
+	}
+	return user, accessToken, nil
+}
+
+func (s *webAuthnService) loadUser(ctx context.Context, userID string) (*webAuthnUser, *utils.ReturnStatus) {
+	user := &domain.User{}
+	if err := s.userRepo.FindById(userID, user); err != nil {
+		return nil, err
+	}
+
+	records, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(records))
+	for _, record := range records {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(record.Data, &credential); err != nil {
+			log.Printf("WebAuthn: skipping unreadable credential %s: %v", record.Id, err)
+			continue
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return &webAuthnUser{user: user, records: records, credentials: credentials}, nil
+}
+
+func (s *webAuthnService) saveSession(ctx context.Context, record *domain.WebAuthnSession, session *webauthn.SessionData) (string, *utils.ReturnStatus) {
+	id, err := utils.GenerateSecureString(webAuthnSessionIDLength, utils.TokenAlphabets[utils.DefaultTokenAlphabet])
+	if err != nil {
+		return "", utils.ResponseMsg(utils.ErrCodeInternal, "Failed to generate WebAuthn session")
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", utils.ResponseMsg(utils.ErrCodeInternal, err.Error())
+	}
+
+	record.Id = id
+	record.Data = data
+	record.ExpiresAt = time.Now().Add(webAuthnSessionTTL)
+	if err := s.repo.SaveSession(ctx, record); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *webAuthnService) consumeSession(ctx context.Context, id string, purpose domain.WebAuthnPurpose) (*domain.WebAuthnSession, *webauthn.SessionData, *utils.ReturnStatus) {
+	record, err := s.repo.ConsumeSession(ctx, id, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, nil, utils.Response(utils.ErrCodeWebAuthnSessionInvalid)
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(record.Data, &session); err != nil {
+		return nil, nil, utils.ResponseMsg(utils.ErrCodeInternal, err.Error())
+	}
+
+	return record, &session, nil
+}
+
+// webAuthnError trả chi tiết lỗi của thư viện (protocol.Error.Details) cho client để dễ debug phía frontend.
+func webAuthnError(err error) *utils.ReturnStatus {
+	if protoErr, ok := err.(*protocol.Error); ok && protoErr.Details != "" {
+		return utils.ResponseMsg(utils.ErrCodeWebAuthnFailed, protoErr.Details)
+	}
+	return utils.Response(utils.ErrCodeWebAuthnFailed)
+}
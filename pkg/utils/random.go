@@ -1,26 +1,52 @@
 package utils
 
 import (
-	"math/rand"
+	"crypto/rand"
+	"errors"
+	"math/big"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-func init() {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
+const DefaultTokenAlphabet = "alphanumeric"
+
+// TokenAlphabets là các bảng ký tự được phép dùng cho share token (chọn qua SystemPolicy).
+var TokenAlphabets = map[string]string{
+	"alphanumeric": charset,
+	"lowercase":    "abcdefghijklmnopqrstuvwxyz0123456789",
+	"base58":       "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz",
 }
 
+// GenerateRandomString sinh chuỗi ngẫu nhiên an toàn (crypto/rand) từ charset mặc định.
 func GenerateRandomString(length int) string {
-	seededRand := rand.New(rand.NewSource(time.Now().UnixNano()))
+	s, err := GenerateSecureString(length, charset)
+	if err != nil {
+		// crypto/rand chỉ lỗi khi hệ điều hành không cung cấp được entropy.
+		panic(err)
+	}
+	return s
+}
+
+// GenerateSecureString sinh chuỗi ngẫu nhiên dài length ký tự lấy từ alphabet.
+// rand.Int cho phân phối đều nên không bị lệch do phép modulo.
+func GenerateSecureString(length int, alphabet string) (string, error) {
+	if length <= 0 || len(alphabet) < 2 {
+		return "", errors.New("invalid token length or alphabet")
+	}
+
+	max := big.NewInt(int64(len(alphabet)))
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[seededRand.Intn(len(charset))]
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
 	}
-	return string(b)
+	return string(b), nil
 }
 
 func GetIntQuery(ctx *gin.Context, key string, defaultValue int) int {
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"fmt"
 	"maps"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,6 +34,8 @@ const (
 	ErrCodeUploadBadRequest       ErrorCode = "Bad Upload request"
 	ErrCodeUploadPasswordTooShort ErrorCode = "Password too short"
 	ErrCodeUploadFileTooBig       ErrorCode = "File size exceeds the system limit"
+	ErrCodeShareTokenTaken        ErrorCode = "Share link is already taken"
+	ErrCodeShareSlugInvalid       ErrorCode = "Share link slug is reserved"
 	ErrCodeFileExpired            ErrorCode = "File has expired"
 
 	ErrCodeDeleteValidationErr ErrorCode = "You do not have permission to delete this file"
@@ -41,6 +46,9 @@ const (
 	ErrCodeDownloadBearerRequired  ErrorCode = "This file requires authentication. Please provide a Bearer token"
 	ErrCodeDownloadPasswordInvalid ErrorCode = "The file password is incorrect"
 	ErrCodeFileLocked              ErrorCode = "File not yet available"
+	ErrCodeDownloadLimitReached    ErrorCode = "Download limit reached"
+	ErrCodeSignedURLInvalid        ErrorCode = "Signed URL is invalid"
+	ErrCodeSignedURLExpired        ErrorCode = "Signed URL has expired"
 
 	ErrCodeStatForbidden    ErrorCode = "You don't have permission to view statistics for this file"
 	ErrCodeFileStatNotFound ErrorCode = "File not found or statistics not available (anonymous upload)"
@@ -50,6 +58,62 @@ const (
 	ErrCodeCleanupNotAdmin   ErrorCode = "You don't have permission to perform cleanup"
 	ErrCodeCleanUpLimited    ErrorCode = "Cleanup endpoint is rate limited. Please try again later."
 
+	ErrCodeAPITokenInvalid   ErrorCode = "Invalid or expired API token"
+	ErrCodeAPITokenNotFound  ErrorCode = "API token not found"
+	ErrCodeAPITokenScope     ErrorCode = "API token scope not allowed"
+	ErrCodeInsufficientScope ErrorCode = "API token does not have the required scope"
+	ErrCodeSessionRequired   ErrorCode = "This action requires an interactive login"
+
+	ErrCodeOIDCProviderNotFound ErrorCode = "Identity provider not found"
+	ErrCodeOIDCStateInvalid     ErrorCode = "Invalid or expired login state"
+	ErrCodeOIDCFailed           ErrorCode = "Single sign-on failed"
+	ErrCodeOIDCEmailUnverified  ErrorCode = "Identity provider email is not verified"
+	ErrCodeOIDCSignupDisabled   ErrorCode = "No account is linked to this identity"
+
+	ErrCodeWebAuthnCredentialNotFound ErrorCode = "Authenticator not found"
+	ErrCodeWebAuthnSessionInvalid     ErrorCode = "Invalid or expired WebAuthn challenge"
+	ErrCodeWebAuthnFailed             ErrorCode = "WebAuthn verification failed"
+	ErrCodeWebAuthnNotConfigured      ErrorCode = "WebAuthn is not configured"
+
+	ErrCodeUserConflict               ErrorCode = "Username or email is already registered"
+	ErrCodeEmailNotVerified           ErrorCode = "Email address is not verified"
+	ErrCodeAccountSuspended           ErrorCode = "Account is suspended"
+	ErrCodeEmailVerificationInvalid   ErrorCode = "Invalid or expired verification link"
+	ErrCodeEmailDomainNotAllowed      ErrorCode = "Email domain is not allowed"
+	ErrCodeEmailDomainRuleNotFound    ErrorCode = "Email domain rule not found"
+	ErrCodeRegistrationInviteRequired ErrorCode = "Registration requires an invitation"
+	ErrCodeInviteInvalid              ErrorCode = "Invalid or expired invitation"
+	ErrCodeInviteNotFound             ErrorCode = "Invitation not found"
+
+	ErrCodePasswordPolicy ErrorCode = "Password does not meet the password policy"
+
+	ErrCodeCurrentPasswordInvalid ErrorCode = "Current password is incorrect"
+	ErrCodeTOTPCodeInvalid        ErrorCode = "Invalid or expired TOTP code"
+	ErrCodeReauthRequired         ErrorCode = "Recent sign-in required"
+	ErrCodeLastAdmin              ErrorCode = "Cannot delete the last administrator account"
+	ErrCodeTransferTargetInvalid  ErrorCode = "Files can only be transferred to another administrator"
+
+	ErrCodeExportNotFound ErrorCode = "Data export not found"
+	ErrCodeExportNotReady ErrorCode = "Data export is not ready yet"
+
+	ErrCodeUserSearchDisabled ErrorCode = "User directory search is disabled"
+
+	ErrCodeGroupNotFound       ErrorCode = "Group not found"
+	ErrCodeGroupForbidden      ErrorCode = "Only group owners can manage this group"
+	ErrCodeGroupMemberExists   ErrorCode = "User is already a member of this group"
+	ErrCodeGroupMemberNotFound ErrorCode = "User is not a member of this group"
+	ErrCodeLastGroupOwner      ErrorCode = "A group must keep at least one owner"
+	ErrCodeShareGroupInvalid   ErrorCode = "Files can only be shared with groups you belong to"
+
+	ErrCodeFolderNotFound    ErrorCode = "Folder not found"
+	ErrCodeFolderNameTaken   ErrorCode = "A folder with this name already exists here"
+	ErrCodeFolderMoveInvalid ErrorCode = "Cannot move a folder into itself or one of its subfolders"
+	ErrCodeFolderForbidden   ErrorCode = "Only the folder owner can modify this folder"
+
+	ErrCodeArchiveFileRejected ErrorCode = "One of the selected files cannot be downloaded"
+
+	ErrCodeCursorInvalid ErrorCode = "Invalid pagination cursor"
+
 	ErrCodeCantAccessResource     ErrorCode = "You don't have permission to access this resource"
 	ErrCodeInvalidMaxMinValidDays ErrorCode = "maxValidityDays must be greater than or equal to minValidityHours"
 )
@@ -71,6 +135,22 @@ func ErrIfExists(code ErrorCode, e error) *ReturnStatus {
 	return ResponseMsg(code, e.Error())
 }
 
+// String dùng cho log và CLI: mã lỗi kèm tham số, ví dụ "CONFLICT (message=...)".
+func (bee *ReturnStatus) String() string {
+	if bee == nil {
+		return "<nil>"
+	}
+	if len(bee.args) == 0 {
+		return string(bee.code)
+	}
+
+	parts := []string{}
+	for _, key := range slices.Sorted(maps.Keys(bee.args)) {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, bee.args[key]))
+	}
+	return fmt.Sprintf("%s (%s)", bee.code, strings.Join(parts, ", "))
+}
+
 func (bee *ReturnStatus) IsErr() bool {
 	if bee != nil {
 		return bee.code != ""
@@ -158,6 +238,18 @@ func (bee *ReturnStatus) Export(c *gin.Context) {
 			"message": "File size exceeds the system limit",
 		})
 
+	case ErrCodeShareTokenTaken:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": "This share link is already taken, please choose another slug",
+		})
+
+	case ErrCodeShareSlugInvalid:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad request",
+			"message": "This slug is reserved and cannot be used as a share link",
+		})
+
 	case ErrCodeBearerInvalid:
 		c.JSON(401, gin.H{
 			"error":   "Unauthorized",
@@ -226,6 +318,24 @@ func (bee *ReturnStatus) Export(c *gin.Context) {
 		maps.Copy(out, args)
 		c.JSON(423, out)
 
+	case ErrCodeDownloadLimitReached:
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "Download limit reached",
+			"message": "This link has reached its maximum number of downloads",
+		})
+
+	case ErrCodeSignedURLInvalid:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "The signed URL is invalid or has been revoked",
+		})
+
+	case ErrCodeSignedURLExpired:
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "Signed URL expired",
+			"message": "The signed URL has expired, please request a new one",
+		})
+
 	case ErrCodeAdminUnauthorized:
 		c.JSON(401, gin.H{
 			"error":   "Unauthorized",
@@ -238,12 +348,315 @@ func (bee *ReturnStatus) Export(c *gin.Context) {
 			"message": "You don't have permission to perform cleanup",
 		})
 
+	case ErrCodeTooManyRequests:
+		out := gin.H{
+			"error":   "Too many requests",
+			"message": "Too many failed attempts. Please try again later.",
+		}
+		if retryAfter, ok := args["retryAfter"]; ok {
+			c.Header("Retry-After", fmt.Sprint(retryAfter))
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusTooManyRequests, out)
+
 	case ErrCodeCleanUpLimited:
 		c.JSON(429, gin.H{
 			"error":   "Too many requests",
 			"message": "Cleanup endpoint is rate limited. Please try again later.",
 		})
 
+	case ErrCodeAPITokenInvalid:
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "Invalid or expired API token",
+		})
+
+	case ErrCodeAPITokenNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "API token not found",
+		})
+
+	case ErrCodeAPITokenScope:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "You are not allowed to create a token with the admin scope",
+		})
+
+	case ErrCodeInsufficientScope:
+		out := gin.H{
+			"error":   "Forbidden",
+			"message": "API token does not have the required scope",
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusForbidden, out)
+
+	case ErrCodeSessionRequired:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "This action cannot be performed with an API token, please log in",
+		})
+
+	case ErrCodeOIDCProviderNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Identity provider not found",
+		})
+
+	case ErrCodeOIDCStateInvalid:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad request",
+			"message": "Invalid or expired login state, please start the login again",
+		})
+
+	case ErrCodeOIDCFailed:
+		out := gin.H{
+			"error":   "Unauthorized",
+			"message": "Single sign-on failed",
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusUnauthorized, out)
+
+	case ErrCodeOIDCEmailUnverified:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": "An account with this email already exists but the identity provider has not verified the email",
+		})
+
+	case ErrCodeOIDCSignupDisabled:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "No account is linked to this identity and sign-up via this provider is disabled",
+		})
+
+	case ErrCodeWebAuthnCredentialNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Authenticator not found",
+		})
+
+	case ErrCodeWebAuthnSessionInvalid:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad request",
+			"message": "Invalid or expired WebAuthn challenge, please start again",
+		})
+
+	case ErrCodeWebAuthnFailed:
+		out := gin.H{
+			"error":   "Unauthorized",
+			"message": "WebAuthn verification failed",
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusUnauthorized, out)
+
+	case ErrCodeWebAuthnNotConfigured:
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Service unavailable",
+			"message": "WebAuthn is not configured on this server",
+		})
+
+	case ErrCodeUserConflict:
+		out := gin.H{
+			"error":   "Conflict",
+			"message": "Username or email is already registered",
+		}
+		switch args["field"] {
+		case "email":
+			out["message"] = "Email is already registered"
+		case "username":
+			out["message"] = "Username is already taken"
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusConflict, out)
+
+	case ErrCodeEmailNotVerified:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Please verify your email address before logging in",
+		})
+
+	case ErrCodeAccountSuspended:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "This account has been suspended, please contact an administrator",
+		})
+
+	case ErrCodeEmailVerificationInvalid:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad request",
+			"message": "Invalid or expired verification link, please request a new one",
+		})
+
+	case ErrCodeEmailDomainNotAllowed:
+		out := gin.H{
+			"error":   "Forbidden",
+			"message": "Registration is not allowed for this email domain",
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusForbidden, out)
+
+	case ErrCodeEmailDomainRuleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Email domain rule not found",
+		})
+
+	case ErrCodeRegistrationInviteRequired:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Registration is invite-only, an invitation code is required",
+		})
+
+	case ErrCodeInviteInvalid:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Invitation is invalid, expired or already used",
+		})
+
+	case ErrCodeInviteNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Invitation not found",
+		})
+
+	case ErrCodePasswordPolicy:
+		out := gin.H{
+			"error":   "Validation error",
+			"message": "Password does not meet the password policy",
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusBadRequest, out)
+
+	case ErrCodeCurrentPasswordInvalid:
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "Current password is incorrect",
+		})
+
+	case ErrCodeTOTPCodeInvalid:
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "Invalid or expired TOTP code",
+		})
+
+	case ErrCodeReauthRequired:
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "Please sign in again before deleting this account",
+		})
+
+	case ErrCodeLastAdmin:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": "Cannot delete the last administrator account",
+		})
+
+	case ErrCodeTransferTargetInvalid:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad request",
+			"message": "Files can only be transferred to another administrator",
+		})
+
+	case ErrCodeExportNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Data export not found or has expired",
+		})
+
+	case ErrCodeExportNotReady:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": "Data export is not ready yet",
+		})
+
+	case ErrCodeUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "User not found",
+		})
+
+	case ErrCodeUserSearchDisabled:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "User directory search is disabled",
+		})
+
+	case ErrCodeGroupNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Group not found",
+		})
+
+	case ErrCodeGroupForbidden:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Only group owners can manage this group",
+		})
+
+	case ErrCodeGroupMemberExists:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": "User is already a member of this group",
+		})
+
+	case ErrCodeGroupMemberNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "User is not a member of this group",
+		})
+
+	case ErrCodeLastGroupOwner:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": "A group must keep at least one owner",
+		})
+
+	case ErrCodeShareGroupInvalid:
+		out := gin.H{
+			"error":   "Bad request",
+			"message": "Files can only be shared with groups you belong to",
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusBadRequest, out)
+
+	case ErrCodeFolderNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Folder not found",
+		})
+
+	case ErrCodeFolderNameTaken:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": "A folder with this name already exists here",
+		})
+
+	case ErrCodeFolderMoveInvalid:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad request",
+			"message": "Cannot move a folder into itself or one of its subfolders",
+		})
+
+	case ErrCodeFolderForbidden:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Only the folder owner can modify this folder",
+		})
+
+	case ErrCodeArchiveFileRejected:
+		out := gin.H{
+			"error":   "Forbidden",
+			"message": "One of the selected files cannot be downloaded",
+		}
+		maps.Copy(out, args)
+		c.JSON(http.StatusForbidden, out)
+
+	case ErrCodeCursorInvalid:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad request",
+			"message": "Invalid pagination cursor, it may belong to a different sort order",
+		})
+
 	case ErrCodeCantAccessResource:
 		c.JSON(403, gin.H{
 			"error":   "Forbidden",
@@ -11,22 +11,19 @@ import (
 )
 
 func RegisterCustomValidation(v *validator.Validate) {
-	var blockedDomains = map[string]bool{
-		"blacklist.com": true,
-		"edu.vn":        true,
-		"abc.com":       true,
-	}
+	// Domain bị chặn/cho phép do admin quản lý trong bảng email_domain_rules (RegistrationService),
+	// ở đây chỉ kiểm tra email có dạng local@domain.tld.
 	v.RegisterValidation("email_advanced", func(fl validator.FieldLevel) bool {
 		email := fl.Field().String()
 
 		parts := strings.Split(email, "@")
-		if len(parts) != 2 {
+		if len(parts) != 2 || parts[0] == "" {
 			return false
 		}
 
 		domain := utils.NormalizeString(parts[1])
 
-		return !blockedDomains[domain]
+		return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
 	})
 
 	v.RegisterValidation("password_strong", func(fl validator.FieldLevel) bool {
@@ -36,12 +33,12 @@ func RegisterCustomValidation(v *validator.Validate) {
 			return false
 		}
 
-		hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-		hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-		hasDigit := regexp.MustCompile(`[0-9]`).MatchString(password)
-		hasSpecial := regexp.MustCompile(`[!@#\$%\^&\*\(\)_\+\-=\[\]\{\};:'",.<>?/\\|]`).MatchString(password)
-
-		return hasLower && hasUpper && hasDigit && hasSpecial
+		for _, class := range PasswordClasses {
+			if !HasPasswordClass(password, class) {
+				return false
+			}
+		}
+		return true
 	})
 
 	var slugRegex = regexp.MustCompile(`^[a-z0-9]+(?:[-.][a-z0-9]+)*$`)
@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Nhóm ký tự có thể bắt buộc trong password (dùng chung với rule password_strong).
+const (
+	PasswordClassLower   = "lower"
+	PasswordClassUpper   = "upper"
+	PasswordClassDigit   = "digit"
+	PasswordClassSpecial = "special"
+)
+
+var PasswordClasses = []string{PasswordClassLower, PasswordClassUpper, PasswordClassDigit, PasswordClassSpecial}
+
+var passwordClassRegex = map[string]*regexp.Regexp{
+	PasswordClassLower:   regexp.MustCompile(`[a-z]`),
+	PasswordClassUpper:   regexp.MustCompile(`[A-Z]`),
+	PasswordClassDigit:   regexp.MustCompile(`[0-9]`),
+	PasswordClassSpecial: regexp.MustCompile(`[!@#\$%\^&\*\(\)_\+\-=\[\]\{\};:'",.<>?/\\|]`),
+}
+
+var passwordClassMessages = map[string]string{
+	PasswordClassLower:   "Password must contain a lowercase letter",
+	PasswordClassUpper:   "Password must contain an uppercase letter",
+	PasswordClassDigit:   "Password must contain a digit",
+	PasswordClassSpecial: "Password must contain a special character",
+}
+
+// bcrypt chỉ dùng 72 byte đầu, password dài hơn bị từ chối thay vì cắt ngầm.
+const PasswordMaxBytes = 72
+
+// Tên các luật trong PasswordViolation.Rule
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleMaxLength = "max_length"
+	PasswordRuleBreached  = "breached"
+)
+
+//go:embed breached_passwords.txt
+var breachedPasswordsFile string
+
+// breachedPasswords: danh sách password phổ biến/bị lộ (chữ thường), nạp một lần khi cần.
+var breachedPasswords = sync.OnceValue(func() map[string]struct{} {
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(breachedPasswordsFile))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+	return set
+})
+
+// PasswordPolicy: các luật áp dụng cho password tài khoản và password file.
+type PasswordPolicy struct {
+	MinLength       int      // tính theo ký tự
+	RequiredClasses []string // lower | upper | digit | special
+	RejectBreached  bool
+}
+
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Check trả về mọi luật bị vi phạm (rỗng = hợp lệ) để client hiển thị đủ một lần.
+func (p PasswordPolicy) Check(password string) []PasswordViolation {
+	var violations []PasswordViolation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    PasswordRuleMinLength,
+			Message: fmt.Sprintf("Password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if len(password) > PasswordMaxBytes {
+		violations = append(violations, PasswordViolation{
+			Rule:    PasswordRuleMaxLength,
+			Message: fmt.Sprintf("Password must be at most %d bytes long", PasswordMaxBytes),
+		})
+	}
+
+	for _, class := range p.RequiredClasses {
+		if !HasPasswordClass(password, class) {
+			violations = append(violations, PasswordViolation{Rule: class, Message: passwordClassMessages[class]})
+		}
+	}
+
+	if p.RejectBreached && IsBreachedPassword(password) {
+		violations = append(violations, PasswordViolation{
+			Rule:    PasswordRuleBreached,
+			Message: "Password is too common or has appeared in a data breach",
+		})
+	}
+
+	return violations
+}
+
+func HasPasswordClass(password, class string) bool {
+	re, ok := passwordClassRegex[class]
+	return ok && re.MatchString(password)
+}
+
+func IsBreachedPassword(password string) bool {
+	_, found := breachedPasswords()[strings.ToLower(password)]
+	return found
+}
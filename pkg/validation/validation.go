@@ -77,7 +77,7 @@ func HandleValidationErrors(err error) gin.H {
 			case "datetime":
 				errors[fieldPath] = fmt.Sprintf("%s must be in YYYY-MM-DD format", fieldPath)
 			case "email_advanced":
-				errors[fieldPath] = fmt.Sprintf("%s is not allowed (blacklisted)", fieldPath)
+				errors[fieldPath] = fmt.Sprintf("%s must be a valid email address with a domain", fieldPath)
 			case "password_strong":
 				errors[fieldPath] = fmt.Sprintf("%s must be at least 8 characters long and contain lowercase, uppercase, numbers, and special characters", fieldPath)
 			case "file_ext":
@@ -36,8 +36,8 @@ func setupAdminToken(t *testing.T) string {
 	}
 
 	// 3. [QUAN TRỌNG] Login LẠI để lấy Token MỚI chứa quyền Admin
-	// Password mặc định trong setupUserAndToken là "123456789"
-	loginBody := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, "123456789")
+	// setupUserAndToken dùng testUserPassword
+	loginBody := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, testUserPassword)
 	reqLogin, _ := http.NewRequest("POST", "/auth/login", bytes.NewBufferString(loginBody))
 	reqLogin.Header.Set("Content-Type", "application/json")
 
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readArchive trả về nội dung các file trong ZIP theo đường dẫn.
+func readArchive(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("Invalid ZIP: %v (%d %s)", err, rec.Code, rec.Body.String())
+	}
+
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		entries[f.Name] = string(content)
+	}
+	return entries
+}
+
+// archiveRequest gọi POST /files/archive, token rỗng = người dùng ẩn danh.
+func archiveRequest(t *testing.T, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/files/archive", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+func archiveNames(entries map[string]string) []string {
+	names := []string{}
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDownload_Archive(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	ownerToken, _ := setupUserAndToken(t)
+	recipientToken, recipientEmail := setupUserAndToken(t)
+
+	first := uploadFileWithFields(t, ownerToken, map[string]string{"isPublic": "true", "maxDownloads": "1"})
+	second := uploadFileWithFields(t, ownerToken, map[string]string{"isPublic": "true"})
+	locked := uploadFileWithFields(t, ownerToken, map[string]string{"isPublic": "true", "password": "SecurePass123"})
+
+	t.Run("Share Tokens", func(t *testing.T) {
+		rec := archiveRequest(t, "", map[string]interface{}{
+			"shareTokens": []interface{}{first["shareToken"], second["shareToken"]},
+		})
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+		entries := readArchive(t, rec)
+		assert.Equal(t, []string{"test_file (2).txt", "test_file.txt"}, archiveNames(entries))
+		assert.Equal(t, "Hello World Content", entries["test_file.txt"])
+	})
+
+	t.Run("Downloads Are Counted", func(t *testing.T) {
+		// first có maxDownloads=1 và đã được tải qua archive ở trên.
+		req, _ := http.NewRequest("GET", "/files/"+first["shareToken"].(string)+"/download", nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 410, rec.Code)
+
+		rec = archiveRequest(t, "", map[string]interface{}{"fileIds": []interface{}{first["id"]}})
+		assert.Equal(t, 403, rec.Code)
+		assert.Equal(t, first["id"], ParseJSON(t, rec)["file"])
+	})
+
+	t.Run("Password Required", func(t *testing.T) {
+		rec := archiveRequest(t, "", map[string]interface{}{"shareTokens": []interface{}{locked["shareToken"]}})
+		assert.Equal(t, 403, rec.Code)
+		assert.Equal(t, locked["shareToken"], ParseJSON(t, rec)["file"])
+
+		rec = archiveRequest(t, "", map[string]interface{}{
+			"shareTokens": []interface{}{locked["shareToken"]},
+			"passwords":   map[string]interface{}{locked["id"].(string): "SecurePass123"},
+		})
+		assert.Equal(t, 200, rec.Code)
+		assert.Len(t, readArchive(t, rec), 1)
+	})
+
+	t.Run("Private File Is Rejected", func(t *testing.T) {
+		private := uploadFileWithFields(t, ownerToken, map[string]string{})
+		rec := archiveRequest(t, recipientToken, map[string]interface{}{"fileIds": []interface{}{private["id"]}})
+		assert.Equal(t, 403, rec.Code)
+	})
+
+	t.Run("Shared Folder", func(t *testing.T) {
+		docsID := createFolderForTest(t, ownerToken, "Docs", "")["id"].(string)
+		subID := createFolderForTest(t, ownerToken, "Sub", docsID)["id"].(string)
+		uploadFileWithFields(t, ownerToken, map[string]string{"folderId": docsID})
+		uploadFileWithFields(t, ownerToken, map[string]string{"folderId": subID})
+		uploadFileWithFields(t, ownerToken, map[string]string{"folderId": subID, "password": "SecurePass123"})
+
+		folder := map[string]interface{}{"folderId": docsID}
+
+		assert.Equal(t, 401, archiveRequest(t, "", folder).Code)
+		assert.Equal(t, 404, archiveRequest(t, recipientToken, folder).Code)
+
+		rec := adminRequest(t, "PUT", "/folders/"+docsID+"/share", ownerToken, map[string]interface{}{"sharedWith": []string{recipientEmail}})
+		assert.Equal(t, 200, rec.Code)
+
+		rec = archiveRequest(t, recipientToken, folder)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), "Docs.zip")
+		// File có password không kèm password bị bỏ qua thay vì làm hỏng cả archive.
+		assert.Equal(t, "1", rec.Header().Get("X-Archive-Skipped"))
+		assert.Equal(t, []string{"Docs/Sub/test_file.txt", "Docs/test_file.txt"}, archiveNames(readArchive(t, rec)))
+	})
+
+	t.Run("Folder Name Is Escaped", func(t *testing.T) {
+		name := `Báo cáo "Q3"; x`
+		folderID := createFolderForTest(t, ownerToken, name, "")["id"].(string)
+		uploadFileWithFields(t, ownerToken, map[string]string{"folderId": folderID})
+
+		rec := archiveRequest(t, ownerToken, map[string]interface{}{"folderId": folderID})
+		assert.Equal(t, 200, rec.Code)
+		disposition, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "attachment", disposition)
+			assert.Equal(t, name+".zip", params["filename"])
+		}
+	})
+
+	t.Run("My Files Filter", func(t *testing.T) {
+		assert.Equal(t, 401, archiveRequest(t, "", map[string]interface{}{"filter": map[string]string{}}).Code)
+
+		rec := archiveRequest(t, ownerToken, map[string]interface{}{
+			"filter": map[string]string{"status": "active", "folderId": "root"},
+		})
+		assert.Equal(t, 200, rec.Code)
+		// first đã hết lượt tải, locked cần password.
+		assert.Equal(t, "2", rec.Header().Get("X-Archive-Skipped"))
+		assert.Len(t, readArchive(t, rec), 2)
+	})
+
+	t.Run("Empty Request", func(t *testing.T) {
+		rec := archiveRequest(t, ownerToken, map[string]interface{}{})
+		assert.Equal(t, 400, rec.Code)
+	})
+}
@@ -17,7 +17,7 @@ func TestAuth_TOTP_Flow(t *testing.T) {
 
 	var (
 		testEmail    = fmt.Sprintf("testuser_%d@example.com", time.Now().UnixNano())
-		testPassword = "Password123"
+		testPassword = "Tr0ub4dor&3"
 		testUsername = "testuser"
 
 		authToken  string
@@ -44,6 +44,9 @@ func TestAuth_TOTP_Flow(t *testing.T) {
 
 		json := ParseJSON(t, rec)
 		assert.Equal(t, "User registered successfully", json["message"])
+		assert.Equal(t, true, json["emailVerificationRequired"])
+
+		verifyEmailForTest(t, testEmail)
 	})
 
 	// ---------------------------
@@ -158,4 +161,62 @@ func TestAuth_TOTP_Flow(t *testing.T) {
 
 		assert.Equal(t, 200, rec.Code)
 	})
-}
\ No newline at end of file
+}
+func TestAuth_LoginLockout(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	_, email := setupUserAndToken(t)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)
+		req := httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, 401, login("wrong-password").Code)
+	}
+
+	// Tài khoản bị khóa tạm thời, kể cả khi nhập đúng password
+	rec := login(testUserPassword)
+	assert.Equal(t, 429, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestAuth_TOTPLockoutSurvivesRelogin(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	_, email := setupUserAndToken(t)
+	_, err := TestApp.DB().Exec(`UPDATE users SET secrettotp = 'JBSWY3DPEHPK3PXP', enabletotp = true WHERE email = $1`, email)
+	assert.NoError(t, err)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	login := func() *httptest.ResponseRecorder {
+		return post("/auth/login", fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, testUserPassword))
+	}
+
+	// Đăng nhập lại bằng password đúng giữa các lần đoán mã không được reset bộ đếm của tài khoản
+	for i := 0; i < 5; i++ {
+		rec := login()
+		if !assert.Equal(t, 200, rec.Code) {
+			return
+		}
+		cid := ParseJSON(t, rec)["cid"].(string)
+		assert.Equal(t, 401, post("/auth/login/totp", fmt.Sprintf(`{"cid": "%s", "code": "000000"}`, cid)).Code)
+	}
+
+	rec := login()
+	assert.Equal(t, 429, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
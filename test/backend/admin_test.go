@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsers_SuspendAndList(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		bob := createUser(t, b, "bob")
+		createUser(t, b, "alice")
+
+		assert.Nil(t, b.user.SetSuspended(bob.Id, true))
+		assert.Nil(t, b.user.SetSuspended(bob.Id, true), "suspending twice keeps the account suspended")
+
+		users, status := b.user.List()
+		assert.Nil(t, status)
+		if assert.Len(t, users, 2) {
+			assert.Equal(t, "alice@example.test", users[0].Email)
+			assert.False(t, users[0].Suspended)
+			assert.True(t, users[1].Suspended)
+		}
+
+		assert.Nil(t, b.user.SetSuspended(bob.Id, false))
+		var found domain.User
+		assert.Nil(t, b.user.FindByEmail(bob.Email, &found))
+		assert.False(t, found.Suspended)
+
+		assert.Equal(t, utils.ErrCodeUserNotFound, b.user.SetSuspended(uuid.NewString(), true).Error())
+	})
+}
+
+func TestAuth_PurgeBlacklist(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		now := time.Now()
+		assert.Nil(t, b.auth.BlacklistToken("expired", now.Add(-time.Minute)))
+		assert.Nil(t, b.auth.BlacklistToken("live", now.Add(time.Minute)))
+		assert.Nil(t, b.auth.RevokeUserTokens(context.Background(), "old", now.Add(-time.Hour)))
+		assert.Nil(t, b.auth.RevokeUserTokens(context.Background(), "recent", now))
+
+		purged, status := b.auth.PurgeBlacklist(now, now.Add(-jwt.AccessTokenTTL))
+		assert.Nil(t, status)
+		assert.Equal(t, int64(2), purged)
+
+		blacklisted, _ := b.auth.IsTokenBlacklisted("live")
+		assert.True(t, blacklisted)
+		revoked, _ := b.auth.IsUserTokenRevoked("recent", now.Add(-time.Second))
+		assert.True(t, revoked)
+	})
+}
+
+func TestAdmin_CleanupAndStorageCheck(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://:memory:")
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		dir := t.TempDir()
+		store := storage.NewLocalStorage(dir)
+		svc := service.NewAdminService(config.NewConfig(), b.file, store, nil, nil)
+		owner := createUser(t, b, "owner")
+
+		live := createFile(t, b, owner, "live.txt", 5, time.Now())
+		expired, status := b.file.CreateFile(ctx, &domain.File{
+			Id:            uuid.NewString(),
+			OwnerId:       &owner.Id,
+			FileName:      "old.txt",
+			FileSize:      3,
+			ShareToken:    uuid.NewString()[:16],
+			AvailableFrom: time.Now().Add(-48 * time.Hour),
+			AvailableTo:   time.Now().Add(-24 * time.Hour),
+			CreatedAt:     time.Now(),
+		})
+		if status != nil {
+			t.Fatalf("Create file failed: %v", status.Error())
+		}
+
+		os.WriteFile(filepath.Join(dir, live.Id), []byte("hello!"), 0644)
+		os.WriteFile(filepath.Join(dir, "stray"), []byte("x"), 0644)
+		os.MkdirAll(filepath.Join(dir, "exports"), 0755)
+
+		report, status := svc.CheckStorage(ctx)
+		assert.Nil(t, status)
+		assert.Equal(t, 2, report.Checked)
+		if assert.Len(t, report.Missing, 1) {
+			assert.Equal(t, expired.Id, report.Missing[0].Id)
+		}
+		if assert.Len(t, report.SizeMismatch, 1) {
+			assert.Equal(t, live.Id, report.SizeMismatch[0].Id)
+		}
+		assert.Equal(t, []string{"stray"}, report.Orphans)
+
+		files, status := svc.PreviewCleanup(ctx)
+		assert.Nil(t, status)
+		if assert.Len(t, files, 1) {
+			assert.Equal(t, expired.Id, files[0].Id)
+		}
+		_, status = b.file.GetFileByID(ctx, expired.Id)
+		assert.Nil(t, status, "dry run must not delete anything")
+	})
+}
+
+func TestUserAdmin_BootstrapSuspendAndReset(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://:memory:")
+	repos := openSQLite(t)
+	ctx := context.Background()
+	policy := config.NewConfig().Policy
+	policy.PasswordCheckBreached = false
+
+	svc := service.NewUserAdminService(policy, repos.User, repos.Auth, repos.WebAuthn)
+	auth := service.NewAuthService(repos.User, repos.Auth, repos.WebAuthn, nil, jwt.NewJWTService(), service.NewBruteForceGuard(repos.Attempt), policy, repos.LoginHistory)
+	const password = "Correct-Horse-42"
+
+	_, status := svc.BootstrapAdmin(ctx, "root", "root@example.test", "short")
+	assert.NotNil(t, status, "password policy applies to the bootstrap admin")
+
+	admin, status := svc.BootstrapAdmin(ctx, "root", "Root@Example.test", password)
+	if status != nil {
+		t.Fatalf("Bootstrap failed: %s", status.String())
+	}
+	assert.Equal(t, "admin", admin.Role)
+	assert.Equal(t, "root@example.test", admin.Email)
+
+	_, status = svc.BootstrapAdmin(ctx, "root2", "root2@example.test", password)
+	assert.Equal(t, utils.ErrCodeConflict, status.Error())
+
+	t.Run("Suspended Account Cannot Log In", func(t *testing.T) {
+		_, token, status := auth.Login(ctx, admin.Email, password, "127.0.0.1")
+		assert.Nil(t, status)
+		assert.NotEmpty(t, token)
+
+		suspended, status := svc.SetSuspended(ctx, admin.Email, true)
+		assert.Nil(t, status)
+		assert.True(t, suspended.Suspended)
+		revoked, _ := repos.Auth.IsUserTokenRevoked(admin.Id, time.Now().Add(-time.Second))
+		assert.True(t, revoked, "suspending must revoke existing sessions")
+
+		_, _, status = auth.Login(ctx, admin.Email, password, "127.0.0.1")
+		assert.Equal(t, utils.ErrCodeAccountSuspended, status.Error())
+
+		_, status = svc.SetSuspended(ctx, admin.Id, false)
+		assert.Nil(t, status)
+		_, _, status = auth.Login(ctx, admin.Email, password, "127.0.0.1")
+		assert.Nil(t, status)
+	})
+
+	t.Run("Reset Two Factor", func(t *testing.T) {
+		assert.Nil(t, repos.Auth.SaveSecret(admin.Id, "SECRET"))
+		assert.Nil(t, repos.Auth.EnableTOTP(admin.Id))
+		assert.Nil(t, repos.WebAuthn.Create(ctx, &domain.WebAuthnCredential{UserId: admin.Id, CredentialID: []byte("cred"), Name: "key", Data: []byte("{}")}))
+
+		user, removed, status := svc.ResetTwoFactor(ctx, admin.Email, true)
+		assert.Nil(t, status)
+		assert.Equal(t, 1, removed)
+		assert.False(t, user.EnableTOTP)
+
+		var found domain.User
+		assert.Nil(t, repos.User.FindById(admin.Id, &found))
+		assert.False(t, found.EnableTOTP)
+		assert.Empty(t, found.SecretTOTP)
+		count, _ := repos.WebAuthn.CountByUser(ctx, admin.Id)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Reset Keeps Passkey As Second Factor", func(t *testing.T) {
+		assert.Nil(t, repos.Auth.SaveSecret(admin.Id, "SECRET"))
+		assert.Nil(t, repos.Auth.EnableTOTP(admin.Id))
+		assert.Nil(t, repos.WebAuthn.Create(ctx, &domain.WebAuthnCredential{UserId: admin.Id, CredentialID: []byte("cred2"), Name: "key", Data: []byte("{}")}))
+
+		_, _, status := svc.ResetTwoFactor(ctx, admin.Id, false)
+		assert.Nil(t, status)
+
+		_, cid, status := auth.Login(ctx, admin.Email, password, "127.0.0.1")
+		assert.Nil(t, status)
+
+		// Secret đã bị xóa, mã của secret rỗng không được thay cho passkey
+		code, _ := totp.GenerateCode("", time.Now())
+		_, token, status := auth.LoginTOTP(ctx, cid, code, "127.0.0.1")
+		if assert.NotNil(t, status) {
+			assert.Equal(t, utils.ErrCodeUnauthorized, status.Error())
+		}
+		assert.Empty(t, token)
+
+		_, _, status = svc.ResetTwoFactor(ctx, admin.Id, true)
+		assert.Nil(t, status)
+	})
+
+	users, status := svc.ListUsers(ctx)
+	assert.Nil(t, status)
+	assert.Len(t, users, 1)
+}
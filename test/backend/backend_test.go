@@ -0,0 +1,333 @@
+// Package backend kiểm tra các repository chạy trong RAM và trên SQLite. Không cần Postgres nên
+// chạy được ở mọi máy: go test ./test/backend/...
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository/memory"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository/sqlite"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// backend là các repository mà cả hai cài đặt đều có; group/folder chỉ có ở SQLite (nil ở memory).
+type backend struct {
+	user   repository.UserRepository
+	auth   repository.AuthRepository
+	file   repository.FileRepository
+	shared repository.SharedRepository
+	group  repository.GroupRepository
+	folder repository.FolderRepository
+	tx     repository.TxManager
+}
+
+// forEachBackend chạy fn trên một store mới của từng backend.
+func forEachBackend(t *testing.T, fn func(t *testing.T, b backend)) {
+	t.Run("memory", func(t *testing.T) {
+		store := memory.NewStore()
+		fn(t, backend{
+			user:   memory.NewUserRepository(store),
+			auth:   memory.NewAuthRepository(store),
+			file:   memory.NewFileRepository(store),
+			shared: memory.NewSharedRepository(store),
+			tx:     memory.NewTxManager(store),
+		})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		db, err := database.OpenSQLite(":memory:")
+		if err != nil {
+			t.Fatalf("Cannot open SQLite: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		repos := sqlite.NewRepositories(db)
+		fn(t, backend{
+			user:   repos.User,
+			auth:   repos.Auth,
+			file:   repos.File,
+			shared: repos.Shared,
+			group:  repos.Group,
+			folder: repos.Folder,
+			tx:     repos.Tx,
+		})
+	})
+}
+
+func createUser(t *testing.T, b backend, username string) *domain.User {
+	t.Helper()
+
+	user, status := b.auth.Create(&domain.User{
+		Id:            uuid.NewString(),
+		Username:      username,
+		Password:      "x",
+		Email:         username + "@example.test",
+		Role:          "user",
+		EmailVerified: true,
+	})
+	if status != nil {
+		t.Fatalf("Create user failed: %v", status.Error())
+	}
+	return user
+}
+
+// createFile tạo file của owner, hiệu lực từ một giờ trước đến ngày mai.
+func createFile(t *testing.T, b backend, owner *domain.User, name string, size int64, createdAt time.Time) *domain.File {
+	t.Helper()
+
+	file, status := b.file.CreateFile(context.Background(), &domain.File{
+		Id:            uuid.NewString(),
+		OwnerId:       &owner.Id,
+		FileName:      name,
+		MimeType:      "text/plain",
+		FileSize:      size,
+		ShareToken:    uuid.NewString()[:16],
+		AvailableFrom: time.Now().Add(-time.Hour),
+		AvailableTo:   time.Now().Add(24 * time.Hour),
+		CreatedAt:     createdAt,
+		Tags:          []string{},
+	})
+	if status != nil {
+		t.Fatalf("Create file failed: %v", status.Error())
+	}
+	return file
+}
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		alice := createUser(t, b, "alice")
+		createUser(t, b, "bob")
+
+		t.Run("Conflicts", func(t *testing.T) {
+			_, status := b.auth.Create(&domain.User{Id: uuid.NewString(), Username: "ALICE", Email: "other@example.test", Role: "user"})
+			assert.Equal(t, utils.ErrCodeUserConflict, status.Error())
+
+			_, status = b.auth.Create(&domain.User{Id: uuid.NewString(), Username: "carol", Email: "alice@example.test", Role: "user"})
+			assert.Equal(t, utils.ErrCodeUserConflict, status.Error())
+
+			assert.Equal(t, utils.ErrCodeUserConflict, b.user.UpdateEmail(context.Background(), alice.Id, "bob@example.test").Error())
+
+			exists, _ := b.user.UsernameExists("Bob")
+			assert.True(t, exists)
+		})
+
+		t.Run("Find And Update", func(t *testing.T) {
+			var found domain.User
+			assert.Nil(t, b.user.FindByEmail("alice@example.test", &found))
+			assert.Equal(t, alice.Id, found.Id)
+
+			assert.Nil(t, b.user.UpdateUsername(context.Background(), alice.Id, "alice2"))
+			assert.Nil(t, b.user.FindById(alice.Id, &found))
+			assert.Equal(t, "alice2", found.Username)
+
+			assert.Equal(t, utils.ErrCodeUserNotFound, b.user.FindById(uuid.NewString(), &found).Error())
+		})
+
+		t.Run("Search", func(t *testing.T) {
+			profiles, status := b.user.Search(context.Background(), domain.UserSearchParams{Query: "b", ExcludeId: alice.Id, Limit: 10})
+			assert.Nil(t, status)
+			assert.Equal(t, []domain.PublicProfile{{Username: "bob", Email: "bob@example.test"}}, profiles)
+		})
+
+		t.Run("Token Revocation", func(t *testing.T) {
+			now := time.Now()
+			assert.Nil(t, b.auth.RevokeUserTokens(context.Background(), alice.Id, now))
+
+			revoked, _ := b.auth.IsUserTokenRevoked(alice.Id, now.Add(-time.Minute))
+			assert.True(t, revoked)
+			revoked, _ = b.auth.IsUserTokenRevoked(alice.Id, now.Add(time.Minute))
+			assert.False(t, revoked)
+		})
+	})
+}
+
+func TestFiles_ShareAndAccess(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		owner := createUser(t, b, "owner")
+		recipient := createUser(t, b, "recipient")
+		now := time.Now()
+		shared := createFile(t, b, owner, "shared.txt", 10, now)
+		createFile(t, b, owner, "private.txt", 10, now.Add(time.Second))
+
+		result, status := b.shared.ShareFileWithUsers(ctx, shared.Id, []string{"recipient@example.test", "nobody@example.test", "recipient@example.test"})
+		assert.Nil(t, status)
+		assert.Equal(t, []string{"recipient@example.test"}, result.Resolved)
+		assert.Equal(t, []string{"nobody@example.test"}, result.Unresolved)
+
+		users, _ := b.shared.GetUsersSharedWith(ctx, shared.Id)
+		assert.Equal(t, []string{recipient.Id}, users.UserIds)
+
+		page := domain.PageRequest{SortBy: domain.SORT_CREATED_AT, Order: domain.ORDER_DESC, Limit: 10}
+		files, next, status := b.file.GetAccessibleFiles(ctx, recipient.Id, page)
+		assert.Nil(t, status)
+		assert.Nil(t, next)
+		if assert.Len(t, files, 1) {
+			assert.Equal(t, shared.Id, files[0].Id)
+			assert.Equal(t, "owner@example.test", files[0].Owner.Email)
+		}
+
+		mine, _, _ := b.file.GetMyFiles(ctx, owner.Id, domain.ListFileParams{Status: "active", Page: page})
+		assert.Len(t, mine, 2)
+
+		summary, _ := b.file.GetFileSummary(ctx, owner.Id)
+		assert.Equal(t, 2, summary.ActiveFiles)
+
+		assert.Nil(t, b.file.DeleteFile(ctx, shared.Id))
+		sharedFiles, _ := b.shared.GetFilesSharedWithUser(ctx, recipient.Id)
+		assert.Empty(t, sharedFiles)
+	})
+}
+
+func TestFiles_Pagination(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		owner := createUser(t, b, "owner")
+		base := time.Now().Add(-time.Hour)
+		for i, name := range []string{"c", "a", "e", "b", "d"} {
+			createFile(t, b, owner, name, int64(i), base.Add(time.Duration(i)*time.Second))
+		}
+
+		page := domain.PageRequest{SortBy: domain.SORT_NAME, Order: domain.ORDER_ASC, Limit: 2}
+		names := []string{}
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatal("pagination does not terminate")
+			}
+			files, next, status := b.file.GetMyFiles(ctx, owner.Id, domain.ListFileParams{Status: "all", Page: page})
+			if status != nil {
+				t.Fatalf("GetMyFiles failed: %v", status.Error())
+			}
+			for _, f := range files {
+				names = append(names, f.FileName)
+			}
+			if next == nil {
+				break
+			}
+			page.After = next
+		}
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+
+		page = domain.PageRequest{SortBy: domain.SORT_CREATED_AT, Order: domain.ORDER_DESC, Limit: 2,
+			After: &domain.Cursor{SortBy: domain.SORT_CREATED_AT, Order: domain.ORDER_DESC, Keys: []string{"yesterday"}, Id: uuid.NewString()}}
+		_, _, status := b.file.GetMyFiles(ctx, owner.Id, domain.ListFileParams{Status: "all", Page: page})
+		assert.Equal(t, utils.ErrCodeCursorInvalid, status.Error())
+	})
+}
+
+func TestFiles_DownloadLimit(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		owner := createUser(t, b, "owner")
+		file := createFile(t, b, owner, "limited.txt", 1, time.Now())
+
+		limit := 2
+		file.MaxDownloads = &limit
+		assert.Nil(t, b.file.UpdateShareLink(ctx, file))
+		assert.Equal(t, 2, file.Version)
+
+		remaining, status := b.file.RegisterDownload(ctx, file.Id, owner.Id)
+		assert.Nil(t, status)
+		assert.Equal(t, int64(1), *remaining)
+
+		remaining, status = b.file.RegisterDownload(ctx, file.Id, "")
+		assert.Nil(t, status)
+		assert.Equal(t, int64(0), *remaining)
+
+		_, status = b.file.RegisterDownload(ctx, file.Id, owner.Id)
+		assert.Equal(t, utils.ErrCodeDownloadLimitReached, status.Error())
+
+		stats, _ := b.file.GetFileStats(ctx, file.Id)
+		assert.Equal(t, 2, stats.TotalDownloadCount)
+		assert.Equal(t, 2, stats.UserDownloadCount)
+		assert.False(t, stats.LastDownloadedAt.IsZero())
+
+		history, _, _ := b.file.GetFileDownloadHistory(ctx, file.Id, domain.PageRequest{SortBy: domain.SORT_DOWNLOADED_AT, Order: domain.ORDER_DESC, Limit: 10})
+		assert.Len(t, history.History, 2)
+
+		downloads, _ := b.file.GetUserDownloads(ctx, owner.Id)
+		assert.Len(t, downloads, 1)
+	})
+}
+
+func TestFiles_Search(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		owner := createUser(t, b, "owner")
+		report := createFile(t, b, owner, "Quarterly report.pdf", 100, time.Now())
+		createFile(t, b, owner, "holiday.png", 200, time.Now())
+		description := "numbers for the board"
+		assert.Nil(t, b.file.UpdateMetadata(ctx, report.Id, &description, []string{"finance"}))
+
+		search := func(params domain.FileSearchParams) []string {
+			params.Page = domain.PageRequest{SortBy: domain.SORT_RELEVANCE, Order: domain.ORDER_DESC, Limit: 10}
+			hits, _, status := b.file.SearchFiles(ctx, owner.Id, params)
+			if status != nil {
+				t.Fatalf("SearchFiles failed: %v", status.Error())
+			}
+			names := []string{}
+			for _, hit := range hits {
+				names = append(names, hit.File.FileName)
+			}
+			return names
+		}
+
+		assert.Equal(t, []string{"Quarterly report.pdf"}, search(domain.FileSearchParams{Query: "rep:*"}))
+		assert.Equal(t, []string{"Quarterly report.pdf"}, search(domain.FileSearchParams{Query: "board:* & quart:*"}))
+		assert.Empty(t, search(domain.FileSearchParams{Query: "rep:* & holiday:*"}))
+		assert.Equal(t, []string{"Quarterly report.pdf"}, search(domain.FileSearchParams{Tags: []string{"finance"}}))
+		min := int64(150)
+		assert.Equal(t, []string{"holiday.png"}, search(domain.FileSearchParams{MinSize: &min}))
+	})
+}
+
+func TestTx_Rollback(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		owner := createUser(t, b, "owner")
+
+		var created *domain.File
+		status := b.tx.WithinTx(ctx, func(ctx context.Context) *utils.ReturnStatus {
+			created = createFileCtx(t, ctx, b, owner)
+			return utils.ResponseMsg(utils.ErrCodeDatabaseError, "boom")
+		})
+		assert.True(t, status.IsErr())
+
+		_, status = b.file.GetFileByID(ctx, created.Id)
+		assert.Equal(t, utils.ErrCodeFileNotFound, status.Error())
+
+		status = b.tx.WithinTx(ctx, func(ctx context.Context) *utils.ReturnStatus {
+			created = createFileCtx(t, ctx, b, owner)
+			return nil
+		})
+		assert.Nil(t, status)
+
+		_, status = b.file.GetFileByID(ctx, created.Id)
+		assert.Nil(t, status)
+	})
+}
+
+func createFileCtx(t *testing.T, ctx context.Context, b backend, owner *domain.User) *domain.File {
+	t.Helper()
+
+	file, status := b.file.CreateFile(ctx, &domain.File{
+		Id:            uuid.NewString(),
+		OwnerId:       &owner.Id,
+		FileName:      "tx.txt",
+		ShareToken:    uuid.NewString()[:16],
+		AvailableFrom: time.Now(),
+		AvailableTo:   time.Now().Add(time.Hour),
+		CreatedAt:     time.Now(),
+	})
+	if status != nil {
+		t.Fatalf("Create file failed: %v", status.Error())
+	}
+	return file
+}
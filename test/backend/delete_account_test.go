@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/jwt"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// failingUserRepository xóa user thật rồi báo lỗi, giả lập lỗi ở bước cuối của xóa tài khoản.
+type failingUserRepository struct {
+	repository.UserRepository
+}
+
+func (r failingUserRepository) Delete(ctx context.Context, id string) *utils.ReturnStatus {
+	if err := r.UserRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return utils.ResponseMsg(utils.ErrCodeDatabaseError, "delete failed")
+}
+
+func TestDeleteAccount_Atomic(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://:memory:")
+	repos := openSQLite(t)
+	ctx := context.Background()
+	cfg := config.NewConfig()
+	const password = "Correct-Horse-42"
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	admin, _ := repos.Auth.Create(&domain.User{Id: uuid.NewString(), Username: "admin", Email: "admin@example.test", Password: string(hashed), Role: "admin", EmailVerified: true})
+	user, _ := repos.Auth.Create(&domain.User{Id: uuid.NewString(), Username: "bob", Email: "bob@example.test", Password: string(hashed), Role: "user", EmailVerified: true})
+	file := createFile(t, backend{file: repos.File}, user, "bob.txt", 3, time.Now())
+
+	store := storage.NewLocalStorage(t.TempDir())
+	guard := service.NewBruteForceGuard(repos.Attempt)
+	exports := service.NewExportService(cfg, repos.Export, repos.User, repos.File, repos.Shared, repos.LoginHistory, store, nil)
+	webAuthn := service.NewWebAuthnService(cfg.WebAuthn, repos.WebAuthn, repos.User, jwt.NewJWTService(), guard)
+	newService := func(userRepo repository.UserRepository) service.UserService {
+		return service.NewUserService(cfg, userRepo, repos.Auth, repos.File, store, nil, guard, exports, webAuthn, repos.Tx)
+	}
+	req := &dto.DeleteAccountRequest{Password: password, Files: dto.AccountFilesTransfer}
+
+	t.Run("Failure Rolls Back", func(t *testing.T) {
+		status := newService(failingUserRepository{repos.User}).DeleteAccount(ctx, user.Id, time.Now(), req, "127.0.0.1")
+		assert.True(t, status.IsErr())
+
+		var found domain.User
+		assert.Nil(t, repos.User.FindById(user.Id, &found), "user must still exist")
+		revoked, _ := repos.Auth.IsUserTokenRevoked(user.Id, time.Now().Add(-time.Second))
+		assert.False(t, revoked, "token revocation must be rolled back")
+		moved, _ := repos.File.GetFileByID(ctx, file.Id)
+		if assert.NotNil(t, moved) {
+			assert.Equal(t, user.Id, *moved.OwnerId, "file transfer must be rolled back")
+		}
+	})
+
+	t.Run("Success Commits Everything", func(t *testing.T) {
+		assert.Nil(t, newService(repos.User).DeleteAccount(ctx, user.Id, time.Now(), req, "127.0.0.1"))
+
+		var found domain.User
+		assert.Equal(t, utils.ErrCodeUserNotFound, repos.User.FindById(user.Id, &found).Error())
+		revoked, _ := repos.Auth.IsUserTokenRevoked(user.Id, time.Now().Add(-time.Second))
+		assert.True(t, revoked)
+		moved, _ := repos.File.GetFileByID(ctx, file.Id)
+		if assert.NotNil(t, moved) {
+			assert.Equal(t, admin.Id, *moved.OwnerId)
+		}
+	})
+}
+
+func TestDeleteAccount_SSOAccount(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://:memory:")
+	repos := openSQLite(t)
+	ctx := context.Background()
+	cfg := config.NewConfig()
+
+	// Tài khoản được tạo khi đăng nhập OIDC lần đầu không có password
+	user, _ := repos.Auth.Create(&domain.User{Id: uuid.NewString(), Username: "alice", Email: "alice@corp.test", Role: "user", EmailVerified: true})
+
+	store := storage.NewLocalStorage(t.TempDir())
+	guard := service.NewBruteForceGuard(repos.Attempt)
+	exports := service.NewExportService(cfg, repos.Export, repos.User, repos.File, repos.Shared, repos.LoginHistory, store, nil)
+	webAuthn := service.NewWebAuthnService(cfg.WebAuthn, repos.WebAuthn, repos.User, jwt.NewJWTService(), guard)
+	svc := service.NewUserService(cfg, repos.User, repos.Auth, repos.File, store, nil, guard, exports, webAuthn, repos.Tx)
+	req := &dto.DeleteAccountRequest{Files: dto.AccountFilesDelete}
+
+	t.Run("Stale Session", func(t *testing.T) {
+		status := svc.DeleteAccount(ctx, user.Id, time.Now().Add(-time.Hour), req, "127.0.0.1")
+		if assert.NotNil(t, status) {
+			assert.Equal(t, utils.ErrCodeReauthRequired, status.Error())
+		}
+	})
+
+	t.Run("Fresh SSO Login", func(t *testing.T) {
+		assert.Nil(t, svc.DeleteAccount(ctx, user.Id, time.Now(), req, "127.0.0.1"))
+
+		var found domain.User
+		assert.Equal(t, utils.ErrCodeUserNotFound, repos.User.FindById(user.Id, &found).Error())
+	})
+}
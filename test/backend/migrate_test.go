@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationSource_PostgresEmbedded(t *testing.T) {
+	src, err := database.MigrationSource(database.DriverPostgres)
+	if err != nil {
+		t.Fatalf("Cannot open embedded migrations: %v", err)
+	}
+	defer src.Close()
+
+	// Version phải liên tục từ 1, mỗi version có đủ up và down
+	want := uint(1)
+	version, err := src.First()
+	for ; err == nil; version, err = src.Next(version) {
+		assert.Equal(t, want, version)
+		_, _, upErr := src.ReadUp(version)
+		_, _, downErr := src.ReadDown(version)
+		assert.NoError(t, upErr, "missing up migration %d", version)
+		assert.NoError(t, downErr, "missing down migration %d", version)
+		want++
+	}
+	assert.Greater(t, want, uint(1))
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	m, err := database.NewMigrator("sqlite://" + t.TempDir() + "/data.db")
+	if err != nil {
+		t.Fatalf("Cannot create migrator: %v", err)
+	}
+	defer m.Close()
+
+	_, _, err = m.Version()
+	assert.ErrorIs(t, err, migrate.ErrNilVersion)
+
+	versions, err := m.Versions()
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, versions) {
+		return
+	}
+	latest := versions[len(versions)-1]
+
+	assert.NoError(t, m.Up())
+	assert.ErrorIs(t, m.Up(), migrate.ErrNoChange)
+	version, dirty, _ := m.Version()
+	assert.Equal(t, latest, version)
+	assert.False(t, dirty)
+
+	assert.NoError(t, m.Down())
+	_, _, err = m.Version()
+	assert.ErrorIs(t, err, migrate.ErrNilVersion)
+
+	assert.NoError(t, m.Force(int(latest)))
+	version, _, _ = m.Version()
+	assert.Equal(t, latest, version)
+}
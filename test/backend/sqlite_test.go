@@ -0,0 +1,236 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository/sqlite"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func openSQLite(t *testing.T) *repository.Repositories {
+	t.Helper()
+
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("Cannot open SQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return sqlite.NewRepositories(db)
+}
+
+func TestSQLite_MigrationsAreIdempotent(t *testing.T) {
+	path := t.TempDir() + "/data.db"
+	for range 2 {
+		db, err := database.OpenSQLite(path)
+		if err != nil {
+			t.Fatalf("Cannot open SQLite: %v", err)
+		}
+		db.Close()
+	}
+}
+
+func TestSQLite_GroupAndFolderSharing(t *testing.T) {
+	repos := openSQLite(t)
+	b := backend{user: repos.User, auth: repos.Auth, file: repos.File, shared: repos.Shared}
+	ctx := context.Background()
+
+	owner := createUser(t, b, "owner")
+	member := createUser(t, b, "member")
+	outsider := createUser(t, b, "outsider")
+
+	group := &domain.Group{Name: "team"}
+	assert.Nil(t, repos.Group.Create(ctx, group, owner.Id))
+	assert.Nil(t, repos.Group.AddMember(ctx, group.Id, member.Id, domain.GROUP_MEMBER))
+	assert.Equal(t, utils.ErrCodeGroupMemberExists, repos.Group.AddMember(ctx, group.Id, member.Id, domain.GROUP_MEMBER).Error())
+
+	found, _ := repos.Group.Find(ctx, group.Id)
+	assert.Equal(t, 2, found.MemberCount)
+
+	viaGroup := createFile(t, b, owner, "group.txt", 1, time.Now())
+	assert.Nil(t, repos.Shared.ShareFileWithGroups(ctx, viaGroup.Id, []string{group.Id, uuid.NewString()}))
+	shared, _ := repos.Shared.SharedViaGroup(ctx, viaGroup.Id, member.Id)
+	assert.True(t, shared)
+	groups, _ := repos.Shared.GetGroupsSharedWith(ctx, viaGroup.Id)
+	assert.Equal(t, []string{group.Id}, groups)
+
+	parent := &domain.Folder{Id: uuid.NewString(), OwnerId: owner.Id, Name: "Docs"}
+	assert.Nil(t, repos.Folder.Create(ctx, parent))
+	child := &domain.Folder{Id: uuid.NewString(), OwnerId: owner.Id, ParentId: &parent.Id, Name: "Reports"}
+	assert.Nil(t, repos.Folder.Create(ctx, child))
+	assert.Equal(t, "/"+parent.Id+"/"+child.Id+"/", child.Path)
+	assert.Equal(t, utils.ErrCodeFolderNameTaken, repos.Folder.Create(ctx, &domain.Folder{Id: uuid.NewString(), OwnerId: owner.Id, Name: "docs"}).Error())
+
+	inChild := createFile(t, b, owner, "nested.txt", 1, time.Now())
+	assert.Nil(t, repos.File.MoveToFolder(ctx, inChild.Id, &child.Id))
+	assert.Nil(t, repos.Folder.SetShares(ctx, parent.Id, []string{"outsider@example.test"}, []string{}))
+
+	shares, _ := repos.Folder.ListShares(ctx, parent.Id)
+	assert.Equal(t, []string{"outsider@example.test"}, shares.SharedWith)
+
+	shared, _ = repos.Shared.SharedViaFolder(ctx, inChild.Id, outsider.Id)
+	assert.True(t, shared, "sharing a folder must cover files in its subfolders")
+	shared, _ = repos.Shared.SharedViaFolder(ctx, inChild.Id, member.Id)
+	assert.False(t, shared)
+
+	page := domain.PageRequest{SortBy: domain.SORT_NAME, Order: domain.ORDER_ASC, Limit: 10}
+	files, _, _ := repos.File.GetAccessibleFiles(ctx, outsider.Id, page)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, inChild.Id, files[0].Id)
+	}
+
+	// Chuyển child ra thư mục gốc: path của cây con đổi theo, quyền từ parent mất.
+	assert.Nil(t, repos.Folder.Move(ctx, child, nil))
+	moved, _ := repos.Folder.Find(ctx, child.Id)
+	assert.Equal(t, "/"+child.Id+"/", moved.Path)
+	assert.Nil(t, moved.ParentId)
+	shared, _ = repos.Shared.SharedViaFolder(ctx, inChild.Id, outsider.Id)
+	assert.False(t, shared)
+}
+
+func TestSQLite_Attempts(t *testing.T) {
+	repos := openSQLite(t)
+	ctx := context.Background()
+	key := domain.AccountAttempt("a@example.test")
+
+	for want := 1; want <= 3; want++ {
+		failures, status := repos.Attempt.Reserve(ctx, key, time.Minute, 3, time.Minute)
+		assert.Nil(t, status)
+		assert.Equal(t, want, failures)
+	}
+
+	// Lần thứ 3 chạm ngưỡng nên đã khóa, lần sau không được tính.
+	remaining, _ := repos.Attempt.LockRemaining(ctx, []domain.AttemptKey{domain.IPAttempt("1.2.3.4"), key})
+	assert.InDelta(t, time.Minute.Seconds(), remaining.Seconds(), 5)
+	failures, status := repos.Attempt.Reserve(ctx, key, time.Minute, 3, time.Minute)
+	assert.Nil(t, status)
+	assert.Zero(t, failures)
+
+	// Lần thứ 3 hóa ra đúng: hoàn lại cả số đếm lẫn khóa nó đã đặt.
+	assert.Nil(t, repos.Attempt.Release(ctx, key, 3))
+	remaining, _ = repos.Attempt.LockRemaining(ctx, []domain.AttemptKey{key})
+	assert.Zero(t, remaining)
+	failures, _ = repos.Attempt.Reserve(ctx, key, time.Minute, 3, time.Minute)
+	assert.Equal(t, 3, failures)
+
+	assert.Nil(t, repos.Attempt.Reset(ctx, key))
+	remaining, _ = repos.Attempt.LockRemaining(ctx, []domain.AttemptKey{key})
+	assert.Zero(t, remaining)
+}
+
+func TestBruteForceGuard_ConcurrentGuesses(t *testing.T) {
+	repos := openSQLite(t)
+	ctx := context.Background()
+	guard := service.NewBruteForceGuard(repos.Attempt)
+	account := domain.AccountAttempt("victim@example.test")
+
+	// Mọi lần đoán cùng qua bước kiểm tra trước khi lần nào kịp so khớp: chỉ đủ ngưỡng tài khoản
+	// (5 lần) được so khớp, phần còn lại bị 429.
+	const guesses = 30
+	var start, done sync.WaitGroup
+	var allowed, limited atomic.Int32
+	start.Add(1)
+	for i := range guesses {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			start.Wait()
+			_, err := guard.Reserve(ctx, domain.IPAttempt(fmt.Sprintf("10.0.0.%d", i)), account)
+			switch {
+			case err == nil:
+				allowed.Add(1)
+			case err.Error() == utils.ErrCodeTooManyRequests:
+				limited.Add(1)
+			}
+		}()
+	}
+	start.Done()
+	done.Wait()
+
+	assert.EqualValues(t, 5, allowed.Load())
+	assert.EqualValues(t, guesses-5, limited.Load())
+
+	// Lần bị từ chối phải hoàn lại IP đã tính trước đó trong cùng lần gọi.
+	remaining, _ := repos.Attempt.LockRemaining(ctx, []domain.AttemptKey{domain.IPAttempt("10.0.0.0")})
+	assert.Zero(t, remaining)
+
+	t.Run("Correct Guess Is Released", func(t *testing.T) {
+		other := domain.AccountAttempt("owner@example.test")
+		for range 10 {
+			reservation, err := guard.Reserve(ctx, other)
+			assert.Nil(t, err)
+			guard.Release(ctx, reservation)
+		}
+	})
+}
+
+func TestSQLite_TokensAndRegistration(t *testing.T) {
+	repos := openSQLite(t)
+	b := backend{user: repos.User, auth: repos.Auth}
+	ctx := context.Background()
+	user := createUser(t, b, "user")
+
+	token := &domain.APIToken{UserId: user.Id, Name: "ci", TokenHash: "hash", Prefix: "pat_abc", Scopes: []string{domain.SCOPE_FILES_READ}, ExpiresAt: time.Now().Add(time.Hour)}
+	assert.Nil(t, repos.APIToken.Create(ctx, token))
+	assert.Nil(t, repos.APIToken.TouchLastUsed(ctx, token.Id))
+
+	found, status := repos.APIToken.FindByHash(ctx, "hash")
+	assert.Nil(t, status)
+	assert.Equal(t, []string{domain.SCOPE_FILES_READ}, found.Scopes)
+	assert.NotNil(t, found.LastUsedAt)
+
+	invite := &domain.Invite{TokenHash: "invite", CreatedBy: user.Id, ExpiresAt: time.Now().Add(time.Hour)}
+	assert.Nil(t, repos.Registration.CreateInvite(ctx, invite))
+	claimed, status := repos.Registration.ClaimInvite(ctx, "invite")
+	assert.Nil(t, status)
+	assert.Equal(t, user.Id, claimed.CreatedBy)
+	_, status = repos.Registration.ClaimInvite(ctx, "invite")
+	assert.Equal(t, utils.ErrCodeInviteInvalid, status.Error())
+
+	assert.Nil(t, repos.Registration.SaveVerification(ctx, user.Id, "new@example.test", "verify", time.Now().Add(time.Hour)))
+	consumed, status := repos.Registration.ConsumeVerification(ctx, "verify")
+	assert.Nil(t, status)
+	assert.True(t, consumed)
+
+	var updated domain.User
+	assert.Nil(t, repos.User.FindById(user.Id, &updated))
+	assert.Equal(t, "new@example.test", updated.Email)
+
+	consumed, _ = repos.Registration.ConsumeVerification(ctx, "verify")
+	assert.False(t, consumed)
+}
+
+func TestSQLite_SearchRelevanceCursor(t *testing.T) {
+	repos := openSQLite(t)
+	b := backend{user: repos.User, auth: repos.Auth, file: repos.File}
+	ctx := context.Background()
+	owner := createUser(t, b, "owner")
+	for i := range 3 {
+		createFile(t, b, owner, "report draft.txt", 1, time.Now().Add(time.Duration(i)*time.Second))
+	}
+
+	params := domain.FileSearchParams{Query: "report:*", Page: domain.PageRequest{SortBy: domain.SORT_RELEVANCE, Order: domain.ORDER_DESC, Limit: 2}}
+	first, next, status := repos.File.SearchFiles(ctx, owner.Id, params)
+	assert.Nil(t, status)
+	assert.Len(t, first, 2)
+	if assert.NotNil(t, next) {
+		params.Page.After = next
+		second, next, status := repos.File.SearchFiles(ctx, owner.Id, params)
+		assert.Nil(t, status)
+		assert.Nil(t, next)
+		if assert.Len(t, second, 1) {
+			assert.NotContains(t, []string{first[0].File.Id, first[1].File.Id}, second[0].File.Id)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingSharedRepository chia sẻ thật rồi báo lỗi, giả lập lỗi ở bước cuối của upload.
+type failingSharedRepository struct {
+	repository.SharedRepository
+}
+
+func (r failingSharedRepository) ShareFileWithUsers(ctx context.Context, fileID string, emails []string) (*domain.ShareResult, *utils.ReturnStatus) {
+	if _, err := r.SharedRepository.ShareFileWithUsers(ctx, fileID, emails); err != nil {
+		return nil, err
+	}
+	return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, "share failed")
+}
+
+// multipartFile dựng *multipart.FileHeader như khi gin bind field "file" của form upload.
+func multipartFile(t *testing.T, name string, content string) *multipart.FileHeader {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", name)
+	part.Write([]byte(content))
+	writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["file"][0]
+}
+
+func TestUploadFile_Atomic(t *testing.T) {
+	t.Setenv("DATABASE_URL", "sqlite://:memory:")
+
+	forEachBackend(t, func(t *testing.T, b backend) {
+		owner := createUser(t, b, "owner")
+		recipient := createUser(t, b, "recipient")
+
+		newService := func(sharedRepo repository.SharedRepository, dir string) service.FileService {
+			return service.NewFileService(config.NewConfig(), b.file, sharedRepo, b.user, b.group, b.folder, b.tx,
+				storage.NewLocalStorage(dir), nil, nil)
+		}
+		req := &dto.UploadRequest{SharedWith: []string{recipient.Email}}
+		page := domain.PageRequest{SortBy: domain.SORT_CREATED_AT, Order: domain.ORDER_DESC}
+
+		t.Run("Share Failure Rolls Back", func(t *testing.T) {
+			dir := t.TempDir()
+			svc := newService(failingSharedRepository{b.shared}, dir)
+
+			_, status := svc.UploadFile(context.Background(), multipartFile(t, "a.txt", "hello"), req, &owner.Id)
+			assert.True(t, status.IsErr())
+
+			files, _, _ := b.file.GetMyFiles(context.Background(), owner.Id, domain.ListFileParams{Status: "all", Page: page})
+			assert.Empty(t, files)
+			shared, _ := b.shared.GetFilesSharedWithUser(context.Background(), recipient.Id)
+			assert.Empty(t, shared)
+
+			entries, _ := os.ReadDir(dir)
+			assert.Empty(t, entries, "stored blob must be removed on rollback")
+		})
+
+		t.Run("Success Commits Everything", func(t *testing.T) {
+			dir := t.TempDir()
+			svc := newService(b.shared, dir)
+
+			file, status := svc.UploadFile(context.Background(), multipartFile(t, "a.txt", "hello"), req, &owner.Id)
+			if status != nil {
+				t.Fatalf("Upload failed: %s", status.Error())
+			}
+
+			files, _, _ := b.file.GetMyFiles(context.Background(), owner.Id, domain.ListFileParams{Status: "all", Page: page})
+			assert.Len(t, files, 1)
+			shared, _ := b.shared.GetFilesSharedWithUser(context.Background(), recipient.Id)
+			assert.Len(t, shared, 1)
+
+			_, statErr := os.Stat(filepath.Join(dir, file.StorageName))
+			assert.NoError(t, statErr)
+		})
+	})
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exportStatusResponse struct {
+	Export struct {
+		Id     string `json:"id"`
+		Status string `json:"status"`
+	} `json:"export"`
+	DownloadURL string `json:"downloadUrl"`
+}
+
+// waitForExport poll GET /user/export/:id tới khi job kết thúc.
+func waitForExport(t *testing.T, token, jobID string) exportStatusResponse {
+	t.Helper()
+
+	var resp exportStatusResponse
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		rec := adminRequest(t, "GET", "/user/export/"+jobID, token, nil)
+		if rec.Code != 200 || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+			t.Fatalf("Get export failed: %d %s", rec.Code, rec.Body.String())
+		}
+		if resp.Export.Status == "ready" || resp.Export.Status == "failed" {
+			return resp
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("export %s did not finish, last status %q", jobID, resp.Export.Status)
+	return resp
+}
+
+func TestUser_DataExport(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, email := setupUserAndToken(t)
+	otherToken, otherEmail := setupUserAndToken(t)
+
+	fileID, _ := uploadFileForTest(t, token, "", "", "", []string{otherEmail})
+	uploadFileForTest(t, otherToken, "", "", "", []string{email})
+
+	rec := adminRequest(t, "POST", "/user/export", token, nil)
+	var started exportStatusResponse
+	if rec.Code != 202 || json.Unmarshal(rec.Body.Bytes(), &started) != nil || started.Export.Id == "" {
+		t.Fatalf("Start export failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	t.Run("Other User Cannot See Job", func(t *testing.T) {
+		rec := adminRequest(t, "GET", "/user/export/"+started.Export.Id, otherToken, nil)
+		assert.Equal(t, 404, rec.Code)
+	})
+
+	status := waitForExport(t, token, started.Export.Id)
+	if status.Export.Status != "ready" || status.DownloadURL == "" {
+		t.Fatalf("Export did not succeed: %+v", status)
+	}
+
+	download := func(signedURL string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", strings.TrimPrefix(signedURL, os.Getenv("PUBLIC_BASE_URL")), nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Archive Contents", func(t *testing.T) {
+		rec := download(status.DownloadURL)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+		archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatalf("Invalid archive: %d %v", rec.Code, err)
+		}
+
+		entries := map[string][]byte{}
+		for _, f := range archive.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatalf("Cannot open %s: %v", f.Name, err)
+			}
+			entries[f.Name], _ = io.ReadAll(r)
+			r.Close()
+		}
+
+		for _, name := range []string{"profile.json", "files/manifest.json", "shares.json", "downloads.json", "logins.json"} {
+			assert.Contains(t, entries, name)
+		}
+		assert.Equal(t, "Hello World Content", string(entries["files/"+fileID+"/test_file.txt"]))
+
+		var profile map[string]any
+		json.Unmarshal(entries["profile.json"], &profile)
+		assert.Equal(t, email, profile["email"])
+
+		var manifest []map[string]any
+		json.Unmarshal(entries["files/manifest.json"], &manifest)
+		if assert.Len(t, manifest, 1) {
+			assert.Equal(t, fileID, manifest[0]["id"])
+			assert.Equal(t, []any{otherEmail}, manifest[0]["sharedWith"])
+		}
+
+		var shares struct {
+			SharedByMe   []map[string]any `json:"sharedByMe"`
+			SharedWithMe []map[string]any `json:"sharedWithMe"`
+		}
+		json.Unmarshal(entries["shares.json"], &shares)
+		assert.Len(t, shares.SharedByMe, 1)
+		if assert.Len(t, shares.SharedWithMe, 1) {
+			assert.Equal(t, otherEmail, shares.SharedWithMe[0]["ownerEmail"])
+		}
+
+		var logins []map[string]any
+		json.Unmarshal(entries["logins.json"], &logins)
+		if assert.NotEmpty(t, logins) {
+			assert.Equal(t, "password", logins[0]["method"])
+		}
+	})
+
+	t.Run("Tampered Signature", func(t *testing.T) {
+		rec := download(strings.Replace(status.DownloadURL, "sig=", "sig=x", 1))
+		assert.Equal(t, 403, rec.Code)
+	})
+
+	t.Run("Requires Session", func(t *testing.T) {
+		rec := adminRequest(t, "POST", "/user/export", "", nil)
+		assert.Equal(t, 401, rec.Code)
+	})
+}
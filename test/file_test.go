@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -24,7 +28,7 @@ func setupUserAndToken(t *testing.T) (string, string) {
 	uniqueID := time.Now().UnixNano()
 	username := fmt.Sprintf("user_%d", uniqueID)
 	email := fmt.Sprintf("user_%d@example.com", uniqueID)
-	password := "123456789"
+	password := testUserPassword
 
 	// 1. Register
 	regBody := fmt.Sprintf(`{"username": "%s", "email": "%s", "password": "%s"}`, username, email, password)
@@ -37,6 +41,7 @@ func setupUserAndToken(t *testing.T) (string, string) {
 	if recReg.Code != 200 && recReg.Code != 201 {
 		t.Fatalf("Register failed: %v", recReg.Body.String())
 	}
+	verifyEmailForTest(t, email)
 
 	// 2. Login
 	loginBody := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)
@@ -201,6 +206,15 @@ func TestDownload_PasswordProtected(t *testing.T) {
 		TestApp.Router().ServeHTTP(rec, req)
 		assert.Equal(t, 200, rec.Code)
 	})
+
+	// Password qua header X-File-Password
+	t.Run("Password Header", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/files/"+shareToken+"/download", nil)
+		req.Header.Set("X-File-Password", pass)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+	})
 }
 
 func TestDownload_TimeRestricted(t *testing.T) {
@@ -389,3 +403,425 @@ func TestPublic_Info_By_ShareToken(t *testing.T) {
 		}
 	})
 }
+
+// uploadFileWithFields: Helper upload with arbitrary form fields
+func uploadFileWithFields(t *testing.T, token string, fields map[string]string) map[string]interface{} {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	part, _ := writer.CreateFormFile("file", "test_file.txt")
+	io.WriteString(part, "Hello World Content")
+
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/files/upload", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+
+	if rec.Code != 201 {
+		t.Fatalf("Upload helper failed: %v", rec.Body.String())
+	}
+
+	return ParseJSON(t, rec)["file"].(map[string]interface{})
+}
+
+func TestDownload_MaxDownloads(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	download := func(shareToken string) int {
+		req, _ := http.NewRequest("GET", "/files/"+shareToken+"/download", nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("Limit Reached", func(t *testing.T) {
+		file := uploadFileWithFields(t, "", map[string]string{"isPublic": "true", "maxDownloads": "2"})
+		shareToken := file["shareToken"].(string)
+
+		assert.Equal(t, 200, download(shareToken))
+		assert.Equal(t, 200, download(shareToken))
+		assert.Equal(t, 410, download(shareToken))
+	})
+
+	t.Run("Burn After Reading", func(t *testing.T) {
+		file := uploadFileWithFields(t, "", map[string]string{"isPublic": "true", "maxDownloads": "1", "deleteOnLimit": "true"})
+		shareToken := file["shareToken"].(string)
+
+		assert.Equal(t, 200, download(shareToken))
+		assert.Equal(t, 404, download(shareToken))
+	})
+
+	// Nhiều request cùng tranh lượt tải cuối: chỉ đúng một request được tải.
+	for _, deleteOnLimit := range []string{"false", "true"} {
+		t.Run("Concurrent Last Slot deleteOnLimit="+deleteOnLimit, func(t *testing.T) {
+			file := uploadFileWithFields(t, "", map[string]string{"isPublic": "true", "maxDownloads": "1", "deleteOnLimit": deleteOnLimit})
+			shareToken := file["shareToken"].(string)
+
+			const workers = 10
+			codes := make(chan int, workers)
+			var wg sync.WaitGroup
+			for range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					codes <- download(shareToken)
+				}()
+			}
+			wg.Wait()
+			close(codes)
+
+			succeeded := 0
+			for code := range codes {
+				if code == 200 {
+					succeeded++
+				}
+			}
+			assert.Equal(t, 1, succeeded)
+		})
+	}
+}
+
+func TestUpload_CustomSlug(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, _ := setupUserAndToken(t)
+	slug := fmt.Sprintf("q3-report-%d", time.Now().UnixNano())
+
+	t.Run("Slug Used As Share Token", func(t *testing.T) {
+		file := uploadFileWithFields(t, token, map[string]string{"isPublic": "true", "slug": slug})
+		assert.Equal(t, slug, file["shareToken"])
+
+		req, _ := http.NewRequest("GET", "/files/"+slug, nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+	})
+
+	upload := func(fields map[string]string, bearer string) int {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "test_file.txt")
+		io.WriteString(part, "Hello World Content")
+		for key, value := range fields {
+			writer.WriteField(key, value)
+		}
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/files/upload", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("Duplicate Slug Conflict", func(t *testing.T) {
+		assert.Equal(t, 409, upload(map[string]string{"isPublic": "true", "slug": slug}, token))
+	})
+
+	t.Run("Invalid Slug", func(t *testing.T) {
+		assert.Equal(t, 400, upload(map[string]string{"isPublic": "true", "slug": "Not A Slug"}, token))
+		assert.Equal(t, 400, upload(map[string]string{"isPublic": "true", "slug": "my"}, token))
+	})
+
+	t.Run("Anonymous Slug Rejected", func(t *testing.T) {
+		assert.Equal(t, 401, upload(map[string]string{"isPublic": "true", "slug": "anon-" + slug}, ""))
+	})
+}
+
+func TestShareLink_QRCode(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, _ := setupUserAndToken(t)
+	fileId, shareToken := uploadFileForTest(t, token, "", "", "", nil)
+
+	t.Run("Share Link Uses Public Base URL", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/files/info/"+fileId, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+
+		assert.Equal(t, 200, rec.Code)
+		data := ParseJSON(t, rec)["file"].(map[string]interface{})
+		assert.Equal(t, os.Getenv("PUBLIC_BASE_URL")+"/files/"+shareToken, data["shareLink"])
+	})
+
+	for format, contentType := range map[string]string{"png": "image/png", "svg": "image/svg+xml"} {
+		t.Run("QR "+format, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/files/info/"+fileId+"/qr?format="+format, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			TestApp.Router().ServeHTTP(rec, req)
+
+			assert.Equal(t, 200, rec.Code)
+			assert.Equal(t, contentType, rec.Header().Get("Content-Type"))
+			assert.NotEmpty(t, rec.Body.Bytes())
+		})
+	}
+
+	t.Run("QR Other User Forbidden", func(t *testing.T) {
+		otherToken, _ := setupUserAndToken(t)
+		req, _ := http.NewRequest("GET", "/files/info/"+fileId+"/qr", nil)
+		req.Header.Set("Authorization", "Bearer "+otherToken)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 403, rec.Code)
+	})
+}
+
+func TestDownload_SignedURL(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, _ := setupUserAndToken(t)
+	pass := "SecurePass123"
+	fileId, shareToken := uploadFileForTest(t, token, pass, "", "", nil)
+
+	issue := func(body string, remoteAddr string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("POST", "/files/"+shareToken+"/signed-url", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-File-Password", pass)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec.Code, ParseJSON(t, rec)
+	}
+
+	fetch := func(signedURL string, remoteAddr string) int {
+		req, _ := http.NewRequest("GET", strings.TrimPrefix(signedURL, os.Getenv("PUBLIC_BASE_URL")), nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("Missing Password", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/files/"+shareToken+"/signed-url", nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 403, rec.Code)
+	})
+
+	t.Run("Download Without Auth", func(t *testing.T) {
+		code, resp := issue(`{"expiresIn": 60}`, "10.0.0.1:1234")
+		assert.Equal(t, 200, code)
+		assert.NotEmpty(t, resp["expiresAt"])
+
+		assert.Equal(t, 200, fetch(resp["url"].(string), "10.0.0.2:1234"))
+	})
+
+	t.Run("Tampered Signature", func(t *testing.T) {
+		_, resp := issue(`{}`, "10.0.0.1:1234")
+		tampered := strings.Replace(resp["url"].(string), "v=1", "v=2", 1)
+		assert.Equal(t, 403, fetch(tampered, "10.0.0.1:1234"))
+	})
+
+	t.Run("IP Bound", func(t *testing.T) {
+		_, resp := issue(`{"bindIp": true}`, "10.0.0.1:1234")
+		assert.Equal(t, 403, fetch(resp["url"].(string), "10.0.0.2:1234"))
+		assert.Equal(t, 200, fetch(resp["url"].(string), "10.0.0.1:5678"))
+	})
+
+	t.Run("TTL Above Policy", func(t *testing.T) {
+		code, _ := issue(`{"expiresIn": 86400}`, "10.0.0.1:1234")
+		assert.Equal(t, 400, code)
+	})
+
+	t.Run("Revoked By Link Change", func(t *testing.T) {
+		_, resp := issue(`{}`, "10.0.0.1:1234")
+
+		req, _ := http.NewRequest("PATCH", "/files/info/"+fileId+"/link", bytes.NewBufferString(`{"maxDownloads": 100}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+
+		assert.Equal(t, 403, fetch(resp["url"].(string), "10.0.0.1:1234"))
+	})
+}
+
+func TestDownload_ContentDispositionEscaping(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	name := `báo cáo "Q3"; v2.txt`
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("file", name)
+	io.WriteString(part, "Hello World Content")
+	writer.WriteField("isPublic", "true")
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/files/upload", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	if rec.Code != 201 {
+		t.Fatalf("Upload failed: %v", rec.Body.String())
+	}
+	shareToken := ParseJSON(t, rec)["file"].(map[string]interface{})["shareToken"].(string)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+	assertDisposition := func(t *testing.T, rec *httptest.ResponseRecorder, expected string) {
+		assert.Equal(t, 200, rec.Code)
+		disposition, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, disposition)
+			assert.Equal(t, name, params["filename"])
+		}
+	}
+
+	t.Run("Preview", func(t *testing.T) {
+		assertDisposition(t, get("/files/"+shareToken+"/preview"), "inline")
+	})
+
+	t.Run("Signed Download", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/files/"+shareToken+"/signed-url", nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		signedURL := ParseJSON(t, rec)["url"].(string)
+		assertDisposition(t, get(strings.TrimPrefix(signedURL, os.Getenv("PUBLIC_BASE_URL"))), "attachment")
+	})
+}
+
+func TestDownload_PasswordBruteForce(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	_, shareToken := uploadFileForTest(t, "", "SecurePass123", "", "", nil)
+
+	download := func(password string, remoteAddr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/files/"+shareToken+"/download", nil)
+		req.Header.Set("X-File-Password", password)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Đổi IP liên tục vẫn bị chặn vì bộ đếm theo share token
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, 403, download("wrong", fmt.Sprintf("10.0.1.%d:1234", i)).Code)
+	}
+
+	rec := download("SecurePass123", "10.0.2.1:1234")
+	assert.Equal(t, 429, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestRateLimit_AnonymousUpload(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	adminToken := setupAdminToken(t)
+
+	setLimit := func(limit int, window int) {
+		body := fmt.Sprintf(`{"rateLimits": {"upload_anonymous": {"limit": %d, "windowSeconds": %d}}}`, limit, window)
+		req, _ := http.NewRequest("PATCH", "/admin/policy", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+	}
+	setLimit(2, 3600)
+	t.Cleanup(func() { setLimit(100000, 1) })
+
+	upload := func(token string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "test_file.txt")
+		io.WriteString(part, "Hello World Content")
+		writer.WriteField("isPublic", "true")
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/files/upload", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.RemoteAddr = "10.0.3.1:1234"
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := upload("")
+	assert.Equal(t, 201, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+
+	assert.Equal(t, 201, upload("").Code)
+
+	blocked := upload("")
+	assert.Equal(t, 429, blocked.Code)
+	assert.NotEmpty(t, blocked.Header().Get("Retry-After"))
+
+	// User đã đăng nhập dùng bucket "upload" riêng theo user ID
+	userToken, _ := setupUserAndToken(t)
+	assert.Equal(t, 201, upload(userToken).Code)
+}
+
+func TestUpload_SharedWithUnresolved(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	ownerToken, _ := setupUserAndToken(t)
+	_, recipientEmail := setupUserAndToken(t)
+	injection := "x'); delete from users; --@example.com"
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("file", "test_file.txt")
+	io.WriteString(part, "Hello World Content")
+	// Email khác hoa/thường hoặc có khoảng trắng vẫn khớp cùng tài khoản/cùng người nhận.
+	for _, share := range []string{recipientEmail, "nobody@example.com", injection, " " + strings.ToUpper(recipientEmail), "Nobody@Example.com"} {
+		writer.WriteField("sharedWith", share)
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/files/upload", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+ownerToken)
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+
+	assert.Equal(t, 201, rec.Code, rec.Body.String())
+	file := ParseJSON(t, rec)["file"].(map[string]interface{})
+	assert.Equal(t, []interface{}{recipientEmail}, file["sharedWith"])
+	assert.Equal(t, []interface{}{"nobody@example.com", injection}, file["unresolvedRecipients"])
+
+	// Email chứa dấu nháy chỉ là dữ liệu, không được chạy như SQL.
+	var users int
+	TestDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users)
+	assert.Equal(t, 2, users)
+
+	var shares int
+	TestDB.QueryRow(`SELECT COUNT(*) FROM shared WHERE file_id = $1`, file["id"]).Scan(&shares)
+	assert.Equal(t, 1, shares)
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createFolderForTest(t *testing.T, token string, name string, parentID string) map[string]interface{} {
+	t.Helper()
+
+	body := map[string]interface{}{"name": name}
+	if parentID != "" {
+		body["parentId"] = parentID
+	}
+
+	rec := adminRequest(t, "POST", "/folders", token, body)
+	if rec.Code != 201 {
+		t.Fatalf("Create folder failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	return ParseJSON(t, rec)["folder"].(map[string]interface{})
+}
+
+func TestFolder_Hierarchy(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, _ := setupUserAndToken(t)
+	otherToken, _ := setupUserAndToken(t)
+
+	docs := createFolderForTest(t, token, "Docs", "")
+	docsID := docs["id"].(string)
+	reports := createFolderForTest(t, token, "Reports", docsID)
+	reportsID := reports["id"].(string)
+
+	t.Run("Materialized Path", func(t *testing.T) {
+		assert.Equal(t, "/"+docsID+"/", docs["path"])
+		assert.Equal(t, "/"+docsID+"/"+reportsID+"/", reports["path"])
+		assert.Equal(t, docsID, reports["parentId"])
+	})
+
+	t.Run("Duplicate Sibling Name", func(t *testing.T) {
+		rec := adminRequest(t, "POST", "/folders", token, map[string]interface{}{"name": "docs"})
+		assert.Equal(t, 409, rec.Code)
+
+		// Tên trùng ở thư mục khác cấp thì hợp lệ.
+		createFolderForTest(t, token, "Docs", docsID)
+	})
+
+	t.Run("Upload Into Folder", func(t *testing.T) {
+		file := uploadFileWithFields(t, token, map[string]string{"folderId": reportsID})
+		assert.Equal(t, reportsID, file["folderId"])
+
+		rec := adminRequest(t, "GET", "/folders/"+reportsID, token, nil)
+		assert.Equal(t, 200, rec.Code)
+		files := ParseJSON(t, rec)["files"].([]interface{})
+		if assert.Len(t, files, 1) {
+			assert.Equal(t, file["id"], files[0].(map[string]interface{})["id"])
+		}
+
+		rec = adminRequest(t, "GET", "/files/my?folderId=root", token, nil)
+		assert.Equal(t, 200, rec.Code)
+		assert.Empty(t, ParseJSON(t, rec)["files"])
+	})
+
+	t.Run("Cannot Create In Foreign Folder", func(t *testing.T) {
+		rec := adminRequest(t, "POST", "/folders", otherToken, map[string]interface{}{"name": "Mine", "parentId": docsID})
+		assert.Equal(t, 404, rec.Code)
+	})
+
+	t.Run("Cannot Move Into Own Subtree", func(t *testing.T) {
+		rec := adminRequest(t, "POST", "/folders/"+docsID+"/move", token, map[string]interface{}{"parentId": reportsID})
+		assert.Equal(t, 400, rec.Code)
+	})
+
+	t.Run("Move Updates Subtree Paths", func(t *testing.T) {
+		archive := createFolderForTest(t, token, "Archive", "")
+		archiveID := archive["id"].(string)
+
+		rec := adminRequest(t, "POST", "/folders/"+docsID+"/move", token, map[string]interface{}{"parentId": archiveID})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		rec = adminRequest(t, "GET", "/folders/"+reportsID, token, nil)
+		folder := ParseJSON(t, rec)["folder"].(map[string]interface{})
+		assert.Equal(t, "/"+archiveID+"/"+docsID+"/"+reportsID+"/", folder["path"])
+	})
+
+	t.Run("Delete Moves Files To Root", func(t *testing.T) {
+		rec := adminRequest(t, "DELETE", "/folders/"+docsID, token, nil)
+		assert.Equal(t, 200, rec.Code)
+
+		assert.Equal(t, 404, adminRequest(t, "GET", "/folders/"+reportsID, token, nil).Code)
+
+		rec = adminRequest(t, "GET", "/files/my?folderId=root", token, nil)
+		assert.Len(t, ParseJSON(t, rec)["files"], 1)
+	})
+}
+
+func TestFolder_Sharing(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	ownerToken, _ := setupUserAndToken(t)
+	recipientToken, recipientEmail := setupUserAndToken(t)
+
+	parentID := createFolderForTest(t, ownerToken, "Project", "")["id"].(string)
+	childID := createFolderForTest(t, ownerToken, "Assets", parentID)["id"].(string)
+
+	before := uploadFileWithFields(t, ownerToken, map[string]string{"folderId": childID})
+
+	getInfo := func(shareToken interface{}) int {
+		return adminRequest(t, "GET", "/files/"+shareToken.(string), recipientToken, nil).Code
+	}
+
+	t.Run("Not Shared Yet", func(t *testing.T) {
+		assert.Equal(t, 403, getInfo(before["shareToken"]))
+		assert.Equal(t, 404, adminRequest(t, "GET", "/folders/"+parentID, recipientToken, nil).Code)
+	})
+
+	rec := adminRequest(t, "PUT", "/folders/"+parentID+"/share", ownerToken, map[string]interface{}{"sharedWith": []string{recipientEmail}})
+	assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+	t.Run("Sharing Covers Subtree", func(t *testing.T) {
+		assert.Equal(t, 200, getInfo(before["shareToken"]))
+		assert.Contains(t, accessibleFileIDs(t, recipientToken), before["id"])
+		assert.Equal(t, 200, adminRequest(t, "GET", "/folders/"+childID, recipientToken, nil).Code)
+	})
+
+	t.Run("Files Added Later Are Shared", func(t *testing.T) {
+		after := uploadFileWithFields(t, ownerToken, map[string]string{"folderId": childID})
+		assert.Equal(t, 200, getInfo(after["shareToken"]))
+		assert.Contains(t, accessibleFileIDs(t, recipientToken), after["id"])
+	})
+
+	t.Run("Recipient Cannot Modify", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/folders/"+parentID, recipientToken, map[string]string{"name": "Mine"})
+		assert.Equal(t, 403, rec.Code)
+
+		rec = adminRequest(t, "GET", "/folders/"+parentID, recipientToken, nil)
+		_, hasShares := ParseJSON(t, rec)["shares"]
+		assert.False(t, hasShares)
+	})
+
+	t.Run("Shared Folder Listing", func(t *testing.T) {
+		rec := adminRequest(t, "GET", "/folders/shared", recipientToken, nil)
+		assert.Equal(t, 200, rec.Code)
+		folders := ParseJSON(t, rec)["folders"].([]interface{})
+		if assert.Len(t, folders, 1) {
+			assert.Equal(t, parentID, folders[0].(map[string]interface{})["id"])
+		}
+	})
+
+	t.Run("Moving File Out Revokes Access", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/files/info/"+before["id"].(string)+"/folder", ownerToken, map[string]interface{}{"folderId": nil})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		assert.Equal(t, 403, getInfo(before["shareToken"]))
+	})
+
+	t.Run("Unsharing Revokes Access", func(t *testing.T) {
+		rec := adminRequest(t, "PUT", "/folders/"+parentID+"/share", ownerToken, map[string]interface{}{"sharedWith": []string{}})
+		assert.Equal(t, 200, rec.Code)
+		assert.Empty(t, ParseJSON(t, rec)["shares"].(map[string]interface{})["sharedWith"])
+
+		assert.Empty(t, accessibleFileIDs(t, recipientToken))
+		assert.Equal(t, 404, adminRequest(t, "GET", "/folders/"+childID, recipientToken, nil).Code)
+	})
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createGroupForTest(t *testing.T, token string, name string) string {
+	t.Helper()
+
+	rec := adminRequest(t, "POST", "/groups", token, map[string]string{"name": name})
+	if rec.Code != 201 {
+		t.Fatalf("Create group failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	return ParseJSON(t, rec)["group"].(map[string]interface{})["id"].(string)
+}
+
+func accessibleFileIDs(t *testing.T, token string) []string {
+	t.Helper()
+
+	rec := adminRequest(t, "GET", "/files/available", token, nil)
+	if rec.Code != 200 {
+		t.Fatalf("List accessible files failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Danh sách rỗng được trả về dưới dạng null.
+	files, _ := ParseJSON(t, rec)["files"].([]interface{})
+
+	ids := []string{}
+	for _, f := range files {
+		ids = append(ids, f.(map[string]interface{})["fileid"].(string))
+	}
+	return ids
+}
+
+func TestGroup_ShareWithGroup(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	ownerToken, _ := setupUserAndToken(t)
+	memberToken, memberEmail := setupUserAndToken(t)
+	outsiderToken, _ := setupUserAndToken(t)
+
+	groupID := createGroupForTest(t, ownerToken, "Engineering")
+
+	fileID, shareToken := uploadFileForTest(t, ownerToken, "", "", "", []string{groupID})
+
+	getInfo := func(token string) *httptest.ResponseRecorder {
+		return adminRequest(t, "GET", "/files/"+shareToken, token, nil)
+	}
+
+	t.Run("Not Yet A Member", func(t *testing.T) {
+		assert.Equal(t, 403, getInfo(memberToken).Code)
+		assert.NotContains(t, accessibleFileIDs(t, memberToken), fileID)
+	})
+
+	rec := adminRequest(t, "POST", "/groups/"+groupID+"/members", ownerToken, map[string]string{"email": memberEmail})
+	assert.Equal(t, 201, rec.Code, rec.Body.String())
+	members := ParseJSON(t, rec)["members"].([]interface{})
+	assert.Len(t, members, 2)
+	memberID := ""
+	for _, m := range members {
+		if m.(map[string]interface{})["email"] == memberEmail {
+			memberID = m.(map[string]interface{})["userId"].(string)
+		}
+	}
+
+	t.Run("Membership Grants Access", func(t *testing.T) {
+		assert.Equal(t, 200, getInfo(memberToken).Code)
+		assert.Contains(t, accessibleFileIDs(t, memberToken), fileID)
+	})
+
+	t.Run("Owner Sees Shared Groups", func(t *testing.T) {
+		rec := adminRequest(t, "GET", "/files/info/"+fileID, ownerToken, nil)
+		assert.Equal(t, 200, rec.Code)
+		file := ParseJSON(t, rec)["file"].(map[string]interface{})
+		assert.Equal(t, []interface{}{groupID}, file["sharedWithGroups"])
+	})
+
+	t.Run("Outsider Is Denied", func(t *testing.T) {
+		assert.Equal(t, 403, getInfo(outsiderToken).Code)
+		assert.Equal(t, 404, adminRequest(t, "GET", "/groups/"+groupID, outsiderToken, nil).Code)
+	})
+
+	t.Run("Cannot Share With Foreign Group", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "test_file.txt")
+		io.WriteString(part, "Hello World Content")
+		writer.WriteField("sharedWith", groupID)
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/files/upload", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+outsiderToken)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+
+		assert.Equal(t, 400, rec.Code)
+		assert.Equal(t, groupID, ParseJSON(t, rec)["groupId"])
+	})
+
+	t.Run("Member Cannot Manage Group", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/groups/"+groupID, memberToken, map[string]string{"name": "Renamed"})
+		assert.Equal(t, 403, rec.Code)
+	})
+
+	t.Run("Last Owner Cannot Leave", func(t *testing.T) {
+		rec := adminRequest(t, "GET", "/groups", ownerToken, nil)
+		assert.Equal(t, 200, rec.Code)
+		groups := ParseJSON(t, rec)["groups"].([]interface{})
+		if assert.Len(t, groups, 1) {
+			assert.Equal(t, "owner", groups[0].(map[string]interface{})["myRole"])
+		}
+
+		rec = adminRequest(t, "GET", "/groups/"+groupID, ownerToken, nil)
+		for _, m := range ParseJSON(t, rec)["members"].([]interface{}) {
+			member := m.(map[string]interface{})
+			if member["role"] == "owner" {
+				rec := adminRequest(t, "DELETE", "/groups/"+groupID+"/members/"+member["userId"].(string), ownerToken, nil)
+				assert.Equal(t, 409, rec.Code)
+			}
+		}
+	})
+
+	t.Run("Removal Revokes Access", func(t *testing.T) {
+		rec := adminRequest(t, "DELETE", "/groups/"+groupID+"/members/"+memberID, ownerToken, nil)
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		assert.Equal(t, 403, getInfo(memberToken).Code)
+		assert.NotContains(t, accessibleFileIDs(t, memberToken), fileID)
+	})
+
+	t.Run("Deleting Group Revokes Access", func(t *testing.T) {
+		adminRequest(t, "POST", "/groups/"+groupID+"/members", ownerToken, map[string]string{"email": memberEmail})
+		assert.Equal(t, 200, getInfo(memberToken).Code)
+
+		rec := adminRequest(t, "DELETE", "/groups/"+groupID, ownerToken, nil)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, 403, getInfo(memberToken).Code)
+	})
+}
@@ -6,13 +6,16 @@ import (
 	"testing"
 )
 
+// testUserPassword: password của các tài khoản tạo trong test, đạt password policy mặc định.
+const testUserPassword = "quiet-harbor-2931"
+
 func ParseJSON(t *testing.T, rr *httptest.ResponseRecorder) map[string]interface{} {
 	var data map[string]interface{}
 	_ = json.Unmarshal(rr.Body.Bytes(), &data)
 	return data
 }
 
-func ResetDB(t *testing.T) {
+func ResetDB(t testing.TB) {
 	_, err := TestDB.Exec(`
 		TRUNCATE TABLE
 		users,
@@ -20,10 +23,42 @@ func ResetDB(t *testing.T) {
 		shared,
 		download,
 		usersLoginSession,
-		jwt_blacklist
+		jwt_blacklist,
+		auth_attempts,
+		api_tokens,
+		oidc_states,
+		user_identities,
+		webauthn_credentials,
+		webauthn_sessions,
+		email_verifications,
+		email_domain_rules,
+		invites,
+		user_token_revocations,
+		login_history,
+		export_jobs,
+		groups,
+		group_members,
+		shared_groups,
+		folders,
+		shared_folders,
+		shared_folder_groups
 		CASCADE;
 	`)
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+// verifyEmailForTest mở link trong email xác minh mới nhất gửi tới email.
+func verifyEmailForTest(t *testing.T, email string) {
+	t.Helper()
+
+	token := TestMail.VerificationToken(t, email)
+	req := httptest.NewRequest("GET", "/auth/verify-email?token="+token, nil)
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+
+	if rec.Code != 200 {
+		t.Fatalf("Verify email failed: %d %s", rec.Code, rec.Body.String())
+	}
+}
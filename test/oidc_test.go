@@ -0,0 +1,182 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// beginOIDCLogin: GET /auth/oidc/mock/login -> authorization URL (Location của redirect 302).
+func beginOIDCLogin(t *testing.T) string {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/auth/oidc/mock/login", nil)
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("OIDC login did not redirect: %d %s", rec.Code, rec.Body.String())
+	}
+	return rec.Header().Get("Location")
+}
+
+func oidcCallback(t *testing.T, code, state string) *httptest.ResponseRecorder {
+	t.Helper()
+	query := url.Values{"code": {code}, "state": {state}}
+	req, _ := http.NewRequest("GET", "/auth/oidc/mock/callback?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOIDC_Login(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	t.Run("Providers", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/auth/oidc/providers", nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, []interface{}{"mock"}, ParseJSON(t, rec)["providers"])
+	})
+
+	t.Run("Unknown Provider", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/auth/oidc/nope/login", nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 404, rec.Code)
+	})
+
+	t.Run("Just In Time Provisioning", func(t *testing.T) {
+		authURL := beginOIDCLogin(t)
+		assert.Contains(t, authURL, TestOIDC.Issuer()+"/authorize?")
+
+		code, state := TestOIDC.Authorize(t, authURL, jwt.MapClaims{
+			"sub":                "alice-sub",
+			"email":              "Alice@Corp.Test",
+			"email_verified":     true,
+			"preferred_username": "alice",
+			"groups":             []string{"staff"},
+		})
+
+		rec := oidcCallback(t, code, state)
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		resp := ParseJSON(t, rec)
+		user := resp["user"].(map[string]interface{})
+		assert.Equal(t, "alice@corp.test", user["email"])
+		assert.Equal(t, "alice", user["username"])
+		assert.Equal(t, "user", user["role"])
+
+		// Access token dùng được như token từ /auth/login
+		req, _ := http.NewRequest("GET", "/user", nil)
+		req.Header.Set("Authorization", "Bearer "+resp["accessToken"].(string))
+		recUser := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(recUser, req)
+		assert.Equal(t, 200, recUser.Code)
+
+		// State chỉ dùng được một lần
+		rec = oidcCallback(t, code, state)
+		assert.Equal(t, 400, rec.Code)
+
+		// Lần đăng nhập sau với cùng sub trả về cùng user
+		code, state = TestOIDC.Authorize(t, beginOIDCLogin(t), jwt.MapClaims{
+			"sub":            "alice-sub",
+			"email":          "alice@corp.test",
+			"email_verified": true,
+		})
+		rec = oidcCallback(t, code, state)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, user["id"], ParseJSON(t, rec)["user"].(map[string]interface{})["id"])
+	})
+
+	t.Run("Role From Claim", func(t *testing.T) {
+		code, state := TestOIDC.Authorize(t, beginOIDCLogin(t), jwt.MapClaims{
+			"sub":            "bob-sub",
+			"email":          "bob@corp.test",
+			"email_verified": true,
+			"groups":         []string{"staff", "fs-admins"},
+		})
+
+		rec := oidcCallback(t, code, state)
+		assert.Equal(t, 200, rec.Code)
+		resp := ParseJSON(t, rec)
+		assert.Equal(t, "admin", resp["user"].(map[string]interface{})["role"])
+
+		req, _ := http.NewRequest("GET", "/admin/policy", nil)
+		req.Header.Set("Authorization", "Bearer "+resp["accessToken"].(string))
+		recPolicy := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(recPolicy, req)
+		assert.Equal(t, 200, recPolicy.Code)
+	})
+
+	t.Run("Nonce Mismatch", func(t *testing.T) {
+		code, state := TestOIDC.Authorize(t, beginOIDCLogin(t), jwt.MapClaims{
+			"sub":   "mallory-sub",
+			"email": "mallory@corp.test",
+			"nonce": "replayed-nonce",
+		})
+
+		rec := oidcCallback(t, code, state)
+		assert.Equal(t, 401, rec.Code)
+	})
+
+	t.Run("Identity Provider Error", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/auth/oidc/mock/callback?error=access_denied&error_description=User+cancelled", nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+
+		assert.Equal(t, 401, rec.Code)
+		assert.Equal(t, "User cancelled", ParseJSON(t, rec)["message"])
+	})
+}
+
+func TestOIDC_LinkExistingAccount(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	_, email := setupUserAndToken(t)
+
+	t.Run("Unverified Email Is Not Linked", func(t *testing.T) {
+		code, state := TestOIDC.Authorize(t, beginOIDCLogin(t), jwt.MapClaims{
+			"sub":            "attacker-sub",
+			"email":          email,
+			"email_verified": false,
+		})
+
+		rec := oidcCallback(t, code, state)
+		assert.Equal(t, 409, rec.Code)
+	})
+
+	t.Run("Verified Email Is Linked", func(t *testing.T) {
+		code, state := TestOIDC.Authorize(t, beginOIDCLogin(t), jwt.MapClaims{
+			"sub":            "owner-sub",
+			"email":          email,
+			"email_verified": true,
+		})
+
+		rec := oidcCallback(t, code, state)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, email, ParseJSON(t, rec)["user"].(map[string]interface{})["email"])
+
+		var linked int
+		err := TestDB.QueryRow(`SELECT COUNT(*) FROM user_identities WHERE provider = 'mock' AND subject = 'owner-sub'`).Scan(&linked)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, linked)
+	})
+
+	t.Run("Password Login Still Works", func(t *testing.T) {
+		body := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, testUserPassword)
+		req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+	})
+}
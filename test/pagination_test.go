@@ -0,0 +1,124 @@
+package test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listPage gọi một endpoint danh sách, trả về id các phần tử (items[].idKey) và pagination.
+func listPage(t *testing.T, token string, path string, query url.Values, itemsKey string, idKey string) ([]string, map[string]interface{}) {
+	t.Helper()
+
+	rec := adminRequest(t, "GET", path+"?"+query.Encode(), token, nil)
+	if rec.Code != 200 {
+		t.Fatalf("List %s failed: %d %s", path, rec.Code, rec.Body.String())
+	}
+
+	body := ParseJSON(t, rec)
+	ids := []string{}
+	for _, item := range body[itemsKey].([]interface{}) {
+		ids = append(ids, item.(map[string]interface{})[idKey].(string))
+	}
+	return ids, body["pagination"].(map[string]interface{})
+}
+
+// listAll đi hết các trang bằng nextCursor.
+func listAll(t *testing.T, token string, path string, query url.Values, itemsKey string, idKey string) []string {
+	t.Helper()
+
+	all := []string{}
+	for range 10 {
+		ids, pagination := listPage(t, token, path, query, itemsKey, idKey)
+		all = append(all, ids...)
+		if pagination["nextCursor"] == nil {
+			return all
+		}
+		query.Set("cursor", pagination["nextCursor"].(string))
+	}
+
+	t.Fatalf("List %s did not terminate", path)
+	return nil
+}
+
+func TestFiles_CursorPagination(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	ownerToken, _ := setupUserAndToken(t)
+	recipientToken, recipientEmail := setupUserAndToken(t)
+
+	shared := map[string]string{"sharedWith": recipientEmail}
+	medium := uploadNamedFileForTest(t, ownerToken, "b.txt", "text/plain", "0123456789", shared)
+	small := uploadNamedFileForTest(t, ownerToken, "c.txt", "text/plain", "01234", shared)
+	large := uploadNamedFileForTest(t, ownerToken, "a.txt", "text/plain", "012345678901234", shared)
+
+	t.Run("My Files Sorted By Size", func(t *testing.T) {
+		query := url.Values{"sortBy": {"size"}, "order": {"asc"}, "limit": {"2"}}
+		ids, pagination := listPage(t, ownerToken, "/files/my", query, "files", "id")
+		assert.Equal(t, []string{small, medium}, ids)
+		assert.Equal(t, true, pagination["hasMore"])
+		assert.Equal(t, "size", pagination["sortBy"])
+
+		query.Set("cursor", pagination["nextCursor"].(string))
+		ids, pagination = listPage(t, ownerToken, "/files/my", query, "files", "id")
+		assert.Equal(t, []string{large}, ids)
+		assert.Equal(t, false, pagination["hasMore"])
+	})
+
+	t.Run("My Files Sorted By Name", func(t *testing.T) {
+		// Tên mặc định tăng dần.
+		ids := listAll(t, ownerToken, "/files/my", url.Values{"sortBy": {"name"}, "limit": {"1"}}, "files", "id")
+		assert.Equal(t, []string{large, medium, small}, ids)
+
+		ids = listAll(t, ownerToken, "/files/my", url.Values{"sortBy": {"createdAt"}, "limit": {"2"}}, "files", "id")
+		assert.Equal(t, []string{large, small, medium}, ids)
+	})
+
+	t.Run("Shared With Me", func(t *testing.T) {
+		ids := listAll(t, recipientToken, "/files/available", url.Values{"sortBy": {"expiry"}, "limit": {"1"}}, "files", "fileid")
+		assert.ElementsMatch(t, []string{small, medium, large}, ids)
+	})
+
+	t.Run("Download History", func(t *testing.T) {
+		file := uploadFileWithFields(t, ownerToken, map[string]string{"isPublic": "true"})
+		for range 3 {
+			req, _ := http.NewRequest("GET", "/files/"+file["shareToken"].(string)+"/download", nil)
+			rec := httptest.NewRecorder()
+			TestApp.Router().ServeHTTP(rec, req)
+			assert.Equal(t, 200, rec.Code)
+		}
+
+		path := "/files/download-history/" + file["id"].(string)
+		ids, pagination := listPage(t, ownerToken, path, url.Values{"limit": {"2"}}, "history", "id")
+		assert.Len(t, ids, 2)
+		assert.Equal(t, "downloadedAt", pagination["sortBy"])
+
+		all := listAll(t, ownerToken, path, url.Values{"limit": {"2"}, "order": {"asc"}}, "history", "id")
+		assert.Len(t, all, 3)
+		assert.Equal(t, ids[0], all[2])
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		_, pagination := listPage(t, ownerToken, "/files/my", url.Values{"sortBy": {"size"}, "limit": {"1"}}, "files", "id")
+		cursor := pagination["nextCursor"].(string)
+
+		// Cursor chỉ dùng được với đúng cách sắp xếp đã sinh ra nó.
+		rec := adminRequest(t, "GET", "/files/my?sortBy=name&cursor="+cursor, ownerToken, nil)
+		assert.Equal(t, 400, rec.Code)
+
+		rec = adminRequest(t, "GET", "/files/my?cursor=not-a-cursor", ownerToken, nil)
+		assert.Equal(t, 400, rec.Code)
+
+		forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"createdAt","o":"desc","k":["not-a-time"],"i":"` + small + `"}`))
+		rec = adminRequest(t, "GET", "/files/my?cursor="+forged, ownerToken, nil)
+		assert.Equal(t, 400, rec.Code)
+
+		rec = adminRequest(t, "GET", "/files/available?sortBy=relevance", recipientToken, nil)
+		assert.Equal(t, 400, rec.Code)
+	})
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// violatedRules trả về danh sách rule trong response lỗi password policy.
+func violatedRules(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+
+	var body struct {
+		Field      string `json:"field"`
+		Violations []struct {
+			Rule string `json:"rule"`
+		} `json:"violations"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid response: %s", rec.Body.String())
+	}
+	assert.Equal(t, "password", body.Field)
+
+	rules := []string{}
+	for _, v := range body.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicy_Register(t *testing.T) {
+	adminToken := setupAdminToken(t)
+	t.Cleanup(func() {
+		adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]interface{}{"passwordRequiredClasses": []string{}})
+		ResetDB(t)
+	})
+
+	register := func(password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{
+			"username": "policy_user",
+			"email":    "policy_user@example.com",
+			"password": password,
+		})
+		req, _ := http.NewRequest("POST", "/auth/register", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Too Short", func(t *testing.T) {
+		rec := register("Ab1!")
+		assert.Equal(t, 400, rec.Code)
+		assert.Equal(t, []string{"min_length"}, violatedRules(t, rec))
+	})
+
+	t.Run("Breached", func(t *testing.T) {
+		rec := register("Password123")
+		assert.Equal(t, 400, rec.Code)
+		assert.Equal(t, []string{"breached"}, violatedRules(t, rec))
+	})
+
+	t.Run("Required Classes", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]interface{}{"passwordRequiredClasses": []string{"upper", "special"}})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		rec = register(testUserPassword)
+		assert.Equal(t, 400, rec.Code)
+		assert.Equal(t, []string{"upper", "special"}, violatedRules(t, rec))
+
+		assert.Equal(t, 200, register("Quiet-Harbor-2931").Code)
+	})
+
+	t.Run("Unknown Class", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]interface{}{"passwordRequiredClasses": []string{"emoji"}})
+		assert.Equal(t, 400, rec.Code)
+	})
+}
+
+func TestPasswordPolicy_FilePassword(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, _ := setupUserAndToken(t)
+
+	upload := func(password string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "test_file.txt")
+		io.WriteString(part, "Hello World Content")
+		writer.WriteField("password", password)
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/files/upload", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := upload("short")
+	assert.Equal(t, 400, rec.Code)
+	assert.Equal(t, []string{"min_length"}, violatedRules(t, rec))
+
+	rec = upload("qwerty123")
+	assert.Equal(t, 400, rec.Code)
+	assert.Equal(t, []string{"breached"}, violatedRules(t, rec))
+
+	assert.Equal(t, 201, upload("SecurePass123").Code)
+}
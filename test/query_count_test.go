@@ -0,0 +1,196 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingConnector bọc connector của lib/pq, đếm mọi câu lệnh gửi tới Postgres.
+type countingConnector struct {
+	driver.Connector
+	queries *atomic.Int64
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, queries: c.queries}, nil
+}
+
+type countingConn struct {
+	driver.Conn
+	queries *atomic.Int64
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.queries.Add(1)
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries.Add(1)
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+// newCountingFileService dựng FileService trên một *sql.DB riêng có đếm query.
+func newCountingFileService(tb testing.TB) (service.FileService, *atomic.Int64) {
+	tb.Helper()
+
+	connector, err := pq.NewConnector(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	queries := &atomic.Int64{}
+	db := sql.OpenDB(&countingConnector{Connector: connector, queries: queries})
+	tb.Cleanup(func() { db.Close() })
+
+	svc := service.NewFileService(config.NewConfig(),
+		repository.NewFileRepository(db), repository.NewSharedRepository(db), repository.NewSQLUserRepository(db),
+		repository.NewGroupRepository(db), repository.NewFolderRepository(db), repository.NewTxManager(db), nil, nil, nil)
+	return svc, queries
+}
+
+// queryFixture: file chính được chia sẻ cho mọi recipient và mỗi recipient đã tải một lần;
+// recipient đầu tiên còn được chia sẻ thêm rows file khác.
+type queryFixture struct {
+	ownerID      string
+	recipientIDs []string
+	fileID       string
+}
+
+func seedQueryFixture(tb testing.TB, rows int) queryFixture {
+	tb.Helper()
+	ResetDB(tb)
+
+	var fixture queryFixture
+	insert := func(query string, args ...any) string {
+		var id string
+		if err := TestDB.QueryRow(query, args...).Scan(&id); err != nil {
+			tb.Fatal(err)
+		}
+		return id
+	}
+	exec := func(query string, args ...any) {
+		if _, err := TestDB.Exec(query, args...); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	newUser := func(name string) string {
+		return insert(`INSERT INTO users (username, password, email, role, email_verified)
+			VALUES ($1, 'x', $1 || '@example.test', 'user', true) RETURNING id`, name)
+	}
+	newFile := func(name string) string {
+		id := insert(`INSERT INTO files (user_id, name, type, size, share_token, available_from, available_to)
+			VALUES ($1, $2, 'text/plain', 1, $2, NOW() - INTERVAL '1 hour', NOW() + INTERVAL '1 day') RETURNING id`, fixture.ownerID, name)
+		exec(`INSERT INTO filestat (file_id) VALUES ($1)`, id)
+		return id
+	}
+
+	fixture.ownerID = newUser("owner")
+	fixture.fileID = newFile("main")
+
+	for i := range rows {
+		recipient := newUser(fmt.Sprintf("recipient%d", i))
+		fixture.recipientIDs = append(fixture.recipientIDs, recipient)
+		exec(`INSERT INTO shared (user_id, file_id) VALUES ($1, $2)`, recipient, fixture.fileID)
+		exec(`INSERT INTO download (user_id, file_id) VALUES ($1, $2)`, recipient, fixture.fileID)
+
+		exec(`INSERT INTO shared (user_id, file_id) VALUES ($1, $2)`, fixture.recipientIDs[0], newFile(fmt.Sprintf("extra%d", i)))
+	}
+
+	return fixture
+}
+
+// queryCountCases là các lời gọi cần số query cố định, trả về số phần tử đọc được.
+var queryCountCases = []struct {
+	name string
+	call func(svc service.FileService, f queryFixture) int
+}{
+	{"FileInfo", func(svc service.FileService, f queryFixture) int {
+		_, _, shared, err := svc.GetFileInfoID(context.Background(), f.fileID, f.ownerID, true)
+		if err != nil {
+			panic(err.Error())
+		}
+		return len(shared)
+	}},
+	{"AccessibleFiles", func(svc service.FileService, f queryFixture) int {
+		files, _, err := svc.GetAccessibleFiles(context.Background(), f.recipientIDs[0], &dto.PageQuery{Limit: 100})
+		if err != nil {
+			panic(err.Error())
+		}
+		return len(files)
+	}},
+	{"SearchFiles", func(svc service.FileService, f queryFixture) int {
+		files, _, err := svc.SearchFiles(context.Background(), f.recipientIDs[0], &dto.FileSearchQuery{PageQuery: dto.PageQuery{Limit: 100}})
+		if err != nil {
+			panic(err.Error())
+		}
+		return len(files)
+	}},
+	{"DownloadHistory", func(svc service.FileService, f queryFixture) int {
+		history, err := svc.GetFileDownloadHistory(context.Background(), f.fileID, f.ownerID, &dto.PageQuery{Limit: 100})
+		if err != nil {
+			panic(err.Error())
+		}
+		return len(history.History)
+	}},
+}
+
+func TestQueryCount_IndependentOfRows(t *testing.T) {
+	t.Cleanup(func() { ResetDB(t) })
+	svc, queries := newCountingFileService(t)
+
+	counts := map[string][]int64{}
+	for _, rows := range []int{1, 10} {
+		fixture := seedQueryFixture(t, rows)
+
+		for _, c := range queryCountCases {
+			before := queries.Load()
+			assert.Positive(t, c.call(svc, fixture), c.name)
+			counts[c.name] = append(counts[c.name], queries.Load()-before)
+		}
+	}
+
+	for name, perSize := range counts {
+		assert.Equal(t, perSize[0], perSize[1], "%s: query count grows with the number of rows", name)
+	}
+}
+
+// BenchmarkQueryCount báo số query mỗi lời gọi (queries/op) bên cạnh thời gian.
+func BenchmarkQueryCount(b *testing.B) {
+	b.Cleanup(func() { ResetDB(b) })
+	svc, queries := newCountingFileService(b)
+
+	for _, rows := range []int{10, 100} {
+		fixture := seedQueryFixture(b, rows)
+
+		for _, c := range queryCountCases {
+			b.Run(fmt.Sprintf("%s/rows=%d", c.name, rows), func(b *testing.B) {
+				before := queries.Load()
+				for b.Loop() {
+					c.call(svc, fixture)
+				}
+				b.ReportMetric(float64(queries.Load()-before)/float64(b.N), "queries/op")
+			})
+		}
+	}
+}
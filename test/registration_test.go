@@ -0,0 +1,201 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func registerForTest(t *testing.T, username, email, inviteToken string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]string{
+		"username":    username,
+		"email":       email,
+		"password":    testUserPassword,
+		"inviteToken": inviteToken,
+	})
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+func loginForTest(t *testing.T, email string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body := fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, testUserPassword)
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+func adminRequest(t *testing.T, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRegistration_EmailVerification(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	email := fmt.Sprintf("verify_%d@example.com", time.Now().UnixNano())
+	rec := registerForTest(t, "verify_me", email, "")
+	assert.Equal(t, 200, rec.Code, rec.Body.String())
+	assert.Equal(t, true, ParseJSON(t, rec)["emailVerificationRequired"])
+
+	t.Run("Login Before Verification", func(t *testing.T) {
+		assert.Equal(t, 403, loginForTest(t, email).Code)
+	})
+
+	t.Run("Resend Replaces Old Link", func(t *testing.T) {
+		oldToken := TestMail.VerificationToken(t, email)
+		sent := TestMail.Count(email)
+
+		body := fmt.Sprintf(`{"email": "%s"}`, email)
+		req, _ := http.NewRequest("POST", "/auth/verify-email/resend", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, sent+1, TestMail.Count(email))
+
+		req, _ = http.NewRequest("POST", "/auth/verify-email", bytes.NewBufferString(fmt.Sprintf(`{"token": "%s"}`, oldToken)))
+		req.Header.Set("Content-Type", "application/json")
+		rec = httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 400, rec.Code)
+	})
+
+	t.Run("Resend Unknown Email", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/auth/verify-email/resend", bytes.NewBufferString(`{"email": "nobody@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, 0, TestMail.Count("nobody@example.com"))
+	})
+
+	t.Run("Verify And Login", func(t *testing.T) {
+		verifyEmailForTest(t, email)
+		assert.Equal(t, 200, loginForTest(t, email).Code)
+
+		// Link chỉ dùng được một lần
+		req, _ := http.NewRequest("GET", "/auth/verify-email?token="+TestMail.VerificationToken(t, email), nil)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 400, rec.Code)
+	})
+
+	t.Run("Duplicate Email", func(t *testing.T) {
+		rec := registerForTest(t, "someone_else", strings.ToUpper(email), "")
+		assert.Equal(t, 409, rec.Code)
+		assert.Equal(t, "email", ParseJSON(t, rec)["field"])
+	})
+
+	t.Run("Duplicate Username", func(t *testing.T) {
+		rec := registerForTest(t, "Verify_Me", fmt.Sprintf("other_%d@example.com", time.Now().UnixNano()), "")
+		assert.Equal(t, 409, rec.Code)
+		assert.Equal(t, "username", ParseJSON(t, rec)["field"])
+	})
+}
+
+func TestRegistration_Modes(t *testing.T) {
+	adminToken := setupAdminToken(t)
+	t.Cleanup(func() {
+		adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]string{"registrationMode": "open"})
+		ResetDB(t)
+	})
+
+	setMode := func(t *testing.T, mode string) {
+		t.Helper()
+		rec := adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]string{"registrationMode": mode})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+	}
+
+	t.Run("Denied Domain", func(t *testing.T) {
+		rec := adminRequest(t, "PUT", "/admin/email-domains/Blocked.test", adminToken, map[string]string{"rule": "deny"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		rec = registerForTest(t, "blocked", "someone@blocked.test", "")
+		assert.Equal(t, 403, rec.Code)
+		assert.Equal(t, "blocked.test", ParseJSON(t, rec)["domain"])
+
+		rec = adminRequest(t, "GET", "/admin/email-domains", adminToken, nil)
+		rules := ParseJSON(t, rec)["rules"].([]interface{})
+		assert.Len(t, rules, 1)
+
+		assert.Equal(t, 200, adminRequest(t, "DELETE", "/admin/email-domains/blocked.test", adminToken, nil).Code)
+		assert.Equal(t, 200, registerForTest(t, "blocked", "someone@blocked.test", "").Code)
+	})
+
+	t.Run("Invite Only", func(t *testing.T) {
+		setMode(t, "invite")
+
+		assert.Equal(t, 403, registerForTest(t, "uninvited", "uninvited@example.com", "").Code)
+
+		rec := adminRequest(t, "POST", "/admin/invites", adminToken, map[string]interface{}{"email": "Guest@Example.com"})
+		assert.Equal(t, 201, rec.Code, rec.Body.String())
+		token := TestMail.InviteToken(t, "guest@example.com")
+		assert.Equal(t, token, ParseJSON(t, rec)["token"])
+
+		// Mã mời gắn với email khác
+		assert.Equal(t, 403, registerForTest(t, "intruder", "intruder@example.com", token).Code)
+
+		rec = registerForTest(t, "guest", "guest@example.com", token)
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		assert.Equal(t, false, ParseJSON(t, rec)["emailVerificationRequired"])
+		assert.Equal(t, 200, loginForTest(t, "guest@example.com").Code)
+
+		// Mã mời chỉ dùng một lần
+		assert.Equal(t, 403, registerForTest(t, "guest2", "guest@example.com", token).Code)
+
+		rec = adminRequest(t, "GET", "/admin/invites", adminToken, nil)
+		invites := ParseJSON(t, rec)["invites"].([]interface{})
+		assert.Len(t, invites, 1)
+		assert.NotNil(t, invites[0].(map[string]interface{})["usedAt"])
+	})
+
+	t.Run("Open Invite Code", func(t *testing.T) {
+		setMode(t, "invite")
+
+		rec := adminRequest(t, "POST", "/admin/invites", adminToken, map[string]interface{}{"expiresInDays": 1})
+		assert.Equal(t, 201, rec.Code)
+		token := ParseJSON(t, rec)["token"].(string)
+
+		// Không gắn email -> vẫn phải xác minh email
+		rec = registerForTest(t, "anyone", "anyone@example.com", token)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, true, ParseJSON(t, rec)["emailVerificationRequired"])
+	})
+
+	t.Run("Allowed Domains Only", func(t *testing.T) {
+		setMode(t, "domain")
+		assert.Equal(t, 200, adminRequest(t, "PUT", "/admin/email-domains/corp.test", adminToken, map[string]string{"rule": "allow"}).Code)
+
+		assert.Equal(t, 403, registerForTest(t, "outsider", "outsider@example.com", "").Code)
+		assert.Equal(t, 200, registerForTest(t, "insider", "insider@corp.test", "").Code)
+	})
+
+	t.Run("Invalid Mode", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]string{"registrationMode": "closed"})
+		assert.Equal(t, 400, rec.Code)
+	})
+}
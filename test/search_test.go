@@ -0,0 +1,151 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadNamedFileForTest upload file với tên, MIME type và nội dung tùy chọn, trả về id.
+func uploadNamedFileForTest(t *testing.T, token string, name string, mimeType string, content string, fields map[string]string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, name))
+	header.Set("Content-Type", mimeType)
+	part, _ := writer.CreatePart(header)
+	io.WriteString(part, content)
+
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/files/upload", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+
+	if rec.Code != 201 {
+		t.Fatalf("Upload failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	return ParseJSON(t, rec)["file"].(map[string]interface{})["id"].(string)
+}
+
+func searchFileIDs(t *testing.T, token string, query url.Values) ([]string, map[string]interface{}) {
+	t.Helper()
+
+	rec := adminRequest(t, "GET", "/files/search?"+query.Encode(), token, nil)
+	if rec.Code != 200 {
+		t.Fatalf("Search failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	body := ParseJSON(t, rec)
+	ids := []string{}
+	for _, f := range body["files"].([]interface{}) {
+		ids = append(ids, f.(map[string]interface{})["id"].(string))
+	}
+	return ids, body["pagination"].(map[string]interface{})
+}
+
+func TestFiles_Search(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	ownerToken, ownerEmail := setupUserAndToken(t)
+	recipientToken, recipientEmail := setupUserAndToken(t)
+
+	report := uploadNamedFileForTest(t, ownerToken, "Bao_cao_quy3.pdf", "application/pdf", "quarterly report content", map[string]string{
+		"tags": "Finance, Q3",
+	})
+	notes := uploadNamedFileForTest(t, ownerToken, "notes.txt", "text/plain", "short", map[string]string{
+		"description": "Ghi chú cho bao cao tuần",
+	})
+	photo := uploadNamedFileForTest(t, ownerToken, "team.png", "image/png", "png bytes here", map[string]string{
+		"sharedWith": recipientEmail,
+		"tags":       "finance",
+	})
+
+	t.Run("Ranked Full-Text Match", func(t *testing.T) {
+		ids, pagination := searchFileIDs(t, ownerToken, url.Values{"q": {"bao ca"}})
+		// Khớp trên tên (trọng số A) xếp trước khớp trên mô tả (trọng số C).
+		assert.Equal(t, []string{report, notes}, ids)
+		assert.Equal(t, "relevance", pagination["sortBy"])
+		assert.Equal(t, false, pagination["hasMore"])
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		ids, _ := searchFileIDs(t, ownerToken, url.Values{"tags": {"finance"}})
+		assert.ElementsMatch(t, []string{report, photo}, ids)
+
+		ids, _ = searchFileIDs(t, ownerToken, url.Values{"tags": {"finance,q3"}})
+		assert.Equal(t, []string{report}, ids)
+
+		ids, _ = searchFileIDs(t, ownerToken, url.Values{"mimeType": {"image/*"}})
+		assert.Equal(t, []string{photo}, ids)
+
+		ids, _ = searchFileIDs(t, ownerToken, url.Values{"minSize": {"10"}, "maxSize": {"20"}})
+		assert.Equal(t, []string{photo}, ids)
+	})
+
+	t.Run("Shared With Me", func(t *testing.T) {
+		ids, _ := searchFileIDs(t, recipientToken, url.Values{"scope": {"shared"}})
+		assert.Equal(t, []string{photo}, ids)
+
+		ids, _ = searchFileIDs(t, recipientToken, url.Values{"owner": {ownerEmail}, "q": {"team"}})
+		assert.Equal(t, []string{photo}, ids)
+
+		ids, _ = searchFileIDs(t, recipientToken, url.Values{"q": {"bao"}})
+		assert.Empty(t, ids)
+	})
+
+	t.Run("Update Tags And Description", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/files/info/"+notes, recipientToken, map[string]interface{}{"tags": []string{"x"}})
+		assert.Equal(t, 403, rec.Code)
+
+		rec = adminRequest(t, "PATCH", "/files/info/"+notes, ownerToken, map[string]interface{}{
+			"tags":        []string{"Meeting", "meeting", " "},
+			"description": "",
+		})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		file := ParseJSON(t, rec)["file"].(map[string]interface{})
+		assert.Equal(t, []interface{}{"meeting"}, file["tags"])
+		assert.Nil(t, file["description"])
+
+		ids, _ := searchFileIDs(t, ownerToken, url.Values{"q": {"meet"}})
+		assert.Equal(t, []string{notes}, ids)
+
+		ids, _ = searchFileIDs(t, ownerToken, url.Values{"q": {"tuần"}})
+		assert.Empty(t, ids)
+	})
+
+	t.Run("Cursor Pagination", func(t *testing.T) {
+		// Không có từ khóa: mới upload nhất trước.
+		first, pagination := searchFileIDs(t, ownerToken, url.Values{"limit": {"2"}})
+		assert.Equal(t, []string{photo, notes}, first)
+		assert.Equal(t, true, pagination["hasMore"])
+
+		second, pagination := searchFileIDs(t, ownerToken, url.Values{"limit": {"2"}, "cursor": {pagination["nextCursor"].(string)}})
+		assert.Equal(t, []string{report}, second)
+		assert.Equal(t, false, pagination["hasMore"])
+		assert.Nil(t, pagination["nextCursor"])
+	})
+
+	t.Run("Invalid Query", func(t *testing.T) {
+		assert.Equal(t, 400, adminRequest(t, "GET", "/files/search?scope=everyone", ownerToken, nil).Code)
+		assert.Equal(t, 400, adminRequest(t, "GET", "/files/search?minSize=10&maxSize=5", ownerToken, nil).Code)
+	})
+}
@@ -4,24 +4,34 @@ import (
 	"database/sql"
 	"log"
 	"os"
-	"path/filepath"
 	"testing"
 
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
 	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/app"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/database"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
 
 var TestApp *app.Application
 var TestDB *sql.DB
+var TestOIDC *mockOIDCProvider
+var TestMail *mockSMTPServer
 
 func TestMain(m *testing.M) {
 	setupEnv()
 
+	// IdP giả phải chạy trước khi đọc config để lấy được issuer URL.
+	TestOIDC = newMockOIDCProvider()
+	setupOIDCEnv(TestOIDC.Issuer())
+
+	// Email xác minh/lời mời được gửi tới SMTP server giả
+	TestMail = newMockSMTPServer()
+	os.Setenv("SMTP_HOST", TestMail.Host())
+	os.Setenv("SMTP_PORT", TestMail.Port())
+
 	dbURL := os.Getenv("DATABASE_URL")
 
 	var err error
@@ -39,12 +49,18 @@ func TestMain(m *testing.M) {
 	runMigration(TestDB)
 
 	cfg := config.NewConfig()
+	// Mọi request trong test đến từ cùng một IP, nới rate limit để các test không chặn lẫn nhau.
+	for name := range cfg.Policy.RateLimits {
+		cfg.Policy.RateLimits[name] = config.RateLimitRule{Limit: 100000, WindowSeconds: 1}
+	}
 	TestApp = app.NewApplication(cfg)
 	if TestApp == nil {
 		log.Fatal("Cannot initialize TestApp")
 	}
 
 	exitCode := m.Run()
+	TestOIDC.Close()
+	TestMail.Close()
 	TestDB.Close()
 	os.Exit(exitCode)
 }
@@ -59,11 +75,24 @@ func setupEnv() {
 		os.Setenv("SERVER_PORT", "9999")
 	}
 
+	if os.Getenv("PUBLIC_BASE_URL") == "" {
+		os.Setenv("PUBLIC_BASE_URL", "https://files.example.test/api")
+	}
+
 	if os.Getenv("JWT_SECRET_KEY") == "" {
 		os.Setenv("JWT_SECRET_KEY", "test_secret_key")
 	}
 }
 
+func setupOIDCEnv(issuer string) {
+	os.Setenv("OIDC_PROVIDERS", "mock")
+	os.Setenv("OIDC_MOCK_ISSUER", issuer)
+	os.Setenv("OIDC_MOCK_CLIENT_ID", mockOIDCClientID)
+	os.Setenv("OIDC_MOCK_CLIENT_SECRET", mockOIDCClientSecret)
+	os.Setenv("OIDC_MOCK_ROLE_CLAIM", "groups")
+	os.Setenv("OIDC_MOCK_ADMIN_VALUES", "fs-admins")
+}
+
 func setupTestSchema(db *sql.DB) {
 	if _, err := db.Exec(`CREATE SCHEMA IF NOT EXISTS test_schema`); err != nil {
 		log.Fatalf("Create schema failed: %v", err)
@@ -76,15 +105,15 @@ func runMigration(db *sql.DB) {
 		log.Fatalf("Create migrate driver failed: %v", err)
 	}
 
-	path, err := filepath.Abs("../internal/infrastructure/database")
+	// Cùng migration nhúng trong binary mà cmd/migrate và AUTO_MIGRATE dùng
+	source, err := database.MigrationSource(database.DriverPostgres)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Migration source error: %v", err)
 	}
 
-	migrationPath := "file://" + filepath.ToSlash(path)
-
-	m, err := migrate.NewWithDatabaseInstance(
-		migrationPath,
+	m, err := migrate.NewWithInstance(
+		"iofs",
+		source,
 		"postgres",
 		driver,
 	)
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"net"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockSMTPServer: SMTP server tối giản chạy local, lưu lại mọi email backend gửi
+// để test lấy link xác minh / mã mời như người dùng mở hộp thư.
+type mockSMTPServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []mockMail
+}
+
+type mockMail struct {
+	To   []string
+	Data string
+}
+
+func newMockSMTPServer() *mockSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	m := &mockSMTPServer{listener: listener}
+	go m.serve()
+	return m
+}
+
+func (m *mockSMTPServer) Host() string {
+	host, _, _ := net.SplitHostPort(m.listener.Addr().String())
+	return host
+}
+
+func (m *mockSMTPServer) Port() string {
+	_, port, _ := net.SplitHostPort(m.listener.Addr().String())
+	return port
+}
+
+func (m *mockSMTPServer) Close() {
+	m.listener.Close()
+}
+
+func (m *mockSMTPServer) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		go m.handle(textproto.NewConn(conn))
+	}
+}
+
+func (m *mockSMTPServer) handle(conn *textproto.Conn) {
+	defer conn.Close()
+
+	var to []string
+	conn.PrintfLine("220 mock ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO", "HELO":
+			conn.PrintfLine("250 mock")
+		case "MAIL":
+			to = nil
+			conn.PrintfLine("250 OK")
+		case "RCPT":
+			addr := line[strings.Index(line, ":")+1:]
+			to = append(to, strings.Trim(strings.TrimSpace(addr), "<>"))
+			conn.PrintfLine("250 OK")
+		case "DATA":
+			conn.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			lines, err := conn.ReadDotLines()
+			if err != nil {
+				return
+			}
+			m.mu.Lock()
+			m.messages = append(m.messages, mockMail{To: to, Data: strings.Join(lines, "\n")})
+			m.mu.Unlock()
+			conn.PrintfLine("250 OK")
+		case "QUIT":
+			conn.PrintfLine("221 Bye")
+			return
+		default:
+			conn.PrintfLine("250 OK")
+		}
+	}
+}
+
+// Last trả về nội dung email mới nhất gửi tới địa chỉ to.
+func (m *mockSMTPServer) Last(t *testing.T, to string) string {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		for _, rcpt := range m.messages[i].To {
+			if strings.EqualFold(rcpt, to) {
+				return m.messages[i].Data
+			}
+		}
+	}
+
+	t.Fatalf("No email sent to %s", to)
+	return ""
+}
+
+func (m *mockSMTPServer) Count(to string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, msg := range m.messages {
+		for _, rcpt := range msg.To {
+			if strings.EqualFold(rcpt, to) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+var (
+	verificationTokenRe = regexp.MustCompile(`verify-email\?token=([A-Za-z0-9]+)`)
+	inviteTokenRe       = regexp.MustCompile(`Invitation code: ([A-Za-z0-9]+)`)
+)
+
+// VerificationToken lấy token từ link trong email xác minh mới nhất.
+func (m *mockSMTPServer) VerificationToken(t *testing.T, to string) string {
+	t.Helper()
+
+	match := verificationTokenRe.FindStringSubmatch(m.Last(t, to))
+	if match == nil {
+		t.Fatalf("No verification link in email to %s", to)
+	}
+	return match[1]
+}
+
+func (m *mockSMTPServer) InviteToken(t *testing.T, to string) string {
+	t.Helper()
+
+	match := inviteTokenRe.FindStringSubmatch(m.Last(t, to))
+	if match == nil {
+		t.Fatalf("No invitation code in email to %s", to)
+	}
+	return match[1]
+}
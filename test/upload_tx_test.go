@@ -0,0 +1,111 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/config"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/api/dto"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/domain"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/infrastructure/storage"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/repository"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/internal/service"
+	"github.com/dath-251-thuanle/file-sharing-web-backend2/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingSharedRepository chia sẻ thật rồi báo lỗi, giả lập lỗi ở bước cuối của upload.
+type failingSharedRepository struct {
+	repository.SharedRepository
+}
+
+func (r failingSharedRepository) ShareFileWithUsers(ctx context.Context, fileID string, emails []string) (*domain.ShareResult, *utils.ReturnStatus) {
+	if _, err := r.SharedRepository.ShareFileWithUsers(ctx, fileID, emails); err != nil {
+		return nil, err
+	}
+	return nil, utils.ResponseMsg(utils.ErrCodeDatabaseError, "share failed")
+}
+
+// multipartFile dựng *multipart.FileHeader như khi gin bind field "file" của form upload.
+func multipartFile(t *testing.T, name string, content string) *multipart.FileHeader {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", name)
+	part.Write([]byte(content))
+	writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["file"][0]
+}
+
+func TestUploadFile_Atomic(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	var ownerID string
+	err := TestDB.QueryRow(`INSERT INTO users (username, password, email, role, email_verified)
+		VALUES ('owner', 'x', 'owner@example.test', 'user', true) RETURNING id`).Scan(&ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TestDB.Exec(`INSERT INTO users (username, password, email, role, email_verified)
+		VALUES ('recipient', 'x', 'recipient@example.test', 'user', true)`); err != nil {
+		t.Fatal(err)
+	}
+
+	newService := func(sharedRepo repository.SharedRepository, dir string) service.FileService {
+		return service.NewFileService(config.NewConfig(),
+			repository.NewFileRepository(TestDB), sharedRepo, repository.NewSQLUserRepository(TestDB),
+			repository.NewGroupRepository(TestDB), repository.NewFolderRepository(TestDB), repository.NewTxManager(TestDB),
+			storage.NewLocalStorage(dir), nil, nil)
+	}
+	countRows := func(query string) int {
+		var n int
+		if err := TestDB.QueryRow(query, ownerID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	req := &dto.UploadRequest{SharedWith: []string{"recipient@example.test"}}
+
+	t.Run("Share Failure Rolls Back", func(t *testing.T) {
+		dir := t.TempDir()
+		svc := newService(failingSharedRepository{repository.NewSharedRepository(TestDB)}, dir)
+
+		_, status := svc.UploadFile(context.Background(), multipartFile(t, "a.txt", "hello"), req, &ownerID)
+		assert.True(t, status.IsErr())
+
+		assert.Equal(t, 0, countRows(`SELECT COUNT(*) FROM files WHERE user_id = $1`))
+		assert.Equal(t, 0, countRows(`SELECT COUNT(*) FROM filestat s JOIN files f ON f.id = s.file_id WHERE f.user_id = $1`))
+		assert.Equal(t, 0, countRows(`SELECT COUNT(*) FROM shared s JOIN files f ON f.id = s.file_id WHERE f.user_id = $1`))
+
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries, "stored blob must be removed on rollback")
+	})
+
+	t.Run("Success Commits Everything", func(t *testing.T) {
+		dir := t.TempDir()
+		svc := newService(repository.NewSharedRepository(TestDB), dir)
+
+		file, status := svc.UploadFile(context.Background(), multipartFile(t, "a.txt", "hello"), req, &ownerID)
+		if status != nil {
+			t.Fatalf("Upload failed: %s", status.Error())
+		}
+
+		assert.Equal(t, 1, countRows(`SELECT COUNT(*) FROM files WHERE user_id = $1`))
+		assert.Equal(t, 1, countRows(`SELECT COUNT(*) FROM filestat s JOIN files f ON f.id = s.file_id WHERE f.user_id = $1`))
+		assert.Equal(t, 1, countRows(`SELECT COUNT(*) FROM shared s JOIN files f ON f.id = s.file_id WHERE f.user_id = $1`))
+
+		_, statErr := os.Stat(filepath.Join(dir, file.StorageName))
+		assert.NoError(t, statErr)
+	})
+}
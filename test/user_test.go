@@ -0,0 +1,396 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func createAPIToken(t *testing.T, sessionToken string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/user/tokens", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+sessionToken)
+
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIToken_Lifecycle(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	session, _ := setupUserAndToken(t)
+	uploadFileForTest(t, session, "", "", "", nil)
+
+	rec := createAPIToken(t, session, `{"name": "ci", "scopes": ["files:read"], "expiresInDays": 7}`)
+	assert.Equal(t, 201, rec.Code)
+	resp := ParseJSON(t, rec)
+	pat, _ := resp["token"].(string)
+	assert.True(t, strings.HasPrefix(pat, "pat_"))
+	apiToken := resp["apiToken"].(map[string]interface{})
+	tokenID := apiToken["id"].(string)
+	assert.Equal(t, pat[:12], apiToken["prefix"])
+
+	t.Run("List Does Not Expose Secret", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/user/tokens", nil)
+		req.Header.Set("Authorization", "Bearer "+session)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+
+		assert.Equal(t, 200, rec.Code)
+		assert.NotContains(t, rec.Body.String(), pat)
+		tokens := ParseJSON(t, rec)["tokens"].([]interface{})
+		assert.Len(t, tokens, 1)
+	})
+
+	t.Run("Read Scope Allows Listing Files", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/files/my", nil)
+		req.Header.Set("Authorization", "Bearer "+pat)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+
+		assert.Equal(t, 200, rec.Code)
+		files := ParseJSON(t, rec)["files"].([]interface{})
+		assert.Len(t, files, 1)
+	})
+
+	t.Run("Missing Write Scope", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/files/info/00000000-0000-0000-0000-000000000000", nil)
+		req.Header.Set("Authorization", "Bearer "+pat)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+
+		assert.Equal(t, 403, rec.Code)
+		assert.Equal(t, "files:write", ParseJSON(t, rec)["requiredScope"])
+	})
+
+	t.Run("Token Cannot Manage Tokens", func(t *testing.T) {
+		rec := createAPIToken(t, pat, `{"name": "escalate", "scopes": ["files:write"]}`)
+		assert.Equal(t, 403, rec.Code)
+	})
+
+	t.Run("Admin Scope Requires Admin Role", func(t *testing.T) {
+		rec := createAPIToken(t, session, `{"name": "admin", "scopes": ["admin"]}`)
+		assert.Equal(t, 403, rec.Code)
+	})
+
+	t.Run("Unknown Scope", func(t *testing.T) {
+		rec := createAPIToken(t, session, `{"name": "bad", "scopes": ["files:everything"]}`)
+		assert.Equal(t, 400, rec.Code)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/user/tokens/%s", tokenID), nil)
+		req.Header.Set("Authorization", "Bearer "+session)
+		rec := httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+
+		req, _ = http.NewRequest("GET", "/files/my", nil)
+		req.Header.Set("Authorization", "Bearer "+pat)
+		rec = httptest.NewRecorder()
+		TestApp.Router().ServeHTTP(rec, req)
+		assert.Equal(t, 401, rec.Code)
+	})
+}
+
+func TestAPIToken_Expired(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	session, _ := setupUserAndToken(t)
+	rec := createAPIToken(t, session, `{"name": "old", "scopes": ["files:read"]}`)
+	assert.Equal(t, 201, rec.Code)
+	pat := ParseJSON(t, rec)["token"].(string)
+
+	_, err := TestDB.Exec("UPDATE api_tokens SET expires_at = NOW() - INTERVAL '1 minute'")
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "/files/my", nil)
+	req.Header.Set("Authorization", "Bearer "+pat)
+	rec = httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	assert.Equal(t, 401, rec.Code)
+}
+
+func TestUser_UpdateProfile(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, email := setupUserAndToken(t)
+
+	getMe := func() map[string]interface{} {
+		rec := adminRequest(t, "GET", "/user", token, nil)
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		return ParseJSON(t, rec)["user"].(map[string]interface{})
+	}
+
+	t.Run("Change Username", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/user", token, map[string]string{"username": "renamed_user"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		assert.Equal(t, "renamed_user", getMe()["username"])
+
+		// Đổi hoa thường của chính mình không bị coi là trùng
+		rec = adminRequest(t, "PATCH", "/user", token, map[string]string{"username": "Renamed_User"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+	})
+
+	t.Run("Username Taken", func(t *testing.T) {
+		otherToken, _ := setupUserAndToken(t)
+		rec := adminRequest(t, "PATCH", "/user", otherToken, map[string]string{"username": "RENAMED_USER"})
+		assert.Equal(t, 409, rec.Code)
+		assert.Equal(t, "username", ParseJSON(t, rec)["field"])
+	})
+
+	t.Run("Failed Email Keeps Username", func(t *testing.T) {
+		_, otherEmail := setupUserAndToken(t)
+		rec := adminRequest(t, "PATCH", "/user", token, map[string]string{"username": "half_applied", "email": otherEmail})
+		assert.Equal(t, 409, rec.Code)
+		assert.Equal(t, "email", ParseJSON(t, rec)["field"])
+		assert.Equal(t, "Renamed_User", getMe()["username"])
+	})
+
+	t.Run("Change Email Requires Verification", func(t *testing.T) {
+		newEmail := fmt.Sprintf("new_%d@example.com", time.Now().UnixNano())
+		rec := adminRequest(t, "PATCH", "/user", token, map[string]string{"email": newEmail})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		assert.Equal(t, true, ParseJSON(t, rec)["emailVerificationRequired"])
+
+		// Email cũ vẫn dùng cho tới khi mở link, và nhận thông báo
+		assert.Equal(t, email, getMe()["email"])
+		assert.Contains(t, TestMail.Last(t, email), newEmail)
+
+		verifyEmailForTest(t, newEmail)
+		assert.Equal(t, newEmail, getMe()["email"])
+		assert.Equal(t, 200, loginForTest(t, newEmail).Code)
+	})
+
+	t.Run("API Token Cannot Change Profile", func(t *testing.T) {
+		rec := createAPIToken(t, token, `{"name": "ci", "scopes": ["files:write"]}`)
+		pat := ParseJSON(t, rec)["token"].(string)
+		assert.Equal(t, 403, adminRequest(t, "PATCH", "/user", pat, map[string]string{"username": "by_script"}).Code)
+	})
+}
+
+func TestUser_DeleteAccount(t *testing.T) {
+	adminToken := setupAdminToken(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	fileOwner := func(fileID string) (string, bool) {
+		var owner string
+		err := TestDB.QueryRow("SELECT user_id FROM files WHERE id = $1", fileID).Scan(&owner)
+		return owner, err == nil
+	}
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		token, _ := setupUserAndToken(t)
+		rec := adminRequest(t, "DELETE", "/user", token, map[string]string{"password": "not-my-password", "files": "delete"})
+		assert.Equal(t, 401, rec.Code)
+		assert.Equal(t, 200, adminRequest(t, "GET", "/user", token, nil).Code)
+	})
+
+	t.Run("Delete Files And Revoke Tokens", func(t *testing.T) {
+		token, email := setupUserAndToken(t)
+		fileID, _ := uploadFileForTest(t, token, "", "", "", nil)
+		pat := ParseJSON(t, createAPIToken(t, token, `{"name": "ci", "scopes": ["files:read"]}`))["token"].(string)
+
+		rec := adminRequest(t, "DELETE", "/user", token, map[string]string{"password": testUserPassword, "files": "delete"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		_, exists := fileOwner(fileID)
+		assert.False(t, exists)
+		assert.Equal(t, 401, adminRequest(t, "GET", "/user", token, nil).Code)
+		assert.Equal(t, 401, adminRequest(t, "GET", "/files/my", pat, nil).Code)
+		assert.Equal(t, 401, loginForTest(t, email).Code)
+	})
+
+	t.Run("Transfer Files To Admin", func(t *testing.T) {
+		token, _ := setupUserAndToken(t)
+		fileID, _ := uploadFileForTest(t, token, "", "", "", nil)
+
+		rec := adminRequest(t, "DELETE", "/user", token, map[string]string{"password": testUserPassword, "files": "transfer"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		admin := ParseJSON(t, adminRequest(t, "GET", "/user", adminToken, nil))["user"].(map[string]interface{})
+		owner, exists := fileOwner(fileID)
+		assert.True(t, exists)
+		assert.Equal(t, admin["id"], owner)
+	})
+
+	t.Run("Transfer Target Must Be Admin", func(t *testing.T) {
+		token, _ := setupUserAndToken(t)
+		_, otherEmail := setupUserAndToken(t)
+		rec := adminRequest(t, "DELETE", "/user", token, map[string]string{"password": testUserPassword, "files": "transfer", "transferTo": otherEmail})
+		assert.Equal(t, 400, rec.Code)
+	})
+
+	t.Run("Passkey Required", func(t *testing.T) {
+		token, _ := setupUserAndToken(t)
+		authenticator := registerAuthenticator(t, token, "Laptop")
+
+		// Chỉ có password không đủ với tài khoản có passkey
+		rec := adminRequest(t, "DELETE", "/user", token, map[string]string{"password": testUserPassword, "files": "delete"})
+		assert.Equal(t, 401, rec.Code)
+		assert.Equal(t, 200, adminRequest(t, "GET", "/user", token, nil).Code)
+
+		rec = adminRequest(t, "POST", "/auth/webauthn/reauth/begin", token, nil)
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		begin := ParseJSON(t, rec)
+
+		rec = adminRequest(t, "DELETE", "/user", token, map[string]interface{}{
+			"password":           testUserPassword,
+			"files":              "delete",
+			"webAuthnSessionId":  begin["sessionId"],
+			"webAuthnCredential": authenticator.Assert(t, begin["options"].(map[string]interface{})),
+		})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		assert.Equal(t, 401, adminRequest(t, "GET", "/user", token, nil).Code)
+	})
+
+	t.Run("Passkey Of Other User", func(t *testing.T) {
+		token, _ := setupUserAndToken(t)
+		registerAuthenticator(t, token, "Laptop")
+		otherToken, _ := setupUserAndToken(t)
+		otherAuthenticator := registerAuthenticator(t, otherToken, "Phone")
+
+		// Session của user khác không dùng để xóa tài khoản này được
+		begin := ParseJSON(t, adminRequest(t, "POST", "/auth/webauthn/reauth/begin", otherToken, nil))
+		rec := adminRequest(t, "DELETE", "/user", token, map[string]interface{}{
+			"password":           testUserPassword,
+			"files":              "delete",
+			"webAuthnSessionId":  begin["sessionId"],
+			"webAuthnCredential": otherAuthenticator.Assert(t, begin["options"].(map[string]interface{})),
+		})
+		assert.Equal(t, 400, rec.Code)
+		assert.Equal(t, 200, adminRequest(t, "GET", "/user", token, nil).Code)
+	})
+
+	t.Run("SSO Account Without Password", func(t *testing.T) {
+		code, state := TestOIDC.Authorize(t, beginOIDCLogin(t), jwt.MapClaims{
+			"sub":            "delete-me-sub",
+			"email":          "delete-me@corp.test",
+			"email_verified": true,
+		})
+		rec := oidcCallback(t, code, state)
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		token := ParseJSON(t, rec)["accessToken"].(string)
+
+		// Phiên vừa đăng nhập qua SSO thay cho password
+		rec = adminRequest(t, "DELETE", "/user", token, map[string]string{"files": "delete"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		assert.Equal(t, 401, adminRequest(t, "GET", "/user", token, nil).Code)
+	})
+
+	t.Run("Last Admin", func(t *testing.T) {
+		rec := adminRequest(t, "DELETE", "/user", adminToken, map[string]string{"password": testUserPassword, "files": "delete"})
+		assert.Equal(t, 409, rec.Code)
+	})
+}
+
+func TestUser_GetProfileAccess(t *testing.T) {
+	adminToken := setupAdminToken(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, _ := setupUserAndToken(t)
+	otherToken, otherEmail := setupUserAndToken(t)
+
+	userID := func(token string) string {
+		rec := adminRequest(t, "GET", "/user", token, nil)
+		return ParseJSON(t, rec)["user"].(map[string]interface{})["id"].(string)
+	}
+	myID, otherID := userID(token), userID(otherToken)
+
+	t.Run("Self", func(t *testing.T) {
+		assert.Equal(t, 200, adminRequest(t, "GET", "/user/"+myID, token, nil).Code)
+	})
+
+	t.Run("Other User Forbidden", func(t *testing.T) {
+		assert.Equal(t, 403, adminRequest(t, "GET", "/user/"+otherID, token, nil).Code)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		rec := adminRequest(t, "GET", "/user/"+otherID, adminToken, nil)
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, otherEmail, ParseJSON(t, rec)["user"].(map[string]interface{})["email"])
+
+		rec = adminRequest(t, "GET", "/user/00000000-0000-0000-0000-000000000000", adminToken, nil)
+		assert.Equal(t, 404, rec.Code)
+	})
+}
+
+func TestUser_Search(t *testing.T) {
+	adminToken := setupAdminToken(t)
+	t.Cleanup(func() {
+		adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]interface{}{"userDirectoryMode": "domain"})
+		ResetDB(t)
+	})
+
+	token, _ := setupUserAndToken(t)
+	_, colleagueEmail := setupUserAndToken(t)
+
+	registerForTest(t, "user_outsider", "user_outsider@other.test", "")
+	verifyEmailForTest(t, "user_outsider@other.test")
+
+	search := func(token, q string) (int, []string) {
+		rec := adminRequest(t, "GET", "/users/search?q="+q, token, nil)
+		emails := []string{}
+		if users, ok := ParseJSON(t, rec)["users"].([]interface{}); ok {
+			for _, u := range users {
+				profile := u.(map[string]interface{})
+				assert.NotContains(t, profile, "role")
+				emails = append(emails, profile["email"].(string))
+			}
+		}
+		return rec.Code, emails
+	}
+
+	t.Run("Same Domain Only", func(t *testing.T) {
+		code, emails := search(token, "user_")
+		assert.Equal(t, 200, code)
+		assert.Contains(t, emails, colleagueEmail)
+		assert.NotContains(t, emails, "user_outsider@other.test")
+	})
+
+	t.Run("Query Too Short", func(t *testing.T) {
+		code, _ := search(token, "u")
+		assert.Equal(t, 400, code)
+	})
+
+	t.Run("Wildcards Are Literal", func(t *testing.T) {
+		_, emails := search(token, "%25%25")
+		assert.Empty(t, emails)
+	})
+
+	t.Run("Exact Mode", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]interface{}{"userDirectoryMode": "exact"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		_, emails := search(token, "user_")
+		assert.Empty(t, emails)
+
+		_, emails = search(token, "user_outsider@other.test")
+		assert.Equal(t, []string{"user_outsider@other.test"}, emails)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		rec := adminRequest(t, "PATCH", "/admin/policy", adminToken, map[string]interface{}{"userDirectoryMode": "off"})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+
+		code, _ := search(token, "user_")
+		assert.Equal(t, 403, code)
+
+		// Admin vẫn tìm được mọi user
+		code, emails := search(adminToken, "user_")
+		assert.Equal(t, 200, code)
+		assert.Contains(t, emails, "user_outsider@other.test")
+	})
+}
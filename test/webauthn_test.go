@@ -0,0 +1,213 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func webAuthnRequest(t *testing.T, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	TestApp.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+// registerAuthenticator chạy ceremony đăng ký và trả về authenticator đã gắn với user.
+func registerAuthenticator(t *testing.T, token, name string) *softAuthenticator {
+	t.Helper()
+
+	rec := webAuthnRequest(t, "POST", "/auth/webauthn/register/begin", token, map[string]string{"name": name})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Register begin failed: %d %s", rec.Code, rec.Body.String())
+	}
+	begin := ParseJSON(t, rec)
+
+	authenticator := newSoftAuthenticator(t)
+	rec = webAuthnRequest(t, "POST", "/auth/webauthn/register/finish", token, map[string]interface{}{
+		"sessionId":  begin["sessionId"],
+		"credential": authenticator.Register(t, begin["options"].(map[string]interface{})),
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Register finish failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	return authenticator
+}
+
+func TestWebAuthn_Credentials(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, _ := setupUserAndToken(t)
+	registerAuthenticator(t, token, "YubiKey")
+	registerAuthenticator(t, token, "")
+
+	rec := webAuthnRequest(t, "GET", "/auth/webauthn/credentials", token, nil)
+	assert.Equal(t, 200, rec.Code)
+	credentials := ParseJSON(t, rec)["credentials"].([]interface{})
+	assert.Len(t, credentials, 2)
+	assert.Equal(t, "YubiKey", credentials[0].(map[string]interface{})["name"])
+	assert.Equal(t, "Authenticator 2", credentials[1].(map[string]interface{})["name"])
+
+	t.Run("Session Is Single Use", func(t *testing.T) {
+		rec := webAuthnRequest(t, "POST", "/auth/webauthn/register/begin", token, map[string]string{})
+		begin := ParseJSON(t, rec)
+		credential := newSoftAuthenticator(t).Register(t, begin["options"].(map[string]interface{}))
+		body := map[string]interface{}{"sessionId": begin["sessionId"], "credential": credential}
+
+		assert.Equal(t, 201, webAuthnRequest(t, "POST", "/auth/webauthn/register/finish", token, body).Code)
+		assert.Equal(t, 400, webAuthnRequest(t, "POST", "/auth/webauthn/register/finish", token, body).Code)
+	})
+
+	t.Run("Other User Cannot Delete", func(t *testing.T) {
+		otherToken, _ := setupUserAndToken(t)
+		id := credentials[0].(map[string]interface{})["id"].(string)
+		rec := webAuthnRequest(t, "DELETE", "/auth/webauthn/credentials/"+id, otherToken, nil)
+		assert.Equal(t, 404, rec.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		id := credentials[0].(map[string]interface{})["id"].(string)
+		rec := webAuthnRequest(t, "DELETE", "/auth/webauthn/credentials/"+id, token, nil)
+		assert.Equal(t, 200, rec.Code)
+
+		rec = webAuthnRequest(t, "GET", "/auth/webauthn/credentials", token, nil)
+		assert.Len(t, ParseJSON(t, rec)["credentials"].([]interface{}), 2)
+	})
+}
+
+func TestWebAuthn_SecondFactor(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, email := setupUserAndToken(t)
+	authenticator := registerAuthenticator(t, token, "Laptop")
+
+	login := func(t *testing.T) string {
+		t.Helper()
+		rec := webAuthnRequest(t, "POST", "/auth/login", "", map[string]string{"email": email, "password": testUserPassword})
+		assert.Equal(t, 200, rec.Code)
+		resp := ParseJSON(t, rec)
+		assert.Equal(t, true, resp["requireWebAuthn"])
+		assert.Equal(t, false, resp["requireTOTP"])
+		assert.Nil(t, resp["accessToken"])
+		return resp["cid"].(string)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		cid := login(t)
+
+		rec := webAuthnRequest(t, "POST", "/auth/login/webauthn/begin", "", map[string]string{"cid": cid})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		begin := ParseJSON(t, rec)
+
+		rec = webAuthnRequest(t, "POST", "/auth/login/webauthn", "", map[string]interface{}{
+			"cid":        cid,
+			"sessionId":  begin["sessionId"],
+			"credential": authenticator.Assert(t, begin["options"].(map[string]interface{})),
+		})
+		assert.Equal(t, 200, rec.Code, rec.Body.String())
+		assert.NotEmpty(t, ParseJSON(t, rec)["accessToken"])
+
+		rec = webAuthnRequest(t, "GET", "/auth/webauthn/credentials", token, nil)
+		credential := ParseJSON(t, rec)["credentials"].([]interface{})[0].(map[string]interface{})
+		assert.NotNil(t, credential["lastUsedAt"])
+	})
+
+	t.Run("Unknown Authenticator", func(t *testing.T) {
+		cid := login(t)
+		begin := ParseJSON(t, webAuthnRequest(t, "POST", "/auth/login/webauthn/begin", "", map[string]string{"cid": cid}))
+
+		rec := webAuthnRequest(t, "POST", "/auth/login/webauthn", "", map[string]interface{}{
+			"cid":        cid,
+			"sessionId":  begin["sessionId"],
+			"credential": newSoftAuthenticator(t).Assert(t, begin["options"].(map[string]interface{})),
+		})
+		assert.Equal(t, 401, rec.Code)
+	})
+
+	t.Run("TOTP Cannot Replace Passkey", func(t *testing.T) {
+		cid := login(t)
+
+		// Secret TOTP của tài khoản chỉ có passkey là rỗng, mã của secret rỗng ai cũng tính được
+		code, err := totp.GenerateCode("", time.Now())
+		assert.NoError(t, err)
+
+		rec := webAuthnRequest(t, "POST", "/auth/login/totp", "", map[string]string{"cid": cid, "code": code})
+		assert.Equal(t, 401, rec.Code)
+		assert.Nil(t, ParseJSON(t, rec)["accessToken"])
+	})
+
+	t.Run("Wrong CID", func(t *testing.T) {
+		cid := login(t)
+		begin := ParseJSON(t, webAuthnRequest(t, "POST", "/auth/login/webauthn/begin", "", map[string]string{"cid": cid}))
+
+		rec := webAuthnRequest(t, "POST", "/auth/login/webauthn", "", map[string]interface{}{
+			"cid":        "not-the-cid",
+			"sessionId":  begin["sessionId"],
+			"credential": authenticator.Assert(t, begin["options"].(map[string]interface{})),
+		})
+		assert.Equal(t, 401, rec.Code)
+	})
+}
+
+func TestWebAuthn_PasskeyLogin(t *testing.T) {
+	ResetDB(t)
+	t.Cleanup(func() { ResetDB(t) })
+
+	token, email := setupUserAndToken(t)
+	authenticator := registerAuthenticator(t, token, "Phone")
+
+	rec := webAuthnRequest(t, "POST", "/auth/passkey/begin", "", nil)
+	assert.Equal(t, 200, rec.Code, rec.Body.String())
+	begin := ParseJSON(t, rec)
+	body := map[string]interface{}{
+		"sessionId":  begin["sessionId"],
+		"credential": authenticator.Assert(t, begin["options"].(map[string]interface{})),
+	}
+
+	rec = webAuthnRequest(t, "POST", "/auth/passkey/login", "", body)
+	assert.Equal(t, 200, rec.Code, rec.Body.String())
+	resp := ParseJSON(t, rec)
+	assert.NotEmpty(t, resp["accessToken"])
+	assert.Equal(t, email, resp["user"].(map[string]interface{})["email"])
+
+	// Challenge chỉ dùng được một lần
+	rec = webAuthnRequest(t, "POST", "/auth/passkey/login", "", body)
+	assert.Equal(t, 400, rec.Code)
+
+	t.Run("Removed Authenticator", func(t *testing.T) {
+		list := ParseJSON(t, webAuthnRequest(t, "GET", "/auth/webauthn/credentials", token, nil))["credentials"].([]interface{})
+		id := list[0].(map[string]interface{})["id"].(string)
+		assert.Equal(t, 200, webAuthnRequest(t, "DELETE", fmt.Sprintf("/auth/webauthn/credentials/%s", id), token, nil).Code)
+
+		begin := ParseJSON(t, webAuthnRequest(t, "POST", "/auth/passkey/begin", "", nil))
+		rec := webAuthnRequest(t, "POST", "/auth/passkey/login", "", map[string]interface{}{
+			"sessionId":  begin["sessionId"],
+			"credential": authenticator.Assert(t, begin["options"].(map[string]interface{})),
+		})
+		assert.Equal(t, 401, rec.Code)
+	})
+}